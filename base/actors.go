@@ -23,8 +23,10 @@
 package base
 
 import (
+	"context"
 	"encoding/json"
 	"io"
+	"time"
 )
 
 type ActionContextRunStatus int
@@ -55,6 +57,9 @@ type NextAction struct {
 	NextOkFalse []*Action
 	//
 	NextKo []*Action
+	// Filled internally. Cleanup actions of a group, run
+	// after the end of the execution if the group ran
+	NextFinally []*Action
 	// filled internally
 	// detect loops through ok and ko
 	NextOkLoop bool
@@ -62,6 +67,8 @@ type NextAction struct {
 	// Parsed in precompiling.
 	Ok json.RawMessage `json:"ok"`
 	Ko json.RawMessage `json:"ko"`
+	// Only allowed in group actions
+	Finally json.RawMessage `json:"finally"`
 }
 
 // Action struct (should be interface? :shrug:)
@@ -72,6 +79,12 @@ type Action struct {
 	DebugPoint       bool
 	KnowParentIDs    map[string]bool
 	SafeID           *string
+	// First action of the body run by a foreach action
+	BodyAction *Action
+	// The action runs other actions (call_blueprint, foreach)
+	SubRoutine bool
+	// How the branches are joined, nil for all of them
+	JoinPolicy *JoinPolicy
 	// GENERICS //
 	Provider    string     `json:"provider" validate:"required"`
 	ActionID    string     `json:"action_id" validate:"required"`
@@ -87,6 +100,12 @@ type Action struct {
 	Output         *string `json:"output"`
 	SaveRawResults bool    `json:"save_raw_results"`
 	DebugNetwork   bool    `json:"debug_network"`
+	// Max run time, zero for no timeout. On timeout the action
+	// is canceled and goes out through his KO port
+	Timeout ActionTimeout `json:"timeout"`
+	// Retry policy. If set, the error hooks of the
+	// providers are not used to retry the action
+	Retry *RetryPolicy `json:"retry"`
 	// Not documented
 	MaxRetries *int `json:"max_retries"`
 	RetryCount int
@@ -107,7 +126,10 @@ type IActionContext interface {
 	// SetProvider(IProvider)
 	// GetProvider() IProvider
 	Done() <-chan struct{}
-	WithCancelCause()
+	// the context of the running action, canceled on
+	// timeout, stop or action end
+	Context() context.Context
+	WithCancelCause(parent context.Context, timeout time.Duration, cause error)
 	WithEventListener(*EventListener)
 	EventListener() *EventListener
 	Cancel(error)
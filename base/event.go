@@ -24,6 +24,7 @@ package base
 
 import (
 	"sync"
+	"sync/atomic"
 )
 
 type EventCode int
@@ -53,11 +54,11 @@ type EventListener struct {
 	// called. If not, this reading is
 	// false and all events sended to
 	// this listener will be discarded
-	reading bool
+	reading atomic.Bool
 }
 
 func (e *EventListener) EventChan() chan IEvent {
-	if !e.reading {
+	if !e.reading.Load() {
 		go func() {
 			<-e.discard
 		}()
@@ -76,11 +77,11 @@ func (e *EventListener) EventChan() chan IEvent {
 // true if EventCode gets found. Return false if events
 // chan gets empty without any ocurrence of EventCode
 func (e *EventListener) ReadUntil(ec EventCode) bool {
-	if e.reading {
+	if e.reading.Load() {
 		panic("hey dev, this is your fault, never call listener two times!")
 	}
-	e.reading = true
-	defer func() { e.reading = false }()
+	e.reading.Store(true)
+	defer func() { e.reading.Store(false) }()
 	for {
 		select {
 		case evt := <-e.events:
@@ -97,11 +98,15 @@ func (e *EventListener) ReadUntil(ec EventCode) bool {
 // Waits for ocurrence of any of given EventCode, returns
 // the first EventCode found
 func (e *EventListener) WaitUntil(ecs []EventCode) EventCode {
-	if e.reading {
+	if e.reading.Load() {
 		panic("hey dev, this is your fault, never call listener two times!")
 	}
-	e.reading = true
-	defer func() { e.reading = false }()
+	e.reading.Store(true)
+	defer func() { e.reading.Store(false) }()
+	return e.waitUntil(ecs)
+}
+
+func (e *EventListener) waitUntil(ecs []EventCode) EventCode {
 	for {
 		evt := <-e.events
 		for _, ec := range ecs {
@@ -113,11 +118,17 @@ func (e *EventListener) WaitUntil(ecs []EventCode) EventCode {
 }
 
 // WaitUntilChan same as WaitUntilChan but it returns a chan that will be
-// filled on event reach
+// filled on event reach. The listener is reading from now on, the events
+// dispatched before the wait starts are not discarded
 func (e *EventListener) WaitUntilChan(ecs []EventCode) chan struct{} {
+	if e.reading.Load() {
+		panic("hey dev, this is your fault, never call listener two times!")
+	}
+	e.reading.Store(true)
 	c := make(chan struct{})
 	go func() {
-		e.WaitUntil(ecs)
+		defer func() { e.reading.Store(false) }()
+		e.waitUntil(ecs)
 		c <- struct{}{}
 	}()
 	return c
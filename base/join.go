@@ -0,0 +1,112 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package base
+
+const (
+	// JoinModeAll waits for every branch (default)
+	JoinModeAll = "all"
+	// JoinModeAny goes on with the first branch to arrive,
+	// the others are canceled
+	JoinModeAny = "any"
+	// JoinModeCount goes on once Count branches arrive,
+	// the others are canceled
+	JoinModeCount = "count"
+)
+
+const (
+	// JoinMergeLast keeps the output of the last branch to
+	// arrive when several branches write it (default)
+	JoinMergeLast = "last"
+	// JoinMergeStack collects the outputs of all the branches
+	// into a StorageRecordStack
+	JoinMergeStack = "stack"
+	// JoinMergeNamespace turns the output into an object with the
+	// value of each branch, by the ID of the action that wrote it
+	JoinMergeNamespace = "namespace"
+	// JoinMergeFail makes the join fail
+	JoinMergeFail = "fail"
+)
+
+// JoinPolicy struct. How a join_threads action waits for his
+// branches and merges their outputs. The timeout of the action
+// limits the wait, on timeout the join goes out through his KO port
+type JoinPolicy struct {
+	Mode  string `json:"mode"`
+	Count int    `json:"count"`
+	// how to merge the outputs written by more than one
+	// branch, one of the JoinMerge consts
+	Merge string `json:"merge"`
+}
+
+// Needed func. The number of branches to wait for,
+// zero for all of them
+func (p *JoinPolicy) Needed() int {
+	if p == nil {
+		return 0
+	}
+	switch p.Mode {
+	case JoinModeAny:
+		return 1
+	case JoinModeCount:
+		return p.Count
+	}
+	return 0
+}
+
+// MergeStrategy func. JoinMergeLast if not set
+func (p *JoinPolicy) MergeStrategy() string {
+	if p == nil || p.Merge == "" {
+		return JoinMergeLast
+	}
+	return p.Merge
+}
+
+// MergeConflict struct. An output written by two actions of
+// different branches of a join
+type MergeConflict struct {
+	RefName string
+	// action of the output kept by the join point
+	ActionID string
+	// action of the output of the arriving branch
+	SourceActionID string
+}
+
+// IsAncestor func. Tells if ancestor runs before action,
+// following the parents of action
+func IsAncestor(ancestor *Action, action *Action) bool {
+	seen := make(map[*Action]bool)
+	queue := append([]*Action(nil), action.Parents...)
+	for len(queue) > 0 {
+		a := queue[0]
+		queue = queue[1:]
+		if a == ancestor || a.ActionID == ancestor.ActionID {
+			return true
+		}
+		if seen[a] {
+			continue
+		}
+		seen[a] = true
+		queue = append(queue, a.Parents...)
+	}
+	return false
+}
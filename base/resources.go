@@ -0,0 +1,95 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package base
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// ResourceAction struct. An action to run on teardown
+type ResourceAction struct {
+	Action     string          `json:"action"`
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+// Resource struct. A resource created by an action and the
+// actions that delete it
+type Resource struct {
+	Provider string `json:"provider"`
+	// instance, volume, server...
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	// id of the action that created the resource
+	CreatedBy string `json:"created_by"`
+	// run in order to delete the resource
+	Teardown []*ResourceAction `json:"teardown"`
+	Deleted  bool              `json:"deleted,omitempty"`
+}
+
+// IResourceProvider interface. Implemented by the providers that
+// report the resources created and deleted by their actions
+type IResourceProvider interface {
+	// the resource created by the action, nil if none
+	CreatedResource(action *Action, aout *ActionOutput) *Resource
+	// ids of the resources deleted by the action
+	DeletedResources(action *Action) []string
+	// the action creates a resource, so a failed run of it
+	// could have left a resource behind
+	CreatesResource(action *Action) bool
+}
+
+// ParameterIDs func. The ids found in the key parameter of the
+// action, a single id or a list of them
+func ParameterIDs(params []byte, key string) []string {
+	var p map[string]interface{}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+	var ids []string
+	switch v := p[key].(type) {
+	case string:
+		ids = append(ids, v)
+	case float64:
+		ids = append(ids, strconv.FormatFloat(v, 'f', -1, 64))
+	case []interface{}:
+		for _, item := range v {
+			switch id := item.(type) {
+			case string:
+				ids = append(ids, id)
+			case float64:
+				ids = append(ids, strconv.FormatFloat(id, 'f', -1, 64))
+			}
+		}
+	}
+	return ids
+}
+
+// NewResourceAction func
+func NewResourceAction(action string, params interface{}) (*ResourceAction, error) {
+	enc, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceAction{Action: action, Parameters: enc}, nil
+}
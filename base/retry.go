@@ -0,0 +1,235 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package base
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"regexp"
+	"time"
+)
+
+const (
+	// RetryBackoffFixed waits Delay between attempts
+	RetryBackoffFixed = "fixed"
+	// RetryBackoffExponential doubles the wait on every attempt
+	RetryBackoffExponential = "exponential"
+	// RetryBackoffJitter waits a random time between Delay and three
+	// times the previous wait (decorrelated jitter)
+	RetryBackoffJitter = "jitter"
+)
+
+// DefaultRetryMaxAttempts const. Attempts made when the retry
+// block has no max_attempts
+const DefaultRetryMaxAttempts = 3
+
+// RetryMatcher struct. Errors to retry on. An error matches if
+// any of the set fields matches
+type RetryMatcher struct {
+	// http status codes of the response
+	HTTPStatus []int `json:"http_status"`
+	// error codes of the aws api (Throttling, RequestLimitExceeded...)
+	AWSCode []string `json:"aws_code"`
+	// network timeouts and action timeouts
+	Timeout bool `json:"timeout"`
+	// regular expression matched against the error message
+	Message string `json:"message"`
+	re      *regexp.Regexp
+}
+
+// RetryPolicy struct. The retry block of an action. Without
+// matchers every error is retried
+type RetryPolicy struct {
+	// attempts including the first one
+	MaxAttempts int    `json:"max_attempts"`
+	Backoff     string `json:"backoff"`
+	// wait after the first failed attempt, 1s by default
+	Delay ActionTimeout `json:"delay"`
+	// max wait between attempts, zero for no limit
+	MaxDelay ActionTimeout   `json:"max_delay"`
+	RetryOn  []*RetryMatcher `json:"retry_on"`
+}
+
+// Validate func. Check the policy and compile the message matchers
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("retry max_attempts cannot be negative")
+	}
+	switch p.Backoff {
+	case "", RetryBackoffFixed, RetryBackoffExponential, RetryBackoffJitter:
+	default:
+		return fmt.Errorf("unknown retry backoff %q, use fixed, exponential or jitter", p.Backoff)
+	}
+	if p.Delay < 0 || p.MaxDelay < 0 {
+		return fmt.Errorf("retry delays cannot be negative")
+	}
+	for _, m := range p.RetryOn {
+		if m.Message == "" {
+			continue
+		}
+		re, err := regexp.Compile(m.Message)
+		if err != nil {
+			return fmt.Errorf("invalid retry_on message %q: %v", m.Message, err)
+		}
+		m.re = re
+	}
+	return nil
+}
+
+// Attempts func. The max number of attempts
+func (p *RetryPolicy) Attempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultRetryMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+// Match func. True if the error should be retried
+func (p *RetryPolicy) Match(err error) bool {
+	if err == nil {
+		return false
+	}
+	var terr *TimeoutError
+	if errors.As(err, &terr) && terr.Global {
+		// the execution is over
+		return false
+	}
+	if len(p.RetryOn) <= 0 {
+		return true
+	}
+	for _, m := range p.RetryOn {
+		if m.Match(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextDelay func. The wait before the next attempt. attempt is
+// the number of the failed attempt, prev the previous wait
+func (p *RetryPolicy) NextDelay(attempt int, prev time.Duration) time.Duration {
+	delay := p.Delay.Duration()
+	if delay <= 0 {
+		delay = time.Second
+	}
+	d := delay
+	switch p.Backoff {
+	case RetryBackoffExponential, "":
+		f := float64(delay) * math.Pow(2, float64(attempt-1))
+		d = time.Duration(math.MaxInt64)
+		if f < float64(math.MaxInt64) {
+			d = time.Duration(f)
+		}
+	case RetryBackoffJitter:
+		upper := prev * 3
+		if upper <= delay {
+			upper = delay * 3
+		}
+		d = delay + time.Duration(rand.Int63n(int64(upper-delay))) // #nosec G404 -- Weak random is OK here
+	}
+	if max := p.MaxDelay.Duration(); max > 0 && d > max {
+		d = max
+	}
+	return d
+}
+
+// Match func
+func (m *RetryMatcher) Match(err error) bool {
+	if len(m.HTTPStatus) > 0 {
+		if status, ok := errorHTTPStatus(err); ok {
+			for _, s := range m.HTTPStatus {
+				if s == status {
+					return true
+				}
+			}
+		}
+	}
+	if len(m.AWSCode) > 0 {
+		if code, ok := errorAWSCode(err); ok {
+			for _, c := range m.AWSCode {
+				if c == code {
+					return true
+				}
+			}
+		}
+	}
+	if m.Timeout {
+		var nerr net.Error
+		if errors.As(err, &nerr) && nerr.Timeout() {
+			return true
+		}
+		var terr *TimeoutError
+		if errors.As(err, &terr) {
+			return true
+		}
+	}
+	if m.Message != "" {
+		if m.re == nil {
+			re, rerr := regexp.Compile(m.Message)
+			if rerr != nil {
+				return false
+			}
+			m.re = re
+		}
+		if m.re.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorHTTPStatus func. The http status of the response that
+// caused the error, if the error has one (aws sdk v1 and v2)
+func errorHTTPStatus(err error) (int, bool) {
+	var v1 interface{ StatusCode() int }
+	if errors.As(err, &v1) {
+		return v1.StatusCode(), true
+	}
+	var v2 interface{ HTTPStatusCode() int }
+	if errors.As(err, &v2) {
+		return v2.HTTPStatusCode(), true
+	}
+	return 0, false
+}
+
+// errorAWSCode func. The aws api error code (aws sdk v1 and v2)
+func errorAWSCode(err error) (string, bool) {
+	var v1 interface {
+		Code() string
+		OrigErr() error
+	}
+	if errors.As(err, &v1) {
+		return v1.Code(), true
+	}
+	var v2 interface {
+		ErrorCode() string
+		ErrorMessage() string
+	}
+	if errors.As(err, &v2) {
+		return v2.ErrorCode(), true
+	}
+	return "", false
+}
@@ -0,0 +1,196 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package base_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/develatio/nebulant-cli/base"
+)
+
+// httpError fakes an aws sdk v1 request failure
+type httpError struct {
+	status int
+}
+
+func (e *httpError) Error() string   { return fmt.Sprintf("status %d", e.status) }
+func (e *httpError) StatusCode() int { return e.status }
+
+// awsV1Error fakes an aws sdk v1 awserr.Error
+type awsV1Error struct {
+	code string
+}
+
+func (e *awsV1Error) Error() string   { return e.code + ": api error" }
+func (e *awsV1Error) Code() string    { return e.code }
+func (e *awsV1Error) OrigErr() error  { return nil }
+func (e *awsV1Error) Message() string { return "api error" }
+
+// awsV2Error fakes an aws sdk v2 smithy.APIError
+type awsV2Error struct {
+	code string
+}
+
+func (e *awsV2Error) Error() string        { return e.code + ": api error" }
+func (e *awsV2Error) ErrorCode() string    { return e.code }
+func (e *awsV2Error) ErrorMessage() string { return "api error" }
+
+// netTimeoutError fakes a net.Error timeout
+type netTimeoutError struct{}
+
+func (e *netTimeoutError) Error() string   { return "i/o timeout" }
+func (e *netTimeoutError) Timeout() bool   { return true }
+func (e *netTimeoutError) Temporary() bool { return true }
+
+func TestRetryNextDelay(t *testing.T) {
+	tests := []struct {
+		policy   *base.RetryPolicy
+		attempt  int
+		expected time.Duration
+	}{
+		// exponential by default, 1s delay by default
+		{&base.RetryPolicy{}, 1, time.Second},
+		{&base.RetryPolicy{}, 3, 4 * time.Second},
+		{&base.RetryPolicy{Backoff: base.RetryBackoffFixed, Delay: base.ActionTimeout(2 * time.Second)}, 1, 2 * time.Second},
+		{&base.RetryPolicy{Backoff: base.RetryBackoffFixed, Delay: base.ActionTimeout(2 * time.Second)}, 5, 2 * time.Second},
+		{&base.RetryPolicy{Backoff: base.RetryBackoffExponential, Delay: base.ActionTimeout(100 * time.Millisecond)}, 4, 800 * time.Millisecond},
+		{&base.RetryPolicy{Backoff: base.RetryBackoffExponential, Delay: base.ActionTimeout(time.Second), MaxDelay: base.ActionTimeout(5 * time.Second)}, 4, 5 * time.Second},
+		// no overflow
+		{&base.RetryPolicy{Backoff: base.RetryBackoffExponential, Delay: base.ActionTimeout(time.Second), MaxDelay: base.ActionTimeout(time.Minute)}, 200, time.Minute},
+	}
+	for _, tt := range tests {
+		if d := tt.policy.NextDelay(tt.attempt, 0); d != tt.expected {
+			t.Errorf("%s attempt %d: expected %v, got %v", tt.policy.Backoff, tt.attempt, tt.expected, d)
+		}
+	}
+}
+
+func TestRetryNextDelayJitter(t *testing.T) {
+	delay := 100 * time.Millisecond
+	p := &base.RetryPolicy{Backoff: base.RetryBackoffJitter, Delay: base.ActionTimeout(delay)}
+	prev := time.Duration(0)
+	for attempt := 1; attempt <= 50; attempt++ {
+		d := p.NextDelay(attempt, prev)
+		upper := prev * 3
+		if upper <= delay {
+			upper = delay * 3
+		}
+		if d < delay || d >= upper {
+			t.Fatalf("attempt %d: %v out of [%v, %v)", attempt, d, delay, upper)
+		}
+		prev = d
+	}
+
+	p.MaxDelay = base.ActionTimeout(150 * time.Millisecond)
+	prev = time.Hour
+	for i := 0; i < 50; i++ {
+		if d := p.NextDelay(2, prev); d < delay || d > 150*time.Millisecond {
+			t.Fatalf("%v out of [%v, %v]", d, delay, 150*time.Millisecond)
+		}
+	}
+}
+
+func TestRetryPolicyMatch(t *testing.T) {
+	all := &base.RetryPolicy{}
+	if all.Match(nil) {
+		t.Error("nil error matched")
+	}
+	if !all.Match(fmt.Errorf("any error")) {
+		t.Error("without matchers every error should be retried")
+	}
+	if !all.Match(&base.TimeoutError{Timeout: time.Second}) {
+		t.Error("action timeout not retried")
+	}
+	if all.Match(&base.TimeoutError{Timeout: time.Second, Global: true}) {
+		t.Error("global timeout retried")
+	}
+	if all.Match(fmt.Errorf("wrapped: %w", &base.TimeoutError{Global: true})) {
+		t.Error("wrapped global timeout retried")
+	}
+
+	p := &base.RetryPolicy{RetryOn: []*base.RetryMatcher{
+		{HTTPStatus: []int{503}},
+		{Message: "(?i)rate exceeded"},
+	}}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Match(&httpError{status: 503}) {
+		t.Error("503 not retried")
+	}
+	if !p.Match(fmt.Errorf("Rate Exceeded")) {
+		t.Error("message not retried")
+	}
+	if p.Match(&httpError{status: 400}) {
+		t.Error("400 retried")
+	}
+}
+
+func TestRetryMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		matcher  *base.RetryMatcher
+		err      error
+		expected bool
+	}{
+		{"http status", &base.RetryMatcher{HTTPStatus: []int{429, 503}}, &httpError{status: 429}, true},
+		{"wrapped http status", &base.RetryMatcher{HTTPStatus: []int{503}}, fmt.Errorf("request: %w", &httpError{status: 503}), true},
+		{"other http status", &base.RetryMatcher{HTTPStatus: []int{503}}, &httpError{status: 500}, false},
+		{"no http status", &base.RetryMatcher{HTTPStatus: []int{503}}, fmt.Errorf("503"), false},
+		{"aws v1 code", &base.RetryMatcher{AWSCode: []string{"Throttling"}}, &awsV1Error{code: "Throttling"}, true},
+		{"aws v2 code", &base.RetryMatcher{AWSCode: []string{"RequestLimitExceeded"}}, &awsV2Error{code: "RequestLimitExceeded"}, true},
+		{"other aws code", &base.RetryMatcher{AWSCode: []string{"Throttling"}}, &awsV1Error{code: "InvalidParameter"}, false},
+		{"action timeout", &base.RetryMatcher{Timeout: true}, &base.TimeoutError{Timeout: time.Second}, true},
+		{"net timeout", &base.RetryMatcher{Timeout: true}, fmt.Errorf("dial: %w", &netTimeoutError{}), true},
+		{"deadline exceeded", &base.RetryMatcher{Timeout: true}, context.DeadlineExceeded, true},
+		{"no timeout", &base.RetryMatcher{Timeout: true}, errors.New("refused"), false},
+		{"message", &base.RetryMatcher{Message: "^connection (reset|refused)"}, errors.New("connection reset by peer"), true},
+		{"other message", &base.RetryMatcher{Message: "^connection (reset|refused)"}, errors.New("no connection"), false},
+		{"invalid message", &base.RetryMatcher{Message: "("}, errors.New("("), false},
+		{"empty", &base.RetryMatcher{}, errors.New("any"), false},
+	}
+	for _, tt := range tests {
+		if m := tt.matcher.Match(tt.err); m != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, m)
+		}
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	for _, p := range []*base.RetryPolicy{
+		{MaxAttempts: -1},
+		{Backoff: "linear"},
+		{Delay: base.ActionTimeout(-time.Second)},
+		{RetryOn: []*base.RetryMatcher{{Message: "("}}},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("%+v: expected error", p)
+		}
+	}
+	if a := (&base.RetryPolicy{}).Attempts(); a != base.DefaultRetryMaxAttempts {
+		t.Errorf("expected %d attempts, got %d", base.DefaultRetryMaxAttempts, a)
+	}
+}
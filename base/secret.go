@@ -0,0 +1,168 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package base
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SecretMask replaces the secret values
+const SecretMask = "********"
+
+// shorter values are not redacted, they would
+// mask too much unrelated text
+const secretMinLen = 4
+
+// how deep the values are walked looking for secrets
+const secretMaxDepth = 12
+
+// names of the vars and fields whose values are secret
+var secretNameRegexp = regexp.MustCompile(`(?i)(password|passwd|passphrase|privkey|private_?key|secret)`)
+
+var secrets = &secretRegistry{values: make(map[string]bool)}
+
+type secretRegistry struct {
+	mu       sync.RWMutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+// IsSecretName func. True if the var or field name looks
+// like a secret one (password, privkey, passphrase...)
+func IsSecretName(name string) bool {
+	return secretNameRegexp.MatchString(name)
+}
+
+// AddSecret func. Register a secret value, from now on it is
+// redacted from the logs, events, reports and debugger output
+func AddSecret(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < secretMinLen {
+		return
+	}
+	secrets.mu.Lock()
+	defer secrets.mu.Unlock()
+	if secrets.values[value] {
+		return
+	}
+	secrets.values[value] = true
+	// longest first, so a secret containing
+	// another one is fully masked
+	vals := make([]string, 0, len(secrets.values))
+	for v := range secrets.values {
+		vals = append(vals, v)
+	}
+	sort.Slice(vals, func(i, j int) bool { return len(vals[i]) > len(vals[j]) })
+	oldnew := make([]string, 0, len(vals)*2)
+	for _, v := range vals {
+		oldnew = append(oldnew, v, SecretMask)
+	}
+	secrets.replacer = strings.NewReplacer(oldnew...)
+}
+
+// Redact func. Replace the registered secret values of s
+func Redact(s string) string {
+	secrets.mu.RLock()
+	defer secrets.mu.RUnlock()
+	if secrets.replacer == nil {
+		return s
+	}
+	return secrets.replacer.Replace(s)
+}
+
+// CollectSecrets func. Register the secret values of v. If secret is
+// true every string of v is a secret, otherwise only the values of
+// the map keys and struct fields with secret names are. JSON objects
+// into strings are also walked
+func CollectSecrets(v interface{}, secret bool) {
+	if v == nil {
+		return
+	}
+	collectSecrets(reflect.ValueOf(v), secret, 0)
+}
+
+func collectSecrets(v reflect.Value, secret bool, depth int) {
+	if !v.IsValid() || depth > secretMaxDepth {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if !v.IsNil() {
+			collectSecrets(v.Elem(), secret, depth+1)
+		}
+	case reflect.String:
+		collectStringSecrets(v.String(), secret, depth)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag != "" && tag != "-" {
+				name = tag
+			}
+			collectSecrets(v.Field(i), secret || IsSecretName(name), depth+1)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			k := iter.Key()
+			s := secret
+			if k.Kind() == reflect.String && IsSecretName(k.String()) {
+				s = true
+			}
+			collectSecrets(iter.Value(), s, depth+1)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// raw json or bytes
+			if v.Kind() == reflect.Slice {
+				collectStringSecrets(string(v.Bytes()), secret, depth)
+			}
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			collectSecrets(v.Index(i), secret, depth+1)
+		}
+	}
+}
+
+func collectStringSecrets(s string, secret bool, depth int) {
+	if secret {
+		AddSecret(s)
+		return
+	}
+	if strings.HasPrefix(strings.TrimSpace(s), "{") {
+		var obj map[string]interface{}
+		if json.Unmarshal([]byte(s), &obj) == nil {
+			collectSecrets(reflect.ValueOf(obj), false, depth+1)
+		}
+	}
+}
@@ -161,6 +161,32 @@ type StorageRecordStack struct {
 	Items []interface{}
 }
 
+// StorageRecordBranches type. Value of an output written by several
+// branches of a join with the namespace merge, by writer action ID
+type StorageRecordBranches map[string]interface{}
+
+// StorageRecordSnapshot struct. The serializable part of a
+// StorageRecord and the store indexes where the record is
+type StorageRecordSnapshot struct {
+	// indexed by ref name if not empty
+	RefName string `json:"ref_name,omitempty"`
+	// indexed by action id if not empty
+	ActionID string `json:"action_id,omitempty"`
+	// indexed by provider prefix + value id if not empty
+	ValueKey string          `json:"value_key,omitempty"`
+	ValueID  string          `json:"value_id,omitempty"`
+	Value    json.RawMessage `json:"value,omitempty"`
+	IsString bool            `json:"is_string,omitempty"`
+	Stack    bool            `json:"stack,omitempty"`
+	Literal  bool            `json:"literal,omitempty"`
+	Fail     bool            `json:"fail,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Retries  int             `json:"retries,omitempty"`
+	Secret   bool            `json:"secret,omitempty"`
+	// the value of a secret record is never saved
+	Redacted bool `json:"redacted,omitempty"`
+}
+
 // StorageRecord struct
 type StorageRecord struct {
 	ValueID    string                    `json:"valueID"`
@@ -181,6 +207,23 @@ type StorageRecord struct {
 	// an err should generated
 	Literal  bool   `json:"-"`
 	ErrorStr string `json:"error"`
+	// failed attempts before this result, see Action.Retry
+	Retries int `json:"-"`
+	// the value is redacted from logs, events and reports. Records
+	// with a secret name (password, privkey...) are always secret
+	Secret bool `json:"-"`
+}
+
+// isJSONKind func. Kinds of record values that are stored as json
+func isJSONKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Map, reflect.Slice, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
 }
 
 func (sr *StorageRecord) BuildInternals() error {
@@ -246,6 +289,15 @@ func (sr *StorageRecord) BuildInternals() error {
 		}
 		sr.PlainValue = cs
 
+	} else if isJSONKind(vof.Kind()) {
+		// typed values (blueprint parameters, called blueprint
+		// outputs...) are exposed as json
+		enc, err := json.MarshalIndent(sr.Value, "", "    ")
+		if err != nil {
+			return err
+		}
+		sr.JSONValue = enc
+		sr.PlainValue = make(map[string]*AttrTreeValue)
 	} else {
 		return fmt.Errorf("Invalid " + sr.RefName + " output [" + vof.Kind().String() + "]")
 	}
@@ -264,6 +316,8 @@ type IStore interface {
 	GetPrivateVar(varname string) interface{}
 	SetPrivateVar(varname string, value interface{})
 	Merge(IStore)
+	// merge the store of a branch arriving to a join point
+	MergeBranch(source IStore, strategy string) ([]*MergeConflict, error)
 	GetActionOutputByActionID(actionID *string) (*ActionOutput, error)
 	Insert(record *StorageRecord, providerPrefix string) error
 	Push(record *StorageRecord, providerPrefix string) error
@@ -274,5 +328,15 @@ type IStore interface {
 	DumpValuesToJSONFile() (*os.File, error)
 	GetByRefName(refname string) (*StorageRecord, error)
 	DeepInterpolation(v interface{}) error
+	// json data to unmarshal into v with the {{ expression }} values
+	// replaced by their native type where v expects no string
+	TypedInterpolation(data []byte, v interface{}) ([]byte, error)
+	// native value of a text that is exactly one {{ expression }}
+	NativeInterpolation(text string) (interface{}, error)
 	ExistsRefName(refname string) bool
+	// serializable copy of the records, used by checkpoints
+	Snapshot() ([]*StorageRecordSnapshot, error)
+	// load the records of a snapshot. actions is used to
+	// link the restored records with his actions
+	Restore(snaps []*StorageRecordSnapshot, actions map[string]*Action) error
 }
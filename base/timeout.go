@@ -0,0 +1,103 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ActionTimeout type. Max run time of an action. Accepts
+// seconds as number (30, 0.5) or duration string ("1m30s")
+type ActionTimeout time.Duration
+
+// UnmarshalJSON func
+func (t *ActionTimeout) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var d time.Duration
+	switch vv := v.(type) {
+	case nil:
+		d = 0
+	case float64:
+		d = time.Duration(vv * float64(time.Second))
+	case string:
+		if vv == "" {
+			break
+		}
+		if secs, err := strconv.ParseFloat(vv, 64); err == nil {
+			d = time.Duration(secs * float64(time.Second))
+			break
+		}
+		var err error
+		d, err = time.ParseDuration(vv)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q: use seconds or a duration like 1m30s", vv)
+		}
+	default:
+		return fmt.Errorf("invalid timeout %s: use seconds or a duration like 1m30s", string(data))
+	}
+	if d < 0 {
+		return fmt.Errorf("invalid timeout %s: must be positive", string(data))
+	}
+	*t = ActionTimeout(d)
+	return nil
+}
+
+// MarshalJSON func
+func (t ActionTimeout) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(t).String())
+}
+
+// Duration func
+func (t ActionTimeout) Duration() time.Duration {
+	return time.Duration(t)
+}
+
+// TimeoutError struct. Returned as action error when an action
+// exceeds his own timeout or the global execution deadline.
+// Intentionally does not unwrap into context.DeadlineExceeded
+// nor implements net.Error, so the retry hooks doesn't retry it
+type TimeoutError struct {
+	Timeout time.Duration
+	// true if the global execution deadline has been
+	// reached instead the action timeout
+	Global bool
+	// the err returned by the action after the cancelation
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	msg := fmt.Sprintf("action timed out after %v", e.Timeout)
+	if e.Global {
+		msg = fmt.Sprintf("execution deadline of %v exceeded", e.Timeout)
+	}
+	if e.Err != nil {
+		msg = msg + ": " + e.Err.Error()
+	}
+	return msg
+}
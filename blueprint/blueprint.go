@@ -34,6 +34,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/develatio/nebulant-cli/base"
 	"github.com/develatio/nebulant-cli/config"
@@ -45,10 +46,19 @@ import (
 const JoinThreadsActionName = "join_threads"
 const DebugActionName = "debug"
 
+// ForeachActionName const
+const ForeachActionName = "foreach"
+const CallBlueprintActionName = "call_blueprint"
+
+// GroupActionName const
+const GroupActionName = "group"
+
 type wrappedBlueprint struct {
-	ExecutionUUID *string         `json:"execution_uuid"`
-	Detail        string          `json:"detail"`
-	Blueprint     json.RawMessage `json:"blueprint"`
+	ExecutionUUID *string `json:"execution_uuid"`
+	Detail        string  `json:"detail"`
+	// concrete version served, also when the latest is requested
+	Version   string          `json:"version"`
+	Blueprint json.RawMessage `json:"blueprint"`
 }
 
 // Blueprint struct
@@ -59,6 +69,17 @@ type Blueprint struct {
 	Raw             *[]byte
 	BuilderErrors   int `json:"n_errors"`
 	BuilderWarnings int `json:"n_warnings"`
+	// Ref names exposed to the caller when the blueprint runs
+	// as a sub-routine (call_blueprint)
+	Outputs []string `json:"outputs"`
+	// Input parameters expected by the blueprint
+	Parameters []*Parameter `json:"parameters"`
+	// Action id of the cleanup chain, always run after
+	// the end of the execution
+	OnExit *string `json:"on_exit"`
+	// bare blueprint json as received, used to
+	// build the canonical form
+	rawbp json.RawMessage
 }
 
 type ConditionalNextActions struct {
@@ -96,6 +117,9 @@ func NewFromFile(bpUrl *BlueprintURL) (*Blueprint, error) {
 	}
 	defer jsonFile.Close()
 	byteValue, _ := io.ReadAll(jsonFile)
+	if IsYAML(bpUrl.FilePath, byteValue) {
+		return NewFromYAML(byteValue)
+	}
 	wrap := &wrappedBlueprint{}
 	if err := json.Unmarshal(byteValue, wrap); err != nil {
 		return nil, err
@@ -104,6 +128,7 @@ func NewFromFile(bpUrl *BlueprintURL) (*Blueprint, error) {
 	if err := util.UnmarshalValidJSON(wrap.Blueprint, &bp); err != nil {
 		return nil, err
 	}
+	bp.rawbp = wrap.Blueprint
 	if err := TestMinCliVersion(&bp); err != nil {
 		return nil, err
 	}
@@ -124,6 +149,7 @@ func NewFromBytes(data []byte) (*Blueprint, error) {
 		return nil, err
 	}
 	bp.Raw = &data
+	bp.rawbp = data
 	if err := TestMinCliVersion(&bp); err != nil {
 		return nil, err
 	}
@@ -140,6 +166,7 @@ func NewFromBuilder(data []byte) (*Blueprint, error) {
 	if err := util.UnmarshalValidJSON(wrap.Blueprint, &bp); err != nil {
 		return nil, err
 	}
+	bp.rawbp = wrap.Blueprint
 	if err := TestMinCliVersion(&bp); err != nil {
 		return nil, err
 	}
@@ -152,33 +179,116 @@ func NewFromBuilder(data []byte) (*Blueprint, error) {
 	return &bp, nil
 }
 
-func NewIRBFromAny(bpurl *BlueprintURL, irbConf *IRBGenConfig) (*IRBlueprint, error) {
-	var bp *Blueprint
-	var err error
+// NewFromData func. Build the blueprint from yaml, wrapped builder
+// json or bare blueprint json. The path is only used as format hint.
+func NewFromData(data []byte, path string) (*Blueprint, error) {
+	if IsYAML(path, data) {
+		return NewFromYAML(data)
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	if _, wrapped := probe["blueprint"]; wrapped {
+		return NewFromBuilder(data)
+	}
+	bp, err := NewFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	randIntString := fmt.Sprintf("%d", rand.Int()) // #nosec G404 -- Weak random is OK here
+	bp.ExecutionUUID = &randIntString
+	return bp, nil
+}
+
+// NewFromReader func
+func NewFromReader(r io.Reader, path string) (*Blueprint, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("empty blueprint")
+	}
+	return NewFromData(data, path)
+}
+
+// httpsClient is the client of the blueprints loaded from https urls
+var httpsClient = &http.Client{Timeout: 60 * time.Second}
+
+// NewFromHTTPS func. Download the blueprint, checking the url
+// checksum if any.
+func NewFromHTTPS(bpUrl *BlueprintURL) (*Blueprint, error) {
+	resp, err := httpsClient.Get(bpUrl.RemoteURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(strconv.Itoa(resp.StatusCode) + " cannot obtain blueprint from " + bpUrl.RemoteURL)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if bpUrl.Checksum != "" {
+		if sum := ContentHash(data); sum != hashPrefix+bpUrl.Checksum {
+			return nil, fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", bpUrl.RemoteURL, bpUrl.Checksum, strings.TrimPrefix(sum, hashPrefix))
+		}
+	}
+	return NewFromData(data, bpUrl.UrlPath)
+}
 
+// NewFromAny func. Obtain the blueprint from the source pointed by the url
+func NewFromAny(bpurl *BlueprintURL) (*Blueprint, error) {
 	switch bpurl.Scheme {
 	case "nebulant":
-		bp, err = NewFromBackend(bpurl)
-		if err != nil {
-			return nil, err
-		}
+		return NewFromBackend(bpurl)
 	case "file":
-		bp, err = NewFromFile(bpurl)
-		if err != nil {
-			return nil, err
-		}
+		return NewFromFile(bpurl)
+	case "https":
+		return NewFromHTTPS(bpurl)
+	case "stdin":
+		return NewFromReader(os.Stdin, "")
 	default:
 		return nil, fmt.Errorf("unknown bp url")
 	}
+}
+
+func NewIRBFromAny(bpurl *BlueprintURL, irbConf *IRBGenConfig) (*IRBlueprint, error) {
+	bp, err := NewFromAny(bpurl)
+	if err != nil {
+		return nil, err
+	}
+
+	if irbConf.RequireSignature {
+		if err := verifyFromConf(bpurl, bp, irbConf); err != nil {
+			return nil, err
+		}
+	}
 
 	irb, err := GenerateIRB(bp, irbConf)
 	if err != nil {
 		return nil, err
 	}
+	irb.URL = bpurl
 	return irb, nil
 }
 
+// NewFromMarket func. Obtain the blueprint from the marketplace,
+// honoring the lockfile, the offline cache and the offline mode.
 func NewFromMarket(bpUrl *BlueprintURL) (*Blueprint, error) {
+	return newFromRemote(bpUrl, fetchFromMarket)
+}
+
+// NewFromBackend func. Obtain the blueprint from the backend, or
+// from the marketplace if the user has no access to the blueprint org,
+// honoring the lockfile, the offline cache and the offline mode.
+func NewFromBackend(bpUrl *BlueprintURL) (*Blueprint, error) {
+	return newFromRemote(bpUrl, fetchFromBackend)
+}
+
+func fetchFromMarket(bpUrl *BlueprintURL) (*wrappedBlueprint, error) {
 	orgslug := bpUrl.OrganizationSlug
 	if orgslug == "" {
 		orgslug = bpUrl.CollectionSlug
@@ -206,23 +316,22 @@ func NewFromMarket(bpUrl *BlueprintURL) (*Blueprint, error) {
 	return getRemoteBP(url)
 }
 
-// NewFromBackend func
-func NewFromBackend(bpUrl *BlueprintURL) (*Blueprint, error) {
+func fetchFromBackend(bpUrl *BlueprintURL) (*wrappedBlueprint, error) {
 	if config.CREDENTIAL.AuthToken == nil {
-		return NewFromMarket(bpUrl)
+		return fetchFromMarket(bpUrl)
 	}
 
 	_, err := config.Login(context.TODO(), nil)
 	if err != nil {
-		return NewFromMarket(bpUrl)
+		return fetchFromMarket(bpUrl)
 	}
 
 	if config.PROFILE == nil {
-		return NewFromMarket(bpUrl)
+		return fetchFromMarket(bpUrl)
 	}
 
 	if config.PROFILE.Organization.Slug != bpUrl.OrganizationSlug {
-		return NewFromMarket(bpUrl)
+		return fetchFromMarket(bpUrl)
 	}
 
 	path := ""
@@ -248,7 +357,7 @@ func NewFromBackend(bpUrl *BlueprintURL) (*Blueprint, error) {
 	return getRemoteBP(url)
 }
 
-func getRemoteBP(url *url.URL) (*Blueprint, error) {
+func getRemoteBP(url *url.URL) (*wrappedBlueprint, error) {
 	rawBody, _ := json.Marshal(map[string]string{
 		"version": config.Version,
 	})
@@ -281,14 +390,7 @@ func getRemoteBP(url *url.URL) (*Blueprint, error) {
 	if err := json.Unmarshal(rawbody, body); err != nil {
 		return nil, err
 	}
-	bp, err := NewFromBytes(body.Blueprint)
-	if err != nil {
-		return nil, err
-	}
-
-	bp.ExecutionUUID = body.ExecutionUUID
-
-	return bp, nil
+	return body, nil
 }
 
 func ParseBPArgs(args []string) ([]*IRBArg, error) {
@@ -312,27 +414,79 @@ func ParseBPArgs(args []string) ([]*IRBArg, error) {
 	return parsed, nil
 }
 
+// mergeArgs func. The overrides replace the args with the same name
+func mergeArgs(args []*IRBArg, overrides []*IRBArg) []*IRBArg {
+	overridden := make(map[string]bool)
+	for _, arg := range overrides {
+		overridden[arg.Name] = true
+	}
+	var merged []*IRBArg
+	for _, arg := range args {
+		if !overridden[arg.Name] {
+			merged = append(merged, arg)
+		}
+	}
+	return append(merged, overrides...)
+}
+
 // IRBArg struct. Represent parsed blueprint cli args
 type IRBArg struct {
-	Name  string
-	Value string
+	Name string
+	// string as parsed from cli, native type once resolved
+	// against the blueprint parameters
+	Value interface{}
+	// declared as secret parameter
+	Secret bool
 }
 
 // IRBlueprint struct. Intermediate Representation Blueprint. Precompiler.
 type IRBlueprint struct {
-	BP            *Blueprint
+	BP *Blueprint
+	// Location of the blueprint, nil if unknown
+	URL           *BlueprintURL
 	ExecutionUUID *string
 	// [thread-action-id][thread-path]*base.Action
 	JoinThreadPoints map[string]*base.Action
 	Actions          map[string]*base.Action
 	StartAction      *base.Action
-	Args             []*IRBArg
+	// First action of the cleanup chain, nil if none
+	OnExit *base.Action
+	Args   []*IRBArg
+	// Global execution deadline, zero for no deadline
+	Timeout time.Duration
+	// Concurrency limits, nil for no limits
+	Limits *RunLimits
+	// Delete the resources created by the execution if it fails
+	RollbackOnFailure bool
+	// Teardown of the resources of a previous execution
+	// (nebulant destroy). Checkpoints are not saved
+	Teardown bool
+	// The signature of the blueprint has been verified. Called
+	// blueprints are verified with the same trusted keys
+	RequireSignature bool
+	TrustedKeys      string
 }
 
 // IRBGenConfig struct
 type IRBGenConfig struct {
 	AllowResultReturn bool
 	Args              []string
+	// json or .env file with args. Args have precedence
+	VarsFile string
+	// Verify the blueprint signature before IR generation
+	RequireSignature bool
+	// Dir with the trusted public keys, TrustedKeysPath() if empty
+	TrustedKeys string
+	// Detached signature path, <filepath>.sig if empty
+	SignaturePath string
+	// Global execution deadline, zero for no deadline
+	Timeout time.Duration
+	// Concurrency limits, nil for no limits
+	Limits *RunLimits
+	// Delete the resources created by the execution if it fails
+	RollbackOnFailure bool
+	// Args already parsed (eg. from a checkpoint). Args have precedence
+	ParsedArgs []*IRBArg
 	// Not implemented
 	// PreventLoop       bool
 }
@@ -381,6 +535,24 @@ func GenerateIRB(bp *Blueprint, irbConf *IRBGenConfig) (*IRBlueprint, error) {
 	if bp.BuilderErrors > 0 {
 		return nil, fmt.Errorf("Refusing to run this blueprint as it contains " + fmt.Sprintf("%v", bp.BuilderErrors) + " errors")
 	}
+	irb, errors, err := generateIRB(bp, irbConf)
+	if err != nil {
+		return nil, err
+	}
+	if len(errors) > 0 {
+		return nil, errors
+	}
+	irb.Args, err = ResolveParameters(bp.Parameters, irb.Args)
+	if err != nil {
+		return nil, err
+	}
+	return irb, nil
+}
+
+// generateIRB func. Build the IRB collecting the errors found in the way. The
+// returned IRB may be incomplete if errors are returned, but it is still
+// usable for static analysis.
+func generateIRB(bp *Blueprint, irbConf *IRBGenConfig) (*IRBlueprint, IRBErrors, error) {
 	var errors IRBErrors
 	irb := &IRBlueprint{
 		BP:               bp,
@@ -396,11 +568,26 @@ func GenerateIRB(bp *Blueprint, irbConf *IRBGenConfig) (*IRBlueprint, error) {
 	// parse cli args
 	pargs, err := ParseBPArgs(irbConf.Args)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if irbConf.VarsFile != "" {
+		fargs, err := ParseVarsFile(irbConf.VarsFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		pargs = mergeArgs(fargs, pargs)
+	}
+	if len(irbConf.ParsedArgs) > 0 {
+		pargs = mergeArgs(irbConf.ParsedArgs, pargs)
 	}
 	irb.Args = pargs
 
 	irb.ExecutionUUID = bp.ExecutionUUID
+	irb.Timeout = irbConf.Timeout
+	irb.Limits = irbConf.Limits
+	irb.RollbackOnFailure = irbConf.RollbackOnFailure
+	irb.RequireSignature = irbConf.RequireSignature
+	irb.TrustedKeys = irbConf.TrustedKeys
 
 	// iterate over bp, check provider access
 	for i := 0; i < len(bp.Actions); i++ {
@@ -421,10 +608,18 @@ func GenerateIRB(bp *Blueprint, irbConf *IRBGenConfig) (*IRBlueprint, error) {
 		if irb.Actions[bp.Actions[i].ActionID].ActionName == JoinThreadsActionName {
 			irb.Actions[bp.Actions[i].ActionID].JoinThreadsPoint = true
 			irb.JoinThreadPoints[bp.Actions[i].ActionID] = irb.Actions[bp.Actions[i].ActionID]
+			policy, err := parseJoinPolicy(irb.Actions[bp.Actions[i].ActionID])
+			if err != nil {
+				errors = append(errors, &iRBError{actionID: bp.Actions[i].ActionID, wErr: err})
+			}
+			irb.Actions[bp.Actions[i].ActionID].JoinPolicy = policy
 		}
 		if irb.Actions[bp.Actions[i].ActionID].ActionName == DebugActionName {
 			irb.Actions[bp.Actions[i].ActionID].DebugPoint = true
 		}
+		if bp.Actions[i].Provider == "generic" && (bp.Actions[i].ActionName == ForeachActionName || bp.Actions[i].ActionName == CallBlueprintActionName) {
+			irb.Actions[bp.Actions[i].ActionID].SubRoutine = true
+		}
 	}
 
 	if irb.StartAction == nil {
@@ -432,6 +627,17 @@ func GenerateIRB(bp *Blueprint, irbConf *IRBGenConfig) (*IRBlueprint, error) {
 		// return nil, fmt.Errorf("no first action found in blueprint")
 	}
 
+	if bp.OnExit != nil && *bp.OnExit != "" {
+		onExit, exists := irb.Actions[*bp.OnExit]
+		if !exists {
+			errors = append(errors, &iRBError{wErr: fmt.Errorf("on_exit action %s not found", *bp.OnExit)})
+		} else if onExit.FirstAction {
+			errors = append(errors, &iRBError{actionID: onExit.ActionID, wErr: fmt.Errorf("the first action cannot be the on_exit action")})
+		} else {
+			irb.OnExit = onExit
+		}
+	}
+
 	// parse next and parents
 	for _, action := range irb.Actions {
 		// empty parameters
@@ -479,6 +685,38 @@ func GenerateIRB(bp *Blueprint, irbConf *IRBGenConfig) (*IRBlueprint, error) {
 			action.NextAction.NextKo = nextKoActions
 		}
 
+		nextFinallyActions, _, _, err := parseNextActions(action.NextAction.Finally, irb.Actions)
+		if err != nil {
+			errors = append(errors, &iRBError{wErr: err})
+		}
+		if nextFinallyActions != nil {
+			if action.ActionName != GroupActionName || action.Provider != "generic" {
+				errors = append(errors, &iRBError{
+					actionID: action.ActionID,
+					wErr:     fmt.Errorf("finally edges are only allowed in group actions"),
+				})
+			}
+			action.NextAction.NextFinally = nextFinallyActions
+		}
+
+		if action.ActionName == ForeachActionName && action.Provider == "generic" {
+			body, err := parseBodyAction(action, irb.Actions)
+			if err != nil {
+				errors = append(errors, &iRBError{actionID: action.ActionID, wErr: err})
+			}
+			action.BodyAction = body
+		}
+
+		if action.Retry != nil {
+			if err := action.Retry.Validate(); err != nil {
+				errors = append(errors, &iRBError{actionID: action.ActionID, wErr: err})
+			}
+		}
+
+		if issue := lintActionPorts(action); issue != nil {
+			errors = append(errors, &iRBError{actionID: action.ActionID, wErr: issue})
+		}
+
 		for _, vl := range ActionValidators {
 			if err := vl(action); err != nil {
 				errors = append(errors, &iRBError{actionID: action.ActionID, wErr: err})
@@ -487,6 +725,12 @@ func GenerateIRB(bp *Blueprint, irbConf *IRBGenConfig) (*IRBlueprint, error) {
 		}
 	}
 
+	for _, action := range irb.JoinThreadPoints {
+		if n := action.JoinPolicy.Needed(); n > len(action.Parents) {
+			errors = append(errors, &iRBError{actionID: action.ActionID, wErr: fmt.Errorf("join %s waits for %d branches but only %d arrive to it", action.ActionID, n, len(action.Parents))})
+		}
+	}
+
 	// WIP: ASK FOR INPUT HOWTO
 	// go func() {
 	// 	var first string
@@ -506,6 +750,7 @@ func GenerateIRB(bp *Blueprint, irbConf *IRBGenConfig) (*IRBlueprint, error) {
 		action.NextAction.NextOkTrue = replaceEndActions(action.NextAction.NextOkTrue, true)
 		action.NextAction.NextOkFalse = replaceEndActions(action.NextAction.NextOkFalse, true)
 		action.NextAction.NextKo = replaceEndActions(action.NextAction.NextKo, false)
+		action.NextAction.NextFinally = replaceEndActions(action.NextAction.NextFinally, true)
 	}
 
 	// prepare for join threads
@@ -534,11 +779,74 @@ func GenerateIRB(bp *Blueprint, irbConf *IRBGenConfig) (*IRBlueprint, error) {
 		}
 	}
 
-	if len(errors) > 0 {
-		return nil, errors
+	return irb, errors, nil
+}
+
+// parseBodyAction func. Find the first action of the body of a foreach
+func parseBodyAction(action *base.Action, actions map[string]*base.Action) (*base.Action, error) {
+	params := &struct {
+		Body string `json:"body"`
+	}{}
+	if err := json.Unmarshal(action.Parameters, params); err != nil {
+		return nil, err
+	}
+	if params.Body == "" {
+		return nil, fmt.Errorf("foreach action %s has no body", action.ActionID)
+	}
+	if params.Body == action.ActionID {
+		return nil, fmt.Errorf("foreach action %s cannot be its own body", action.ActionID)
 	}
+	body, exists := actions[params.Body]
+	if !exists {
+		return nil, fmt.Errorf("body action %s of foreach %s not found", params.Body, action.ActionID)
+	}
+	if body.FirstAction {
+		return nil, fmt.Errorf("body action %s of foreach %s cannot be the first action", params.Body, action.ActionID)
+	}
+	return body, nil
+}
 
-	return irb, nil
+// parseJoinPolicy func. Read the join mode and merge of a join_threads action.
+// A count without mode implies the count mode
+func parseJoinPolicy(action *base.Action) (*base.JoinPolicy, error) {
+	if len(action.Parameters) <= 0 {
+		return nil, nil
+	}
+	policy := &base.JoinPolicy{}
+	if err := json.Unmarshal(action.Parameters, policy); err != nil {
+		return nil, fmt.Errorf("invalid join parameters: %v", err)
+	}
+	if policy.Mode == "" && policy.Count > 0 {
+		policy.Mode = base.JoinModeCount
+	}
+	switch policy.Merge {
+	case "", base.JoinMergeLast, base.JoinMergeStack, base.JoinMergeNamespace, base.JoinMergeFail:
+	default:
+		return nil, fmt.Errorf("unknown join merge %q, use last, stack, namespace or fail", policy.Merge)
+	}
+	switch policy.Mode {
+	case "", base.JoinModeAll:
+		if policy.Merge == "" || policy.Merge == base.JoinMergeLast {
+			return nil, nil
+		}
+		return policy, nil
+	case base.JoinModeAny:
+		return policy, nil
+	case base.JoinModeCount:
+		if policy.Count < 1 {
+			return nil, fmt.Errorf("join %s needs a count greater than zero", action.ActionID)
+		}
+		return policy, nil
+	}
+	return nil, fmt.Errorf("unknown join mode %q, use all, any or count", policy.Mode)
+}
+
+// BodyActions func. The actions of the body of a foreach action, by id
+func BodyActions(action *base.Action) map[string]*base.Action {
+	if action.BodyAction == nil {
+		return nil
+	}
+	return walkReachable([]*base.Action{action.BodyAction}, nil)
 }
 
 // buildDirectAscendants func
@@ -0,0 +1,329 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/develatio/nebulant-cli/cast"
+	"github.com/develatio/nebulant-cli/config"
+)
+
+// DefaultLockFile is the lockfile looked up into the working dir
+const DefaultLockFile = "nebulant.lock"
+
+const hashPrefix = "sha256:"
+
+// LockEntry struct. Pinned version and content hash of a remote
+// blueprint. Entries written by older releases may have an empty
+// version, pinning the latest version at lock time.
+type LockEntry struct {
+	Version string `json:"version"`
+	Hash    string `json:"hash"`
+}
+
+// LockFile struct. Remote blueprints pinned by slug.
+type LockFile struct {
+	Blueprints map[string]*LockEntry `json:"blueprints"`
+	path       string
+}
+
+// LoadLockFile func. A missing lockfile is not an error, an empty
+// one is returned instead.
+func LoadLockFile(path string) (*LockFile, error) {
+	lock := &LockFile{Blueprints: make(map[string]*LockEntry), path: path}
+	data, err := os.ReadFile(path) // #nosec G304 -- user provided lockfile
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lock, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s: %s", path, err.Error())
+	}
+	if lock.Blueprints == nil {
+		lock.Blueprints = make(map[string]*LockEntry)
+	}
+	return lock, nil
+}
+
+// Save func.
+func (l *LockFile) Save() error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.path, append(data, '\n'), 0600)
+}
+
+// Slug func. org/coll/bp without version, used as lockfile key.
+func (b *BlueprintURL) Slug() string {
+	if b.OrganizationSlug == "" {
+		return b.CollectionSlug + "/" + b.BlueprintSlug
+	}
+	return b.OrganizationSlug + "/" + b.CollectionSlug + "/" + b.BlueprintSlug
+}
+
+// ContentHash func.
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// CachePath func. Dir of the offline blueprint cache.
+func CachePath() string {
+	return filepath.Join(config.AppHomePath(), "cache", "blueprints")
+}
+
+func cacheIndexPath() string {
+	return filepath.Join(CachePath(), "index.json")
+}
+
+func cacheIndexLockPath() string {
+	return cacheIndexPath() + ".lock"
+}
+
+// cacheLockTimeout is how long to wait for the cache index lock
+var cacheLockTimeout = 10 * time.Second
+
+// cacheLockStale is the age of a lock left by a dead process
+const cacheLockStale = time.Minute
+
+// lockCacheIndex func. Take the lock of the cache index, shared with
+// the other nebulant processes. The returned func releases it.
+func lockCacheIndex() (func(), error) {
+	path := cacheIndexLockPath()
+	deadline := time.Now().Add(cacheLockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600) // #nosec G304 -- cache file
+		if err == nil {
+			if err := f.Close(); err != nil {
+				return nil, err
+			}
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > cacheLockStale {
+			// left by a dead process
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout waiting for the cache lock %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func cacheKey(slug string, version string) string {
+	if version == "" {
+		return slug
+	}
+	return slug + ":" + version
+}
+
+func cacheFilePath(hash string) (string, error) {
+	sum, found := strings.CutPrefix(hash, hashPrefix)
+	if !found {
+		return "", fmt.Errorf("unsupported hash %s", hash)
+	}
+	if _, err := hex.DecodeString(sum); err != nil || len(sum) != sha256.Size*2 {
+		return "", fmt.Errorf("malformed hash %s", hash)
+	}
+	return filepath.Join(CachePath(), sum+".json"), nil
+}
+
+func loadCacheIndex() (map[string]string, error) {
+	index := make(map[string]string)
+	data, err := os.ReadFile(cacheIndexPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("corrupted cache index: %s", err.Error())
+	}
+	return index, nil
+}
+
+// cachePut func. Store the blueprint by its content hash and point the
+// slug[:version] keys to it.
+func cachePut(keys []string, data []byte) (string, error) {
+	hash := ContentHash(data)
+	if err := os.MkdirAll(CachePath(), 0700); err != nil {
+		return hash, err
+	}
+	path, err := cacheFilePath(hash)
+	if err != nil {
+		return hash, err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return hash, err
+	}
+
+	unlock, err := lockCacheIndex()
+	if err != nil {
+		return hash, err
+	}
+	defer unlock()
+	index, err := loadCacheIndex()
+	if err != nil {
+		return hash, err
+	}
+	for _, key := range keys {
+		index[key] = hash
+	}
+	raw, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return hash, err
+	}
+	// write + rename to never leave a half written index
+	tmp := cacheIndexPath() + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return hash, err
+	}
+	return hash, os.Rename(tmp, cacheIndexPath())
+}
+
+// cacheGet func. Read a cached blueprint checking its content hash.
+func cacheGet(hash string) ([]byte, error) {
+	path, err := cacheFilePath(hash)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- cache file
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("blueprint %s not found in cache", hash)
+		}
+		return nil, err
+	}
+	if got := ContentHash(data); got != hash {
+		return nil, fmt.Errorf("cached blueprint hash mismatch: expected %s, got %s", hash, got)
+	}
+	return data, nil
+}
+
+func lockFilePath() string {
+	if config.LockFileFlag != nil && *config.LockFileFlag != "" {
+		return *config.LockFileFlag
+	}
+	return DefaultLockFile
+}
+
+// newFromRemote func. Resolve a remote blueprint through the
+// lockfile and the cache. In offline mode the blueprint is only
+// read from cache.
+func newFromRemote(bpUrl *BlueprintURL, fetch func(*BlueprintURL) (*wrappedBlueprint, error)) (*Blueprint, error) {
+	offline := config.OfflineFlag != nil && *config.OfflineFlag
+	update := config.UpdateLockFlag != nil && *config.UpdateLockFlag
+
+	lock, err := LoadLockFile(lockFilePath())
+	if err != nil {
+		return nil, err
+	}
+	slug := bpUrl.Slug()
+	version := bpUrl.Version
+	entry := lock.Blueprints[slug]
+	if entry != nil && version == "" && !update {
+		version = entry.Version
+	}
+	pinned := entry != nil && entry.Version == version && !update
+
+	if offline {
+		hash := ""
+		if pinned {
+			hash = entry.Hash
+		} else {
+			index, err := loadCacheIndex()
+			if err != nil {
+				return nil, err
+			}
+			var exists bool
+			hash, exists = index[cacheKey(slug, version)]
+			if !exists {
+				return nil, fmt.Errorf("offline mode: blueprint %s not found in cache", cacheKey(slug, version))
+			}
+		}
+		data, err := cacheGet(hash)
+		if err != nil {
+			return nil, fmt.Errorf("offline mode: %s", err.Error())
+		}
+		bp, err := NewFromBytes(data)
+		if err != nil {
+			return nil, err
+		}
+		randIntString := fmt.Sprintf("%d", rand.Int()) // #nosec G404 -- Weak random is OK here
+		bp.ExecutionUUID = &randIntString
+		return bp, nil
+	}
+
+	pinnedUrl := *bpUrl
+	pinnedUrl.Version = version
+	wrap, err := fetch(&pinnedUrl)
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{cacheKey(slug, version)}
+	if version == "" && wrap.Version != "" {
+		// latest requested, keep the version served
+		version = wrap.Version
+		keys = append(keys, cacheKey(slug, version))
+	}
+	hash := ContentHash(wrap.Blueprint)
+	if pinned && hash != entry.Hash {
+		return nil, fmt.Errorf("blueprint %s does not match %s: locked hash %s, got %s. Use --update-lock to accept the new content", cacheKey(slug, version), lockFilePath(), entry.Hash, hash)
+	}
+	bp, err := NewFromBytes(wrap.Blueprint)
+	if err != nil {
+		return nil, err
+	}
+	bp.ExecutionUUID = wrap.ExecutionUUID
+
+	if _, err := cachePut(keys, wrap.Blueprint); err != nil {
+		cast.LogWarn("Cannot cache blueprint "+slug+": "+err.Error(), nil)
+	}
+	if update {
+		if version == "" {
+			return nil, fmt.Errorf("cannot pin %s: the server did not report the version, please request one (%s:<version>)", slug, slug)
+		}
+		lock.Blueprints[slug] = &LockEntry{Version: version, Hash: hash}
+		if err := lock.Save(); err != nil {
+			return nil, err
+		}
+	}
+	return bp, nil
+}
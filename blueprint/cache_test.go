@@ -0,0 +1,168 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/develatio/nebulant-cli/config"
+)
+
+const testRemoteBP = `{"actions": [{"action_id": "a1", "provider": "generic", "action": "log", "parameters": {"content": "hi"}}]}`
+
+// setTestCache func. Point the cache, the lockfile and the remote
+// flags to temp paths, restored at the end of the test
+func setTestCache(t *testing.T) (lockPath string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	lockPath = filepath.Join(t.TempDir(), DefaultLockFile)
+	offline, update := false, false
+	prevOffline, prevUpdate, prevLock := config.OfflineFlag, config.UpdateLockFlag, config.LockFileFlag
+	config.OfflineFlag, config.UpdateLockFlag, config.LockFileFlag = &offline, &update, &lockPath
+	t.Cleanup(func() {
+		config.OfflineFlag, config.UpdateLockFlag, config.LockFileFlag = prevOffline, prevUpdate, prevLock
+	})
+	return lockPath
+}
+
+func TestRemoteLockVersion(t *testing.T) {
+	lockPath := setTestCache(t)
+	var requested []string
+	fetch := func(u *BlueprintURL) (*wrappedBlueprint, error) {
+		requested = append(requested, u.Version)
+		return &wrappedBlueprint{Version: "1.2.0", Blueprint: []byte(testRemoteBP)}, nil
+	}
+	bpUrl := &BlueprintURL{OrganizationSlug: "org", CollectionSlug: "coll", BlueprintSlug: "bp"}
+
+	*config.UpdateLockFlag = true
+	if _, err := newFromRemote(bpUrl, fetch); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := LoadLockFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := lock.Blueprints["org/coll/bp"]
+	if entry == nil || entry.Version != "1.2.0" || entry.Hash != ContentHash([]byte(testRemoteBP)) {
+		t.Fatalf("unexpected lock entry %+v", entry)
+	}
+
+	// the pinned version is requested
+	*config.UpdateLockFlag = false
+	if _, err := newFromRemote(bpUrl, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if len(requested) != 2 || requested[0] != "" || requested[1] != "1.2.0" {
+		t.Errorf("unexpected requested versions %q", requested)
+	}
+
+	// offline, from the lockfile or from the latest cached
+	*config.OfflineFlag = true
+	noFetch := func(u *BlueprintURL) (*wrappedBlueprint, error) {
+		t.Fatal("fetch in offline mode")
+		return nil, nil
+	}
+	if _, err := newFromRemote(bpUrl, noFetch); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"", "1.2.0"} {
+		u := *bpUrl
+		u.Version = version
+		if _, err := newFromRemote(&u, noFetch); err != nil {
+			t.Errorf("version %q: %v", version, err)
+		}
+	}
+}
+
+func TestRemoteLockUnknownVersion(t *testing.T) {
+	lockPath := setTestCache(t)
+	*config.UpdateLockFlag = true
+	fetch := func(u *BlueprintURL) (*wrappedBlueprint, error) {
+		return &wrappedBlueprint{Blueprint: []byte(testRemoteBP)}, nil
+	}
+	_, err := newFromRemote(&BlueprintURL{CollectionSlug: "coll", BlueprintSlug: "bp"}, fetch)
+	if err == nil || !strings.Contains(err.Error(), "did not report the version") {
+		t.Fatalf("expected an unknown version error, got %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("lockfile written without version")
+	}
+}
+
+func TestCacheIndexLock(t *testing.T) {
+	setTestCache(t)
+
+	// concurrent writers don't lose keys
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("coll/bp%d", i)
+			if _, err := cachePut([]string{key}, []byte(fmt.Sprintf(`{"n": %d}`, i))); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	index, err := loadCacheIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index) != 20 {
+		t.Errorf("expected 20 cache keys, got %d", len(index))
+	}
+	if _, err := os.Stat(cacheIndexLockPath()); !os.IsNotExist(err) {
+		t.Errorf("lock not released")
+	}
+
+	// a lock held by another process
+	prevTimeout := cacheLockTimeout
+	cacheLockTimeout = 50 * time.Millisecond
+	defer func() { cacheLockTimeout = prevTimeout }()
+	if err := os.WriteFile(cacheIndexLockPath(), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cachePut([]string{"coll/held"}, []byte(`{}`)); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected a lock timeout, got %v", err)
+	}
+
+	// a lock left by a dead process
+	old := time.Now().Add(-2 * cacheLockStale)
+	if err := os.Chtimes(cacheIndexLockPath(), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cachePut([]string{"coll/stale"}, []byte(`{}`)); err != nil {
+		t.Errorf("stale lock not taken over: %v", err)
+	}
+}
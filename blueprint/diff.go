@@ -0,0 +1,401 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffKind of an action change
+type DiffKind string
+
+const (
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+	DiffRenamed DiffKind = "renamed"
+	DiffChanged DiffKind = "changed"
+)
+
+// builderOnlyKeys are blueprint attrs that only matter to the builder
+var builderOnlyKeys = append([]string{"diagram_version", "n_warnings", "n_errors", "builder_version"}, builderLayoutKeys...)
+
+// ValueChange struct. A change of a json value. Old or New is nil
+// when the value has been added or removed; OldMissing/NewMissing
+// tell apart a missing value from an explicit null.
+type ValueChange struct {
+	Path       string      `json:"path"`
+	Old        interface{} `json:"old,omitempty"`
+	New        interface{} `json:"new,omitempty"`
+	OldMissing bool        `json:"old_missing,omitempty"`
+	NewMissing bool        `json:"new_missing,omitempty"`
+}
+
+// ActionChange struct.
+type ActionChange struct {
+	Kind     DiffKind `json:"kind"`
+	ActionID string   `json:"action_id"`
+	// Previous ID of renamed actions
+	OldActionID string         `json:"old_action_id,omitempty"`
+	Provider    string         `json:"provider"`
+	Action      string         `json:"action"`
+	Changes     []*ValueChange `json:"changes,omitempty"`
+}
+
+// EdgeChange struct. An added or removed ok/ko/true/false link. Old
+// IDs of renamed actions are translated to the new ones.
+type EdgeChange struct {
+	Kind DiffKind  `json:"kind"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	Port GraphPort `json:"port"`
+}
+
+// Diff struct. Semantic differences between two blueprints.
+type Diff struct {
+	Blueprint []*ValueChange  `json:"blueprint"`
+	Actions   []*ActionChange `json:"actions"`
+	Edges     []*EdgeChange   `json:"edges"`
+}
+
+// Empty func.
+func (d *Diff) Empty() bool {
+	return len(d.Blueprint) == 0 && len(d.Actions) == 0 && len(d.Edges) == 0
+}
+
+type diffAction struct {
+	id       string
+	provider string
+	action   string
+	// everything but action_id and next_action
+	body  map[string]interface{}
+	edges []diffEdge
+}
+
+type diffEdge struct {
+	from string
+	to   string
+	port GraphPort
+}
+
+// diffDecode func. Decode the raw blueprint into its top level attrs
+// (builder only attrs removed) and its actions.
+func diffDecode(bp *Blueprint) (map[string]interface{}, []*diffAction, error) {
+	if len(bp.rawbp) == 0 {
+		return nil, nil, fmt.Errorf("raw blueprint not available")
+	}
+	var top map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(bp.rawbp))
+	dec.UseNumber()
+	if err := dec.Decode(&top); err != nil {
+		return nil, nil, err
+	}
+	for _, key := range builderOnlyKeys {
+		delete(top, key)
+	}
+	rawActions, _ := top["actions"].([]interface{})
+	delete(top, "actions")
+
+	var actions []*diffAction
+	for _, ra := range rawActions {
+		body, ok := ra.(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("malformed action")
+		}
+		da := &diffAction{body: body}
+		da.id, _ = body["action_id"].(string)
+		da.provider, _ = body["provider"].(string)
+		da.action, _ = body["action"].(string)
+		if next, ok := body["next_action"].(map[string]interface{}); ok {
+			da.addEdges(next["ok"], GraphPortOk)
+			da.addEdges(next["ko"], GraphPortKo)
+			da.addEdges(next["finally"], GraphPortFinally)
+		}
+		delete(body, "action_id")
+		delete(body, "next_action")
+		actions = append(actions, da)
+	}
+	return top, actions, nil
+}
+
+func (da *diffAction) addEdges(okko interface{}, port GraphPort) {
+	switch v := okko.(type) {
+	case []interface{}:
+		for _, id := range v {
+			if sid, ok := id.(string); ok {
+				da.edges = append(da.edges, diffEdge{to: sid, port: port})
+			}
+		}
+	case map[string]interface{}:
+		if port == GraphPortKo {
+			da.addEdges(v["true"], port)
+			da.addEdges(v["false"], port)
+			return
+		}
+		da.addEdges(v["true"], GraphPortTrue)
+		da.addEdges(v["false"], GraphPortFalse)
+	}
+}
+
+// DiffBlueprints func. Compare blueprints a and b semantically: the
+// builder canvas, the undo history and the order of the actions are
+// ignored. Actions are matched by ID; a removed action and an added
+// one with the same body are reported as a rename.
+func DiffBlueprints(a, b *Blueprint) (*Diff, error) {
+	atop, aactions, err := diffDecode(a)
+	if err != nil {
+		return nil, err
+	}
+	btop, bactions, err := diffDecode(b)
+	if err != nil {
+		return nil, err
+	}
+	diff := &Diff{}
+	diff.Blueprint = diffValues("$", atop, btop, false, false, nil)
+
+	aby := make(map[string]*diffAction)
+	for _, da := range aactions {
+		aby[da.id] = da
+	}
+	bby := make(map[string]*diffAction)
+	for _, db := range bactions {
+		bby[db.id] = db
+	}
+
+	var removed []*diffAction
+	for _, da := range aactions {
+		if _, exists := bby[da.id]; !exists {
+			removed = append(removed, da)
+		}
+	}
+	// old id -> new id
+	renames := make(map[string]string)
+	for _, db := range bactions {
+		if _, exists := aby[db.id]; exists {
+			continue
+		}
+		for i, da := range removed {
+			if reflect.DeepEqual(da.body, db.body) {
+				renames[da.id] = db.id
+				removed = append(removed[:i], removed[i+1:]...)
+				break
+			}
+		}
+	}
+
+	for _, db := range bactions {
+		da, exists := aby[db.id]
+		if exists {
+			changes := diffValues("$", da.body, db.body, false, false, nil)
+			if len(changes) > 0 {
+				diff.Actions = append(diff.Actions, &ActionChange{Kind: DiffChanged, ActionID: db.id, Provider: db.provider, Action: db.action, Changes: changes})
+			}
+			continue
+		}
+		renamed := false
+		for oldID, newID := range renames {
+			if newID == db.id {
+				diff.Actions = append(diff.Actions, &ActionChange{Kind: DiffRenamed, ActionID: db.id, OldActionID: oldID, Provider: db.provider, Action: db.action})
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			diff.Actions = append(diff.Actions, &ActionChange{Kind: DiffAdded, ActionID: db.id, Provider: db.provider, Action: db.action})
+		}
+	}
+	for _, da := range removed {
+		diff.Actions = append(diff.Actions, &ActionChange{Kind: DiffRemoved, ActionID: da.id, Provider: da.provider, Action: da.action})
+	}
+
+	translate := func(id string) string {
+		if newID, exists := renames[id]; exists {
+			return newID
+		}
+		return id
+	}
+	aedges := make(map[diffEdge]bool)
+	for _, da := range aactions {
+		for _, e := range da.edges {
+			aedges[diffEdge{from: translate(da.id), to: translate(e.to), port: e.port}] = true
+		}
+	}
+	bedges := make(map[diffEdge]bool)
+	for _, db := range bactions {
+		for _, e := range db.edges {
+			bedges[diffEdge{from: db.id, to: e.to, port: e.port}] = true
+		}
+	}
+	for e := range bedges {
+		if !aedges[e] {
+			diff.Edges = append(diff.Edges, &EdgeChange{Kind: DiffAdded, From: e.from, To: e.to, Port: e.port})
+		}
+	}
+	for e := range aedges {
+		if !bedges[e] {
+			diff.Edges = append(diff.Edges, &EdgeChange{Kind: DiffRemoved, From: e.from, To: e.to, Port: e.port})
+		}
+	}
+	sort.Slice(diff.Edges, func(i, j int) bool {
+		ei, ej := diff.Edges[i], diff.Edges[j]
+		if ei.From != ej.From {
+			return ei.From < ej.From
+		}
+		if ei.To != ej.To {
+			return ei.To < ej.To
+		}
+		if ei.Port != ej.Port {
+			return ei.Port < ej.Port
+		}
+		return ei.Kind > ej.Kind
+	})
+	// empty lists instead of null on json output
+	if diff.Blueprint == nil {
+		diff.Blueprint = []*ValueChange{}
+	}
+	if diff.Actions == nil {
+		diff.Actions = []*ActionChange{}
+	}
+	if diff.Edges == nil {
+		diff.Edges = []*EdgeChange{}
+	}
+	return diff, nil
+}
+
+// diffPath func. Append key to the json path, quoting when needed.
+func diffPath(path string, key string) string {
+	for _, r := range key {
+		if !(r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return path + "[" + strconv.Quote(key) + "]"
+		}
+	}
+	return path + "." + key
+}
+
+// diffValues func. Recursive diff of decoded json values.
+func diffValues(path string, a, b interface{}, amissing, bmissing bool, out []*ValueChange) []*ValueChange {
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+	if aok && bok && !amissing && !bmissing {
+		keys := make(map[string]bool)
+		for k := range am {
+			keys[k] = true
+		}
+		for k := range bm {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			av, ainm := am[k]
+			bv, binm := bm[k]
+			out = diffValues(diffPath(path, k), av, bv, !ainm, !binm, out)
+		}
+		return out
+	}
+	al, aok := a.([]interface{})
+	bl, bok := b.([]interface{})
+	if aok && bok && !amissing && !bmissing {
+		for i := 0; i < len(al) || i < len(bl); i++ {
+			var av, bv interface{}
+			if i < len(al) {
+				av = al[i]
+			}
+			if i < len(bl) {
+				bv = bl[i]
+			}
+			out = diffValues(path+"["+strconv.Itoa(i)+"]", av, bv, i >= len(al), i >= len(bl), out)
+		}
+		return out
+	}
+	if amissing == bmissing && reflect.DeepEqual(a, b) {
+		return out
+	}
+	return append(out, &ValueChange{Path: path, Old: a, New: b, OldMissing: amissing, NewMissing: bmissing})
+}
+
+func diffValueString(v interface{}, missing bool) string {
+	if missing {
+		return "(none)"
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(raw)
+}
+
+// WriteText func. Human readable diff.
+func (d *Diff) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	if d.Empty() {
+		sb.WriteString("no semantic changes\n")
+	}
+	writeChanges := func(indent string, changes []*ValueChange) {
+		for _, c := range changes {
+			fmt.Fprintf(&sb, "%s%s: %s -> %s\n", indent, c.Path, diffValueString(c.Old, c.OldMissing), diffValueString(c.New, c.NewMissing))
+		}
+	}
+	if len(d.Blueprint) > 0 {
+		sb.WriteString("blueprint:\n")
+		writeChanges("  ", d.Blueprint)
+	}
+	if len(d.Actions) > 0 {
+		sb.WriteString("actions:\n")
+		for _, a := range d.Actions {
+			kind := a.Provider + "/" + a.Action
+			switch a.Kind {
+			case DiffAdded:
+				fmt.Fprintf(&sb, "  + %s (%s)\n", a.ActionID, kind)
+			case DiffRemoved:
+				fmt.Fprintf(&sb, "  - %s (%s)\n", a.ActionID, kind)
+			case DiffRenamed:
+				fmt.Fprintf(&sb, "  ~ %s -> %s (%s)\n", a.OldActionID, a.ActionID, kind)
+			case DiffChanged:
+				fmt.Fprintf(&sb, "  * %s (%s)\n", a.ActionID, kind)
+				writeChanges("      ", a.Changes)
+			}
+		}
+	}
+	if len(d.Edges) > 0 {
+		sb.WriteString("edges:\n")
+		for _, e := range d.Edges {
+			sign := "+"
+			if e.Kind == DiffRemoved {
+				sign = "-"
+			}
+			fmt.Fprintf(&sb, "  %s %s -%s-> %s\n", sign, e.From, e.Port, e.To)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
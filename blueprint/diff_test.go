@@ -0,0 +1,118 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+func diffLoad(t *testing.T, data string) *blueprint.Blueprint {
+	t.Helper()
+	bp, err := blueprint.NewFromBytes([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bp
+}
+
+func TestDiffRenameAndChange(t *testing.T) {
+	a := diffLoad(t, `{"diagram": {"x": 1}, "actions": [
+		{"action_id": "a1", "provider": "generic", "action": "log", "parameters": {"content": "hi"}, "next_action": {"ok": ["a2"]}},
+		{"action_id": "a2", "provider": "generic", "action": "log", "parameters": {"content": "bye"}}]}`)
+	// a1 renamed to x1, builder layout and action order changed
+	b := diffLoad(t, `{"diagram": {"x": 2}, "actions": [
+		{"action_id": "a2", "provider": "generic", "action": "log", "parameters": {"content": "ciao"}, "next_action": {"ko": ["x1"]}},
+		{"action_id": "x1", "provider": "generic", "action": "log", "parameters": {"content": "hi"}, "next_action": {"ok": ["a2"]}}]}`)
+
+	diff, err := blueprint.DiffBlueprints(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Blueprint) != 0 {
+		t.Errorf("builder only changes reported: %+v", diff.Blueprint)
+	}
+	if len(diff.Actions) != 2 {
+		t.Fatalf("expected 2 action changes, got %+v", diff.Actions)
+	}
+	changes := make(map[blueprint.DiffKind]*blueprint.ActionChange)
+	for _, ac := range diff.Actions {
+		changes[ac.Kind] = ac
+	}
+	if ac := changes[blueprint.DiffRenamed]; ac == nil || ac.ActionID != "x1" || ac.OldActionID != "a1" {
+		t.Errorf("rename not detected: %+v", ac)
+	}
+	ac := changes[blueprint.DiffChanged]
+	if ac == nil || ac.ActionID != "a2" || len(ac.Changes) != 1 {
+		t.Fatalf("parameter change not detected: %+v", ac)
+	}
+	if vc := ac.Changes[0]; vc.Path != "$.parameters.content" || vc.Old != "bye" || vc.New != "ciao" {
+		t.Errorf("unexpected value change %+v", vc)
+	}
+
+	// the ok edge of the renamed action is kept, the ko one is new
+	if len(diff.Edges) != 1 {
+		t.Fatalf("expected 1 edge change, got %+v", diff.Edges)
+	}
+	if e := diff.Edges[0]; e.Kind != blueprint.DiffAdded || e.From != "a2" || e.To != "x1" || e.Port != blueprint.GraphPortKo {
+		t.Errorf("unexpected edge change %+v", e)
+	}
+
+	var text strings.Builder
+	if err := diff.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"a1", "x1", "bye", "ciao"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("%q not found in the text diff:\n%s", want, text.String())
+		}
+	}
+}
+
+func TestDiffAddRemove(t *testing.T) {
+	a := diffLoad(t, `{"actions": [
+		{"action_id": "a1", "provider": "generic", "action": "log", "parameters": {"content": "hi"}}]}`)
+	b := diffLoad(t, `{"actions": [
+		{"action_id": "b1", "provider": "generic", "action": "log", "parameters": {"content": "other"}}]}`)
+	diff, err := blueprint.DiffBlueprints(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]blueprint.DiffKind)
+	for _, ac := range diff.Actions {
+		kinds[ac.ActionID] = ac.Kind
+	}
+	if len(kinds) != 2 || kinds["a1"] != blueprint.DiffRemoved || kinds["b1"] != blueprint.DiffAdded {
+		t.Errorf("unexpected action changes %v", kinds)
+	}
+
+	diff, err = blueprint.DiffBlueprints(a, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Empty() {
+		t.Errorf("diff of the same blueprint is not empty: %+v", diff)
+	}
+}
@@ -0,0 +1,290 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/develatio/nebulant-cli/base"
+)
+
+// GraphPort type. The port of the action an edge leaves through
+type GraphPort string
+
+const (
+	GraphPortOk    GraphPort = "ok"
+	GraphPortKo    GraphPort = "ko"
+	GraphPortTrue  GraphPort = "true"
+	GraphPortFalse GraphPort = "false"
+	// foreach body, runs once per item
+	GraphPortBody GraphPort = "body"
+	// cleanup of a group, runs after the end of the execution
+	GraphPortFinally GraphPort = "finally"
+)
+
+// GraphEdge struct. An edge of the compiled graph
+type GraphEdge struct {
+	From *base.Action
+	To   *base.Action
+	Port GraphPort
+	// The edge starts a new thread (fan-out)
+	Thread bool
+	// The edge goes back to an already visited action
+	Loop bool
+	// ID of the end action removed in compilation that this edge goes through
+	ViaEnd string
+}
+
+// Graph struct. Plain view of the compiled graph of an IRB, used to export it
+type Graph struct {
+	Nodes []*base.Action
+	Edges []*GraphEdge
+	Start *base.Action
+}
+
+// NewGraph func. Build the graph of the IRB. End actions are shown as a
+// terminal node when they finish the execution; otherwise the edges that go
+// through them are marked with the id of the removed end action.
+func NewGraph(irb *IRBlueprint) *Graph {
+	g := &Graph{Start: irb.StartAction}
+	usedEnds := make(map[string]bool)
+
+	for i := range irb.BP.Actions {
+		action := &irb.BP.Actions[i]
+		if isEndAction(action) {
+			continue
+		}
+		ok, okTrue, okFalse, _ := parseNextActions(action.NextAction.Ok, irb.Actions)
+		ko, _, _, _ := parseNextActions(action.NextAction.Ko, irb.Actions)
+		if action.NextAction.ConditionalNext {
+			g.addEdges(action, okTrue, GraphPortTrue, usedEnds)
+			g.addEdges(action, okFalse, GraphPortFalse, usedEnds)
+		} else {
+			g.addEdges(action, ok, GraphPortOk, usedEnds)
+		}
+		g.addEdges(action, ko, GraphPortKo, usedEnds)
+		finally, _, _, _ := parseNextActions(action.NextAction.Finally, irb.Actions)
+		g.addEdges(action, finally, GraphPortFinally, usedEnds)
+		if action.BodyAction != nil {
+			g.Edges = append(g.Edges, &GraphEdge{From: action, To: action.BodyAction, Port: GraphPortBody})
+		}
+	}
+
+	for i := range irb.BP.Actions {
+		action := &irb.BP.Actions[i]
+		if isEndAction(action) && !usedEnds[action.ActionID] {
+			continue
+		}
+		g.Nodes = append(g.Nodes, action)
+	}
+	g.markLoops()
+	return g
+}
+
+func (g *Graph) addEdges(from *base.Action, nexts []*base.Action, port GraphPort, usedEnds map[string]bool) {
+	var edges []*GraphEdge
+	for _, next := range nexts {
+		if !isEndAction(next) {
+			edges = append(edges, &GraphEdge{From: from, To: next, Port: port})
+			continue
+		}
+		endNexts := next.NextAction.NextOk
+		if port == GraphPortKo {
+			endNexts = next.NextAction.NextKo
+		}
+		if len(endNexts) <= 0 {
+			usedEnds[next.ActionID] = true
+			edges = append(edges, &GraphEdge{From: from, To: next, Port: port})
+			continue
+		}
+		for _, en := range endNexts {
+			edges = append(edges, &GraphEdge{From: from, To: en, Port: port, ViaEnd: next.ActionID})
+		}
+	}
+	if len(edges) > 1 {
+		for _, e := range edges {
+			e.Thread = true
+		}
+	}
+	g.Edges = append(g.Edges, edges...)
+}
+
+// markLoops func. Mark as loop the edges that go back to an action in the
+// current path (back edges of a depth first walk).
+func (g *Graph) markLoops() {
+	out := make(map[*base.Action][]*GraphEdge)
+	for _, e := range g.Edges {
+		out[e.From] = append(out[e.From], e)
+	}
+	// 0: unvisited, 1: in path, 2: done
+	state := make(map[*base.Action]int)
+	var walk func(a *base.Action)
+	walk = func(a *base.Action) {
+		state[a] = 1
+		for _, e := range out[a] {
+			switch state[e.To] {
+			case 0:
+				walk(e.To)
+			case 1:
+				e.Loop = true
+			}
+		}
+		state[a] = 2
+	}
+	if g.Start != nil {
+		walk(g.Start)
+	}
+	for _, n := range g.Nodes {
+		if state[n] == 0 {
+			walk(n)
+		}
+	}
+}
+
+func graphNodeLabel(action *base.Action) string {
+	if isEndAction(action) {
+		return "end"
+	}
+	label := action.Provider + ": " + action.ActionName
+	if action.Output != nil && *action.Output != "" {
+		label = label + "\n" + *action.Output
+	}
+	return label
+}
+
+func graphEdgeLabel(e *GraphEdge) string {
+	label := string(e.Port)
+	if e.Thread {
+		label = label + " (thread)"
+	}
+	if e.Loop {
+		label = label + " (loop)"
+	}
+	if e.ViaEnd != "" {
+		label = label + " (via end)"
+	}
+	return label
+}
+
+var graphPortColors = map[GraphPort]string{
+	GraphPortOk:      "#2e7d32",
+	GraphPortKo:      "#c62828",
+	GraphPortTrue:    "#1565c0",
+	GraphPortFalse:   "#ef6c00",
+	GraphPortBody:    "#6a1b9a",
+	GraphPortFinally: "#5d4037",
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	return "\"" + s + "\""
+}
+
+// WriteDOT func. Write the graph in Graphviz DOT format
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph blueprint {\n")
+	b.WriteString("\trankdir=TB;\n")
+	b.WriteString("\tnode [shape=box, style=rounded, fontname=\"sans-serif\"];\n")
+	b.WriteString("\tedge [fontname=\"sans-serif\", fontsize=10];\n")
+	for _, n := range g.Nodes {
+		attrs := []string{"label=" + dotQuote(graphNodeLabel(n)), "tooltip=" + dotQuote(n.ActionID)}
+		switch {
+		case isEndAction(n):
+			attrs = append(attrs, "shape=doublecircle")
+		case n.JoinThreadsPoint:
+			attrs = append(attrs, "shape=invtrapezium", "style=filled", "fillcolor=\"#eeeeee\"")
+		case n.NextAction.ConditionalNext:
+			attrs = append(attrs, "shape=diamond", "style=solid")
+		}
+		if n == g.Start {
+			attrs = append(attrs, "penwidth=2")
+		}
+		fmt.Fprintf(&b, "\t%s [%s];\n", dotQuote(n.ActionID), strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		attrs := []string{"label=" + dotQuote(graphEdgeLabel(e)), "color=" + dotQuote(graphPortColors[e.Port])}
+		switch {
+		case e.Loop:
+			attrs = append(attrs, "style=dashed", "constraint=false")
+		case e.ViaEnd != "":
+			attrs = append(attrs, "style=dotted")
+		case e.Thread:
+			attrs = append(attrs, "style=bold")
+		}
+		fmt.Fprintf(&b, "\t%s -> %s [%s];\n", dotQuote(e.From.ActionID), dotQuote(e.To.ActionID), strings.Join(attrs, ", "))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func mermaidQuote(s string) string {
+	s = strings.ReplaceAll(s, "\"", "#quot;")
+	s = strings.ReplaceAll(s, "\n", "<br/>")
+	return "\"" + s + "\""
+}
+
+// WriteMermaid func. Write the graph as a Mermaid flowchart
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	ids := make(map[*base.Action]string)
+	for i, n := range g.Nodes {
+		ids[n] = fmt.Sprintf("n%d", i)
+	}
+	b.WriteString("flowchart TD\n")
+	for _, n := range g.Nodes {
+		label := mermaidQuote(graphNodeLabel(n))
+		switch {
+		case isEndAction(n):
+			fmt.Fprintf(&b, "\t%s(((%s)))\n", ids[n], label)
+		case n.JoinThreadsPoint:
+			fmt.Fprintf(&b, "\t%s[\\%s/]\n", ids[n], label)
+		case n.NextAction.ConditionalNext:
+			fmt.Fprintf(&b, "\t%s{%s}\n", ids[n], label)
+		case n == g.Start:
+			fmt.Fprintf(&b, "\t%s([%s])\n", ids[n], label)
+		default:
+			fmt.Fprintf(&b, "\t%s[%s]\n", ids[n], label)
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		switch {
+		case e.Loop, e.ViaEnd != "":
+			arrow = "-.->"
+		case e.Thread:
+			arrow = "==>"
+		}
+		fmt.Fprintf(&b, "\t%s %s|%s| %s\n", ids[e.From], arrow, mermaidQuote(graphEdgeLabel(e)), ids[e.To])
+	}
+	for i, e := range g.Edges {
+		fmt.Fprintf(&b, "\tlinkStyle %d stroke:%s\n", i, graphPortColors[e.Port])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,131 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+const testGraphBP = `
+actions:
+  - id: a
+    provider: generic
+    action: log
+    first: true
+    output: OUT
+    next: {ok: [b, c], ko: [d]}
+  - id: b
+    provider: generic
+    action: log
+    next: {ok: [a]}
+  - id: c
+    provider: generic
+    action: log
+    next: {ok: [e]}
+  - id: d
+    provider: generic
+    action: foreach
+    parameters: {items: [1, 2], body: f}
+  - id: e
+    provider: generic
+    action: end
+  - id: f
+    provider: generic
+    action: log
+`
+
+const testGraphDOT = `digraph blueprint {
+	rankdir=TB;
+	node [shape=box, style=rounded, fontname="sans-serif"];
+	edge [fontname="sans-serif", fontsize=10];
+	"a" [label="generic: log\nOUT", tooltip="a", penwidth=2];
+	"b" [label="generic: log", tooltip="b"];
+	"c" [label="generic: log", tooltip="c"];
+	"d" [label="generic: foreach", tooltip="d"];
+	"e" [label="end", tooltip="e", shape=doublecircle];
+	"f" [label="generic: log", tooltip="f"];
+	"a" -> "b" [label="ok (thread)", color="#2e7d32", style=bold];
+	"a" -> "c" [label="ok (thread)", color="#2e7d32", style=bold];
+	"a" -> "d" [label="ko", color="#c62828"];
+	"b" -> "a" [label="ok (loop)", color="#2e7d32", style=dashed, constraint=false];
+	"c" -> "e" [label="ok", color="#2e7d32"];
+	"d" -> "f" [label="body", color="#6a1b9a"];
+}
+`
+
+const testGraphMermaid = `flowchart TD
+	n0(["generic: log<br/>OUT"])
+	n1["generic: log"]
+	n2["generic: log"]
+	n3["generic: foreach"]
+	n4((("end")))
+	n5["generic: log"]
+	n0 ==>|"ok (thread)"| n1
+	n0 ==>|"ok (thread)"| n2
+	n0 -->|"ko"| n3
+	n1 -.->|"ok (loop)"| n0
+	n2 -->|"ok"| n4
+	n3 -->|"body"| n5
+	linkStyle 0 stroke:#2e7d32
+	linkStyle 1 stroke:#2e7d32
+	linkStyle 2 stroke:#c62828
+	linkStyle 3 stroke:#2e7d32
+	linkStyle 4 stroke:#2e7d32
+	linkStyle 5 stroke:#6a1b9a
+`
+
+func newTestGraph(t *testing.T) *blueprint.Graph {
+	t.Helper()
+	bp, err := blueprint.NewFromYAML([]byte(testGraphBP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	irb, err := blueprint.GenerateIRB(bp, &blueprint.IRBGenConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blueprint.NewGraph(irb)
+}
+
+func TestGraphDOT(t *testing.T) {
+	var b strings.Builder
+	if err := newTestGraph(t).WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != testGraphDOT {
+		t.Errorf("unexpected DOT output:\n%s", b.String())
+	}
+}
+
+func TestGraphMermaid(t *testing.T) {
+	var b strings.Builder
+	if err := newTestGraph(t).WriteMermaid(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != testGraphMermaid {
+		t.Errorf("unexpected Mermaid output:\n%s", b.String())
+	}
+}
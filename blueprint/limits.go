@@ -0,0 +1,99 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RunLimits struct. Concurrency limits of the execution
+type RunLimits struct {
+	// max actions running at once, zero for no limit
+	MaxParallel int
+	// limits by provider name (aws, hetznerCloud...)
+	Providers map[string]*ProviderLimit
+}
+
+// ProviderLimit struct
+type ProviderLimit struct {
+	// max actions of the provider running at once, zero for no limit
+	Concurrency int
+	// max actions of the provider started per second, zero for no limit
+	RPS float64
+}
+
+// IsZero func. True if no limit is set
+func (l *RunLimits) IsZero() bool {
+	return l == nil || l.MaxParallel <= 0 && len(l.Providers) == 0
+}
+
+// ParseProviderLimit func. Parse a provider limit in the form
+// provider=concurrency[:rps], eg. "hetznerCloud=4:2" or "aws=:10"
+func ParseProviderLimit(spec string) (string, *ProviderLimit, error) {
+	name, value, found := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || strings.TrimSpace(value) == "" {
+		return "", nil, fmt.Errorf("invalid provider limit %q, use provider=concurrency[:rps]", spec)
+	}
+	limit := &ProviderLimit{}
+	conc, rps, _ := strings.Cut(value, ":")
+	if conc = strings.TrimSpace(conc); conc != "" {
+		n, err := strconv.Atoi(conc)
+		if err != nil || n < 0 {
+			return "", nil, fmt.Errorf("invalid concurrency in provider limit %q", spec)
+		}
+		limit.Concurrency = n
+	}
+	if rps = strings.TrimSpace(rps); rps != "" {
+		n, err := strconv.ParseFloat(rps, 64)
+		if err != nil || n < 0 {
+			return "", nil, fmt.Errorf("invalid rps in provider limit %q", spec)
+		}
+		limit.RPS = n
+	}
+	return name, limit, nil
+}
+
+// String func
+func (l *RunLimits) String() string {
+	if l.IsZero() {
+		return "no limits"
+	}
+	var parts []string
+	if l.MaxParallel > 0 {
+		parts = append(parts, fmt.Sprintf("max-parallel=%d", l.MaxParallel))
+	}
+	names := make([]string, 0, len(l.Providers))
+	for name := range l.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pl := l.Providers[name]
+		parts = append(parts, fmt.Sprintf("%s=%d:%v", name, pl.Concurrency, pl.RPS))
+	}
+	return strings.Join(parts, " ")
+}
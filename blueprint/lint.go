@@ -0,0 +1,509 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/expr"
+)
+
+// LintSeverity type
+type LintSeverity string
+
+const (
+	// LintError const. The blueprint cannot run
+	LintError LintSeverity = "error"
+	// LintWarning const. The blueprint can run, but probably not as expected
+	LintWarning LintSeverity = "warning"
+)
+
+const (
+	LintRuleBuilderErrors       = "builder-errors"
+	LintRuleIRGeneration        = "ir-generation"
+	LintRuleMissingPort         = "missing-port"
+	LintRuleUnreachableAction   = "unreachable-action"
+	LintRuleUndefinedReference  = "undefined-reference"
+	LintRuleParallelOutputClash = "parallel-output-clash"
+	LintRuleSingleParentJoin    = "single-parent-join"
+	LintRuleParameters          = "parameter-declaration"
+)
+
+// LintRules map. Short description of every lint rule
+var LintRules map[string]string = map[string]string{
+	LintRuleBuilderErrors:       "The builder reported errors for this blueprint",
+	LintRuleIRGeneration:        "The blueprint cannot be compiled into an executable representation",
+	LintRuleMissingPort:         "An edge leaves through a port (OK/KO) that the action does not have",
+	LintRuleUnreachableAction:   "The action cannot be reached from the first action",
+	LintRuleUndefinedReference:  "A {{ reference }} is not defined by any upstream action",
+	LintRuleParallelOutputClash: "The same output name is defined in parallel branches",
+	LintRuleSingleParentJoin:    "A join point has less than two parents and joins nothing",
+	LintRuleParameters:          "The declaration of the blueprint parameters is not valid",
+}
+
+// LintIssue struct. A problem found by the static analysis of a blueprint
+type LintIssue struct {
+	Rule     string       `json:"rule"`
+	Severity LintSeverity `json:"severity"`
+	ActionID string       `json:"action_id,omitempty"`
+	Message  string       `json:"message"`
+}
+
+func (li *LintIssue) Error() string {
+	return li.Message
+}
+
+// Validate func. Run the IR generation and the lint passes over the
+// blueprint. Unlike GenerateIRB, errors found building the IR does not stop
+// the analysis.
+func Validate(bp *Blueprint) []*LintIssue {
+	var issues []*LintIssue
+
+	if bp.BuilderErrors > 0 {
+		issues = append(issues, &LintIssue{
+			Rule:     LintRuleBuilderErrors,
+			Severity: LintError,
+			Message:  fmt.Sprintf("the builder reported %v errors for this blueprint", bp.BuilderErrors),
+		})
+	}
+
+	if err := CheckParameters(bp.Parameters); err != nil {
+		issues = append(issues, &LintIssue{
+			Rule:     LintRuleParameters,
+			Severity: LintError,
+			Message:  err.Error(),
+		})
+	}
+
+	irb, irbErrors, err := generateIRB(bp, &IRBGenConfig{})
+	if err != nil {
+		return append(issues, &LintIssue{
+			Rule:     LintRuleIRGeneration,
+			Severity: LintError,
+			Message:  err.Error(),
+		})
+	}
+	for _, ie := range irbErrors {
+		var li *LintIssue
+		if errors.As(ie.WErr(), &li) {
+			issues = append(issues, li)
+			continue
+		}
+		issues = append(issues, &LintIssue{
+			Rule:     LintRuleIRGeneration,
+			Severity: LintError,
+			ActionID: ie.ActionID(),
+			Message:  ie.Error(),
+		})
+	}
+
+	issues = append(issues, Lint(irb)...)
+	return issues
+}
+
+// Lint func. Run the lint passes over an IRB
+func Lint(irb *IRBlueprint) []*LintIssue {
+	var issues []*LintIssue
+	issues = append(issues, lintUnreachable(irb)...)
+	issues = append(issues, lintUndefinedReferences(irb)...)
+	issues = append(issues, lintParallelOutputs(irb)...)
+	issues = append(issues, lintSingleParentJoins(irb)...)
+	return issues
+}
+
+// lintActionPorts func. Check the edges of the action against the ports
+// reported by the provider
+func lintActionPorts(action *base.Action) *LintIssue {
+	ok, ko, found := ActionPorts(action)
+	if !found {
+		return nil
+	}
+	if !ko && len(action.NextAction.NextKo) > 0 {
+		return &LintIssue{
+			Rule:     LintRuleMissingPort,
+			Severity: LintError,
+			ActionID: action.ActionID,
+			Message:  action.Provider + ": action " + action.ActionName + " has no KO port",
+		}
+	}
+	if !ok && len(action.NextAction.NextOk) > 0 {
+		return &LintIssue{
+			Rule:     LintRuleMissingPort,
+			Severity: LintError,
+			ActionID: action.ActionID,
+			Message:  action.Provider + ": action " + action.ActionName + " has no OK port",
+		}
+	}
+	return nil
+}
+
+// isEndAction func. End actions are removed from the graph while generating
+// the IRB
+func isEndAction(action *base.Action) bool {
+	return action.ActionName == "end" && action.Provider == "generic"
+}
+
+// walkReachable func. Return the ids of the actions reachable from the
+// given actions, following OK, KO, finally and foreach body edges. Actions for which stop returns
+// true are not followed (but are reported as reachable).
+func walkReachable(from []*base.Action, stop func(*base.Action) bool) map[string]*base.Action {
+	reached := make(map[string]*base.Action)
+	queue := from
+	for len(queue) > 0 {
+		action := queue[0]
+		queue = queue[1:]
+		if _, exists := reached[action.ActionID]; exists {
+			continue
+		}
+		reached[action.ActionID] = action
+		if stop != nil && stop(action) {
+			continue
+		}
+		queue = append(queue, action.NextAction.NextOk...)
+		queue = append(queue, action.NextAction.NextKo...)
+		queue = append(queue, action.NextAction.NextFinally...)
+		if action.BodyAction != nil {
+			queue = append(queue, action.BodyAction)
+		}
+	}
+	return reached
+}
+
+func lintUnreachable(irb *IRBlueprint) []*LintIssue {
+	var issues []*LintIssue
+	if irb.StartAction == nil {
+		return nil
+	}
+	from := []*base.Action{irb.StartAction}
+	if irb.OnExit != nil {
+		from = append(from, irb.OnExit)
+	}
+	reached := walkReachable(from, nil)
+	for i := range irb.BP.Actions {
+		action := &irb.BP.Actions[i]
+		if isEndAction(action) {
+			continue
+		}
+		if _, exists := reached[action.ActionID]; !exists {
+			issues = append(issues, &LintIssue{
+				Rule:     LintRuleUnreachableAction,
+				Severity: LintWarning,
+				ActionID: action.ActionID,
+				Message:  "action " + action.ActionName + " is unreachable from the first action",
+			})
+		}
+	}
+	return issues
+}
+
+var lintRefRegexp = regexp.MustCompile(`{{([^{}]*)}}`)
+var lintRefNameRegexp = regexp.MustCompile(`(?:\\.|[^.[|\\]+)+`)
+
+// builtinRefNames are reference names resolved by the store without any
+// record behind them.
+var builtinRefNames = []string{"env", "runtime", "state"}
+
+func isBuiltinRefName(name string) bool {
+	for _, b := range builtinRefNames {
+		if strings.ToLower(name) == b {
+			return true
+		}
+	}
+	return false
+}
+
+// extractRefNames func. Return the root names of the {{ references }} found
+// in text, in order of appearance, without duplicates and builtins.
+func extractRefNames(text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range lintRefRegexp.FindAllStringSubmatch(text, -1) {
+		refpaths := []string{strings.TrimSpace(match[1])}
+		if ex, err := expr.Parse(refpaths[0]); err == nil && !ex.IsReference() {
+			// references with a default value are not required
+			refpaths = ex.RequiredRefs()
+		}
+		for _, refpath := range refpaths {
+			m := lintRefNameRegexp.FindAllStringSubmatch(refpath, -1)
+			if len(m) <= 0 {
+				continue
+			}
+			name := strings.TrimSpace(m[0][0])
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			if !isBuiltinRefName(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// parameterStrings func. Return the decoded strings of the json
+// parameters, so that escaped quotes of the expressions are unescaped.
+func parameterStrings(raw json.RawMessage) string {
+	var params interface{}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return string(raw)
+	}
+	var sb strings.Builder
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch vv := v.(type) {
+		case string:
+			sb.WriteString(vv)
+			sb.WriteByte('\n')
+		case map[string]interface{}:
+			keys := make([]string, 0, len(vv))
+			for k := range vv {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				sb.WriteString(k)
+				sb.WriteByte('\n')
+				walk(vv[k])
+			}
+		case []interface{}:
+			for _, item := range vv {
+				walk(item)
+			}
+		}
+	}
+	walk(params)
+	return sb.String()
+}
+
+type lintDefineVarsParameters struct {
+	Vars []struct {
+		Key string `json:"key"`
+	} `json:"vars"`
+	Files []string `json:"files"`
+}
+
+// definedRefNames func. Return the reference names an action puts into the
+// store. dynamic is true if the names cannot be known before the execution.
+func definedRefNames(action *base.Action) (names []string, dynamic bool) {
+	if action.Output != nil && *action.Output != "" {
+		names = append(names, *action.Output)
+	}
+	if action.Provider != "generic" {
+		return names, false
+	}
+	if action.ActionName != "define_variables" && action.ActionName != "start" {
+		return names, false
+	}
+	params := &lintDefineVarsParameters{}
+	if err := json.Unmarshal(action.Parameters, params); err != nil {
+		return names, false
+	}
+	for _, v := range params.Vars {
+		names = append(names, v.Key)
+	}
+	return names, len(params.Files) > 0
+}
+
+func lintUndefinedReferences(irb *IRBlueprint) []*LintIssue {
+	var issues []*LintIssue
+	// the body of a foreach can use item, index and the
+	// vars defined upstream of the foreach
+	loops := make(map[string][]*base.Action)
+	for _, action := range irb.Actions {
+		for id := range BodyActions(action) {
+			loops[id] = append(loops[id], action)
+		}
+	}
+	// the cleanup chains run with the vars of all
+	// the threads, anything could be defined
+	var cleanupStarts []*base.Action
+	if irb.OnExit != nil {
+		cleanupStarts = append(cleanupStarts, irb.OnExit)
+	}
+	for _, action := range irb.Actions {
+		cleanupStarts = append(cleanupStarts, action.NextAction.NextFinally...)
+	}
+	cleanup := walkReachable(cleanupStarts, nil)
+	for i := range irb.BP.Actions {
+		action := &irb.BP.Actions[i]
+		refs := extractRefNames(parameterStrings(action.Parameters))
+		if len(refs) <= 0 {
+			continue
+		}
+		if _, exists := cleanup[action.ActionID]; exists {
+			continue
+		}
+		defined := make(map[string]bool)
+		for _, p := range irb.BP.Parameters {
+			defined[p.Name] = true
+		}
+		// define_variables can use the vars defined by itself, but no action
+		// can use its own output
+		names, dynamic := definedRefNames(action)
+		for _, n := range names {
+			if action.Output == nil || n != *action.Output {
+				defined[n] = true
+			}
+		}
+		ascendants := buildDirectAscendants(action)
+		for _, loop := range loops[action.ActionID] {
+			defined["item"] = true
+			defined["index"] = true
+			for id := range buildDirectAscendants(loop) {
+				ascendants[id] = true
+			}
+		}
+		for parentID := range ascendants {
+			parent, exists := irb.Actions[parentID]
+			if !exists {
+				continue
+			}
+			names, dyn := definedRefNames(parent)
+			for _, n := range names {
+				defined[n] = true
+			}
+			dynamic = dynamic || dyn
+		}
+		if dynamic {
+			continue
+		}
+		for _, ref := range refs {
+			if defined[ref] {
+				continue
+			}
+			issues = append(issues, &LintIssue{
+				Rule:     LintRuleUndefinedReference,
+				Severity: LintWarning,
+				ActionID: action.ActionID,
+				Message:  "{{ " + ref + " }} is not defined by any upstream action nor declared as parameter, it must be provided as a blueprint argument",
+			})
+		}
+	}
+	return issues
+}
+
+// forks func. Return the groups of actions that run in parallel after the
+// action. Conditional branches are exclusive, so each one is a group.
+func forks(action *base.Action) [][]*base.Action {
+	var groups [][]*base.Action
+	if action.NextAction.ConditionalNext {
+		groups = append(groups, action.NextAction.NextOkTrue, action.NextAction.NextOkFalse)
+	} else {
+		groups = append(groups, action.NextAction.NextOk)
+	}
+	groups = append(groups, action.NextAction.NextKo)
+	var res [][]*base.Action
+	for _, g := range groups {
+		if len(g) > 1 {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+func lintParallelOutputs(irb *IRBlueprint) []*LintIssue {
+	var issues []*LintIssue
+	reported := make(map[string]bool)
+	stopAtJoin := func(a *base.Action) bool { return a.JoinThreadsPoint }
+	for i := range irb.BP.Actions {
+		action := &irb.BP.Actions[i]
+		for _, group := range forks(action) {
+			branches := make([]map[string]*base.Action, len(group))
+			for b, child := range group {
+				branches[b] = walkReachable([]*base.Action{child}, stopAtJoin)
+			}
+			for b1 := 0; b1 < len(branches); b1++ {
+				for b2 := b1 + 1; b2 < len(branches); b2++ {
+					for _, a1 := range sortedActions(branches[b1]) {
+						if _, shared := branches[b2][a1.ActionID]; shared || a1.Output == nil || *a1.Output == "" {
+							continue
+						}
+						for _, a2 := range sortedActions(branches[b2]) {
+							if _, shared := branches[b1][a2.ActionID]; shared || a2.Output == nil {
+								continue
+							}
+							if *a1.Output != *a2.Output {
+								continue
+							}
+							ids := []string{a1.ActionID, a2.ActionID}
+							sort.Strings(ids)
+							key := *a1.Output + "\x00" + ids[0] + "\x00" + ids[1]
+							if reported[key] {
+								continue
+							}
+							reported[key] = true
+							issues = append(issues, &LintIssue{
+								Rule:     LintRuleParallelOutputClash,
+								Severity: LintWarning,
+								ActionID: a2.ActionID,
+								Message:  "output " + *a2.Output + " is also defined by action " + a1.ActionID + " in a parallel branch forked at action " + action.ActionID,
+							})
+						}
+					}
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// sortedActions func. Return the actions of the map ordered by id, to get
+// a stable output
+func sortedActions(actions map[string]*base.Action) []*base.Action {
+	var res []*base.Action
+	for _, a := range actions {
+		res = append(res, a)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ActionID < res[j].ActionID })
+	return res
+}
+
+func lintSingleParentJoins(irb *IRBlueprint) []*LintIssue {
+	var issues []*LintIssue
+	for i := range irb.BP.Actions {
+		action := &irb.BP.Actions[i]
+		if !action.JoinThreadsPoint {
+			continue
+		}
+		parents := make(map[string]bool)
+		for _, p := range action.Parents {
+			parents[p.ActionID] = true
+		}
+		if len(parents) < 2 {
+			issues = append(issues, &LintIssue{
+				Rule:     LintRuleSingleParentJoin,
+				Severity: LintWarning,
+				ActionID: action.ActionID,
+				Message:  fmt.Sprintf("join point has %d parent(s), nothing to join", len(parents)),
+			})
+		}
+	}
+	return issues
+}
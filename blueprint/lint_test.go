@@ -0,0 +1,314 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+const testLintBP = `
+actions:
+  - id: a
+    provider: generic
+    action: log
+    first: true
+    parameters: {content: "{{ FOO }} {{ env.HOME }}"}
+    next: {ok: [b, c]}
+  - id: b
+    provider: generic
+    action: log
+    output: OUT
+    next: {ok: [j]}
+  - id: c
+    provider: generic
+    action: noop
+    output: OUT
+    next: {ok: [j], ko: [d]}
+  - id: d
+    provider: generic
+    action: log
+    parameters: {content: "{{ OUT }}"}
+  - id: j
+    provider: generic
+    action: join_threads
+  - id: z
+    provider: generic
+    action: join_threads
+`
+
+// testSpec struct. Layout of the actions of the lint tests
+type testSpec struct {
+	ko bool
+}
+
+func (s testSpec) Ports() (bool, bool) { return true, s.ko }
+func (s testSpec) Calls() string       { return "" }
+
+func TestValidate(t *testing.T) {
+	blueprint.RegisterActions("generic", map[string]testSpec{
+		"log":  {ko: true},
+		"noop": {ko: false},
+	})
+	defer blueprint.RegisterActions("generic", map[string]testSpec{})
+
+	bp, err := blueprint.NewFromYAML([]byte(testLintBP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]bool)
+	for _, issue := range blueprint.Validate(bp) {
+		found[issue.Rule+":"+issue.ActionID] = true
+	}
+	expected := []string{
+		blueprint.LintRuleMissingPort + ":c",
+		blueprint.LintRuleUnreachableAction + ":z",
+		blueprint.LintRuleUndefinedReference + ":a",
+		blueprint.LintRuleParallelOutputClash + ":c",
+		blueprint.LintRuleSingleParentJoin + ":z",
+	}
+	for _, e := range expected {
+		if !found[e] {
+			t.Errorf("expected issue %s, got %v", e, found)
+		}
+	}
+	if len(found) != len(expected) {
+		t.Errorf("expected %d issues, got %v", len(expected), found)
+	}
+}
+
+const testForeachBP = `
+actions:
+  - id: v
+    provider: generic
+    action: define_variables
+    first: true
+    parameters: {vars: [{key: NAMES, value: "[1, 2]"}]}
+    next: {ok: [f]}
+  - id: f
+    provider: generic
+    action: foreach
+    output: RES
+    parameters: {items: "{{ NAMES }}", body: b}
+  - id: b
+    provider: generic
+    action: log
+    parameters: {content: "{{ index }} {{ item }} {{ NAMES }} {{ RES }}"}
+`
+
+func TestValidateForeach(t *testing.T) {
+	bp, err := blueprint.NewFromYAML([]byte(testForeachBP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := blueprint.Validate(bp)
+	if len(issues) != 1 || issues[0].Rule != blueprint.LintRuleUndefinedReference || issues[0].ActionID != "b" {
+		for _, issue := range issues {
+			t.Log(issue.Rule, issue.ActionID, issue.Message)
+		}
+		t.Fatalf("expected only the undefined reference to RES in the body, got %d issues", len(issues))
+	}
+
+	bp.Actions[1].Parameters = []byte(`{"items": "[]", "body": "x"}`)
+	found := false
+	for _, issue := range blueprint.Validate(bp) {
+		if issue.Rule == blueprint.LintRuleIRGeneration && issue.ActionID == "f" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected an error for the unknown body action")
+	}
+}
+
+const testCleanupBP = `
+on_exit: x
+actions:
+  - id: g
+    provider: generic
+    action: group
+    first: true
+    next: {ok: [a], finally: [f]}
+  - id: a
+    provider: generic
+    action: log
+    output: OUT
+    next: {finally: [f]}
+  - id: f
+    provider: generic
+    action: log
+    parameters: {content: "{{ OUT }} {{ runtime.exit_status }}"}
+  - id: x
+    provider: generic
+    action: log
+    parameters: {content: "{{ OUT }}"}
+`
+
+func TestValidateCleanup(t *testing.T) {
+	bp, err := blueprint.NewFromYAML([]byte(testCleanupBP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := blueprint.Validate(bp)
+	if len(issues) != 1 || issues[0].Rule != blueprint.LintRuleIRGeneration || issues[0].ActionID != "a" {
+		for _, issue := range issues {
+			t.Log(issue.Rule, issue.ActionID, issue.Message)
+		}
+		t.Fatalf("expected only the finally edge error of a non group action, got %d issues", len(issues))
+	}
+}
+
+const testJoinBP = `
+actions:
+  - id: s
+    provider: generic
+    action: log
+    first: true
+    next: {ok: [a, b]}
+  - id: a
+    provider: generic
+    action: log
+    next: {ok: [j]}
+  - id: b
+    provider: generic
+    action: log
+    next: {ok: [j]}
+  - id: j
+    provider: generic
+    action: join_threads
+    parameters: {mode: any}
+    timeout: 10s
+    next: {ok: [l], ko: [l]}
+  - id: l
+    provider: generic
+    action: log
+`
+
+func TestValidateJoin(t *testing.T) {
+	bp, err := blueprint.NewFromYAML([]byte(testJoinBP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range blueprint.Validate(bp) {
+		t.Errorf("unexpected issue %s %s: %s", issue.Rule, issue.ActionID, issue.Message)
+	}
+
+	for params, valid := range map[string]bool{
+		`{"count": 2}`:                          true,
+		`{"mode": "all"}`:                       true,
+		`{"count": 3}`:                          false,
+		`{"mode": "count"}`:                     false,
+		`{"mode": "first"}`:                     false,
+		`{"mode": "any", "count": 2}`:           true,
+		`{"merge": "stack"}`:                    true,
+		`{"mode": "any", "merge": "namespace"}`: true,
+		`{"merge": "random"}`:                   false,
+	} {
+		bp, err := blueprint.NewFromYAML([]byte(testJoinBP))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bp.Actions[3].Parameters = []byte(params)
+		found := false
+		for _, issue := range blueprint.Validate(bp) {
+			if issue.Rule == blueprint.LintRuleIRGeneration && issue.ActionID == "j" {
+				found = true
+			}
+		}
+		if found == valid {
+			t.Errorf("expected valid=%v for join parameters %s", valid, params)
+		}
+	}
+}
+
+func TestValidateRetry(t *testing.T) {
+	for retry, valid := range map[string]bool{
+		`{"max_attempts": 5, "backoff": "jitter", "delay": "1s", "max_delay": "30s"}`: true,
+		`{"retry_on": [{"http_status": [429, 503]}, {"message": "^throttl"}]}`:        true,
+		`{"backoff": "linear"}`:                false,
+		`{"retry_on": [{"message": "(oops"}]}`: false,
+		`{"max_attempts": -1}`:                 false,
+	} {
+		bp, err := blueprint.NewFromYAML([]byte(testJoinBP))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bp.Actions[1].Retry = &base.RetryPolicy{}
+		if err := json.Unmarshal([]byte(retry), bp.Actions[1].Retry); err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, issue := range blueprint.Validate(bp) {
+			if issue.Rule == blueprint.LintRuleIRGeneration && issue.ActionID == "a" {
+				found = true
+			}
+		}
+		if found == valid {
+			t.Errorf("expected valid=%v for retry %s", valid, retry)
+		}
+	}
+}
+
+const testExpressionsBP = `
+actions:
+  - id: a
+    provider: generic
+    action: log
+    first: true
+    parameters: {content: "{{ FOO ?? \"x\" | upper }} {{ BAR + 1 > 2 ? env.HOME : \"no\" }}"}
+`
+
+func TestValidateExpressions(t *testing.T) {
+	bp, err := blueprint.NewFromYAML([]byte(testExpressionsBP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var undefined []string
+	for _, issue := range blueprint.Validate(bp) {
+		if issue.Rule == blueprint.LintRuleUndefinedReference {
+			undefined = append(undefined, issue.Message)
+		}
+	}
+	// FOO has a default value
+	if len(undefined) != 1 || !strings.Contains(undefined[0], "{{ BAR }}") {
+		t.Errorf("expected BAR as the only undefined reference, got %v", undefined)
+	}
+}
+
+func TestActionSpecs(t *testing.T) {
+	blueprint.RegisterActions("test", map[string]testSpec{"run": {ko: false}})
+	ok, ko, found := blueprint.ActionPorts(&base.Action{Provider: "test", ActionName: "run"})
+	if !found || !ok || ko {
+		t.Errorf("unexpected ports ok=%v ko=%v found=%v", ok, ko, found)
+	}
+	if _, found := blueprint.ActionCall(&base.Action{Provider: "test", ActionName: "missing"}); found {
+		t.Error("unknown action found")
+	}
+	if _, _, found := blueprint.ActionPorts(&base.Action{Provider: "unknown", ActionName: "run"}); found {
+		t.Error("action of an unknown provider found")
+	}
+}
@@ -0,0 +1,314 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// ParameterType string
+type ParameterType string
+
+const (
+	ParameterTypeString ParameterType = "string"
+	ParameterTypeInt    ParameterType = "int"
+	ParameterTypeBool   ParameterType = "bool"
+	ParameterTypeList   ParameterType = "list"
+	ParameterTypeEnum   ParameterType = "enum"
+)
+
+// Parameter struct. Input parameter declared by the blueprint
+type Parameter struct {
+	Name        string        `json:"name" yaml:"name"`
+	Type        ParameterType `json:"type,omitempty" yaml:"type,omitempty"`
+	Default     interface{}   `json:"default,omitempty" yaml:"default,omitempty"`
+	Required    bool          `json:"required,omitempty" yaml:"required,omitempty"`
+	Regex       string        `json:"regex,omitempty" yaml:"regex,omitempty"`
+	Description string        `json:"description,omitempty" yaml:"description,omitempty"`
+	// Allowed values of enum parameters
+	Choices []string `json:"choices,omitempty" yaml:"choices,omitempty"`
+	// the value is redacted from logs, events and reports. Parameters
+	// with a secret name (password, privkey...) are always secret
+	Secret bool `json:"secret,omitempty" yaml:"secret,omitempty"`
+}
+
+// GetType func. String is the default type
+func (p *Parameter) GetType() ParameterType {
+	if p.Type == "" {
+		return ParameterTypeString
+	}
+	return p.Type
+}
+
+// check func. Validate the declaration of the parameter
+func (p *Parameter) check() error {
+	if p.Name == "" {
+		return fmt.Errorf("parameter with empty name")
+	}
+	switch p.GetType() {
+	case ParameterTypeString, ParameterTypeInt, ParameterTypeBool, ParameterTypeList:
+	case ParameterTypeEnum:
+		if len(p.Choices) <= 0 {
+			return fmt.Errorf("enum parameter %s has no choices", p.Name)
+		}
+	default:
+		return fmt.Errorf("parameter %s has unknown type %s", p.Name, p.Type)
+	}
+	if p.Regex != "" {
+		if _, err := regexp.Compile(p.Regex); err != nil {
+			return fmt.Errorf("parameter %s has bad regex: %s", p.Name, err.Error())
+		}
+	}
+	if p.Default != nil {
+		if _, err := p.Convert(p.Default); err != nil {
+			return fmt.Errorf("bad default value: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// Convert func. Convert a raw value (a string from cli or any json value)
+// into the type of the parameter, checking the regex and the choices.
+func (p *Parameter) Convert(value interface{}) (interface{}, error) {
+	switch p.GetType() {
+	case ParameterTypeInt:
+		var n int64
+		var err error
+		switch v := value.(type) {
+		case string:
+			n, err = strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		case json.Number:
+			n, err = v.Int64()
+		case float64:
+			if v != math.Trunc(v) {
+				err = fmt.Errorf("%v is not an integer", v)
+			}
+			n = int64(v)
+		case int:
+			n = int64(v)
+		case int64:
+			n = v
+		default:
+			err = fmt.Errorf("%v is not an integer", v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s expects an int: %s", p.Name, err.Error())
+		}
+		return n, nil
+	case ParameterTypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("parameter %s expects a bool, got %s", p.Name, v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("parameter %s expects a bool, got %v", p.Name, value)
+	case ParameterTypeList:
+		var items []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			items = v
+		case []string:
+			for _, item := range v {
+				items = append(items, item)
+			}
+		case string:
+			trimmed := strings.TrimSpace(v)
+			if strings.HasPrefix(trimmed, "[") {
+				if err := json.Unmarshal([]byte(trimmed), &items); err != nil {
+					return nil, fmt.Errorf("parameter %s expects a list: %s", p.Name, err.Error())
+				}
+				break
+			}
+			items = []interface{}{}
+			if trimmed == "" {
+				break
+			}
+			for _, item := range strings.Split(v, ",") {
+				items = append(items, strings.TrimSpace(item))
+			}
+		default:
+			return nil, fmt.Errorf("parameter %s expects a list, got %v", p.Name, value)
+		}
+		for _, item := range items {
+			if err := p.match(fmt.Sprintf("%v", item)); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	// string and enum
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case json.Number, float64, int, int64, bool:
+		s = fmt.Sprintf("%v", v)
+	default:
+		return nil, fmt.Errorf("parameter %s expects a string, got %v", p.Name, value)
+	}
+	if p.GetType() == ParameterTypeEnum {
+		found := false
+		for _, c := range p.Choices {
+			if c == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("parameter %s should be one of %s, got %s", p.Name, strings.Join(p.Choices, ", "), s)
+		}
+	}
+	if err := p.match(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (p *Parameter) match(s string) error {
+	if p.Regex == "" {
+		return nil
+	}
+	if !regexp.MustCompile(p.Regex).MatchString(s) {
+		return fmt.Errorf("parameter %s value %s does not match %s", p.Name, s, p.Regex)
+	}
+	return nil
+}
+
+// CheckParameters func. Validate the parameters declaration
+func CheckParameters(params []*Parameter) error {
+	seen := make(map[string]bool)
+	for _, p := range params {
+		if err := p.check(); err != nil {
+			return err
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicated parameter %s", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
+
+// ResolveParameters func. Convert the args declared as parameters into
+// their types and add the defaults of the missing ones. Args not declared
+// are returned as is. Missing required parameters are reported as error.
+func ResolveParameters(params []*Parameter, args []*IRBArg) ([]*IRBArg, error) {
+	if err := CheckParameters(params); err != nil {
+		return nil, err
+	}
+	declared := make(map[string]*Parameter)
+	for _, p := range params {
+		declared[p.Name] = p
+	}
+
+	var resolved []*IRBArg
+	given := make(map[string]*IRBArg)
+	for _, arg := range args {
+		p, exists := declared[arg.Name]
+		if !exists {
+			resolved = append(resolved, arg)
+			continue
+		}
+		value, err := p.Convert(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		if prev, repeated := given[arg.Name]; repeated {
+			// repeated list parameters are concatenated
+			if p.GetType() != ParameterTypeList {
+				return nil, fmt.Errorf("parameter %s given more than once", p.Name)
+			}
+			prev.Value = append(prev.Value.([]interface{}), value.([]interface{})...)
+			continue
+		}
+		targ := &IRBArg{Name: arg.Name, Value: value, Secret: p.Secret}
+		given[arg.Name] = targ
+		resolved = append(resolved, targ)
+	}
+
+	var missing []string
+	for _, p := range params {
+		if _, exists := given[p.Name]; exists {
+			continue
+		}
+		if p.Default != nil {
+			value, _ := p.Convert(p.Default)
+			resolved = append(resolved, &IRBArg{Name: p.Name, Value: value, Secret: p.Secret})
+			continue
+		}
+		if p.Required {
+			missing = append(missing, p.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required parameter(s): %s", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+// ParseVarsFile func. Read blueprint args from a json object or a .env file
+func ParseVarsFile(path string) ([]*IRBArg, error) {
+	var args []*IRBArg
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err := os.ReadFile(path) // #nosec G304 -- Not a file inclusion, just a json read
+		if err != nil {
+			return nil, err
+		}
+		vars := make(map[string]interface{})
+		if err := json.Unmarshal(data, &vars); err != nil {
+			return nil, fmt.Errorf("cannot read vars file %s: %s", path, err.Error())
+		}
+		for name, value := range vars {
+			args = append(args, &IRBArg{Name: name, Value: value})
+		}
+	case ".env":
+		vars, err := godotenv.Read(path)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range vars {
+			args = append(args, &IRBArg{Name: name, Value: value})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported vars file %s, use .json or .env", path)
+	}
+	sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
+	return args, nil
+}
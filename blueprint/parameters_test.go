@@ -0,0 +1,78 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+func TestResolveParameters(t *testing.T) {
+	params := []*blueprint.Parameter{
+		{Name: "COUNT", Type: blueprint.ParameterTypeInt, Required: true},
+		{Name: "DRY", Type: blueprint.ParameterTypeBool, Default: false},
+		{Name: "TAGS", Type: blueprint.ParameterTypeList},
+		{Name: "REGION", Type: blueprint.ParameterTypeEnum, Choices: []string{"eu", "us"}},
+		{Name: "NAME", Regex: "^[a-z]+$"},
+	}
+	args, err := blueprint.ParseBPArgs([]string{"--COUNT=3", "--TAGS=a,b", "--TAGS=c", "--REGION=us", "--NAME=bob", "--OTHER=x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := blueprint.ResolveParameters(params, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]interface{})
+	for _, arg := range resolved {
+		values[arg.Name] = arg.Value
+	}
+	expected := map[string]interface{}{
+		"COUNT":  int64(3),
+		"DRY":    false,
+		"TAGS":   []interface{}{"a", "b", "c"},
+		"REGION": "us",
+		"NAME":   "bob",
+		"OTHER":  "x",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	bad := [][]string{
+		{},
+		{"--COUNT=x"},
+		{"--COUNT=1", "--COUNT=2"},
+		{"--COUNT=1", "--REGION=asia"},
+		{"--COUNT=1", "--NAME=Bob"},
+		{"--COUNT=1", "--DRY=maybe"},
+	}
+	for _, b := range bad {
+		args, _ := blueprint.ParseBPArgs(b)
+		if _, err := blueprint.ResolveParameters(params, args); err == nil {
+			t.Errorf("expected error for args %v", b)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/cast"
+	"github.com/develatio/nebulant-cli/expr"
+	"github.com/develatio/nebulant-cli/storage"
+	"github.com/develatio/nebulant-cli/util"
+)
+
+// destructivePrefixes are the action name prefixes of the actions that
+// remove, detach or stop resources
+var destructivePrefixes = []string{"delete_", "release_", "remove_", "detach_", "dettach_", "unassign_", "stop_"}
+
+// PlanBranch struct. Next actions of a step through a port.
+type PlanBranch struct {
+	Port GraphPort `json:"port"`
+	To   []string  `json:"to"`
+	// More than one next action through ok: they run in parallel threads
+	Parallel bool `json:"parallel,omitempty"`
+	// Next actions already planned (the branch loops back)
+	Loops []string `json:"loops,omitempty"`
+}
+
+// PlanStep struct. What an action would do.
+type PlanStep struct {
+	Index    int    `json:"index"`
+	ActionID string `json:"action_id"`
+	Provider string `json:"provider"`
+	Action   string `json:"action"`
+	// External calls made by the actor, empty if the actor is local
+	Call        string `json:"call,omitempty"`
+	Destructive bool   `json:"destructive,omitempty"`
+	// Parameters with the references resolved when possible
+	Parameters interface{} `json:"parameters,omitempty"`
+	Output     string      `json:"output,omitempty"`
+	JoinPoint  bool        `json:"join_point,omitempty"`
+	// Branches the join waits for, zero for all of them
+	JoinCount int `json:"join_count,omitempty"`
+	// References that depend on values only known at run time
+	Pending []string `json:"pending,omitempty"`
+	// Errors found rehearsing the action
+	Errors   []string      `json:"errors,omitempty"`
+	Branches []*PlanBranch `json:"branches,omitempty"`
+}
+
+// Plan struct. Dry-run of a compiled blueprint.
+type Plan struct {
+	Steps []*PlanStep `json:"steps"`
+}
+
+// planVar struct. Origin of a reference.
+type planVar struct {
+	// the value is into the store of the scope
+	known  bool
+	secret bool
+	// action that defines the value at run time
+	from string
+}
+
+// planScope struct. Values known before the execution. The known values
+// are kept into a store, so the references are resolved by the same
+// evaluator used at run time.
+type planScope struct {
+	vars  map[string]*planVar
+	store *storage.Store
+}
+
+func newPlanScope() *planScope {
+	st := storage.NewStore()
+	st.SetLogger(&cast.DummyLogger{})
+	return &planScope{vars: make(map[string]*planVar), store: st}
+}
+
+// define func. Set a value known before the execution.
+func (sc *planScope) define(name string, value interface{}, secret bool) {
+	secret = secret || base.IsSecretName(name)
+	err := sc.store.Insert(&base.StorageRecord{RefName: name, Value: value, Literal: true, Secret: secret}, "")
+	sc.vars[name] = &planVar{known: err == nil, secret: secret}
+}
+
+// NewPlan func. Walk the graph of the IRB from the start action without
+// running any actor. References to blueprint args and literal variables are
+// resolved; references to action outputs are replaced with placeholders.
+// Every step is rehearsed by the actor with the known values.
+func NewPlan(irb *IRBlueprint) *Plan {
+	plan := &Plan{}
+	g := NewGraph(irb)
+	outgoing := make(map[string][]*GraphEdge)
+	for _, e := range g.Edges {
+		outgoing[e.From.ActionID] = append(outgoing[e.From.ActionID], e)
+	}
+
+	sc := newPlanScope()
+	for _, arg := range irb.Args {
+		sc.define(arg.Name, arg.Value, arg.Secret)
+	}
+
+	if g.Start == nil {
+		return plan
+	}
+	planned := make(map[string]bool)
+	queue := []*base.Action{g.Start}
+	planned[g.Start.ActionID] = true
+	// the on_exit chain is planned after the main graph
+	onExit := irb.OnExit
+	for len(queue) > 0 || (onExit != nil && !planned[onExit.ActionID]) {
+		if len(queue) <= 0 {
+			queue = append(queue, onExit)
+			planned[onExit.ActionID] = true
+		}
+		action := queue[0]
+		queue = queue[1:]
+		step := newPlanStep(action, sc)
+		step.Index = len(plan.Steps) + 1
+		plan.Steps = append(plan.Steps, step)
+
+		byPort := make(map[GraphPort]*PlanBranch)
+		for _, e := range outgoing[action.ActionID] {
+			br, exists := byPort[e.Port]
+			if !exists {
+				br = &PlanBranch{Port: e.Port}
+				byPort[e.Port] = br
+			}
+			if e.Loop || planned[e.To.ActionID] {
+				br.Loops = append(br.Loops, e.To.ActionID)
+				continue
+			}
+			br.To = append(br.To, e.To.ActionID)
+			planned[e.To.ActionID] = true
+			queue = append(queue, e.To)
+		}
+		for _, port := range []GraphPort{GraphPortBody, GraphPortOk, GraphPortTrue, GraphPortFalse, GraphPortKo, GraphPortFinally} {
+			if br, exists := byPort[port]; exists {
+				br.Parallel = port == GraphPortOk && len(br.To)+len(br.Loops) > 1
+				step.Branches = append(step.Branches, br)
+			}
+		}
+	}
+	return plan
+}
+
+// Err func. The errors found rehearsing the steps, nil if none.
+func (p *Plan) Err() error {
+	var errs []error
+	for _, step := range p.Steps {
+		for _, e := range step.Errors {
+			errs = append(errs, fmt.Errorf("step %d (%s): %s", step.Index, step.ActionID, e))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func newPlanStep(action *base.Action, sc *planScope) *PlanStep {
+	step := &PlanStep{
+		ActionID:  action.ActionID,
+		Provider:  action.Provider,
+		Action:    action.ActionName,
+		JoinPoint: action.JoinThreadsPoint,
+		JoinCount: action.JoinPolicy.Needed(),
+	}
+	for _, prefix := range destructivePrefixes {
+		if strings.HasPrefix(action.ActionName, prefix) {
+			step.Destructive = true
+			break
+		}
+	}
+	if call, found := ActionCall(action); found {
+		step.Call = call
+	}
+
+	pending := make(map[string]bool)
+	var resolved interface{}
+	if len(action.Parameters) > 0 {
+		var params interface{}
+		dec := json.NewDecoder(bytes.NewReader(action.Parameters))
+		dec.UseNumber()
+		if err := dec.Decode(&params); err == nil {
+			step.Parameters, resolved = sc.resolve(params, pending, &step.Errors)
+		}
+	}
+	if rf, exists := ActionRehearsalFuncs[action.Provider]; exists {
+		if err := rf(action, &rehearsalStore{Store: sc.store, sc: sc}); err != nil {
+			step.Errors = append(step.Errors, err.Error())
+		}
+	}
+	for ref := range pending {
+		step.Pending = append(step.Pending, ref)
+	}
+	sort.Strings(step.Pending)
+
+	// values defined by this action for the next steps
+	names, _ := definedRefNames(action)
+	for _, name := range names {
+		sc.vars[name] = &planVar{from: action.ActionID}
+	}
+	if action.Output != nil && *action.Output != "" {
+		step.Output = *action.Output
+	}
+	if action.Provider == "generic" && (action.ActionName == "define_variables" || action.ActionName == "start") {
+		sc.defineVars(resolved)
+	}
+	return step
+}
+
+// rehearsalStore struct. Store of the rehearsal of the steps. The typed
+// parameters get the known values, or typed placeholders for the values
+// only known at run time.
+type rehearsalStore struct {
+	*storage.Store
+	sc *planScope
+}
+
+// TypedInterpolation func
+func (rs *rehearsalStore) TypedInterpolation(data []byte, v interface{}) ([]byte, error) {
+	return util.TypedJSON(data, v, func(expression string, t reflect.Type) (interface{}, bool, error) {
+		ex, err := expr.Parse(expression)
+		if err != nil {
+			return nil, false, nil
+		}
+		if !rs.sc.known(ex) {
+			return util.TypedPlaceholder(t), true, nil
+		}
+		nv, err := rs.Evaluate(ex)
+		if err != nil {
+			return nil, false, err
+		}
+		return nv, true, nil
+	})
+}
+
+// known func. True if every reference of the expression
+// is known before the execution.
+func (sc *planScope) known(ex *expr.Expr) bool {
+	for _, ref := range ex.Refs() {
+		pv, exists := sc.vars[expr.RefName(ref)]
+		if !exists || !pv.known {
+			return false
+		}
+	}
+	return true
+}
+
+// defineVars func. Literal values of define_variables are known
+// before the execution, unless they are asked at run time.
+func (sc *planScope) defineVars(params interface{}) {
+	pm, ok := params.(map[string]interface{})
+	if !ok {
+		return
+	}
+	list, _ := pm["vars"].([]interface{})
+	for _, v := range list {
+		vm, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, _ := vm["key"].(string)
+		value, hasValue := vm["value"]
+		if key == "" || !hasValue || value == nil || vm["ask_at_runtime"] == true {
+			continue
+		}
+		if s, ok := value.(string); ok && lintRefRegexp.MatchString(s) {
+			// depends on a pending value
+			continue
+		}
+		sc.define(key, value, vm["secret"] == true)
+	}
+}
+
+// resolve func. Resolve references in every string of the decoded json.
+// Returns the value to show, secrets masked, and the resolved value. The
+// references only known at run time are kept in the resolved value.
+func (sc *planScope) resolve(v interface{}, pending map[string]bool, errs *[]string) (interface{}, interface{}) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		// vars of define_variables
+		key, _ := vv["key"].(string)
+		secretVar := vv["secret"] == true || base.IsSecretName(key)
+		shown := make(map[string]interface{}, len(vv))
+		resolved := make(map[string]interface{}, len(vv))
+		for k, item := range vv {
+			shown[k], resolved[k] = sc.resolve(item, pending, errs)
+			if _, isString := item.(string); isString && (base.IsSecretName(k) || (secretVar && k == "value")) {
+				shown[k] = base.SecretMask
+			}
+		}
+		return shown, resolved
+	case []interface{}:
+		shown := make([]interface{}, len(vv))
+		resolved := make([]interface{}, len(vv))
+		for i, item := range vv {
+			shown[i], resolved[i] = sc.resolve(item, pending, errs)
+		}
+		return shown, resolved
+	case string:
+		return sc.resolveString(vv, pending, errs)
+	}
+	return v, v
+}
+
+// resolveString func. As resolve, for the references of a string.
+func (sc *planScope) resolveString(text string, pending map[string]bool, errs *[]string) (string, string) {
+	shown, resolved := text, text
+	for _, match := range lintRefRegexp.FindAllStringSubmatch(text, -1) {
+		refpath := strings.TrimSpace(match[1])
+		value, known, secret, err := sc.resolveRef(match[0], refpath, pending)
+		if err != nil {
+			*errs = append(*errs, err.Error())
+			shown = strings.Replace(shown, match[0], "<{{ "+refpath+" }} invalid>", 1)
+			continue
+		}
+		if !known {
+			// placeholder, the reference is kept
+			shown = strings.Replace(shown, match[0], value, 1)
+			continue
+		}
+		resolved = strings.Replace(resolved, match[0], value, 1)
+		if secret {
+			value = base.SecretMask
+		}
+		shown = strings.Replace(shown, match[0], value, 1)
+	}
+	return shown, resolved
+}
+
+// resolveRef func. The value of a reference or expression evaluated by
+// the store of the scope, or a placeholder if it depends on values only
+// known at run time. known is false for placeholders.
+func (sc *planScope) resolveRef(match string, refpath string, pending map[string]bool) (value string, known bool, secret bool, err error) {
+	ex, perr := expr.Parse(refpath)
+	plain := perr != nil || ex.IsReference()
+	refs := []string{refpath}
+	if perr == nil {
+		refs = ex.Refs()
+	}
+	placeholder := func(text string) (string, bool, bool, error) {
+		for _, ref := range refs {
+			pending[ref] = true
+		}
+		return text, false, false, nil
+	}
+
+	for _, ref := range refs {
+		name := expr.RefName(ref)
+		if perr != nil {
+			// not a valid expression, legacy syntax ({{ a | $[0] }})
+			m := lintRefNameRegexp.FindAllString(ref, -1)
+			if len(m) <= 0 {
+				return placeholder(match)
+			}
+			name = strings.TrimSpace(m[0])
+		}
+		pv, exists := sc.vars[name]
+		switch {
+		case exists && pv.from != "" && plain:
+			return placeholder("<{{ " + refpath + " }} from " + pv.from + ">")
+		case exists && !pv.known && plain:
+			return placeholder("<{{ " + refpath + " }}>")
+		case exists && !pv.known:
+			return placeholder("<{{ " + ex.String() + " }}>")
+		case !exists && isBuiltinRefName(name) && plain:
+			return placeholder("<{{ " + refpath + " }} at run time>")
+		case !exists && isBuiltinRefName(name):
+			return placeholder("<{{ " + ex.String() + " }}>")
+		case !exists && plain:
+			return placeholder("<{{ " + refpath + " }} undefined>")
+		}
+		// undefined references of expressions are evaluated,
+		// defaults ({{ a ?? "x" }}) apply
+		secret = secret || (exists && pv.secret)
+	}
+	value = match
+	if err := sc.store.Interpolate(&value); err != nil {
+		return "", false, false, fmt.Errorf("%s: %v", match, err)
+	}
+	return value, true, secret, nil
+}
+
+// WriteText func. Human readable plan.
+func (p *Plan) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	ndestructive := 0
+	ninvalid := 0
+	for _, step := range p.Steps {
+		if len(step.Errors) > 0 {
+			ninvalid++
+		}
+		mark := ""
+		if step.Destructive {
+			ndestructive++
+			mark = "  [DESTRUCTIVE]"
+		}
+		fmt.Fprintf(&sb, "%3d. %s/%s (%s)%s\n", step.Index, step.Provider, step.Action, step.ActionID, mark)
+		if step.Call != "" {
+			fmt.Fprintf(&sb, "       call: %s\n", step.Call)
+		}
+		if step.Parameters != nil {
+			buf := new(bytes.Buffer)
+			enc := json.NewEncoder(buf)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(step.Parameters); err == nil {
+				fmt.Fprintf(&sb, "       parameters: %s", buf.String())
+			}
+		}
+		if step.Output != "" {
+			fmt.Fprintf(&sb, "       output: {{ %s }}\n", step.Output)
+		}
+		for _, e := range step.Errors {
+			fmt.Fprintf(&sb, "       invalid: %s\n", e)
+		}
+		if step.JoinPoint {
+			switch step.JoinCount {
+			case 0:
+				fmt.Fprintf(&sb, "       waits for all the parallel threads (join)\n")
+			case 1:
+				fmt.Fprintf(&sb, "       waits for the first parallel thread, the others are canceled (join)\n")
+			default:
+				fmt.Fprintf(&sb, "       waits for %d parallel threads, the others are canceled (join)\n", step.JoinCount)
+			}
+		}
+		for _, br := range step.Branches {
+			targets := append([]string{}, br.To...)
+			for _, l := range br.Loops {
+				targets = append(targets, l+" (loop)")
+			}
+			par := ""
+			if br.Parallel {
+				par = " in parallel"
+			}
+			fmt.Fprintf(&sb, "       %s -> %s%s\n", br.Port, strings.Join(targets, ", "), par)
+		}
+	}
+	fmt.Fprintf(&sb, "\n%d step(s), %d destructive, %d invalid. Nothing has been executed.\n", len(p.Steps), ndestructive, ninvalid)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,151 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package blueprint_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/util"
+)
+
+const testPlanBP = `
+actions:
+  - id: a
+    provider: generic
+    action: define_variables
+    first: true
+    parameters:
+      vars:
+        - {key: GREETING, value: "hello {{ NAME }}"}
+    next: {ok: [b]}
+  - id: b
+    provider: test
+    action: run
+    output: OUT
+    parameters:
+      count: "{{ COUNT }}"
+      name: "{{ GREETING | upper }}"
+      password: "{{ DB_PASSWORD }}"
+      home: "{{ env.HOME }}"
+      region: "{{ REGION ?? \"eu\" }}"
+    next: {ok: [c]}
+  - id: c
+    provider: test
+    action: run
+    parameters:
+      count: "{{ OUT.count }}"
+      name: "{{ NAME }}-{{ OUT.id }}"
+`
+
+// testPlanParams struct. Parameters of the actions of the test provider
+type testPlanParams struct {
+	Count int    `json:"count"`
+	Name  string `json:"name"`
+}
+
+func newTestPlan(t *testing.T, data string, args ...string) *blueprint.Plan {
+	t.Helper()
+	blueprint.ActionRehearsalFuncs["test"] = func(action *base.Action, store base.IStore) error {
+		return util.UnmarshalParameters(store, action.Parameters, &testPlanParams{})
+	}
+	t.Cleanup(func() { delete(blueprint.ActionRehearsalFuncs, "test") })
+
+	bp, err := blueprint.NewFromYAML([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	irb, err := blueprint.GenerateIRB(bp, &blueprint.IRBGenConfig{Args: args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blueprint.NewPlan(irb)
+}
+
+func TestPlanResolve(t *testing.T) {
+	plan := newTestPlan(t, testPlanBP, "--NAME=bob", "--COUNT=3", "--DB_PASSWORD=hunter2hunter2")
+	if err := plan.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(plan.Steps))
+	}
+	expected := map[string]interface{}{
+		"count":    "3",
+		"name":     "HELLO BOB",
+		"password": base.SecretMask,
+		"home":     "<{{ env.HOME }} at run time>",
+		"region":   "eu",
+	}
+	if b := plan.Steps[1]; !reflect.DeepEqual(b.Parameters, expected) {
+		t.Errorf("expected %v, got %v", expected, b.Parameters)
+	}
+	expected = map[string]interface{}{
+		"count": "<{{ OUT.count }} from b>",
+		"name":  "bob-<{{ OUT.id }} from b>",
+	}
+	c := plan.Steps[2]
+	if !reflect.DeepEqual(c.Parameters, expected) {
+		t.Errorf("expected %v, got %v", expected, c.Parameters)
+	}
+	if !reflect.DeepEqual(c.Pending, []string{"OUT.count", "OUT.id"}) {
+		t.Errorf("unexpected pending references %v", c.Pending)
+	}
+}
+
+func TestPlanRehearsal(t *testing.T) {
+	plan := newTestPlan(t, testPlanBP, "--NAME=bob", "--COUNT=many")
+	err := plan.Err()
+	if err == nil {
+		t.Fatal("expected the invalid count to fail")
+	}
+	if len(plan.Steps[1].Errors) != 1 || len(plan.Steps[2].Errors) != 0 {
+		t.Fatalf("expected an error in step 2 only, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "step 2 (b)") {
+		t.Errorf("unexpected error %v", err)
+	}
+	sb := &strings.Builder{}
+	if err := plan.WriteText(sb); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sb.String(), "invalid: ") || !strings.Contains(sb.String(), "1 invalid") {
+		t.Errorf("the invalid step is not shown:\n%s", sb.String())
+	}
+}
+
+func TestPlanExpressionErrors(t *testing.T) {
+	plan := newTestPlan(t, `
+actions:
+  - id: a
+    provider: test
+    action: run
+    first: true
+    parameters: {name: "{{ NAME | nofilter }}"}
+`, "--NAME=bob")
+	if len(plan.Steps) != 1 || len(plan.Steps[0].Errors) != 1 {
+		t.Fatalf("expected the unknown filter to fail, got %+v", plan.Steps)
+	}
+}
@@ -0,0 +1,307 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/develatio/nebulant-cli/config"
+)
+
+// SignatureAlgorithm is the only algorithm supported by now
+const SignatureAlgorithm = "ed25519"
+
+// SignatureExt is appended to the blueprint file path to build the
+// default path of its detached signature
+const SignatureExt = ".sig"
+
+// ErrUnsigned is returned when a signature is required but the
+// blueprint has none
+var ErrUnsigned = fmt.Errorf("blueprint is not signed")
+
+// Signature struct. Detached signature of a blueprint.
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	// sha256 fingerprint of the public key
+	KeyID     string `json:"key_id"`
+	Signature string `json:"signature"`
+}
+
+// KeysPath func. Dir where the signing keys are stored.
+func KeysPath() string {
+	return filepath.Join(config.AppHomePath(), "keys")
+}
+
+// TrustedKeysPath func. Default dir of the trusted public keys.
+func TrustedKeysPath() string {
+	return filepath.Join(KeysPath(), "trusted")
+}
+
+// KeyID func. Return the fingerprint of the public key.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:])
+}
+
+// Canonical func. Return the canonical json of the blueprint: keys
+// sorted, no insignificant whitespace and without the builder layout
+// keys, so moving actions around the canvas doesn't break signatures.
+func (bp *Blueprint) Canonical() ([]byte, error) {
+	if len(bp.rawbp) == 0 {
+		return nil, fmt.Errorf("cannot build canonical form: raw blueprint not available")
+	}
+	var data interface{}
+	dec := json.NewDecoder(bytes.NewReader(bp.rawbp))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return nil, err
+	}
+	if m, ok := data.(map[string]interface{}); ok {
+		for _, key := range builderLayoutKeys {
+			delete(m, key)
+		}
+	}
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(data); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// GenerateKeyPair func. Generate a new ed25519 key pair and store it
+// into KeysPath() as <name>.key and <name>.pub. Existing keys are
+// never overwritten.
+func GenerateKeyPair(name string) (string, string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(KeysPath(), 0700); err != nil {
+		return "", "", err
+	}
+	privPath := filepath.Join(KeysPath(), name+".key")
+	pubPath := filepath.Join(KeysPath(), name+".pub")
+	for _, p := range []string{privPath, pubPath} {
+		if _, err := os.Stat(p); err == nil {
+			return "", "", fmt.Errorf("key %s already exists", p)
+		}
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return "", "", err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", "", err
+	}
+	privPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+	if err := os.WriteFile(privPath, privPEM, 0600); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(pubPath, pubPEM, 0600); err != nil {
+		return "", "", err
+	}
+	return privPath, pubPath, nil
+}
+
+// ResolveKeyPath func. A key can be referenced by path or by name,
+// the latter being looked up into KeysPath().
+func ResolveKeyPath(key string, ext string) string {
+	if strings.ContainsRune(key, os.PathSeparator) || filepath.Ext(key) != "" {
+		return key
+	}
+	return filepath.Join(KeysPath(), key+ext)
+}
+
+// LoadPrivateKey func.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- user provided key
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: not a PEM private key", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 private key", path)
+	}
+	return priv, nil
+}
+
+// LoadPublicKey func.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- user provided key
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s: not a PEM public key", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 public key", path)
+	}
+	return pub, nil
+}
+
+// LoadTrustedKeys func. Load every *.pub key of dir indexed by key id.
+func LoadTrustedKeys(dir string) (map[string]ed25519.PublicKey, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.pub"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	keys := make(map[string]ed25519.PublicKey)
+	for _, path := range matches {
+		pub, err := LoadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys[KeyID(pub)] = pub
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no trusted keys found in %s", dir)
+	}
+	return keys, nil
+}
+
+// SignBlueprint func. Sign the canonical form of the blueprint.
+func SignBlueprint(bp *Blueprint, priv ed25519.PrivateKey) (*Signature, error) {
+	data, err := bp.Canonical()
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := priv.Public().(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("cannot get public key")
+	}
+	return &Signature{
+		Algorithm: SignatureAlgorithm,
+		KeyID:     KeyID(pub),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data)),
+	}, nil
+}
+
+// LoadSignature func. Read a detached signature. ErrUnsigned is
+// returned if the file does not exist.
+func LoadSignature(path string) (*Signature, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- user provided signature
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: signature %s not found", ErrUnsigned, path)
+		}
+		return nil, err
+	}
+	sig := &Signature{}
+	if err := json.Unmarshal(data, sig); err != nil {
+		return nil, fmt.Errorf("invalid signature file %s: %s", path, err.Error())
+	}
+	return sig, nil
+}
+
+// VerifyBlueprint func. Check the signature of the blueprint against
+// the public keys of trustedDir.
+func VerifyBlueprint(bp *Blueprint, sig *Signature, trustedDir string) error {
+	if sig == nil {
+		return ErrUnsigned
+	}
+	if sig.Algorithm != SignatureAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+	}
+	keys, err := LoadTrustedKeys(trustedDir)
+	if err != nil {
+		return err
+	}
+	pub, exists := keys[sig.KeyID]
+	if !exists {
+		return fmt.Errorf("blueprint signed with untrusted key %s", sig.KeyID)
+	}
+	rawsig, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %s", err.Error())
+	}
+	data, err := bp.Canonical()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, data, rawsig) {
+		return fmt.Errorf("signature verification failed: the blueprint has been modified after signing")
+	}
+	return nil
+}
+
+// SignaturePath func. Default path of the detached signature of a
+// blueprint, empty if the blueprint has no local path.
+func SignaturePath(bpurl *BlueprintURL) string {
+	if bpurl.Scheme != "file" {
+		return ""
+	}
+	return bpurl.FilePath + SignatureExt
+}
+
+func verifyFromConf(bpurl *BlueprintURL, bp *Blueprint, irbConf *IRBGenConfig) error {
+	sigpath := irbConf.SignaturePath
+	if sigpath == "" {
+		sigpath = SignaturePath(bpurl)
+	}
+	if sigpath == "" {
+		return fmt.Errorf("%w: no detached signature provided for remote blueprint", ErrUnsigned)
+	}
+	trusted := irbConf.TrustedKeys
+	if trusted == "" {
+		trusted = TrustedKeysPath()
+	}
+	sig, err := LoadSignature(sigpath)
+	if err != nil {
+		return err
+	}
+	return VerifyBlueprint(bp, sig, trusted)
+}
@@ -0,0 +1,87 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+func TestSignBlueprint(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	_, pubPath, err := blueprint.GenerateKeyPair("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := blueprint.GenerateKeyPair("test"); err == nil {
+		t.Error("existing keys should not be overwritten")
+	}
+	trusted := t.TempDir()
+	pub, err := os.ReadFile(pubPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(trusted, "test.pub"), pub, 0600); err != nil {
+		t.Fatal(err)
+	}
+	priv, err := blueprint.LoadPrivateKey(blueprint.ResolveKeyPath("test", ".key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	load := func(data string) *blueprint.Blueprint {
+		bp, err := blueprint.NewFromBytes([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bp
+	}
+	bp := load(`{"actions": [{"action_id": "a1", "provider": "generic", "action": "log", "parameters": {"content": "hi"}}], "diagram": {"x": 1}}`)
+	sig, err := blueprint.SignBlueprint(bp, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blueprint.VerifyBlueprint(bp, sig, trusted); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+
+	// key order, whitespace and canvas layout are not signed
+	moved := load(`{"diagram": {"x": 2},
+		"actions": [{"provider": "generic", "action_id": "a1", "action": "log", "parameters": {"content": "hi"}}]}`)
+	if err := blueprint.VerifyBlueprint(moved, sig, trusted); err != nil {
+		t.Errorf("layout change rejected: %v", err)
+	}
+
+	tampered := load(`{"actions": [{"action_id": "a1", "provider": "generic", "action": "log", "parameters": {"content": "ho"}}]}`)
+	if err := blueprint.VerifyBlueprint(tampered, sig, trusted); err == nil {
+		t.Error("tampered blueprint accepted")
+	}
+
+	if err := blueprint.VerifyBlueprint(bp, sig, t.TempDir()); err == nil {
+		t.Error("signature accepted without trusted keys")
+	}
+}
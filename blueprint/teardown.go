@@ -0,0 +1,82 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/develatio/nebulant-cli/base"
+)
+
+// NewTeardown func. Build the blueprint that deletes the resources, given
+// in creation order. Resources are deleted in reverse order, so dependent
+// resources go first. Every action goes to the next one on ok and on ko,
+// a failed delete doesn't stop the teardown of the others
+func NewTeardown(resources []*base.Resource) (*Blueprint, error) {
+	type teardownAction struct {
+		Provider    string          `json:"provider"`
+		ActionID    string          `json:"action_id"`
+		ActionName  string          `json:"action"`
+		FirstAction bool            `json:"first_action"`
+		Input       json.RawMessage `json:"input"`
+		Parameters  json.RawMessage `json:"parameters"`
+		NextAction  struct {
+			Ok []string `json:"ok"`
+			Ko []string `json:"ko"`
+		} `json:"next_action"`
+	}
+
+	var actions []*teardownAction
+	for i := len(resources) - 1; i >= 0; i-- {
+		res := resources[i]
+		if res.Deleted {
+			continue
+		}
+		for _, ra := range res.Teardown {
+			if n := len(actions); n > 0 {
+				// previous action goes to this one
+				id := fmt.Sprintf("destroy-%d-%s", n, ra.Action)
+				actions[n-1].NextAction.Ok = []string{id}
+				actions[n-1].NextAction.Ko = []string{id}
+			}
+			actions = append(actions, &teardownAction{
+				Provider:   res.Provider,
+				ActionID:   fmt.Sprintf("destroy-%d-%s", len(actions), ra.Action),
+				ActionName: ra.Action,
+				Input:      json.RawMessage("{}"),
+				Parameters: ra.Parameters,
+			})
+		}
+	}
+	if len(actions) <= 0 {
+		return nil, fmt.Errorf("no resources to delete")
+	}
+	actions[0].FirstAction = true
+
+	data, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		return nil, err
+	}
+	return NewFromBytes(data)
+}
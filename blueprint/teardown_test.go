@@ -0,0 +1,78 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+func TestNewTeardown(t *testing.T) {
+	resources := []*base.Resource{
+		{Provider: "aws", ID: "vol-1", Teardown: []*base.ResourceAction{
+			{Action: "set_region", Parameters: json.RawMessage(`{"Region":"eu-west-1"}`)},
+			{Action: "delete_volume", Parameters: json.RawMessage(`{"VolumeId":"vol-1"}`)},
+		}},
+		{Provider: "hetznerCloud", ID: "1", Deleted: true, Teardown: []*base.ResourceAction{
+			{Action: "delete_server", Parameters: json.RawMessage(`{"ID":"1"}`)},
+		}},
+		{Provider: "hetznerCloud", ID: "2", Teardown: []*base.ResourceAction{
+			{Action: "delete_server", Parameters: json.RawMessage(`{"ID":"2"}`)},
+		}},
+	}
+	bp, err := blueprint.NewTeardown(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	irb, err := blueprint.GenerateIRB(bp, &blueprint.IRBGenConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// last created first, deleted ones skipped, ko goes on
+	expected := []string{"delete_server", "set_region", "delete_volume"}
+	action := irb.StartAction
+	for i, name := range expected {
+		if action == nil {
+			t.Fatalf("expected %d teardown actions, got %d", len(expected), i)
+		}
+		if action.ActionName != name {
+			t.Errorf("expected action %d to be %s, got %s", i, name, action.ActionName)
+		}
+		if i < len(expected)-1 {
+			if len(action.NextAction.NextKo) != 1 || action.NextAction.NextKo[0] != action.NextAction.NextOk[0] {
+				t.Errorf("expected action %d to continue on ko", i)
+			}
+			action = action.NextAction.NextOk[0]
+		} else if len(action.NextAction.NextOk) > 0 {
+			t.Errorf("expected action %d to be the last one", i)
+		}
+	}
+
+	if _, err := blueprint.NewTeardown(resources[1:2]); err == nil {
+		t.Error("expected error without resources to delete")
+	}
+}
@@ -23,10 +23,13 @@
 package blueprint
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/url"
 	"os"
 	"regexp"
+	"strings"
 )
 
 type BlueprintURL struct {
@@ -37,9 +40,19 @@ type BlueprintURL struct {
 	Version          string
 	FilePath         string
 	UrlPath          string
+	// full https url without fragment
+	RemoteURL string
+	// expected sha256 of the https content, from the url fragment
+	Checksum string
 }
 
+// StdinPath is the blueprint path that reads the blueprint from stdin
+const StdinPath = "-"
+
 func ParsePath(path string) (*BlueprintURL, error) {
+	if path == StdinPath {
+		return &BlueprintURL{Scheme: "stdin"}, nil
+	}
 	u, err := url.Parse(path)
 	if err != nil {
 		return nil, err
@@ -55,10 +68,16 @@ func ParsePath(path string) (*BlueprintURL, error) {
 }
 
 func ParseURL(path string) (*BlueprintURL, error) {
+	if path == StdinPath {
+		return &BlueprintURL{Scheme: "stdin"}, nil
+	}
 	u, err := url.Parse(path)
 	if err != nil {
 		return nil, err
 	}
+	if u.Scheme == "https" {
+		return parseHTTPSURL(u)
+	}
 	out := &BlueprintURL{
 		UrlPath:  u.Path,
 		FilePath: fmt.Sprintf("%s%s", u.Host, u.Path),
@@ -99,3 +118,31 @@ func ParseURL(path string) (*BlueprintURL, error) {
 
 	return out, nil
 }
+
+// parseHTTPSURL func. The fragment, if any, pins the content checksum:
+// https://example.com/bp.yaml#sha256=<hex>
+func parseHTTPSURL(u *url.URL) (*BlueprintURL, error) {
+	out := &BlueprintURL{
+		Scheme:  "https",
+		UrlPath: u.Path,
+	}
+	if u.Fragment != "" {
+		algo, sum, found := strings.Cut(u.Fragment, "=")
+		if !found {
+			algo, sum, found = strings.Cut(u.Fragment, ":")
+		}
+		if !found || strings.ToLower(algo) != "sha256" {
+			return nil, fmt.Errorf("bad checksum %s: use #sha256=<hex>", u.Fragment)
+		}
+		sum = strings.ToLower(sum)
+		if _, err := hex.DecodeString(sum); err != nil || len(sum) != sha256.Size*2 {
+			return nil, fmt.Errorf("malformed sha256 checksum %s", sum)
+		}
+		out.Checksum = sum
+	}
+	rem := *u
+	rem.Fragment = ""
+	rem.RawFragment = ""
+	out.RemoteURL = rem.String()
+	return out, nil
+}
@@ -0,0 +1,145 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testURLBP = `{"actions": [{"action_id": "a1", "provider": "generic", "action": "log", "parameters": {"content": "hi"}}]}`
+
+func TestParseHTTPSURL(t *testing.T) {
+	sum := sha256.Sum256([]byte(testURLBP))
+	hexsum := hex.EncodeToString(sum[:])
+
+	for _, fragment := range []string{"", "#sha256=" + hexsum, "#sha256:" + hexsum, "#SHA256=" + strings.ToUpper(hexsum)} {
+		u, err := ParseURL("https://example.com/bp.json" + fragment)
+		if err != nil {
+			t.Fatalf("%s: %v", fragment, err)
+		}
+		if u.Scheme != "https" || u.RemoteURL != "https://example.com/bp.json" {
+			t.Errorf("%s: unexpected url %+v", fragment, u)
+		}
+		if fragment != "" && u.Checksum != hexsum {
+			t.Errorf("%s: unexpected checksum %s", fragment, u.Checksum)
+		}
+	}
+
+	for _, fragment := range []string{"#md5=" + hexsum, "#sha256", "#sha256=xyz", "#sha256=" + hexsum[:10]} {
+		if _, err := ParseURL("https://example.com/bp.json" + fragment); err == nil {
+			t.Errorf("%s: expected error", fragment)
+		}
+	}
+}
+
+func TestNewFromHTTPS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testURLBP))
+	}))
+	defer srv.Close()
+	prevClient := httpsClient
+	httpsClient = srv.Client()
+	defer func() { httpsClient = prevClient }()
+
+	sum := sha256.Sum256([]byte(testURLBP))
+	u, err := ParseURL(srv.URL + "/bp.json#sha256=" + hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bp, err := NewFromAny(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bp.Actions) != 1 || bp.Actions[0].ActionID != "a1" {
+		t.Errorf("unexpected blueprint %+v", bp.Actions)
+	}
+
+	// tampered content
+	other := sha256.Sum256([]byte("other content"))
+	u, err = ParseURL(srv.URL + "/bp.json#sha256=" + hex.EncodeToString(other[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFromAny(u); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+}
+
+func TestNewFromStdin(t *testing.T) {
+	for _, parse := range []func(string) (*BlueprintURL, error){ParsePath, ParseURL} {
+		u, err := parse(StdinPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Scheme != "stdin" {
+			t.Errorf("unexpected scheme %s", u.Scheme)
+		}
+	}
+
+	setStdin := func(data string) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "stdin")
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(path) // #nosec G304 -- test file
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		os.Stdin = f
+	}
+	prevStdin := os.Stdin
+	defer func() { os.Stdin = prevStdin }()
+
+	setStdin(testURLBP)
+	bp, err := NewFromAny(&BlueprintURL{Scheme: "stdin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bp.Actions) != 1 {
+		t.Errorf("unexpected blueprint %+v", bp.Actions)
+	}
+
+	// yaml is detected without extension
+	setStdin("actions:\n  - id: a1\n    provider: generic\n    action: log\n")
+	bp, err = NewFromAny(&BlueprintURL{Scheme: "stdin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bp.Actions) != 1 || bp.Actions[0].ActionID != "a1" {
+		t.Errorf("unexpected yaml blueprint %+v", bp.Actions)
+	}
+
+	setStdin("  \n")
+	if _, err := NewFromAny(&BlueprintURL{Scheme: "stdin"}); err == nil || !strings.Contains(err.Error(), "empty blueprint") {
+		t.Errorf("expected an empty blueprint error, got %v", err)
+	}
+}
@@ -29,6 +29,65 @@ type ActionValidatorFunc func(action *base.Action) error
 
 var ActionValidators map[string]ActionValidatorFunc = make(map[string]ActionValidatorFunc)
 
+// ActionSpec interface. The layout of an action as declared by the
+// actors of his provider
+type ActionSpec interface {
+	// Ports func. The ports (OK/KO) available for the action
+	Ports() (ok bool, ko bool)
+	// Calls func. The external calls (API operations, commands...)
+	// made by the action
+	Calls() string
+}
+
+// actionSpecs lookups by provider name, see RegisterActions
+var actionSpecs map[string]func(actionName string) (ActionSpec, bool) = make(map[string]func(actionName string) (ActionSpec, bool))
+
+// RegisterActions func. Register the actions of the provider, as in
+// the ActionFuncMap of his actors
+func RegisterActions[T ActionSpec](provider string, actions map[string]T) {
+	actionSpecs[provider] = func(actionName string) (ActionSpec, bool) {
+		spec, exists := actions[actionName]
+		return spec, exists
+	}
+}
+
+// lookupAction func. found is false if the provider of the action
+// is not registered or has no action with that name
+func lookupAction(action *base.Action) (spec ActionSpec, found bool) {
+	lookup, exists := actionSpecs[action.Provider]
+	if !exists {
+		return nil, false
+	}
+	return lookup(action.ActionName)
+}
+
+// ActionPorts func. Report the ports (OK/KO) available for the action,
+// found is false if the action is unknown.
+func ActionPorts(action *base.Action) (ok bool, ko bool, found bool) {
+	spec, found := lookupAction(action)
+	if !found {
+		return false, false, false
+	}
+	ok, ko = spec.Ports()
+	return ok, ko, true
+}
+
+// ActionCall func. Report the external calls made by the action,
+// found is false if the action is unknown.
+func ActionCall(action *base.Action) (call string, found bool) {
+	spec, found := lookupAction(action)
+	if !found {
+		return "", false
+	}
+	return spec.Calls(), true
+}
+
+// ActionRehearsalFunc type. Run the actor of the action in rehearsal mode,
+// resolving the typed parameters against store.
+type ActionRehearsalFunc func(action *base.Action, store base.IStore) error
+
+var ActionRehearsalFuncs map[string]ActionRehearsalFunc = make(map[string]ActionRehearsalFunc)
+
 // PreValidate func
 func PreValidate(sp *Blueprint) error {
 	// Some prevalidation here
@@ -0,0 +1,493 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+
+	"github.com/develatio/nebulant-cli/util"
+	"gopkg.in/yaml.v3"
+)
+
+// builderLayoutKeys are the keys of the builder blueprint that only
+// describe the canvas (undo history, positions...). They are kept
+// on conversion to yaml unless the layout is explicitly stripped.
+var builderLayoutKeys = []string{"cm", "diagram"}
+
+// yamlList is a list that is omitted only if missing, an
+// empty list (ko: []) is kept on conversion
+type yamlList []string
+
+// IsZero func. Used by yaml on omitempty
+func (l yamlList) IsZero() bool {
+	return l == nil
+}
+
+// yamlNextAction is the compact form of base.NextAction. True and
+// False are only used by conditional actions.
+type yamlNextAction struct {
+	Ok    yamlList `yaml:"ok,omitempty"`
+	Ko    yamlList `yaml:"ko,omitempty"`
+	True  yamlList `yaml:"true,omitempty"`
+	False yamlList `yaml:"false,omitempty"`
+	// Cleanup actions of group actions
+	Finally yamlList `yaml:"finally,omitempty"`
+}
+
+// yamlAction is the hand-writable form of base.Action
+type yamlAction struct {
+	ID         string          `yaml:"id"`
+	Provider   string          `yaml:"provider"`
+	Action     string          `yaml:"action"`
+	First      bool            `yaml:"first,omitempty"`
+	Output     *string         `yaml:"output,omitempty"`
+	Parameters interface{}     `yaml:"parameters,omitempty"`
+	Next       *yamlNextAction `yaml:"next,omitempty"`
+	// Any other action attr (version, debug_network...) is kept
+	// as is to allow lossless round-trip
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// yamlBlueprint is the hand-writable form of a builder blueprint
+type yamlBlueprint struct {
+	Name          string        `yaml:"name,omitempty"`
+	Description   string        `yaml:"description,omitempty"`
+	Version       string        `yaml:"version,omitempty"`
+	MinCLIVersion *string       `yaml:"min_cli_version,omitempty"`
+	Parameters    []*Parameter  `yaml:"parameters,omitempty"`
+	Outputs       yamlList      `yaml:"outputs,omitempty"`
+	OnExit        string        `yaml:"on_exit,omitempty"`
+	Actions       []*yamlAction `yaml:"actions"`
+	// Builder-only attrs of the blueprint (builder_version,
+	// n_errors, cm...)
+	Builder map[string]interface{} `yaml:"builder,omitempty"`
+}
+
+// IsYAML func returns true if the blueprint file seems to be
+// written in the yaml format
+func IsYAML(path string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	case ".json", ".nbp", ".nbl":
+		return false
+	}
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && trimmed[0] != '{'
+}
+
+// NewFromYAML func
+func NewFromYAML(data []byte) (*Blueprint, error) {
+	_, rawbp, err := yamlToBuilder(data)
+	if err != nil {
+		return nil, err
+	}
+	var bp Blueprint
+	if err := util.UnmarshalValidJSON(rawbp, &bp); err != nil {
+		return nil, err
+	}
+	if err := TestMinCliVersion(&bp); err != nil {
+		return nil, err
+	}
+	randIntString := fmt.Sprintf("%d", rand.Int()) // #nosec G404 -- Weak random is OK here
+	bp.ExecutionUUID = &randIntString
+	bp.Raw = &data
+	bp.rawbp = rawbp
+	return &bp, nil
+}
+
+// YAMLToBuilder func converts a yaml blueprint into the builder
+// (wrapped) json format
+func YAMLToBuilder(data []byte) ([]byte, error) {
+	wrap, _, err := yamlToBuilder(data)
+	if err != nil {
+		return nil, err
+	}
+	return wrap, nil
+}
+
+// BuilderToYAML func converts a builder (wrapped) json blueprint or a
+// bare json blueprint into yaml. The canvas layout is only kept if
+// keepLayout is true.
+func BuilderToYAML(data []byte, keepLayout bool) ([]byte, error) {
+	var wrap map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&wrap); err != nil {
+		return nil, err
+	}
+
+	ybp := &yamlBlueprint{}
+	rawbp := wrap
+	if _, bare := wrap["actions"]; !bare {
+		inner, ok := wrap["blueprint"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot find blueprint into json data")
+		}
+		rawbp = inner
+		ybp.Name, _ = wrap["name"].(string)
+		ybp.Description, _ = wrap["description"].(string)
+		ybp.Version, _ = wrap["version"].(string)
+	}
+
+	for key, value := range rawbp {
+		switch key {
+		case "actions":
+			continue
+		case "min_cli_version":
+			if mcv, ok := value.(string); ok {
+				ybp.MinCLIVersion = &mcv
+			}
+			continue
+		case "parameters":
+			params, err := parametersFromJSON(value)
+			if err == nil {
+				ybp.Parameters = params
+				continue
+			}
+		case "outputs":
+			outputs, err := toStringSlice(value)
+			if err == nil {
+				ybp.Outputs = outputs
+				continue
+			}
+		case "on_exit":
+			if onExit, ok := value.(string); ok {
+				ybp.OnExit = onExit
+				continue
+			}
+		}
+		if !keepLayout && isLayoutKey(key) {
+			continue
+		}
+		if ybp.Builder == nil {
+			ybp.Builder = make(map[string]interface{})
+		}
+		ybp.Builder[key] = normalizeJSONValue(value)
+	}
+
+	rawactions, _ := rawbp["actions"].([]interface{})
+	for _, ra := range rawactions {
+		rawaction, ok := ra.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("malformed action %v", ra)
+		}
+		ya, err := yamlActionFromJSON(rawaction)
+		if err != nil {
+			return nil, err
+		}
+		ybp.Actions = append(ybp.Actions, ya)
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(ybp); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func isLayoutKey(key string) bool {
+	for _, lk := range builderLayoutKeys {
+		if lk == key {
+			return true
+		}
+	}
+	return false
+}
+
+func yamlActionFromJSON(rawaction map[string]interface{}) (*yamlAction, error) {
+	ya := &yamlAction{}
+	for key, value := range rawaction {
+		switch key {
+		case "action_id":
+			ya.ID, _ = value.(string)
+		case "provider":
+			ya.Provider, _ = value.(string)
+		case "action":
+			ya.Action, _ = value.(string)
+		case "first_action":
+			ya.First, _ = value.(bool)
+		case "output":
+			if output, ok := value.(string); ok {
+				ya.Output = &output
+			}
+		case "parameters":
+			ya.Parameters = normalizeJSONValue(value)
+		case "next_action":
+			next, err := yamlNextFromJSON(value)
+			if err != nil {
+				return nil, fmt.Errorf("action %v: %s", rawaction["action_id"], err.Error())
+			}
+			ya.Next = next
+		default:
+			if ya.Extra == nil {
+				ya.Extra = make(map[string]interface{})
+			}
+			ya.Extra[key] = normalizeJSONValue(value)
+		}
+	}
+	return ya, nil
+}
+
+func yamlNextFromJSON(value interface{}) (*yamlNextAction, error) {
+	rawnext, ok := value.(map[string]interface{})
+	if !ok || len(rawnext) == 0 {
+		return nil, nil
+	}
+	next := &yamlNextAction{}
+	var err error
+	switch ok := rawnext["ok"].(type) {
+	case nil:
+	case []interface{}:
+		next.Ok, err = toStringSlice(ok)
+	case map[string]interface{}:
+		if next.True, err = toStringSlice(ok["true"]); err != nil {
+			return nil, err
+		}
+		next.False, err = toStringSlice(ok["false"])
+	default:
+		err = fmt.Errorf("cannot parse Next syntax")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if next.Ko, err = toStringSlice(rawnext["ko"]); err != nil {
+		return nil, err
+	}
+	if next.Finally, err = toStringSlice(rawnext["finally"]); err != nil {
+		return nil, err
+	}
+	if next.Ok == nil && next.Ko == nil && next.True == nil && next.False == nil && next.Finally == nil {
+		return nil, nil
+	}
+	return next, nil
+}
+
+// parametersFromJSON func. Parse the parameters of a json blueprint
+// keeping their order and types
+func parametersFromJSON(value interface{}) ([]*Parameter, error) {
+	enc, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var params []*Parameter
+	dec := json.NewDecoder(bytes.NewReader(enc))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&params); err != nil {
+		return nil, err
+	}
+	for _, p := range params {
+		p.Default = normalizeJSONValue(p.Default)
+	}
+	return params, nil
+}
+
+// toStringSlice func. nil if v is nil, an empty
+// list is kept as an empty slice
+func toStringSlice(v interface{}) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot parse Next syntax")
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot parse Next syntax")
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// yamlToBuilder func returns both, the wrapped builder json
+// and the bare blueprint json
+func yamlToBuilder(data []byte) ([]byte, []byte, error) {
+	ybp := &yamlBlueprint{}
+	if err := yaml.Unmarshal(data, ybp); err != nil {
+		return nil, nil, err
+	}
+
+	rawbp := make(map[string]interface{})
+	for key, value := range ybp.Builder {
+		rawbp[key] = normalizeYAMLValue(value)
+	}
+	if ybp.MinCLIVersion != nil {
+		rawbp["min_cli_version"] = *ybp.MinCLIVersion
+	}
+	if ybp.Parameters != nil {
+		for _, p := range ybp.Parameters {
+			p.Default = normalizeYAMLValue(p.Default)
+		}
+		rawbp["parameters"] = ybp.Parameters
+	}
+	if ybp.Outputs != nil {
+		rawbp["outputs"] = ybp.Outputs
+	}
+	if ybp.OnExit != "" {
+		rawbp["on_exit"] = ybp.OnExit
+	}
+
+	seen := make(map[string]bool)
+	actions := make([]interface{}, 0, len(ybp.Actions))
+	for idx, ya := range ybp.Actions {
+		if ya == nil {
+			continue
+		}
+		if ya.ID == "" {
+			return nil, nil, fmt.Errorf("action #%d has no id", idx)
+		}
+		if seen[ya.ID] {
+			return nil, nil, fmt.Errorf("duplicated action id %s", ya.ID)
+		}
+		seen[ya.ID] = true
+		actions = append(actions, ya.toJSON())
+	}
+	rawbp["actions"] = actions
+
+	bpenc, err := json.Marshal(rawbp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wrap := map[string]interface{}{
+		"name":        ybp.Name,
+		"description": ybp.Description,
+		"version":     ybp.Version,
+		"blueprint":   json.RawMessage(bpenc),
+	}
+	wrapenc, err := json.MarshalIndent(wrap, "", "    ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return wrapenc, bpenc, nil
+}
+
+func (ya *yamlAction) toJSON() map[string]interface{} {
+	rawaction := make(map[string]interface{})
+	for key, value := range ya.Extra {
+		rawaction[key] = normalizeYAMLValue(value)
+	}
+	rawaction["action_id"] = ya.ID
+	rawaction["provider"] = ya.Provider
+	rawaction["action"] = ya.Action
+	if ya.First {
+		rawaction["first_action"] = true
+	}
+	if ya.Output != nil {
+		rawaction["output"] = *ya.Output
+	}
+	if ya.Parameters != nil {
+		rawaction["parameters"] = normalizeYAMLValue(ya.Parameters)
+	}
+	next := make(map[string]interface{})
+	if ya.Next != nil {
+		if ya.Next.True != nil || ya.Next.False != nil {
+			next["ok"] = map[string]interface{}{
+				"true":  emptyIfNil(ya.Next.True),
+				"false": emptyIfNil(ya.Next.False),
+			}
+		} else if ya.Next.Ok != nil {
+			next["ok"] = ya.Next.Ok
+		}
+		if ya.Next.Ko != nil {
+			next["ko"] = ya.Next.Ko
+		}
+		if ya.Next.Finally != nil {
+			next["finally"] = ya.Next.Finally
+		}
+	}
+	rawaction["next_action"] = next
+	return rawaction
+}
+
+func emptyIfNil(s yamlList) yamlList {
+	if s == nil {
+		return yamlList{}
+	}
+	return s
+}
+
+// normalizeJSONValue converts json.Number values into int64 or float64
+// to prevent yaml from quoting them as strings
+func normalizeJSONValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			return i
+		}
+		if f, err := vv.Float64(); err == nil {
+			return f
+		}
+		return vv.String()
+	case map[string]interface{}:
+		for k, item := range vv {
+			vv[k] = normalizeJSONValue(item)
+		}
+		return vv
+	case []interface{}:
+		for i, item := range vv {
+			vv[i] = normalizeJSONValue(item)
+		}
+		return vv
+	}
+	return v
+}
+
+// normalizeYAMLValue converts the map[interface{}]interface{} values
+// that yaml could produce into json-friendly map[string]interface{}
+func normalizeYAMLValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(vv))
+		for k, item := range vv {
+			out[fmt.Sprintf("%v", k)] = normalizeYAMLValue(item)
+		}
+		return out
+	case map[string]interface{}:
+		for k, item := range vv {
+			vv[k] = normalizeYAMLValue(item)
+		}
+		return vv
+	case []interface{}:
+		for i, item := range vv {
+			vv[i] = normalizeYAMLValue(item)
+		}
+		return vv
+	}
+	return v
+}
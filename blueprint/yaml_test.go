@@ -0,0 +1,216 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package blueprint_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+const testBuilderBP = `{
+    "name": "test",
+    "description": "yaml round trip",
+    "blueprint": {
+        "builder_version": "1.0.0",
+        "n_errors": 0,
+        "n_warnings": 0,
+        "min_cli_version": "0.0.1",
+        "actions": [
+            {
+                "action_id": "a",
+                "provider": "generic",
+                "action": "condition",
+                "first_action": true,
+                "parameters": {"conditions": {"and": []}},
+                "next_action": {"ok": {"true": ["b"], "false": ["c"]}}
+            },
+            {
+                "action_id": "b",
+                "provider": "generic",
+                "action": "log",
+                "output": "LOG_OUT",
+                "parameters": {"content": "multi\nline", "count": 3},
+                "next_action": {"ok": ["c"], "ko": []}
+            },
+            {
+                "action_id": "c",
+                "provider": "generic",
+                "action": "end",
+                "next_action": {}
+            }
+        ]
+    }
+}`
+
+func TestYAMLRoundTrip(t *testing.T) {
+	y, err := blueprint.BuilderToYAML([]byte(testBuilderBP), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := blueprint.YAMLToBuilder(y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y2, err := blueprint.BuilderToYAML(j, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(y) != string(y2) {
+		t.Errorf("yaml round trip mismatch:\n%s\n---\n%s", y, y2)
+	}
+
+	var wrapped struct {
+		Blueprint struct {
+			Actions []struct {
+				ActionID   string                 `json:"action_id"`
+				Parameters map[string]interface{} `json:"parameters"`
+				NextAction map[string]interface{} `json:"next_action"`
+			} `json:"actions"`
+		} `json:"blueprint"`
+	}
+	if err := json.Unmarshal(j, &wrapped); err != nil {
+		t.Fatal(err)
+	}
+	actions := wrapped.Blueprint.Actions
+	if len(actions) != 3 {
+		t.Fatalf("expected 3 actions, got %d", len(actions))
+	}
+	ok, _ := actions[0].NextAction["ok"].(map[string]interface{})
+	if ok == nil || ok["true"] == nil || ok["false"] == nil {
+		t.Errorf("conditional next lost on conversion: %v", actions[0].NextAction)
+	}
+	if actions[1].Parameters["count"] != float64(3) {
+		t.Errorf("numeric parameter changed on conversion: %v", actions[1].Parameters["count"])
+	}
+}
+
+// testLayoutBP has the canvas layout of the builder and
+// empty ports and outputs
+const testLayoutBP = `{
+    "name": "layout",
+    "description": "json round trip",
+    "blueprint": {
+        "builder_version": "1.0.0",
+        "cm": {"undo": [{"op": "add", "id": "a"}], "pos": 2},
+        "diagram": {"a": {"x": 10, "y": 20.5}, "b": {"x": 10, "y": 80}},
+        "outputs": [],
+        "actions": [
+            {
+                "action_id": "a",
+                "provider": "generic",
+                "action": "log",
+                "first_action": true,
+                "parameters": {"content": "hi"},
+                "next_action": {"ok": ["b"], "ko": []}
+            },
+            {
+                "action_id": "b",
+                "provider": "generic",
+                "action": "group",
+                "next_action": {"ok": [], "ko": [], "finally": []}
+            },
+            {
+                "action_id": "c",
+                "provider": "generic",
+                "action": "condition",
+                "parameters": {"conditions": {"and": []}},
+                "next_action": {"ok": {"true": [], "false": ["b"]}}
+            }
+        ]
+    }
+}`
+
+// decodeJSON func. Decode data into generic values to compare them
+func decodeJSON(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	var v map[string]interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	// the layout is kept by default (convert)
+	y, err := blueprint.BuilderToYAML([]byte(testLayoutBP), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := blueprint.YAMLToBuilder(y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, dst := decodeJSON(t, []byte(testLayoutBP)), decodeJSON(t, j)
+	for _, key := range []string{"name", "description", "blueprint"} {
+		if !reflect.DeepEqual(src[key], dst[key]) {
+			t.Errorf("%s changed on json round trip:\n%v\n---\n%v\nyaml:\n%s", key, src[key], dst[key], y)
+		}
+	}
+}
+
+func TestYAMLEmptyPorts(t *testing.T) {
+	y, err := blueprint.BuilderToYAML([]byte(testLayoutBP), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, port := range []string{"ko: []", "finally: []", `"true": []`, "outputs: []"} {
+		if !strings.Contains(string(y), port) {
+			t.Errorf("expected %s in the yaml:\n%s", port, y)
+		}
+	}
+	if strings.Contains(string(y), "diagram") {
+		t.Errorf("the layout should be stripped:\n%s", y)
+	}
+
+	// missing ports are still omitted
+	y, err = blueprint.BuilderToYAML([]byte(testBuilderBP), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(y), "[]") != 2 {
+		t.Errorf("expected only the empty ko port of b and the empty and condition:\n%s", y)
+	}
+}
+
+func TestYAMLDuplicateID(t *testing.T) {
+	y := []byte("actions:\n  - id: a\n    provider: generic\n    action: log\n  - id: a\n    provider: generic\n    action: end\n")
+	if _, err := blueprint.YAMLToBuilder(y); err == nil {
+		t.Error("expected duplicate id error")
+	}
+}
+
+func TestIsYAML(t *testing.T) {
+	if !blueprint.IsYAML("bp.yaml", []byte("{}")) {
+		t.Error("expected .yaml extension to be detected as yaml")
+	}
+	if blueprint.IsYAML("bp.nbp", []byte("actions: []")) {
+		t.Error("expected .nbp extension to be detected as json")
+	}
+	if blueprint.IsYAML("bp", []byte("  {\"actions\": []}")) {
+		t.Error("expected json content to be detected as json")
+	}
+}
@@ -135,6 +135,9 @@ const (
 	EventInteractiveMenuStart
 	EventPrompt
 	EventPromptDone
+	// the action is waiting for a free slot
+	// of the run limits (max parallel...)
+	EventActionWaiting
 )
 
 // EventPromptType int
@@ -495,8 +498,95 @@ func SBusDisconnect(fLink *BusConsumerLink) {
 	SBus.disconnect <- fLink
 }
 
+// redactBusData func. Mask the secret values of the message
+// and of the extra data, nested maps and slices included
+func redactBusData(bdata *BusData) {
+	if bdata.M != nil {
+		m := base.Redact(*bdata.M)
+		bdata.M = &m
+	}
+	if bdata.Extra != nil {
+		if extra, changed := redactValue(bdata.Extra); changed {
+			bdata.Extra = extra.(map[string]interface{})
+		}
+	}
+}
+
+// redactValue func. Returns v with the secret strings masked. Maps
+// and slices are copied only if something changes, so the values of
+// the caller are never modified
+func redactValue(v interface{}) (interface{}, bool) {
+	switch vv := v.(type) {
+	case string:
+		rv := base.Redact(vv)
+		return rv, rv != vv
+	case *string:
+		if vv == nil {
+			return v, false
+		}
+		rv := base.Redact(*vv)
+		if rv == *vv {
+			return v, false
+		}
+		return &rv, true
+	case map[string]interface{}:
+		var redacted map[string]interface{}
+		for k, item := range vv {
+			ritem, changed := redactValue(item)
+			if !changed {
+				continue
+			}
+			if redacted == nil {
+				redacted = make(map[string]interface{}, len(vv))
+				for kk, vvv := range vv {
+					redacted[kk] = vvv
+				}
+			}
+			redacted[k] = ritem
+		}
+		if redacted == nil {
+			return v, false
+		}
+		return redacted, true
+	case []interface{}:
+		var redacted []interface{}
+		for i, item := range vv {
+			ritem, changed := redactValue(item)
+			if !changed {
+				continue
+			}
+			if redacted == nil {
+				redacted = append([]interface{}(nil), vv...)
+			}
+			redacted[i] = ritem
+		}
+		if redacted == nil {
+			return v, false
+		}
+		return redacted, true
+	case []string:
+		var redacted []string
+		for i, item := range vv {
+			ritem := base.Redact(item)
+			if ritem == item {
+				continue
+			}
+			if redacted == nil {
+				redacted = append([]string(nil), vv...)
+			}
+			redacted[i] = ritem
+		}
+		if redacted == nil {
+			return v, false
+		}
+		return redacted, true
+	}
+	return v, false
+}
+
 // PushBusData func
 func PushBusData(bdata *BusData) {
+	redactBusData(bdata)
 L:
 	for i := 0; i < 10; i++ {
 		select {
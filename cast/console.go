@@ -144,6 +144,8 @@ func FormatConsoleLogMsg(fback *BusData, verbose bool) *string {
 		switch *fback.EventID {
 		case EventActionInit:
 			return p(fmt.Sprintf(format, prefxmap[*fback.LogLevel]) + " start")
+		case EventActionWaiting:
+			return p(fmt.Sprintf(format, prefxmap[*fback.LogLevel]) + " " + *fback.M)
 		case EventActionKO:
 			return p(fmt.Sprintf(format, prefxmap[*fback.LogLevel]) + " " + *fback.M)
 		case EventActionUnCaughtKO:
@@ -206,6 +208,7 @@ func (c *ConsoleLogger) printMessage(fback *BusData) bool {
 		switch *fback.EventID {
 		case EventNewThread,
 			EventActionInit,
+			EventActionWaiting,
 			EventActionKO,
 			EventActionUnCaughtKO,
 			EventActionOK,
@@ -155,6 +155,11 @@ var BridgeSecretFlag *string
 
 var ForceFileFlag *bool
 
+// remote blueprints cache and lockfile
+var OfflineFlag *bool
+var LockFileFlag *string
+var UpdateLockFlag *bool
+
 func AppHomePath() string {
 	var userHomePath string
 	if runtime.GOOS == "windows" {
@@ -65,6 +65,16 @@ func RemoveDirector() {
 type HandleIRBConfig struct {
 	Manager *Manager
 	IRB     *blueprint.IRBlueprint
+	// called when the manager ends his run, also on panic. The
+	// manager keeps his runtime until OnEnd returns
+	OnEnd func(manager *Manager, err error)
+}
+
+// end func. Call OnEnd if any
+func (h *HandleIRBConfig) end(manager *Manager, err error) {
+	if h.OnEnd != nil {
+		h.OnEnd(manager, err)
+	}
 }
 
 // Director struct
@@ -167,6 +177,7 @@ L:
 			if manager == nil {
 				if _, exists := d.managersByExecutionID[*irb.BP.ExecutionUUID]; exists {
 					cast.LogErr("[Director] bp already running...", irb.BP.ExecutionUUID)
+					hirbcfg.end(nil, fmt.Errorf("bp already running"))
 					continue
 				}
 				manager = NewManager(d.serverMode)
@@ -178,6 +189,7 @@ L:
 				irb = manager.IRB
 				if _, exists := d.managersByExecutionID[*irb.BP.ExecutionUUID]; exists {
 					cast.LogErr("[Director] bp already running...", irb.BP.ExecutionUUID)
+					hirbcfg.end(manager, fmt.Errorf("bp already running"))
 					continue
 				}
 			}
@@ -225,8 +237,16 @@ L:
 				defer func() {
 					d.UnregisterManager <- manager
 				}()
+				var err error
+				defer func() {
+					if r := recover(); r != nil {
+						hirbcfg.end(manager, fmt.Errorf("panic: %v", r))
+						panic(r)
+					}
+					hirbcfg.end(manager, err)
+				}()
 				cast.LogDebug("Starting manager loop...", nil)
-				err := manager.Run()
+				err = manager.Run()
 				if err != nil {
 					cast.LogErr(err.Error(), nil)
 				}
@@ -57,9 +57,15 @@ type Manager struct {
 	ExecutionUUID *string
 	IRB           *blueprint.IRBlueprint
 	serverMode    bool
-	Logger        *cast.Logger
-	Stats         *stats
-	mu            sync.Mutex
+	// checkpoint of a previous run of the IRB. If not nil, the
+	// execution is resumed from it instead of the start action
+	Resume *runtime.Checkpoint
+	// if not empty, the report of the execution is written
+	// into this path (JUnit XML if .xml, JSON otherwise)
+	ReportPath string
+	Logger     *cast.Logger
+	Stats      *stats
+	mu         sync.Mutex
 }
 
 type stats struct {
@@ -72,6 +78,7 @@ func (m *Manager) reset() {
 	defer m.mu.Unlock()
 	m.Runtime = nil
 	m.ExecutionUUID = nil
+	m.Resume = nil
 	m.Stats = &stats{}
 	m.Logger = &cast.Logger{}
 	m.Logger.SetThreadID("manager")
@@ -102,7 +109,7 @@ func (m *Manager) PrepareIRB(irb *blueprint.IRBlueprint) {
 }
 
 // Run func
-func (m *Manager) Run() error {
+func (m *Manager) Run() (err error) {
 	exit := false
 	defer func() {
 		exit = true
@@ -123,6 +130,13 @@ func (m *Manager) Run() error {
 
 	m.Logger.LogDebug("[Manager] Starting...")
 
+	// the report is also written when the runtime cannot start
+	defer func() {
+		if err != nil {
+			m.writeReport(err)
+		}
+	}()
+
 	cast.LogDebug("Sending EventRuntimeStarting", nil)
 	cast.PushEvent(cast.EventRuntimeStarting, m.ExecutionUUID)
 	if m.IRB.StartAction == nil {
@@ -142,52 +156,62 @@ func (m *Manager) Run() error {
 	}()
 	defer ipcs.Close()
 
-	// init store
-	st := storage.NewStore()
-
-	// set ipcs into store
-	st.SetPrivateVar("IPCS", ipcs)
-
-	// set vars from cli args
-	for _, irbarg := range m.IRB.Args {
-		if st.ExistsRefName(irbarg.Name) {
-			// this is an stack var wich can acumulate values
-			err := st.Push(&base.StorageRecord{
-				RefName: irbarg.Name,
-				Aout:    nil,
-				Value:   irbarg.Value,
-				Action:  nil,
-			}, "")
+	newStore := func() (base.IStore, error) {
+		// init store
+		st := storage.NewStore()
+
+		// set ipcs into store
+		st.SetPrivateVar("IPCS", ipcs)
+
+		// set runtime into store, used by actions
+		// that run other blueprints
+		st.SetPrivateVar("RUNTIME", m.Runtime)
+		st.SetLogger(m.GetLogger().Duplicate())
+		return st, nil
+	}
+
+	// for run stats
+	startTime := time.Now()
+
+	var started bool
+	if m.Resume != nil {
+		// the args are already into the checkpoint records, but
+		// the secret ones, loaded again to refill them
+		m.Logger.LogInfo(fmt.Sprintf("Resuming execution %s from checkpoint...", m.Resume.ExecutionUUID))
+		newArgsStore := func() (base.IStore, error) {
+			st, err := newStore()
 			if err != nil {
-				return err
+				return nil, err
 			}
-		} else {
-			err := st.Insert(&base.StorageRecord{
-				RefName: irbarg.Name,
-				Aout:    nil,
-				Value:   irbarg.Value,
-				Literal: true,
-				Action:  nil,
-			}, "")
-			if err != nil {
-				return err
+			if err := runtime.LoadArgs(st, m.IRB.Args); err != nil {
+				return nil, err
 			}
+			return st, nil
 		}
-	}
+		if err := m.Runtime.ResumeThreads(m.Resume, newArgsStore); err != nil {
+			return err
+		}
+		started = true
+	} else {
+		st, _ := newStore()
 
-	m.Logger.ParanoidLogDebug(fmt.Sprintf("[Manager] Setting %s as start point", m.IRB.StartAction.ActionName))
+		// set vars from cli args
+		if err := runtime.LoadArgs(st, m.IRB.Args); err != nil {
+			return err
+		}
 
-	startActionContext := m.Runtime.NewAContext(nil, m.IRB.StartAction)
-	m.Logger.ParanoidLogDebug("after set context")
-	st.SetLogger(m.GetLogger().Duplicate())
-	startActionContext.SetStore(st)
-	m.Logger.ParanoidLogDebug("after set store")
+		m.Logger.ParanoidLogDebug(fmt.Sprintf("[Manager] Setting %s as start point", m.IRB.StartAction.ActionName))
 
-	// for run stats
-	startTime := time.Now()
+		startActionContext := m.Runtime.NewAContext(nil, m.IRB.StartAction)
+		m.Logger.ParanoidLogDebug("after set context")
+		startActionContext.SetStore(st)
+		m.Logger.ParanoidLogDebug("after set store")
+
+		started = m.Runtime.NewThread(startActionContext)
+	}
 
 	// start to run
-	if m.Runtime.NewThread(startActionContext) {
+	if started {
 		cast.LogDebug("Sending EventRuntimeStarted", nil)
 		cast.PushEvent(cast.EventRuntimeStarted, m.ExecutionUUID)
 		m.Logger.ParanoidLogDebug("after push event")
@@ -218,6 +242,10 @@ func (m *Manager) Run() error {
 		cast.LogErr(fmt.Sprintf("%s\n\n***", lerr.Error()), m.ExecutionUUID)
 	}
 
+	report := m.writeReport(nil)
+	m.Stats.actions = report.Totals.Actions
+	m.Stats.stages = report.Totals.Threads
+
 	elapsedTime := time.Since(startTime).String()
 	m.Logger.LogInfo("[Manager] stats: " + strconv.Itoa(m.Stats.actions) + " actions executed by " + strconv.Itoa(m.Stats.stages) + " stages in " + elapsedTime)
 
@@ -226,3 +254,24 @@ func (m *Manager) Run() error {
 	m.Logger.LogInfo("[Manager] out")
 	return nil
 }
+
+// writeReport func. Write the report of the execution if requested.
+// runErr is the error that prevented the runtime from running
+func (m *Manager) writeReport(runErr error) *runtime.Report {
+	report := m.Runtime.Report()
+	if runErr != nil {
+		report.Errors = append(report.Errors, base.Redact(runErr.Error()))
+		if report.ExitCode == 0 {
+			report.ExitCode = 1
+			report.ExitStatus = runtime.ExitStatusFailure
+		}
+	}
+	if m.ReportPath != "" {
+		if err := runtime.WriteReportFile(m.ReportPath, report); err != nil {
+			m.Logger.LogErr(fmt.Sprintf("Cannot write the execution report: %v", err))
+		} else {
+			m.Logger.LogInfo("Execution report written to " + m.ReportPath)
+		}
+	}
+	return report
+}
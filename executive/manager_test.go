@@ -0,0 +1,86 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package executive_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/cast"
+	"github.com/develatio/nebulant-cli/executive"
+	"github.com/develatio/nebulant-cli/runtime"
+)
+
+func TestMain(m *testing.M) {
+	cast.InitSystemBus()
+	os.Exit(m.Run())
+}
+
+// the report is written also when the runtime cannot start
+func TestManagerReportOnResumeError(t *testing.T) {
+	bp, err := blueprint.NewFromYAML([]byte(`
+actions:
+  - id: a
+    provider: generic
+    action: log
+    first: true
+    parameters: {content: hi}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	irb, err := blueprint.GenerateIRB(bp, &blueprint.IRBGenConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager := executive.NewManager(false)
+	manager.PrepareIRB(irb)
+	manager.Resume = &runtime.Checkpoint{
+		ExecutionUUID: "test",
+		Threads:       []*runtime.ThreadCheckpoint{{Pending: []string{"removed"}}},
+	}
+	manager.ReportPath = filepath.Join(t.TempDir(), "report.json")
+	if err := manager.Run(); err == nil || !strings.Contains(err.Error(), "removed not found") {
+		t.Fatalf("expected a resume error, got %v", err)
+	}
+
+	data, err := os.ReadFile(manager.ReportPath)
+	if err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+	rep := &runtime.Report{}
+	if err := json.Unmarshal(data, rep); err != nil {
+		t.Fatal(err)
+	}
+	if rep.ExitCode == 0 || rep.ExitStatus != runtime.ExitStatusFailure {
+		t.Errorf("unexpected exit %d %s", rep.ExitCode, rep.ExitStatus)
+	}
+	if len(rep.Errors) != 1 || !strings.Contains(rep.Errors[0], "removed not found") {
+		t.Errorf("unexpected errors %v", rep.Errors)
+	}
+}
@@ -0,0 +1,493 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package expr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bhmj/jsonslice"
+)
+
+type filterFunc func(v interface{}, args []interface{}) (interface{}, error)
+
+type filterDef struct {
+	minArgs int
+	maxArgs int
+	fn      filterFunc
+}
+
+var filters map[string]*filterDef
+
+func init() {
+	filters = map[string]*filterDef{
+		"upper": {fn: func(v interface{}, args []interface{}) (interface{}, error) {
+			return strings.ToUpper(String(v)), nil
+		}},
+		"lower": {fn: func(v interface{}, args []interface{}) (interface{}, error) {
+			return strings.ToLower(String(v)), nil
+		}},
+		"trim": {fn: func(v interface{}, args []interface{}) (interface{}, error) {
+			return strings.TrimSpace(String(v)), nil
+		}},
+		"b64enc": {fn: func(v interface{}, args []interface{}) (interface{}, error) {
+			return base64.StdEncoding.EncodeToString([]byte(String(v))), nil
+		}},
+		"b64dec": {fn: func(v interface{}, args []interface{}) (interface{}, error) {
+			s := strings.TrimSpace(String(v))
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				// allow unpadded input
+				b, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+				if err != nil {
+					return nil, fmt.Errorf("invalid base64 input")
+				}
+			}
+			return string(b), nil
+		}},
+		"json": {fn: func(v interface{}, args []interface{}) (interface{}, error) {
+			b, err := marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			return string(b), nil
+		}},
+		"join": {maxArgs: 1, fn: func(v interface{}, args []interface{}) (interface{}, error) {
+			sep := ","
+			if len(args) > 0 {
+				sep = String(args[0])
+			}
+			list, ok := v.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("join needs a list, got %s", typeName(v))
+			}
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = String(item)
+			}
+			return strings.Join(items, sep), nil
+		}},
+		"length": {fn: func(v interface{}, args []interface{}) (interface{}, error) {
+			switch vv := v.(type) {
+			case nil:
+				return float64(0), nil
+			case string:
+				return float64(utf8.RuneCountInString(vv)), nil
+			case []interface{}:
+				return float64(len(vv)), nil
+			case map[string]interface{}:
+				return float64(len(vv)), nil
+			}
+			return nil, fmt.Errorf("length needs a string, list or object, got %s", typeName(v))
+		}},
+		"sha256": {fn: func(v interface{}, args []interface{}) (interface{}, error) {
+			sum := sha256.Sum256([]byte(String(v)))
+			return hex.EncodeToString(sum[:]), nil
+		}},
+	}
+}
+
+// Filters func. Returns the names of the available filters
+func Filters() []string {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eval func. Evaluates the expression resolving references with r.
+// The result is a native value, use String to render it
+func (e *Expr) Eval(r Resolver) (interface{}, error) {
+	ev := &evaluator{src: e.src, r: r}
+	return ev.eval(e.root)
+}
+
+type evaluator struct {
+	src string
+	r   Resolver
+}
+
+func (ev *evaluator) errorf(n node, err error) error {
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return &Error{Source: ev.src, Column: n.pos() + 1, Msg: err.Error(), Err: err}
+}
+
+func (ev *evaluator) eval(n node) (interface{}, error) {
+	switch nn := n.(type) {
+	case *literalNode:
+		return nn.v, nil
+	case *refNode:
+		v, err := ev.r.Resolve(nn.path)
+		if err != nil {
+			return nil, ev.errorf(nn, err)
+		}
+		return normalize(v), nil
+	case *unaryNode:
+		x, err := ev.eval(nn.x)
+		if err != nil {
+			return nil, err
+		}
+		if nn.op == "!" {
+			return !Truthy(x), nil
+		}
+		f, ok := toNumber(x)
+		if !ok {
+			return nil, ev.errorf(nn, fmt.Errorf("cannot negate %s", typeName(x)))
+		}
+		return -f, nil
+	case *ternaryNode:
+		cond, err := ev.eval(nn.cond)
+		if err != nil {
+			return nil, err
+		}
+		if Truthy(cond) {
+			return ev.eval(nn.a)
+		}
+		return ev.eval(nn.b)
+	case *binaryNode:
+		return ev.evalBinary(nn)
+	case *filterNode:
+		return ev.evalFilter(nn)
+	}
+	return nil, ev.errorf(n, fmt.Errorf("unknown node"))
+}
+
+func (ev *evaluator) evalBinary(n *binaryNode) (interface{}, error) {
+	l, err := ev.eval(n.l)
+	switch n.op {
+	case "??":
+		if err != nil {
+			if !IsUndefined(err) {
+				return nil, err
+			}
+			l = nil
+		}
+		if l == nil {
+			return ev.eval(n.r)
+		}
+		return l, nil
+	case "||":
+		if err != nil {
+			return nil, err
+		}
+		if Truthy(l) {
+			return l, nil
+		}
+		return ev.eval(n.r)
+	case "&&":
+		if err != nil {
+			return nil, err
+		}
+		if !Truthy(l) {
+			return l, nil
+		}
+		return ev.eval(n.r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r, err := ev.eval(n.r)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "<", "<=", ">", ">=":
+		c, err := compare(l, r)
+		if err != nil {
+			return nil, ev.errorf(n, err)
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}
+	lf, lok := toNumber(l)
+	rf, rok := toNumber(r)
+	if n.op == "+" && (!lok || !rok) {
+		if isScalar(l) && isScalar(r) {
+			return String(l) + String(r), nil
+		}
+	}
+	if !lok || !rok {
+		return nil, ev.errorf(n, fmt.Errorf("operator %s needs numbers, got %s and %s", n.op, typeName(l), typeName(r)))
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, ev.errorf(n, fmt.Errorf("division by zero"))
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, ev.errorf(n, fmt.Errorf("modulo by zero"))
+		}
+		return math.Mod(lf, rf), nil
+	}
+	return nil, ev.errorf(n, fmt.Errorf("unknown operator %s", n.op))
+}
+
+func (ev *evaluator) evalFilter(n *filterNode) (interface{}, error) {
+	x, err := ev.eval(n.x)
+	if err != nil {
+		return nil, err
+	}
+	if n.path != "" {
+		v, err := JSONPath(x, n.path)
+		if err != nil {
+			return nil, ev.errorf(n, err)
+		}
+		return v, nil
+	}
+	def := filters[n.name]
+	if len(n.args) < def.minArgs || len(n.args) > def.maxArgs {
+		if def.maxArgs == 0 {
+			return nil, ev.errorf(n, fmt.Errorf("filter %s takes no arguments", n.name))
+		}
+		return nil, ev.errorf(n, fmt.Errorf("filter %s takes at most %d argument(s)", n.name, def.maxArgs))
+	}
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		args[i], err = ev.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	v, err := def.fn(x, args)
+	if err != nil {
+		return nil, ev.errorf(n, err)
+	}
+	return v, nil
+}
+
+// JSONPath func. Applies a json path ($.a[0].b) to a native value
+func JSONPath(v interface{}, path string) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	res, err := jsonslice.Get(b, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(res)) == 0 {
+		return nil, &UndefinedError{Msg: fmt.Sprintf("path %s not found", path)}
+	}
+	return Decode(res)
+}
+
+// Decode func. Decodes JSON into a native value
+func Decode(b []byte) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// normalize func. Turns go values returned by resolvers into the
+// native types used by the evaluator
+func normalize(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case nil, string, bool, float64, []interface{}, map[string]interface{}:
+		return v
+	case int:
+		return float64(vv)
+	case int64:
+		return float64(vv)
+	case float32:
+		return float64(vv)
+	case json.Number:
+		if f, err := vv.Float64(); err == nil {
+			return f
+		}
+		return vv.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	nv, err := Decode(b)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return nv
+}
+
+// Truthy func. nil, false, 0, "", "false" and empty lists or objects
+// are false, anything else is true
+func Truthy(v interface{}) bool {
+	switch vv := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return vv
+	case float64:
+		return vv != 0
+	case string:
+		return vv != "" && vv != "false"
+	case []interface{}:
+		return len(vv) > 0
+	case map[string]interface{}:
+		return len(vv) > 0
+	}
+	return true
+}
+
+// String func. Renders a native value as interpolated text: strings
+// as they are, integral numbers without decimals, nil as empty string
+// and lists or objects as compact JSON
+func String(v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vv
+	case bool:
+		return strconv.FormatBool(vv)
+	case float64:
+		if vv == math.Trunc(vv) && math.Abs(vv) < 1e15 {
+			return strconv.FormatInt(int64(vv), 10)
+		}
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	}
+	b, err := marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch vv := v.(type) {
+	case float64:
+		return vv, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(vv), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case nil, string, bool, float64:
+		return true
+	}
+	return false
+}
+
+func equal(l, r interface{}) bool {
+	lf, lok := toNumber(l)
+	rf, rok := toNumber(r)
+	if lok && rok {
+		return lf == rf
+	}
+	if isScalar(l) && isScalar(r) {
+		if l == nil || r == nil {
+			return l == nil && r == nil
+		}
+		return String(l) == String(r)
+	}
+	lb, lerr := json.Marshal(l)
+	rb, rerr := json.Marshal(r)
+	return lerr == nil && rerr == nil && bytes.Equal(lb, rb)
+}
+
+func compare(l, r interface{}) (int, error) {
+	lf, lok := toNumber(l)
+	rf, rok := toNumber(r)
+	if lok && rok {
+		switch {
+		case lf < rf:
+			return -1, nil
+		case lf > rf:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if lok && rok {
+		return strings.Compare(ls, rs), nil
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", typeName(l), typeName(r))
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,444 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package expr implements the small expression language available
+// inside {{ }} interpolations: references, literals, defaults (??),
+// arithmetic, comparisons, ternaries and filters (| upper).
+//
+//	{{ server.Name ?? "unnamed" | upper }}
+//	{{ count.value > 2 ? "many" : "few" }}
+//	{{ tags.value | $[0].Key | lower }}
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var refNameRegexp = regexp.MustCompile(`(?:\\.|[^.[|\\]+)+`)
+
+// Error struct. An expression error with the column where it happened
+type Error struct {
+	// Source is the expression text, without braces
+	Source string
+	// Column is 1-based
+	Column int
+	Msg    string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("expression error at column %d of \"{{ %s }}\": %s", e.Column, e.Source, e.Msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(src string, pos int, format string, args ...interface{}) *Error {
+	return &Error{Source: src, Column: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// UndefinedError struct. Resolvers should return this error when a
+// reference or path does not exists, so that defaults (??) can be
+// applied
+type UndefinedError struct {
+	Msg string
+}
+
+func (e *UndefinedError) Error() string {
+	return e.Msg
+}
+
+// IsUndefined func. Tells if err is, or wraps, an UndefinedError
+func IsUndefined(err error) bool {
+	var uerr *UndefinedError
+	return errors.As(err, &uerr)
+}
+
+// Resolver interface. Returns the native value (string, float64, bool,
+// nil, []interface{}, map[string]interface{}) of a reference path as
+// a.b[0].c
+type Resolver interface {
+	Resolve(path string) (interface{}, error)
+}
+
+// ResolverFunc type. Adapter to use ordinary funcs as Resolver
+type ResolverFunc func(path string) (interface{}, error)
+
+// Resolve func.
+func (f ResolverFunc) Resolve(path string) (interface{}, error) {
+	return f(path)
+}
+
+type node interface {
+	pos() int
+}
+
+type literalNode struct {
+	p int
+	v interface{}
+}
+
+type refNode struct {
+	p    int
+	path string
+}
+
+type unaryNode struct {
+	p  int
+	op string
+	x  node
+}
+
+type binaryNode struct {
+	p    int
+	op   string
+	l, r node
+}
+
+type ternaryNode struct {
+	p          int
+	cond, a, b node
+}
+
+type filterNode struct {
+	p    int
+	x    node
+	name string
+	// json path filter (| $.a.b)
+	path string
+	args []node
+}
+
+func (n *literalNode) pos() int { return n.p }
+func (n *refNode) pos() int     { return n.p }
+func (n *unaryNode) pos() int   { return n.p }
+func (n *binaryNode) pos() int  { return n.p }
+func (n *ternaryNode) pos() int { return n.p }
+func (n *filterNode) pos() int  { return n.p }
+
+// Expr struct. A parsed expression
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse func. Parses the text between {{ and }}
+func Parse(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, tokens: tokens}
+	if p.peek().kind == tkEOF {
+		return nil, newError(src, 0, "empty expression")
+	}
+	root, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if tk := p.peek(); tk.kind != tkEOF {
+		return nil, p.unexpected(tk)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String func.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// IsReference func. Tells if the expression is a plain reference,
+// optionally followed by json path filters ({{ a.b | $[0].c }}), which
+// is the syntax supported before the expression language existed
+func (e *Expr) IsReference() bool {
+	n := e.root
+	for {
+		switch nn := n.(type) {
+		case *refNode:
+			return true
+		case *filterNode:
+			if nn.path == "" {
+				return false
+			}
+			n = nn.x
+		default:
+			return false
+		}
+	}
+}
+
+// Refs func. Returns the paths of all the references used by the
+// expression, in order of appearance
+func (e *Expr) Refs() []string {
+	return collectRefs(e.root, false)
+}
+
+// RequiredRefs func. As Refs, but without the references that have a
+// default value (the left side of ??)
+func (e *Expr) RequiredRefs() []string {
+	return collectRefs(e.root, true)
+}
+
+func collectRefs(root node, required bool) []string {
+	var refs []string
+	var walk func(n node)
+	walk = func(n node) {
+		switch nn := n.(type) {
+		case *refNode:
+			refs = append(refs, nn.path)
+		case *unaryNode:
+			walk(nn.x)
+		case *binaryNode:
+			if !required || nn.op != "??" {
+				walk(nn.l)
+			}
+			walk(nn.r)
+		case *ternaryNode:
+			walk(nn.cond)
+			walk(nn.a)
+			walk(nn.b)
+		case *filterNode:
+			walk(nn.x)
+			for _, arg := range nn.args {
+				walk(arg)
+			}
+		}
+	}
+	walk(root)
+	return refs
+}
+
+// RefName func. Returns the name part of a reference path
+// (my-var of my-var.a[0])
+func RefName(path string) string {
+	return strings.TrimSpace(refNameRegexp.FindString(path))
+}
+
+type parser struct {
+	src    string
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) advance() token {
+	tk := p.tokens[p.i]
+	if tk.kind != tkEOF {
+		p.i++
+	}
+	return tk
+}
+
+func (p *parser) isOp(op string) bool {
+	tk := p.peek()
+	return tk.kind == tkOp && tk.text == op
+}
+
+func (p *parser) unexpected(tk token) error {
+	if tk.kind == tkEOF {
+		return newError(p.src, tk.pos, "unexpected end of expression")
+	}
+	return newError(p.src, tk.pos, "unexpected %q", tk.text)
+}
+
+func (p *parser) expectOp(op string) error {
+	if !p.isOp(op) {
+		tk := p.peek()
+		if tk.kind == tkEOF {
+			return newError(p.src, tk.pos, "expected %q, found end of expression", op)
+		}
+		return newError(p.src, tk.pos, "expected %q, found %q", op, tk.text)
+	}
+	p.advance()
+	return nil
+}
+
+// ternary := default ("?" ternary ":" ternary)?
+func (p *parser) parseTernary() (node, error) {
+	cond, err := p.parseDefault()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("?") {
+		return cond, nil
+	}
+	tk := p.advance()
+	a, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectOp(":"); err != nil {
+		return nil, err
+	}
+	b, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryNode{p: tk.pos, cond: cond, a: a, b: b}, nil
+}
+
+// binary precedence levels, lowest first
+var binaryLevels = [][]string{
+	{"??"},
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseDefault() (node, error) {
+	return p.parseBinary(0)
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level >= len(binaryLevels) {
+		return p.parseUnary()
+	}
+	l, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tk := p.peek()
+		if tk.kind != tkOp || !contains(binaryLevels[level], tk.text) {
+			return l, nil
+		}
+		p.advance()
+		r, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{p: tk.pos, op: tk.text, l: l, r: r}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!") || p.isOp("-") {
+		tk := p.advance()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{p: tk.pos, op: tk.text, x: x}, nil
+	}
+	return p.parsePipe()
+}
+
+// pipe := primary ("|" (path | name ("(" args ")")?))*
+func (p *parser) parsePipe() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("|") {
+		p.advance()
+		tk := p.advance()
+		switch tk.kind {
+		case tkPath:
+			x = &filterNode{p: tk.pos, x: x, path: tk.text}
+			continue
+		case tkIdent:
+		default:
+			if tk.kind == tkEOF {
+				return nil, newError(p.src, tk.pos, "expected filter name, found end of expression")
+			}
+			return nil, newError(p.src, tk.pos, "expected filter name, found %q", tk.text)
+		}
+		if _, ok := filters[tk.text]; !ok {
+			return nil, newError(p.src, tk.pos, "unknown filter %q", tk.text)
+		}
+		f := &filterNode{p: tk.pos, x: x, name: tk.text}
+		if p.isOp("(") {
+			p.advance()
+			for !p.isOp(")") {
+				arg, err := p.parseTernary()
+				if err != nil {
+					return nil, err
+				}
+				f.args = append(f.args, arg)
+				if !p.isOp(",") {
+					break
+				}
+				p.advance()
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+		}
+		x = f
+	}
+	return x, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tk := p.advance()
+	switch tk.kind {
+	case tkNumber:
+		f, err := strconv.ParseFloat(tk.text, 64)
+		if err != nil {
+			return nil, newError(p.src, tk.pos, "invalid number %q", tk.text)
+		}
+		return &literalNode{p: tk.pos, v: f}, nil
+	case tkString:
+		return &literalNode{p: tk.pos, v: tk.str}, nil
+	case tkIdent:
+		switch tk.text {
+		case "true":
+			return &literalNode{p: tk.pos, v: true}, nil
+		case "false":
+			return &literalNode{p: tk.pos, v: false}, nil
+		case "null", "nil":
+			return &literalNode{p: tk.pos, v: nil}, nil
+		}
+		return &refNode{p: tk.pos, path: tk.text}, nil
+	case tkOp:
+		if tk.text == "(" {
+			x, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+	return nil, p.unexpected(tk)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
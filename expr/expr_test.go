@@ -0,0 +1,196 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package expr_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/expr"
+)
+
+var testValues = map[string]interface{}{
+	"name":  "web",
+	"count": 3,
+	"tags":  []interface{}{"a", "b"},
+	"obj":   map[string]interface{}{"k": "v"},
+	"empty": "",
+	"nil":   nil,
+}
+
+var testResolver = expr.ResolverFunc(func(path string) (interface{}, error) {
+	v, exists := testValues[path]
+	if !exists {
+		return nil, &expr.UndefinedError{Msg: path + " is not defined"}
+	}
+	return v, nil
+})
+
+func eval(src string) (string, error) {
+	e, err := expr.Parse(src)
+	if err != nil {
+		return "", err
+	}
+	v, err := e.Eval(testResolver)
+	if err != nil {
+		return "", err
+	}
+	return expr.String(v), nil
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		// precedence
+		{`1 + 2 * 3`, "7"},
+		{`(1 + 2) * 3`, "9"},
+		{`10 - 4 - 3`, "3"},
+		{`2 * 3 % 4`, "2"},
+		{`-2 * 3`, "-6"},
+		{`7 / 2`, "3.5"},
+		{`1 + 2 == 3`, "true"},
+		{`true || false && false`, "true"},
+		{`!false && false`, "false"},
+		{`1 < 2 && 2 < 3`, "true"},
+		{`"a" + 1`, "a1"},
+		{`count + 1`, "4"},
+		// defaults
+		{`missing ?? "def"`, "def"},
+		{`name ?? "def"`, "web"},
+		{`nil ?? 5`, "5"},
+		{`empty ?? "def"`, ""},
+		{`missing ?? other ?? 1`, "1"},
+		{`missing ?? "a" | upper`, "A"},
+		{`missing ?? 1 + 1`, "2"},
+		// ternaries
+		{`count > 2 ? "many" : "few"`, "many"},
+		{`count > 5 ? "many" : "few"`, "few"},
+		{`count == 1 ? "one" : count == 3 ? "three" : "other"`, "three"},
+		{`missing ?? 0 ? "y" : "n"`, "n"},
+		{`empty ? "y" : "n"`, "n"},
+		// filters
+		{`name | upper`, "WEB"},
+		{`" x " | trim`, "x"},
+		{`tags | join("-")`, "a-b"},
+		{`tags | join`, "a,b"},
+		{`tags | length`, "2"},
+		{`name | b64enc | b64dec`, "web"},
+		{`obj | json`, `{"k":"v"}`},
+		{`tags | $[1] | upper`, "B"},
+		{`obj | $.k`, "v"},
+		{`missing | $.k ?? "none"`, "none"},
+	}
+	for _, tt := range tests {
+		got, err := eval(tt.src)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.src, tt.want, got)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		src    string
+		column int
+		msg    string
+	}{
+		{``, 1, "empty expression"},
+		{`1 +`, 4, "unexpected end of expression"},
+		{`(1 + 2`, 7, `expected ")"`},
+		{`"abc`, 1, "unterminated string"},
+		{`2x`, 2, "after number"},
+		{`a[0`, 2, "unclosed bracket"},
+		{`name | nope`, 8, `unknown filter "nope"`},
+		{`name |`, 7, "expected filter name"},
+		{`name | upper(1)`, 8, "takes no arguments"},
+		{`1 / 0`, 3, "division by zero"},
+		{`tags > 1`, 6, "cannot compare"},
+		{`obj * 2`, 5, "needs numbers"},
+		{`-name`, 1, "cannot negate"},
+		{`tags | length | upper | join`, 25, "join needs a list"},
+		{`count + missing`, 9, "missing is not defined"},
+	}
+	for _, tt := range tests {
+		_, err := eval(tt.src)
+		if err == nil {
+			t.Errorf("%s: expected error", tt.src)
+			continue
+		}
+		var eerr *expr.Error
+		if !errors.As(err, &eerr) {
+			t.Errorf("%s: expected *expr.Error, got %T", tt.src, err)
+			continue
+		}
+		if eerr.Column != tt.column {
+			t.Errorf("%s: expected column %d, got %d (%v)", tt.src, tt.column, eerr.Column, err)
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: expected %q in %q", tt.src, tt.msg, err.Error())
+		}
+	}
+
+	_, err := eval(`missing`)
+	if !expr.IsUndefined(err) {
+		t.Errorf("expected undefined error, got %v", err)
+	}
+}
+
+func TestRefs(t *testing.T) {
+	e, err := expr.Parse(`a ?? b + c.d[0] | upper`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs := e.Refs(); !reflect.DeepEqual(refs, []string{"a", "b", "c.d[0]"}) {
+		t.Errorf("unexpected refs %v", refs)
+	}
+	if refs := e.RequiredRefs(); !reflect.DeepEqual(refs, []string{"b", "c.d[0]"}) {
+		t.Errorf("unexpected required refs %v", refs)
+	}
+	if name := expr.RefName("my-var.a[0]"); name != "my-var" {
+		t.Errorf("unexpected ref name %s", name)
+	}
+
+	for src, want := range map[string]bool{
+		`a.b`:             true,
+		`a.b | $[0].c`:    true,
+		`a | upper`:       false,
+		`a ?? "b"`:        false,
+		`a | $[0] | trim`: false,
+	} {
+		e, err := expr.Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.IsReference() != want {
+			t.Errorf("%s: expected IsReference %v", src, want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package expr
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tkEOF tokenKind = iota
+	tkNumber
+	tkString
+	// reference, keyword or filter name
+	tkIdent
+	// json path ($.a.b) after a pipe
+	tkPath
+	tkOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// string value of tkString
+	str string
+	// offset into the source
+	pos int
+}
+
+// operators, longest first
+var operators = []string{
+	"??", "||", "&&", "==", "!=", "<=", ">=",
+	"?", ":", "|", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", ",",
+}
+
+// delimiters of references, any other char (as - or .) is part of
+// the reference name or path, as in the classic {{ my-var.a.b }}
+const refDelimiters = "|?()!,=<>+*/%&:\"'"
+
+type lexer struct {
+	src    string
+	pos    int
+	tokens []token
+}
+
+func lex(src string) ([]token, error) {
+	l := &lexer{src: src}
+	for {
+		tk, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		l.tokens = append(l.tokens, tk)
+		if tk.kind == tkEOF {
+			return l.tokens, nil
+		}
+	}
+}
+
+func (l *lexer) errorf(pos int, format string, args ...interface{}) error {
+	return newError(l.src, pos, format, args...)
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tkEOF, pos: l.pos}, nil
+	}
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case c == '"' || c == '\'':
+		return l.lexString()
+	case c >= '0' && c <= '9':
+		return l.lexNumber()
+	case c == '$':
+		text, err := l.lexRef()
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tkPath, text: text, pos: start}, nil
+	case c == '_' || c == '\\' || unicode.IsLetter(rune(c)) || c >= 0x80:
+		text, err := l.lexRef()
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tkIdent, text: text, pos: start}, nil
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tkOp, text: op, pos: start}, nil
+		}
+	}
+	return token{}, l.errorf(start, "unexpected character %q", c)
+}
+
+func (l *lexer) lexString() (token, error) {
+	start := l.pos
+	quote := l.src[l.pos]
+	l.pos++
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			return token{kind: tkString, text: l.src[start:l.pos], str: sb.String(), pos: start}, nil
+		case c == '\\' && l.pos+1 < len(l.src):
+			l.pos++
+			switch e := l.src[l.pos]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			default:
+				sb.WriteByte(e)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+		l.pos++
+	}
+	return token{}, l.errorf(start, "unterminated string")
+}
+
+func (l *lexer) lexNumber() (token, error) {
+	start := l.pos
+	digits := func() {
+		for l.pos < len(l.src) && l.src[l.pos] >= '0' && l.src[l.pos] <= '9' {
+			l.pos++
+		}
+	}
+	digits()
+	if l.pos+1 < len(l.src) && l.src[l.pos] == '.' && l.src[l.pos+1] >= '0' && l.src[l.pos+1] <= '9' {
+		l.pos++
+		digits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		digits()
+	}
+	if l.pos < len(l.src) && (unicode.IsLetter(rune(l.src[l.pos])) || l.src[l.pos] == '_') {
+		return token{}, l.errorf(l.pos, "unexpected character %q after number", l.src[l.pos])
+	}
+	return token{kind: tkNumber, text: l.src[start:l.pos], pos: start}, nil
+}
+
+// lexRef func. A reference with his path (a.b[0]["c d"].e) or
+// a json path. Brackets are consumed as a whole
+func (l *lexer) lexRef() (string, error) {
+	start := l.pos
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\' && l.pos+1 < len(l.src):
+			l.pos += 2
+			continue
+		case c == '[':
+			if err := l.skipBrackets(); err != nil {
+				return "", err
+			}
+			continue
+		case unicode.IsSpace(rune(c)) || strings.IndexByte(refDelimiters, c) >= 0:
+			return l.src[start:l.pos], nil
+		}
+		l.pos++
+	}
+	return l.src[start:l.pos], nil
+}
+
+func (l *lexer) skipBrackets() error {
+	start := l.pos
+	depth := 0
+	var quote byte
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				l.pos++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				l.pos++
+				return nil
+			}
+		}
+		l.pos++
+	}
+	return l.errorf(start, "unclosed bracket")
+}
@@ -33,6 +33,7 @@ require (
 	golang.org/x/crypto v0.25.0
 	golang.org/x/mod v0.19.0
 	golang.org/x/term v0.22.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -23,6 +23,8 @@
 package actors
 
 import (
+	"context"
+
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
@@ -39,6 +41,17 @@ type ActionContext struct {
 	Store        base.IStore
 	Logger       base.ILogger
 	NewEC2Client ec2Client
+	// canceled on action timeout
+	Context context.Context
+}
+
+// Ctx func. The context of the running action, canceled on
+// timeout. Background if not set
+func (a *ActionContext) Ctx() context.Context {
+	if a.Context == nil {
+		return context.Background()
+	}
+	return a.Context
 }
 
 var NewActionContext = func(awsSess *session.Session, action *base.Action, store base.IStore, logger base.ILogger) *ActionContext {
@@ -73,61 +86,95 @@ const (
 type ActionLayout struct {
 	F ActionFunc
 	N NextType
+	// API calls made by the actor, shown by run --plan
+	C string
+}
+
+// Ports func. The ports (OK/KO) available for the action
+func (a *ActionLayout) Ports() (ok bool, ko bool) {
+	return a.N != NextKO, a.N != NextOK
+}
+
+// Calls func. The external calls made by the action
+func (a *ActionLayout) Calls() string {
+	return a.C
 }
 
 // ActionFuncMap map
 var ActionFuncMap map[string]*ActionLayout = map[string]*ActionLayout{
-	"attach_volume":  {F: AttachVolume, N: NextOKKO},
-	"create_volume":  {F: CreateVolume, N: NextOKKO},
-	"delete_volume":  {F: DeleteVolume, N: NextOKKO},
-	"find_volumes":   {F: FindVolumes, N: NextOKKO},
-	"findone_volume": {F: FindOneVolume, N: NextOKKO},
-	"detach_volume":  {F: DetachVolume, N: NextOKKO},
-
-	"run_instance":     {F: RunInstance, N: NextOKKO},
-	"delete_instance":  {F: DeleteInstance, N: NextOKKO},
-	"find_instances":   {F: FindInstances, N: NextOKKO},
-	"findone_instance": {F: FindOneInstance, N: NextOKKO},
-	"stop_instance":    {F: StopInstance, N: NextOKKO},
-	"start_instance":   {F: StartInstance, N: NextOKKO},
-
-	"find_images":   {F: FindImages, N: NextOKKO},
-	"findone_image": {F: FindOneImage, N: NextOKKO},
-
-	"find_ifaces":   {F: FindNetworkInterfaces, N: NextOKKO},
-	"findone_iface": {F: FindNetworkInterface, N: NextOKKO},
-	"delete_iface":  {F: DeleteNetworkInterface, N: NextOKKO},
-
-	"find_databases":    {F: FindDatabases, N: NextOKKO},
-	"findone_database":  {F: FindOneDatabase, N: NextOKKO},
-	"create_db":         {F: CreateDatabase, N: NextOKKO},
-	"delete_db":         {F: DeleteDatabase, N: NextOKKO},
-	"database_snapshot": {F: CreateDatabase, N: NextOKKO},
+	"attach_volume":  {F: AttachVolume, N: NextOKKO, C: "ec2:AttachVolume"},
+	"create_volume":  {F: CreateVolume, N: NextOKKO, C: "ec2:CreateVolume"},
+	"delete_volume":  {F: DeleteVolume, N: NextOKKO, C: "ec2:DeleteVolume"},
+	"find_volumes":   {F: FindVolumes, N: NextOKKO, C: "ec2:DescribeVolumes"},
+	"findone_volume": {F: FindOneVolume, N: NextOKKO, C: "ec2:DescribeVolumes"},
+	"detach_volume":  {F: DetachVolume, N: NextOKKO, C: "ec2:DetachVolume"},
+
+	"run_instance":     {F: RunInstance, N: NextOKKO, C: "ec2:RunInstances"},
+	"delete_instance":  {F: DeleteInstance, N: NextOKKO, C: "ec2:TerminateInstances"},
+	"find_instances":   {F: FindInstances, N: NextOKKO, C: "ec2:DescribeInstances"},
+	"findone_instance": {F: FindOneInstance, N: NextOKKO, C: "ec2:DescribeInstances"},
+	"stop_instance":    {F: StopInstance, N: NextOKKO, C: "ec2:StopInstances"},
+	"start_instance":   {F: StartInstance, N: NextOKKO, C: "ec2:StartInstances"},
+
+	"find_images":   {F: FindImages, N: NextOKKO, C: "ec2:DescribeImages"},
+	"findone_image": {F: FindOneImage, N: NextOKKO, C: "ec2:DescribeImages"},
+
+	"find_ifaces":   {F: FindNetworkInterfaces, N: NextOKKO, C: "ec2:DescribeNetworkInterfaces"},
+	"findone_iface": {F: FindNetworkInterface, N: NextOKKO, C: "ec2:DescribeNetworkInterfaces"},
+	"delete_iface":  {F: DeleteNetworkInterface, N: NextOKKO, C: "ec2:DeleteNetworkInterface"},
+
+	"find_databases":    {F: FindDatabases, N: NextOKKO, C: "rds:DescribeDBInstances"},
+	"findone_database":  {F: FindOneDatabase, N: NextOKKO, C: "rds:DescribeDBInstances"},
+	"create_db":         {F: CreateDatabase, N: NextOKKO, C: "rds:CreateDBInstance"},
+	"delete_db":         {F: DeleteDatabase, N: NextOKKO, C: "rds:DeleteDBInstance"},
+	"database_snapshot": {F: CreateDatabase, N: NextOKKO, C: "rds:CreateDBInstance"},
 	"restore_snapshot":  {F: RestoreSnapshotDatabase, N: NextOKKO},
 
-	"allocate_address": {F: AllocateAddress, N: NextOKKO},
-	"find_addresses":   {F: FindAddresses, N: NextOKKO},
-	"findone_address":  {F: FindOneAddress, N: NextOKKO},
+	"allocate_address": {F: AllocateAddress, N: NextOKKO, C: "ec2:AllocateAddress"},
+	"find_addresses":   {F: FindAddresses, N: NextOKKO, C: "ec2:DescribeAddresses"},
+	"findone_address":  {F: FindOneAddress, N: NextOKKO, C: "ec2:DescribeAddresses"},
 
-	"attach_address":  {F: AttachAddress, N: NextOKKO},
-	"release_address": {F: ReleaseAddress, N: NextOKKO},
-	"detach_address":  {F: DetachAddress, N: NextOKKO},
+	"attach_address":  {F: AttachAddress, N: NextOKKO, C: "ec2:AssociateAddress"},
+	"release_address": {F: ReleaseAddress, N: NextOKKO, C: "ec2:ReleaseAddress"},
+	"detach_address":  {F: DetachAddress, N: NextOKKO, C: "ec2:DisassociateAddress"},
 
 	"set_region": {F: SetRegion, N: NextOKKO},
 
-	"find_vpcs":   {F: FindVpcs, N: NextOKKO},
-	"findone_vpc": {F: FindOneVpc, N: NextOKKO},
-	"delete_vpc":  {F: DeleteVpc, N: NextOKKO},
+	"find_vpcs":   {F: FindVpcs, N: NextOKKO, C: "ec2:DescribeVpcs"},
+	"findone_vpc": {F: FindOneVpc, N: NextOKKO, C: "ec2:DescribeVpcs"},
+	"delete_vpc":  {F: DeleteVpc, N: NextOKKO, C: "ec2:DeleteVpc"},
+
+	"find_subnets":   {F: FindSubnets, N: NextOKKO, C: "ec2:DescribeSubnets"},
+	"findone_subnet": {F: FindOneSubnet, N: NextOKKO, C: "ec2:DescribeSubnets"},
+	"delete_subnet":  {F: DeleteSubnet, N: NextOKKO, C: "ec2:DeleteSubnet"},
 
-	"find_subnets":   {F: FindSubnets, N: NextOKKO},
-	"findone_subnet": {F: FindOneSubnet, N: NextOKKO},
-	"delete_subnet":  {F: DeleteSubnet, N: NextOKKO},
+	"find_securitygroups":   {F: FindSecurityGroups, N: NextOKKO, C: "ec2:DescribeSecurityGroups"},
+	"findone_securitygroup": {F: FindOneSecurityGroup, N: NextOKKO, C: "ec2:DescribeSecurityGroups"},
+	"delete_securitygroup":  {F: DeleteSecurityGroup, N: NextOKKO, C: "ec2:DeleteSecurityGroup"},
 
-	"find_securitygroups":   {F: FindSecurityGroups, N: NextOKKO},
-	"findone_securitygroup": {F: FindOneSecurityGroup, N: NextOKKO},
-	"delete_securitygroup":  {F: DeleteSecurityGroup, N: NextOKKO},
+	"find_keypairs":   {F: FindKeyPairs, N: NextOKKO, C: "ec2:DescribeKeyPairs"},
+	"findone_keypair": {F: FindOneKeyPair, N: NextOKKO, C: "ec2:DescribeKeyPairs"},
+	"delete_keypair":  {F: DeleteKeyPair, N: NextOKKO, C: "ec2:DeleteKeyPair"},
+}
+
+// ResourceLayout struct. The resource created by an action
+// and the action that deletes it
+type ResourceLayout struct {
+	Type string
+	// delete action
+	D string
+	// parameter of the delete action with the resource id
+	P string
+	// P is a list of ids
+	L bool
+	// other parameters of the delete action
+	X map[string]interface{}
+}
 
-	"find_keypairs":   {F: FindKeyPairs, N: NextOKKO},
-	"findone_keypair": {F: FindOneKeyPair, N: NextOKKO},
-	"delete_keypair":  {F: DeleteKeyPair, N: NextOKKO},
+// ResourceFuncMap map. Resources created by action name
+var ResourceFuncMap map[string]*ResourceLayout = map[string]*ResourceLayout{
+	"run_instance":     {Type: "instance", D: "delete_instance", P: "InstanceIds", L: true, X: map[string]interface{}{"_waiters": []string{"WaitUntilInstanceTerminated"}}},
+	"create_volume":    {Type: "volume", D: "delete_volume", P: "VolumeId"},
+	"allocate_address": {Type: "address", D: "release_address", P: "AllocationId"},
+	"create_db":        {Type: "database", D: "delete_db", P: "DBInstanceIdentifier", X: map[string]interface{}{"SkipFinalSnapshot": true}},
 }
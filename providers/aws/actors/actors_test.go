@@ -38,6 +38,7 @@ func TestDeepInterpolation(t *testing.T) {
 		RefName: "SINGLE_VAR_NAME",
 		Aout:    nil,
 		Value:   "varvalue",
+		Literal: true,
 	}, "generic")
 
 	text1 := "holi"
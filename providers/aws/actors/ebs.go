@@ -23,7 +23,6 @@
 package actors
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -37,7 +36,7 @@ import (
 func FindVolumes(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DescribeVolumesInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -87,11 +86,11 @@ func FindOneVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 // CreateVolume func
 func CreateVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 	awsinput := new(ec2.CreateVolumeInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	internalparams := new(blueprint.InternalParameters)
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +125,7 @@ func CreateVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 			switch waitername {
 			case "WaitUntilVolumeAvailable":
 				ctx.Logger.LogInfo("Waiting for volume to be available...")
-				err = svc.WaitUntilVolumeAvailable(waitinput)
+				err = svc.WaitUntilVolumeAvailableWithContext(ctx.Ctx(), waitinput)
 				if err != nil {
 					return nil, err
 				}
@@ -147,11 +146,11 @@ func AttachVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 	svc := ctx.NewEC2Client()
 
 	awsinput := new(ec2.AttachVolumeInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	internalparams := new(blueprint.InternalParameters)
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +176,7 @@ func AttachVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 			switch waitername {
 			case "WaitUntilVolumeInUse":
 				ctx.Logger.LogInfo("Waiting for volume to be attached...")
-				err = svc.WaitUntilVolumeInUse(waitinput)
+				err = svc.WaitUntilVolumeInUseWithContext(ctx.Ctx(), waitinput)
 				if err != nil {
 					return nil, err
 				}
@@ -196,7 +195,7 @@ func AttachVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 func DetachVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DetachVolumeInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -222,11 +221,11 @@ func DetachVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 func DeleteVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DeleteVolumeInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	internalparams := new(blueprint.InternalParameters)
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -253,7 +252,7 @@ func DeleteVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 			switch waitername {
 			case "WaitUntilVolumeDeleted":
 				ctx.Logger.LogInfo("Waiting for volume to be deleted...")
-				err = svc.WaitUntilVolumeDeleted(waitinput)
+				err = svc.WaitUntilVolumeDeletedWithContext(ctx.Ctx(), waitinput)
 				if err != nil {
 					return nil, err
 				}
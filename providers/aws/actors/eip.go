@@ -33,7 +33,7 @@ import (
 // AllocateAddress func
 func AllocateAddress(ctx *ActionContext) (*base.ActionOutput, error) {
 	awsinput := new(ec2.AllocateAddressInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -63,7 +63,7 @@ func AllocateAddress(ctx *ActionContext) (*base.ActionOutput, error) {
 func FindAddresses(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DescribeAddressesInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -112,7 +112,7 @@ func FindOneAddress(ctx *ActionContext) (*base.ActionOutput, error) {
 func AttachAddress(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.AssociateAddressInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -143,7 +143,7 @@ func AttachAddress(ctx *ActionContext) (*base.ActionOutput, error) {
 func DetachAddress(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DisassociateAddressInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -169,7 +169,7 @@ func DetachAddress(ctx *ActionContext) (*base.ActionOutput, error) {
 func ReleaseAddress(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.ReleaseAddressInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -34,7 +34,7 @@ import (
 func FindNetworkInterfaces(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DescribeNetworkInterfacesInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -84,7 +84,7 @@ func FindNetworkInterface(ctx *ActionContext) (*base.ActionOutput, error) {
 func DeleteNetworkInterface(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DeleteNetworkInterfaceInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -34,7 +34,7 @@ import (
 func FindImages(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DescribeImagesInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -23,7 +23,6 @@
 package actors
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -37,11 +36,11 @@ import (
 func RunInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.RunInstancesInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	internalparams := new(blueprint.InternalParameters)
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -79,19 +78,19 @@ func RunInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 			switch waitername {
 			case "WaitUntilInstanceRunning":
 				ctx.Logger.LogInfo("Waiting for instances to become ready...")
-				err = svc.WaitUntilInstanceRunning(waitinput)
+				err = svc.WaitUntilInstanceRunningWithContext(ctx.Ctx(), waitinput)
 				if err != nil {
 					return nil, err
 				}
 			case "WaitUntilInstanceStatusOk":
 				ctx.Logger.LogInfo("Waiting for instances to become status OK...")
-				err = svc.WaitUntilInstanceStatusOk(waitstatusinput)
+				err = svc.WaitUntilInstanceStatusOkWithContext(ctx.Ctx(), waitstatusinput)
 				if err != nil {
 					return nil, err
 				}
 			case "WaitUntilInstanceExists":
 				ctx.Logger.LogInfo("Waiting for instances to exist...")
-				err = svc.WaitUntilInstanceExists(waitinput)
+				err = svc.WaitUntilInstanceExistsWithContext(ctx.Ctx(), waitinput)
 				if err != nil {
 					return nil, err
 				}
@@ -113,11 +112,11 @@ func RunInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 func DeleteInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.TerminateInstancesInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	internalparams := new(blueprint.InternalParameters)
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -144,7 +143,7 @@ func DeleteInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 			switch waitername {
 			case "WaitUntilInstanceTerminated":
 				ctx.Logger.LogInfo("Waiting for instances to be terminated...")
-				err = svc.WaitUntilInstanceTerminated(waitinput)
+				err = svc.WaitUntilInstanceTerminatedWithContext(ctx.Ctx(), waitinput)
 				if err != nil {
 					return nil, err
 				}
@@ -162,11 +161,11 @@ func DeleteInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 func StopInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.StopInstancesInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	internalparams := new(blueprint.InternalParameters)
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -193,7 +192,7 @@ func StopInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 			switch waitername {
 			case "WaitUntilInstanceStopped":
 				ctx.Logger.LogInfo("Waiting for instances to be stopped...")
-				err = svc.WaitUntilInstanceStopped(waitinput)
+				err = svc.WaitUntilInstanceStoppedWithContext(ctx.Ctx(), waitinput)
 				if err != nil {
 					return nil, err
 				}
@@ -211,11 +210,11 @@ func StopInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 func StartInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.StartInstancesInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	internalparams := new(blueprint.InternalParameters)
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -245,13 +244,13 @@ func StartInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 			switch waitername {
 			case "WaitUntilInstanceRunning":
 				ctx.Logger.LogInfo("Waiting for instances to become ready ....")
-				err = svc.WaitUntilInstanceRunning(waitinput)
+				err = svc.WaitUntilInstanceRunningWithContext(ctx.Ctx(), waitinput)
 				if err != nil {
 					return nil, err
 				}
 			case "WaitUntilInstanceStatusOk":
 				ctx.Logger.LogInfo("Waiting for instances to become status OK ....")
-				err = svc.WaitUntilInstanceStatusOk(waitstatusinput)
+				err = svc.WaitUntilInstanceStatusOkWithContext(ctx.Ctx(), waitstatusinput)
 				if err != nil {
 					return nil, err
 				}
@@ -269,7 +268,7 @@ func StartInstance(ctx *ActionContext) (*base.ActionOutput, error) {
 func FindInstances(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DescribeInstancesInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -34,7 +34,7 @@ import (
 func FindDatabases(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(rds.DescribeDBInstancesInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -84,7 +84,7 @@ func FindOneDatabase(ctx *ActionContext) (*base.ActionOutput, error) {
 func CreateDatabase(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(rds.CreateDBInstanceInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -105,7 +105,7 @@ func CreateDatabase(ctx *ActionContext) (*base.ActionOutput, error) {
 func DeleteDatabase(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(rds.DeleteDBInstanceInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -130,7 +130,7 @@ func DeleteDatabase(ctx *ActionContext) (*base.ActionOutput, error) {
 func CreateSnapshotDatabase(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(rds.CreateDBSnapshotInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
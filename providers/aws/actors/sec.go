@@ -34,7 +34,7 @@ import (
 // FindSecurityGroups func
 func FindSecurityGroups(ctx *ActionContext) (*base.ActionOutput, error) {
 	awsinput := new(ec2.DescribeSecurityGroupsInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -95,7 +95,7 @@ func FindOneSecurityGroup(ctx *ActionContext) (*base.ActionOutput, error) {
 func DeleteSecurityGroup(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DeleteSecurityGroupInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -33,7 +33,7 @@ import (
 // FindSubnets func
 func FindSubnets(ctx *ActionContext) (*base.ActionOutput, error) {
 	awsinput := new(ec2.DescribeSubnetsInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -81,7 +81,7 @@ func FindOneSubnet(ctx *ActionContext) (*base.ActionOutput, error) {
 func DeleteSubnet(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DeleteSubnetInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
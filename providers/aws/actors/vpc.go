@@ -33,7 +33,7 @@ import (
 // FindVpcs func
 func FindVpcs(ctx *ActionContext) (*base.ActionOutput, error) {
 	awsinput := new(ec2.DescribeVpcsInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -81,7 +81,7 @@ func FindOneVpc(ctx *ActionContext) (*base.ActionOutput, error) {
 func DeleteVpc(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	awsinput := new(ec2.DeleteVpcInput)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, awsinput); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, awsinput); err != nil {
 		return nil, err
 	}
 	if ctx.Rehearsal {
@@ -23,12 +23,14 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 
 	"github.com/develatio/nebulant-cli/base"
@@ -37,6 +39,12 @@ import (
 )
 
 func ActionValidator(action *base.Action) error {
+	return ActionRehearsal(action, nil)
+}
+
+// ActionRehearsal func. Run the actor of the action in rehearsal mode.
+// The typed parameters are resolved against store, if any
+func ActionRehearsal(action *base.Action, store base.IStore) error {
 	if action.Provider != "aws" {
 		return nil
 	}
@@ -44,17 +52,10 @@ func ActionValidator(action *base.Action) error {
 	if !exists {
 		return fmt.Errorf("aws: invalid action name " + action.ActionName)
 	}
-	if al.N == actors.NextOK && len(action.NextAction.NextKo) > 0 {
-		return fmt.Errorf("aws: action " + action.ActionName + " has no KO port")
-	}
-
-	if al.N == actors.NextKO && len(action.NextAction.NextOk) > 0 {
-		return fmt.Errorf("aws: action " + action.ActionName + " has no OK port")
-	}
-
 	ac := &actors.ActionContext{
 		Rehearsal: true,
 		Action:    action,
+		Store:     store,
 	}
 	_, err := al.F(ac)
 	if err != nil {
@@ -100,11 +101,84 @@ func (p *Provider) HandleAction(actx base.IActionContext) (*base.ActionOutput, e
 
 	if al, exists := actors.ActionFuncMap[action.ActionName]; exists {
 		sess := p.store.GetPrivateVar("awsSess").(*session.Session)
-		return al.F(actors.NewActionContext(sess, action, p.store, p.Logger))
+		sess = sessionWithContext(sess, actx.Context())
+		actrctx := actors.NewActionContext(sess, action, p.store, p.Logger)
+		actrctx.Context = actx.Context()
+		return al.F(actrctx)
 	}
 	return nil, fmt.Errorf("AWS: Unknown action: " + action.ActionName)
 }
 
+// sessionWithContext func. Copy of sess whose requests are bound to
+// ctx, so the in-flight api calls are aborted on action timeout
+func sessionWithContext(sess *session.Session, ctx context.Context) *session.Session {
+	sess = sess.Copy()
+	sess.Handlers.Build.PushFront(func(r *request.Request) {
+		r.SetContext(ctx)
+	})
+	return sess
+}
+
+// CreatedResource func. Report the resource created by the action. The
+// resource is deleted in the region where it was created
+func (p *Provider) CreatedResource(action *base.Action, aout *base.ActionOutput) *base.Resource {
+	rl, exists := actors.ResourceFuncMap[action.ActionName]
+	if !exists || aout == nil || len(aout.Records) <= 0 || aout.Records[0].ValueID == "" {
+		return nil
+	}
+	id := aout.Records[0].ValueID
+	res := &base.Resource{
+		Provider:  action.Provider,
+		Type:      rl.Type,
+		ID:        id,
+		CreatedBy: action.ActionID,
+	}
+	if sess, ok := p.store.GetPrivateVar("awsSess").(*session.Session); ok && sess.Config.Region != nil {
+		ra, err := base.NewResourceAction("set_region", map[string]string{"Region": *sess.Config.Region})
+		if err != nil {
+			return nil
+		}
+		res.Teardown = append(res.Teardown, ra)
+	}
+	params := make(map[string]interface{})
+	for k, v := range rl.X {
+		params[k] = v
+	}
+	if rl.L {
+		params[rl.P] = []string{id}
+	} else {
+		params[rl.P] = id
+	}
+	ra, err := base.NewResourceAction(rl.D, params)
+	if err != nil {
+		return nil
+	}
+	res.Teardown = append(res.Teardown, ra)
+	return res
+}
+
+// CreatesResource func
+func (p *Provider) CreatesResource(action *base.Action) bool {
+	_, exists := actors.ResourceFuncMap[action.ActionName]
+	return exists
+}
+
+// DeletedResources func. Report the ids of the resources deleted by
+// the action
+func (p *Provider) DeletedResources(action *base.Action) []string {
+	for _, rl := range actors.ResourceFuncMap {
+		if rl.D != action.ActionName {
+			continue
+		}
+		params := string(action.Parameters)
+		if err := p.store.Interpolate(&params); err != nil {
+			return nil
+		}
+		return base.ParameterIDs([]byte(params), rl.P)
+	}
+	return nil
+}
+
 // OnActionErrorHook func
 func (p *Provider) OnActionErrorHook(aout *base.ActionOutput) ([]*base.Action, error) {
 
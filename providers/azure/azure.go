@@ -31,6 +31,11 @@ func ActionValidator(action *base.Action) error {
 	return nil
 }
 
+// ActionRehearsal func
+func ActionRehearsal(action *base.Action, store base.IStore) error {
+	return nil
+}
+
 // New var
 var New base.ProviderInitFunc = func(store base.IStore) (base.IProvider, error) {
 	prov := &Provider{
@@ -23,6 +23,8 @@
 package actors
 
 import (
+	"context"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 
@@ -40,6 +42,17 @@ type ActionContext struct {
 	Logger              base.ILogger
 	NewS3Client         s3ClientFunc
 	NewCloudFlareClient interface{}
+	// canceled on action timeout
+	Context context.Context
+}
+
+// Ctx func. The context of the running action, canceled on
+// timeout. Background if not set
+func (a *ActionContext) Ctx() context.Context {
+	if a.Context == nil {
+		return context.Background()
+	}
+	return a.Context
 }
 
 var NewActionContext = func(awsConf aws.Config, action *base.Action, store base.IStore, logger base.ILogger) *ActionContext {
@@ -75,9 +88,21 @@ const (
 type ActionLayout struct {
 	F ActionFunc
 	N NextType
+	// API calls made by the actor, shown by run --plan
+	C string
+}
+
+// Ports func. The ports (OK/KO) available for the action
+func (a *ActionLayout) Ports() (ok bool, ko bool) {
+	return a.N != NextKO, a.N != NextOK
+}
+
+// Calls func. The external calls made by the action
+func (a *ActionLayout) Calls() string {
+	return a.C
 }
 
 // ActionFuncMap map
 var ActionFuncMap map[string]*ActionLayout = map[string]*ActionLayout{
-	"r2_upload": {F: R2Upload, N: NextOKKO},
+	"r2_upload": {F: R2Upload, N: NextOKKO, C: "s3:PutObject"},
 }
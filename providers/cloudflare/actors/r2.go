@@ -71,6 +71,7 @@ func (w *asyncWalk) close() {
 }
 
 type r2uploadonefile struct {
+	Ctx      context.Context
 	Uploader *manager.Uploader
 	Logger   base.ILogger
 	Basepath string
@@ -92,7 +93,7 @@ func (r *r2uploadonefile) upload() (*manager.UploadOutput, error) {
 	}
 	defer upfile.Close()
 	r.Logger.LogDebug(fmt.Sprintf("uploading file to bucket %s and key %v", r.Bucket, filepath.Join(r.Dst, rel)))
-	result, err := r.Uploader.Upload(context.TODO(), &s3.PutObjectInput{
+	result, err := r.Uploader.Upload(r.Ctx, &s3.PutObjectInput{
 		Bucket: &r.Bucket,
 		Key:    aws.String(filepath.Join(r.Dst, rel)),
 		Body:   upfile,
@@ -113,7 +114,7 @@ func (r *r2uploadonefile) upload() (*manager.UploadOutput, error) {
 // R2Upload func
 func R2Upload(ctx *ActionContext) (*base.ActionOutput, error) {
 	params := &r2UploadParameters{}
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, params); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
 		return nil, err
 	}
 
@@ -142,6 +143,7 @@ func R2Upload(ctx *ActionContext) (*base.ActionOutput, error) {
 		u.Concurrency = 3
 	})
 	r2up := &r2uploadonefile{
+		Ctx:      ctx.Ctx(),
 		Uploader: uploader,
 	}
 
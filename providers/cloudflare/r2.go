@@ -41,6 +41,12 @@ import (
 )
 
 func ActionValidator(action *base.Action) error {
+	return ActionRehearsal(action, nil)
+}
+
+// ActionRehearsal func. Run the actor of the action in rehearsal mode.
+// The typed parameters are resolved against store, if any
+func ActionRehearsal(action *base.Action, store base.IStore) error {
 	if action.Provider != "cloudflare" {
 		return nil
 	}
@@ -48,17 +54,10 @@ func ActionValidator(action *base.Action) error {
 	if !exists {
 		return fmt.Errorf("cloudflare: invalid action name " + action.ActionName)
 	}
-	if al.N == actors.NextOK && len(action.NextAction.NextKo) > 0 {
-		return fmt.Errorf("cloudflare: action " + action.ActionName + " has no KO port")
-	}
-
-	if al.N == actors.NextKO && len(action.NextAction.NextOk) > 0 {
-		return fmt.Errorf("cloudflare: action " + action.ActionName + " has no OK port")
-	}
-
 	ac := &actors.ActionContext{
 		Rehearsal: true,
 		Action:    action,
+		Store:     store,
 	}
 	_, err := al.F(ac)
 	if err != nil {
@@ -105,7 +104,9 @@ func (p *Provider) HandleAction(actx base.IActionContext) (*base.ActionOutput, e
 	if al, exists := actors.ActionFuncMap[action.ActionName]; exists {
 		cfg := p.store.GetPrivateVar("r2AwsConfig").(aws.Config)
 		p.Logger.LogDebug("Launching provider func")
-		return al.F(actors.NewActionContext(cfg, action, p.store, p.Logger))
+		actrctx := actors.NewActionContext(cfg, action, p.store, p.Logger)
+		actrctx.Context = actx.Context()
+		return al.F(actrctx)
 	}
 	return nil, fmt.Errorf("AWS: Unknown action: " + action.ActionName)
 }
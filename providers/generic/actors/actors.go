@@ -23,6 +23,7 @@
 package actors
 
 import (
+	"context"
 	"io"
 	"sync"
 
@@ -60,6 +61,15 @@ type ActionContext struct {
 	Actx      base.IActionContext
 }
 
+// Ctx func. The context of the running action, canceled on
+// timeout. Background if the action runs outside the runtime
+func (a *ActionContext) Ctx() context.Context {
+	if a.Actx == nil {
+		return context.Background()
+	}
+	return a.Actx.Context()
+}
+
 func (a *ActionContext) DebugInit() {
 	a.Actx.DebugInit()
 }
@@ -93,15 +103,27 @@ type ActionLayout struct {
 	N NextType
 	// Retriable or not
 	R bool
+	// External calls made by the actor, shown by run --plan
+	C string
+}
+
+// Ports func. The ports (OK/KO) available for the action
+func (a *ActionLayout) Ports() (ok bool, ko bool) {
+	return a.N != NextKO, a.N != NextOK
+}
+
+// Calls func. The external calls made by the action
+func (a *ActionLayout) Calls() string {
+	return a.C
 }
 
 // ActionFuncMap map
 var ActionFuncMap map[string]*ActionLayout = map[string]*ActionLayout{
-	"run_script":       {F: RunScript, N: NextOKKO, R: true},
+	"run_script":       {F: RunScript, N: NextOKKO, R: true, C: "exec:command"},
 	"define_envs":      {F: DefineEnvs, N: NextOKKO, R: false},
 	"define_variables": {F: DefineVars, N: NextOKKO, R: false},
-	"upload_files":     {F: RemoteCopy, N: NextOKKO, R: true},
-	"download_files":   {F: RemoteCopy, N: NextOKKO, R: true},
+	"upload_files":     {F: RemoteCopy, N: NextOKKO, R: true, C: "scp:upload"},
+	"download_files":   {F: RemoteCopy, N: NextOKKO, R: true, C: "scp:download"},
 	"condition":        {F: ConditionParse, N: NextOKKO, R: false},
 	"start":            {F: DefineVars, N: NextOKKO, R: false},
 	"group":            {F: NOOP, N: NextOKKO, R: false},
@@ -112,12 +134,17 @@ var ActionFuncMap map[string]*ActionLayout = map[string]*ActionLayout{
 	"log":              {F: Log, N: NextOKKO, R: false},
 	"noop":             {F: NOOP, N: NextOK, R: false},
 	"panic":            {F: Panic, N: NextOKKO, R: false},
-	"send_mail":        {F: SendMail, N: NextOKKO, R: true},
-	"send_email":       {F: SendMail, N: NextOKKO, R: true},
-	"http_request":     {F: HttpRequest, N: NextOKKO, R: true},
-	"read_file":        {F: ReadFile, N: NextOKKO, R: false},
-	"write_file":       {F: WriteFile, N: NextOKKO, R: false},
+	"send_mail":        {F: SendMail, N: NextOKKO, R: true, C: "smtp:send"},
+	"send_email":       {F: SendMail, N: NextOKKO, R: true, C: "smtp:send"},
+	"http_request":     {F: HttpRequest, N: NextOKKO, R: true, C: "http:request"},
+	"read_file":        {F: ReadFile, N: NextOKKO, R: false, C: "fs:read"},
+	"write_file":       {F: WriteFile, N: NextOKKO, R: false, C: "fs:write"},
+	"call_blueprint":   {F: CallBlueprint, N: NextOKKO, R: false, C: "nebulant:run"},
+	"foreach":          {F: Foreach, N: NextOKKO, R: false},
+	"get_state":        {F: GetState, N: NextOKKO, R: true, C: "state:read"},
+	"set_state":        {F: SetState, N: NextOKKO, R: true, C: "state:write"},
+	"delete_state":     {F: DeleteState, N: NextOKKO, R: true, C: "state:delete"},
 	// handled by core stage
-	"join_threads": {F: NOOP, N: NextOK, R: false},
+	"join_threads": {F: NOOP, N: NextOKKO, R: false},
 	"debug":        {F: NOOP, N: NextOK, R: false},
 }
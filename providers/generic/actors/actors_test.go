@@ -0,0 +1,182 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package actors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/cast"
+	"github.com/develatio/nebulant-cli/ipc"
+	"github.com/develatio/nebulant-cli/providers/generic"
+	"github.com/develatio/nebulant-cli/runtime"
+	"github.com/develatio/nebulant-cli/storage"
+)
+
+// testParams struct. Parameters of the actions of the test provider
+type testParams struct {
+	Sleep string `json:"sleep"`
+	Fail  bool   `json:"fail"`
+	// interpolated and saved as the action output
+	Value string `json:"value"`
+	// fail if the interpolated value is this one
+	FailOn string `json:"fail_on"`
+}
+
+// testRun struct. A run of an action of the test provider
+type testRun struct {
+	value string
+	start time.Time
+	end   time.Time
+}
+
+// testRuns registry, reset by runTestIRB
+var testRuns = &struct {
+	mu   sync.Mutex
+	runs map[string][]*testRun
+}{runs: make(map[string][]*testRun)}
+
+func getRuns(actionID string) []*testRun {
+	testRuns.mu.Lock()
+	defer testRuns.mu.Unlock()
+	return append([]*testRun{}, testRuns.runs[actionID]...)
+}
+
+type testProvider struct{}
+
+func (p *testProvider) DumpPrivateVars(freshStore base.IStore) {}
+
+func (p *testProvider) OnActionErrorHook(aout *base.ActionOutput) ([]*base.Action, error) {
+	return nil, nil
+}
+
+func (p *testProvider) HandleAction(actx base.IActionContext) (*base.ActionOutput, error) {
+	action := actx.GetAction()
+	params := &testParams{}
+	if err := json.Unmarshal(action.Parameters, params); err != nil {
+		return nil, err
+	}
+	run := &testRun{start: time.Now()}
+	defer func() {
+		run.end = time.Now()
+		testRuns.mu.Lock()
+		defer testRuns.mu.Unlock()
+		testRuns.runs[action.ActionID] = append(testRuns.runs[action.ActionID], run)
+	}()
+	if params.Sleep != "" {
+		d, err := time.ParseDuration(params.Sleep)
+		if err != nil {
+			return nil, err
+		}
+		time.Sleep(d)
+	}
+	run.value = params.Value
+	if err := actx.GetStore().Interpolate(&run.value); err != nil {
+		return nil, err
+	}
+	if params.Fail || (params.FailOn != "" && params.FailOn == run.value) {
+		return nil, fmt.Errorf("%s failed", run.value)
+	}
+	return base.NewActionOutput(action, run.value, &run.value), nil
+}
+
+// testIPCS is the ipc server of the scripts run by the tests
+var testIPCS *ipc.IPC
+
+func TestMain(m *testing.M) {
+	cast.InitSystemBus()
+	var err error
+	testIPCS, err = ipc.NewIPCServer()
+	if err != nil {
+		panic(err)
+	}
+	go func() { _ = testIPCS.Accept() }()
+	cast.SBus.RegisterProviderInitFunc("generic", generic.New)
+	cast.SBus.RegisterProviderInitFunc("test", func(store base.IStore) (base.IProvider, error) {
+		return &testProvider{}, nil
+	})
+	code := m.Run()
+	_ = testIPCS.Close()
+	os.Exit(code)
+}
+
+func newTestIRB(t *testing.T, data string, irbConf *blueprint.IRBGenConfig) *blueprint.IRBlueprint {
+	t.Helper()
+	bp, err := blueprint.NewFromYAML([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	irb, err := blueprint.GenerateIRB(bp, irbConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return irb
+}
+
+// runTestIRB func. Run irb from his start action and wait
+// until the runtime ends
+func runTestIRB(t *testing.T, irb *blueprint.IRBlueprint) *runtime.Runtime {
+	t.Helper()
+	testRuns.mu.Lock()
+	testRuns.runs = make(map[string][]*testRun)
+	testRuns.mu.Unlock()
+
+	r := runtime.NewRuntime(irb, false)
+	end := r.NewEventListener().WaitUntilChan([]base.EventCode{base.RuntimeEndEvent})
+	st := storage.NewStore()
+	st.SetLogger(&cast.Logger{})
+	st.SetPrivateVar("RUNTIME", r)
+	st.SetPrivateVar("IPCS", testIPCS)
+	actx := r.NewAContext(nil, irb.StartAction)
+	actx.SetStore(st)
+	if !r.NewThread(actx) {
+		t.Fatal("cannot start the runtime")
+	}
+	select {
+	case <-end:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the runtime did not end")
+	}
+	return r
+}
+
+func runTestBlueprint(t *testing.T, data string) *runtime.Runtime {
+	t.Helper()
+	return runTestIRB(t, newTestIRB(t, data, &blueprint.IRBGenConfig{}))
+}
+
+// runValue func. The value of the only run of actionID
+func runValue(t *testing.T, actionID string) string {
+	t.Helper()
+	runs := getRuns(actionID)
+	if len(runs) != 1 {
+		t.Fatalf("expected one run of %s, got %d", actionID, len(runs))
+	}
+	return runs[0].value
+}
@@ -0,0 +1,261 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package actors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/runtime"
+	"github.com/develatio/nebulant-cli/storage"
+	"github.com/develatio/nebulant-cli/util"
+)
+
+// MaxCallDepth is the max number of nested called blueprints
+const MaxCallDepth = 32
+
+// callChainVar is the private var of the store with the
+// blueprints called up to the running one
+const callChainVar = "CALL_CHAIN"
+
+type callBlueprintParameters struct {
+	// file path, file://path or [org/]collection/blueprint[:version]
+	Blueprint *string `json:"blueprint" validate:"required"`
+	// args in cli format: --varname=value
+	Args []string `json:"args"`
+	// deadline of the called blueprint (e.g. 30s, 10m), his
+	// cleanup chains run after it
+	Timeout string `json:"timeout"`
+	// max actions of the called blueprint running at once
+	MaxParallel int `json:"max_parallel"`
+	// limits by provider of the called blueprint in the
+	// form provider=concurrency[:rps]
+	Limits []string `json:"limits"`
+}
+
+// runConfig func. The timeout and the run limits of the called blueprint
+func (p *callBlueprintParameters) runConfig() (time.Duration, *blueprint.RunLimits, error) {
+	var timeout time.Duration
+	if p.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(p.Timeout); err != nil {
+			return 0, nil, fmt.Errorf("invalid timeout %q: %w", p.Timeout, err)
+		}
+	}
+	if p.MaxParallel < 0 {
+		return 0, nil, fmt.Errorf("invalid max_parallel %d", p.MaxParallel)
+	}
+	limits := &blueprint.RunLimits{
+		MaxParallel: p.MaxParallel,
+		Providers:   make(map[string]*blueprint.ProviderLimit),
+	}
+	for _, spec := range p.Limits {
+		name, limit, err := blueprint.ParseProviderLimit(spec)
+		if err != nil {
+			return 0, nil, err
+		}
+		limits.Providers[name] = limit
+	}
+	if limits.IsZero() {
+		limits = nil
+	}
+	return timeout, limits, nil
+}
+
+// resolveBlueprintURL func. Local files are preferred over remote
+// blueprints with the same path. Relative paths are relative to dir,
+// the directory of the caller blueprint, or to the working directory
+// if the caller is not a local file (dir is empty)
+func resolveBlueprintURL(path string, dir string) (*blueprint.BlueprintURL, error) {
+	fpath := strings.TrimPrefix(path, "file://")
+	if dir != "" && !filepath.IsAbs(fpath) {
+		fpath = filepath.Join(dir, fpath)
+	}
+	if fi, err := os.Stat(fpath); err == nil && !fi.IsDir() {
+		return blueprint.ParsePath(fpath)
+	}
+	if strings.HasPrefix(path, "file://") {
+		// a missing local file, not a remote blueprint
+		return blueprint.ParsePath(fpath)
+	}
+	return blueprint.ParseURL(path)
+}
+
+// callerDir func. Directory of the blueprint that runs the
+// call, empty if it is not a local file
+func callerDir(rt *runtime.Runtime, chain []string) string {
+	if len(chain) > 0 {
+		if path, ok := strings.CutPrefix(chain[len(chain)-1], "file://"); ok {
+			return filepath.Dir(path)
+		}
+		return ""
+	}
+	if bpUrl := rt.IRB().URL; bpUrl != nil && bpUrl.Scheme == "file" {
+		if abs, err := filepath.Abs(bpUrl.FilePath); err == nil {
+			return filepath.Dir(abs)
+		}
+	}
+	return ""
+}
+
+// blueprintID func. Identify the blueprint of bpUrl regardless of
+// how its path was written
+func blueprintID(bpUrl *blueprint.BlueprintURL) string {
+	switch bpUrl.Scheme {
+	case "file":
+		if abs, err := filepath.Abs(bpUrl.FilePath); err == nil {
+			return "file://" + abs
+		}
+		return "file://" + bpUrl.FilePath
+	case "https":
+		return bpUrl.RemoteURL
+	}
+	id := bpUrl.CollectionSlug + "/" + bpUrl.BlueprintSlug
+	if bpUrl.OrganizationSlug != "" {
+		id = bpUrl.OrganizationSlug + "/" + id
+	}
+	return id
+}
+
+// exportRecordValue func. Convert the record into a value that can be
+// marshaled into the json of the caller record
+func exportRecordValue(record *base.StorageRecord) interface{} {
+	switch {
+	case record.Value == nil:
+		return nil
+	case record.IsString:
+		return record.Value
+	case len(record.JSONValue) > 0 && json.Valid(record.JSONValue):
+		return json.RawMessage(record.JSONValue)
+	default:
+		return fmt.Sprintf("%v", record.Value)
+	}
+}
+
+// CallBlueprint func. Run another blueprint as a sub-routine with its own
+// store. The outputs declared by the called blueprint (or the outputs of
+// all of its actions if none declared) are exposed as a record.
+func CallBlueprint(ctx *ActionContext) (*base.ActionOutput, error) {
+	params := new(callBlueprintParameters)
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
+		return nil, err
+	}
+	if _, err := blueprint.ParseBPArgs(params.Args); err != nil {
+		return nil, err
+	}
+	timeout, limits, err := params.runConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Rehearsal {
+		return nil, nil
+	}
+
+	bppath := *params.Blueprint
+	if err := ctx.Store.Interpolate(&bppath); err != nil {
+		return nil, err
+	}
+	args := make([]string, len(params.Args))
+	for i, arg := range params.Args {
+		if err := ctx.Store.Interpolate(&arg); err != nil {
+			return nil, err
+		}
+		args[i] = arg
+	}
+
+	rt, ok := ctx.Store.GetPrivateVar("RUNTIME").(*runtime.Runtime)
+	if !ok {
+		return nil, fmt.Errorf("cannot call blueprint outside of a runtime")
+	}
+
+	chain, _ := ctx.Store.GetPrivateVar(callChainVar).([]string)
+	bpUrl, err := resolveBlueprintURL(bppath, callerDir(rt, chain))
+	if err != nil {
+		return nil, err
+	}
+	id := blueprintID(bpUrl)
+	for _, called := range chain {
+		if called == id {
+			return nil, fmt.Errorf("recursive blueprint call: %s", strings.Join(append(chain, id), " -> "))
+		}
+	}
+	if len(chain) >= MaxCallDepth {
+		return nil, fmt.Errorf("cannot call blueprint %s: max depth of %d nested blueprints reached", bppath, MaxCallDepth)
+	}
+	// the called blueprints honor the signature
+	// requirements of the running blueprint
+	irb, err := blueprint.NewIRBFromAny(bpUrl, &blueprint.IRBGenConfig{
+		Args:             args,
+		RequireSignature: rt.IRB().RequireSignature,
+		TrustedKeys:      rt.IRB().TrustedKeys,
+		Timeout:          timeout,
+		Limits:           limits,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	st := storage.NewStore()
+	st.SetLogger(ctx.Store.GetLogger().Duplicate())
+	st.SetPrivateVar("IPCS", ctx.Store.GetPrivateVar("IPCS"))
+	st.SetPrivateVar("RUNTIME", rt)
+	st.SetPrivateVar(callChainVar, append(append([]string{}, chain...), id))
+	if err := runtime.LoadArgs(st, irb.Args); err != nil {
+		return nil, err
+	}
+
+	ctx.Logger.LogInfo("Calling blueprint " + bppath)
+	stores, runErr := rt.RunBlueprint(ctx.Ctx(), irb, st)
+
+	outputNames := irb.BP.Outputs
+	if outputNames == nil {
+		for _, action := range irb.BP.Actions {
+			if action.Output != nil && *action.Output != "" {
+				outputNames = append(outputNames, *action.Output)
+			}
+		}
+	}
+	outputs := make(map[string]interface{})
+	for _, name := range outputNames {
+		for _, s := range stores {
+			if record, err := s.GetByRefName(name); err == nil {
+				outputs[name] = exportRecordValue(record)
+			}
+		}
+	}
+
+	aout := base.NewActionOutput(ctx.Action, outputs, nil)
+	aout.Records[0].Literal = true
+	if runErr != nil {
+		return aout, fmt.Errorf("called blueprint %s ended with error: %w", bppath, runErr)
+	}
+	ctx.Logger.LogInfo("Blueprint " + bppath + " done")
+	return aout, nil
+}
@@ -0,0 +1,321 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package actors_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+const testChildBP = `
+actions:
+  - id: c1
+    provider: test
+    action: run
+    first: true
+    output: RESULT
+    parameters: {value: "child {{ NAME }}"}
+`
+
+const testCallerBP = `
+actions:
+  - id: call
+    provider: generic
+    action: call_blueprint
+    first: true
+    output: CALL
+    parameters: {blueprint: %q, args: ["--NAME=neb"]}
+    next: {ok: [ok], ko: [ko]}
+  - id: ok
+    provider: test
+    action: run
+    parameters: {value: "{{ CALL.RESULT }}"}
+  - id: ko
+    provider: test
+    action: run
+    parameters: {value: "{{ CALL.__error }}"}
+`
+
+func writeBlueprint(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "child.yaml")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCallBlueprintSignature(t *testing.T) {
+	child := writeBlueprint(t, testChildBP)
+	irb := newTestIRB(t, fmt.Sprintf(testCallerBP, child), &blueprint.IRBGenConfig{
+		RequireSignature: true,
+		TrustedKeys:      t.TempDir(),
+	})
+	runTestIRB(t, irb)
+	if runs := getRuns("ok"); len(runs) != 0 {
+		t.Fatal("unsigned blueprint called by a blueprint that requires signatures")
+	}
+	if msg := runValue(t, "ko"); !strings.Contains(msg, blueprint.ErrUnsigned.Error()) {
+		t.Errorf("expected an unsigned blueprint error, got %s", msg)
+	}
+}
+
+func TestCallBlueprintOutputs(t *testing.T) {
+	child := writeBlueprint(t, testChildBP)
+	r := runTestBlueprint(t, fmt.Sprintf(testCallerBP, child))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	if v := runValue(t, "ok"); v != "child neb" {
+		t.Errorf("expected the output of the called blueprint, got %s", v)
+	}
+}
+
+const testFailingChildBP = `
+actions:
+  - id: c1
+    provider: test
+    action: run
+    first: true
+    parameters: {value: "child of {{ NAME }}", fail: true}
+`
+
+func TestCallBlueprintKO(t *testing.T) {
+	child := writeBlueprint(t, testFailingChildBP)
+	r := runTestBlueprint(t, fmt.Sprintf(testCallerBP, child))
+	if r.ExitCode() != 0 {
+		t.Fatalf("the KO of the called blueprint should be handled by the caller: %v", r.Error())
+	}
+	if runs := getRuns("ok"); len(runs) != 0 {
+		t.Fatal("the caller went on through the OK port")
+	}
+	if msg := runValue(t, "ko"); !strings.Contains(msg, "child of neb failed") {
+		t.Errorf("expected the error of the called blueprint, got %s", msg)
+	}
+}
+
+const testRecursiveChildBP = `
+actions:
+  - id: c1
+    provider: generic
+    action: call_blueprint
+    first: true
+    parameters: {blueprint: %q}
+`
+
+func TestCallBlueprintRecursion(t *testing.T) {
+	child := filepath.Join(t.TempDir(), "child.yaml")
+	if err := os.WriteFile(child, []byte(fmt.Sprintf(testRecursiveChildBP, child)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r := runTestBlueprint(t, fmt.Sprintf(testCallerBP, child))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	if msg := runValue(t, "ko"); !strings.Contains(msg, "recursive blueprint call") {
+		t.Errorf("expected a recursive call error, got %s", msg)
+	}
+}
+
+// c1 runs inside the group g, the finally edge of g and the
+// on_exit chain of the called blueprint see his exit status
+const testCleanupChildBP = `
+on_exit: x
+actions:
+  - id: g
+    provider: generic
+    action: group
+    first: true
+    next: {ok: [c1], finally: [f]}
+  - id: c1
+    provider: test
+    action: run
+    parameters: %s
+    next: {ok: [c2]}
+  - id: c2
+    provider: test
+    action: run
+    parameters: {value: "c2"}
+  - id: f
+    provider: test
+    action: run
+    parameters: {value: "{{ runtime.exit_status }}"}
+  - id: x
+    provider: test
+    action: run
+    parameters: {value: "{{ runtime.exit_status }}"}
+`
+
+const testCallerParamsBP = `
+actions:
+  - id: call
+    provider: generic
+    action: call_blueprint
+    first: true
+    output: CALL
+    parameters: %s
+    next: {ok: [ok], ko: [ko]}
+  - id: ok
+    provider: test
+    action: run
+    parameters: {value: "ok"}
+  - id: ko
+    provider: test
+    action: run
+    parameters: {value: "{{ CALL.__error }}"}
+`
+
+// checkChildCleanup func. The finally edge of the group ran before the
+// on_exit chain, both before the caller went on and seeing status
+func checkChildCleanup(t *testing.T, status string, next string) {
+	t.Helper()
+	f, x, n := getRuns("f"), getRuns("x"), getRuns(next)
+	if len(f) != 1 || len(x) != 1 || len(n) != 1 {
+		t.Fatalf("expected f, x and %s to run once, got %d, %d and %d", next, len(f), len(x), len(n))
+	}
+	if f[0].value != status || x[0].value != status {
+		t.Errorf("expected exit status %q, got %q and %q", status, f[0].value, x[0].value)
+	}
+	if x[0].start.Before(f[0].end) {
+		t.Error("the on_exit chain started before the finally edge ended")
+	}
+	if n[0].start.Before(x[0].end) {
+		t.Error("the caller went on before the cleanup of the called blueprint ended")
+	}
+}
+
+func TestCallBlueprintCleanup(t *testing.T) {
+	child := writeBlueprint(t, fmt.Sprintf(testCleanupChildBP, `{value: "c1"}`))
+	r := runTestBlueprint(t, fmt.Sprintf(testCallerParamsBP, fmt.Sprintf("{blueprint: %q}", child)))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	checkChildCleanup(t, "success", "ok")
+}
+
+func TestCallBlueprintCleanupKO(t *testing.T) {
+	child := writeBlueprint(t, fmt.Sprintf(testCleanupChildBP, `{value: "c1", fail: true}`))
+	r := runTestBlueprint(t, fmt.Sprintf(testCallerParamsBP, fmt.Sprintf("{blueprint: %q}", child)))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	checkChildCleanup(t, "failure", "ko")
+}
+
+func TestCallBlueprintTimeout(t *testing.T) {
+	child := writeBlueprint(t, fmt.Sprintf(testCleanupChildBP, `{value: "c1", sleep: 500ms}`))
+	r := runTestBlueprint(t, fmt.Sprintf(testCallerParamsBP, fmt.Sprintf("{blueprint: %q, timeout: 100ms}", child)))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	checkChildCleanup(t, "timeout", "ko")
+	if runs := getRuns("c2"); len(runs) != 0 {
+		t.Error("the called blueprint went on after his timeout")
+	}
+	if msg := runValue(t, "ko"); !strings.Contains(msg, "timed out after 100ms") {
+		t.Errorf("expected a timeout error, got %s", msg)
+	}
+}
+
+const testParallelChildBP = `
+actions:
+  - id: start
+    provider: test
+    action: run
+    first: true
+    next: {ok: [a, b]}
+  - id: a
+    provider: test
+    action: run
+    parameters: {sleep: 200ms}
+  - id: b
+    provider: test
+    action: run
+    parameters: {sleep: 200ms}
+`
+
+func TestCallBlueprintLimits(t *testing.T) {
+	child := writeBlueprint(t, testParallelChildBP)
+	for name, params := range map[string]string{
+		"max parallel": "{blueprint: %q, max_parallel: 1}",
+		"provider":     "{blueprint: %q, limits: [test=1]}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := runTestBlueprint(t, fmt.Sprintf(testCallerParamsBP, fmt.Sprintf(params, child)))
+			if r.ExitCode() != 0 {
+				t.Fatal(r.Error())
+			}
+			if len(getRuns("ok")) != 1 {
+				t.Fatal("the call failed")
+			}
+			a, b := getRuns("a"), getRuns("b")
+			if len(a) != 1 || len(b) != 1 {
+				t.Fatalf("expected a and b to run once, got %d and %d", len(a), len(b))
+			}
+			if a[0].start.Before(b[0].end) && b[0].start.Before(a[0].end) {
+				t.Error("the actions of the called blueprint ran at once")
+			}
+		})
+	}
+}
+
+func TestCallBlueprintRelativePath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "child.yaml"), []byte(testChildBP), 0600); err != nil {
+		t.Fatal(err)
+	}
+	caller := filepath.Join(dir, "caller.yaml")
+	if err := os.WriteFile(caller, []byte(fmt.Sprintf(testCallerBP, "child.yaml")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// the working directory is not the one of the blueprints
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	bpUrl, err := blueprint.ParsePath(caller)
+	if err != nil {
+		t.Fatal(err)
+	}
+	irb, err := blueprint.NewIRBFromAny(bpUrl, &blueprint.IRBGenConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := runTestIRB(t, irb)
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	if v := runValue(t, "ok"); v != "child neb" {
+		t.Errorf("expected the output of the called blueprint, got %s", v)
+	}
+}
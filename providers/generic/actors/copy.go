@@ -75,7 +75,7 @@ func ScpCopy(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	var i int
 	params := new(scpCopyParameters)
-	err = util.UnmarshalValidJSON(ctx.Action.Parameters, params)
+	err = util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params)
 	if err != nil {
 		return nil, err
 	}
@@ -23,6 +23,7 @@
 package actors
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -61,6 +62,9 @@ type defineVarsParametersVar struct {
 	Options      []defineVarsParametersVarOptions `json:"options"`
 	Required     bool                             `json:"required"`
 	Stack        *bool                            `json:"stack"`
+	// redact the value from logs and events. Vars with a
+	// secret name (password, privkey...) are always secret
+	Secret bool `json:"secret"`
 }
 
 func (d *defineVarsParametersVar) askForValue() error {
@@ -86,7 +90,7 @@ func (d *defineVarsParametersVar) askForValue() error {
 			if !isEmpty && !isNotValid {
 				vv = d.Value.(int)
 			}
-			vcn, err := cast.PromptInt("Please, enter value for "+d.Key, d.Required, string(vv))
+			vcn, err := cast.PromptInt("Please, enter value for "+d.Key, d.Required, strconv.Itoa(vv))
 			if err != nil {
 				return err
 			}
@@ -364,7 +368,7 @@ func Stop(ctx *ActionContext) (*base.ActionOutput, error) {
 func ConditionParse(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	params := new(conditionParameters)
-	if err = util.UnmarshalValidJSON(ctx.Action.Parameters, params); err != nil {
+	if err = util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
 		return nil, err
 	}
 
@@ -385,7 +389,7 @@ func ConditionParse(ctx *ActionContext) (*base.ActionOutput, error) {
 // Sleep func
 func Sleep(ctx *ActionContext) (*base.ActionOutput, error) {
 	params := new(SleepParameters)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, params); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
 		return nil, err
 	}
 
@@ -394,14 +398,20 @@ func Sleep(ctx *ActionContext) (*base.ActionOutput, error) {
 	}
 	ctx.Logger.LogInfo("Sleeping for " + strconv.FormatInt(params.Seconds, 10) + " seconds")
 	// Duration == type int64
-	time.Sleep(time.Duration(params.Seconds) * time.Second)
+	timer := time.NewTimer(time.Duration(params.Seconds) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Ctx().Done():
+		return nil, context.Cause(ctx.Ctx())
+	}
 	return nil, nil
 }
 
 // OKKO func
 func OKKO(ctx *ActionContext) (*base.ActionOutput, error) {
 	params := new(okkoParameters)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, params); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
 		return nil, err
 	}
 
@@ -434,7 +444,7 @@ func OKKO(ctx *ActionContext) (*base.ActionOutput, error) {
 func Log(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	params := new(logParameters)
-	if err = util.UnmarshalValidJSON(ctx.Action.Parameters, params); err != nil {
+	if err = util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
 		return nil, err
 	}
 
@@ -454,7 +464,7 @@ func Log(ctx *ActionContext) (*base.ActionOutput, error) {
 func Panic(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	params := new(panicParameters)
-	err = util.UnmarshalValidJSON(ctx.Action.Parameters, params)
+	err = util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params)
 	if err != nil {
 		if ctx.Rehearsal {
 			return nil, err
@@ -490,7 +500,7 @@ func Panic(ctx *ActionContext) (*base.ActionOutput, error) {
 // DefineVars func
 func DefineVars(ctx *ActionContext) (*base.ActionOutput, error) {
 	params := new(defineVarsParameters)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, params); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
 		return nil, err
 	}
 
@@ -598,6 +608,7 @@ func DefineVars(ctx *ActionContext) (*base.ActionOutput, error) {
 				Value:   recordvalue,
 				// note that literal is not allowed
 				Action: ctx.Action,
+				Secret: v.Secret,
 			}, ctx.Action.Provider)
 			if err != nil {
 				return nil, err
@@ -609,6 +620,7 @@ func DefineVars(ctx *ActionContext) (*base.ActionOutput, error) {
 				Value:   recordvalue,
 				Literal: true,
 				Action:  ctx.Action,
+				Secret:  v.Secret,
 			}, ctx.Action.Provider)
 			if err != nil {
 				return nil, err
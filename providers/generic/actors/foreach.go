@@ -0,0 +1,234 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package actors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/runtime"
+	"github.com/develatio/nebulant-cli/util"
+)
+
+type foreachParameters struct {
+	// json array or a string with a reference to a list: a stacked var,
+	// a json array or a path to a list inside an output, eg.
+	// {{ servers.Reservations }}
+	Items json.RawMessage `json:"items" validate:"required"`
+	// id of the first action of the body
+	Body *string `json:"body" validate:"required"`
+	// max items running at the same time, sequential if <= 1
+	Parallel int `json:"parallel"`
+	// output of the body to collect, all of them if empty
+	Collect string `json:"collect"`
+	// keep running the remaining items when an item fails
+	ContinueOnError bool `json:"continue_on_error"`
+}
+
+var foreachRefRegexp = regexp.MustCompile(`^{{\s*([^.[|{}\s]+)\s*}}$`)
+
+// foreachItems func. Resolve the items parameter into a list. Stacked
+// vars are iterated in the order the values were pushed
+func foreachItems(store base.IStore, raw json.RawMessage) ([]interface{}, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		var items []interface{}
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("items should be a list or a reference to a list")
+		}
+		for i, item := range items {
+			if s, ok := item.(string); ok {
+				if err := store.Interpolate(&s); err != nil {
+					return nil, err
+				}
+				items[i] = s
+			}
+		}
+		return items, nil
+	}
+
+	text = strings.TrimSpace(text)
+	if m := foreachRefRegexp.FindStringSubmatch(text); m != nil && store.ExistsRefName(m[1]) {
+		record, err := store.GetByRefName(m[1])
+		if err != nil {
+			return nil, err
+		}
+		if stack, ok := record.Value.(*base.StorageRecordStack); ok {
+			items := make([]interface{}, len(stack.Items))
+			for i, item := range stack.Items {
+				items[len(items)-1-i] = item
+			}
+			return items, nil
+		}
+		if !record.Literal && len(record.ValueID) <= 0 {
+			// outputs of actions are not primitive
+			// values, iterate over the json value
+			text = string(record.JSONValue)
+		}
+	}
+
+	if err := store.Interpolate(&text); err != nil {
+		return nil, err
+	}
+	var items []interface{}
+	if err := json.Unmarshal([]byte(text), &items); err != nil {
+		return nil, fmt.Errorf("items is not a list: %s", err.Error())
+	}
+	return items, nil
+}
+
+// Foreach func. Run the body subgraph once per item with {{ item }} and
+// {{ index }} bound. The outputs of the body are collected into a list
+// in the order of the items.
+func Foreach(ctx *ActionContext) (*base.ActionOutput, error) {
+	params := new(foreachParameters)
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
+		return nil, err
+	}
+	if params.Parallel < 0 {
+		return nil, fmt.Errorf("parallel should be a positive number")
+	}
+
+	if ctx.Rehearsal {
+		return nil, nil
+	}
+
+	if ctx.Action.BodyAction == nil {
+		return nil, fmt.Errorf("body action %s not found", *params.Body)
+	}
+	rt, ok := ctx.Store.GetPrivateVar("RUNTIME").(*runtime.Runtime)
+	if !ok {
+		return nil, fmt.Errorf("cannot run foreach outside of a runtime")
+	}
+
+	items, err := foreachItems(ctx.Store, params.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyActions := blueprint.BodyActions(ctx.Action)
+	var outputNames []string
+	if params.Collect != "" {
+		outputNames = []string{params.Collect}
+	} else {
+		for _, action := range bodyActions {
+			if action.Output != nil && *action.Output != "" {
+				outputNames = append(outputNames, *action.Output)
+			}
+		}
+	}
+
+	parallel := params.Parallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	results := make([]interface{}, len(items))
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	ctx.Logger.LogInfo(fmt.Sprintf("Running %d items, %d at a time", len(items), parallel))
+L:
+	for i, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Ctx().Done():
+			break L
+		}
+		if failed.Load() && !params.ContinueOnError {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(i int, item interface{}) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			st := ctx.Store.Duplicate()
+			if err := st.Insert(&base.StorageRecord{RefName: "item", Value: item, Literal: true}, ""); err != nil {
+				errs[i] = err
+				failed.Store(true)
+				return
+			}
+			if err := st.Insert(&base.StorageRecord{RefName: "index", Value: i, Literal: true}, ""); err != nil {
+				errs[i] = err
+				failed.Store(true)
+				return
+			}
+			stores, runErr := rt.RunBody(ctx.Ctx(), ctx.Action, st)
+			outputs := make(map[string]interface{})
+			for _, name := range outputNames {
+				for _, s := range stores {
+					record, err := s.GetByRefName(name)
+					if err != nil || record.Action == nil {
+						continue
+					}
+					// skip the vars inherited from the caller
+					if _, exists := bodyActions[record.Action.ActionID]; exists {
+						outputs[name] = exportRecordValue(record)
+					}
+				}
+			}
+			if params.Collect != "" {
+				results[i] = outputs[params.Collect]
+			} else {
+				results[i] = outputs
+			}
+			if runErr != nil {
+				errs[i] = fmt.Errorf("item %d: %w", i, runErr)
+				failed.Store(true)
+			}
+		}(i, item)
+	}
+	wg.Wait()
+
+	aout := base.NewActionOutput(ctx.Action, results, nil)
+	aout.Records[0].Literal = true
+	if err := context.Cause(ctx.Ctx()); err != nil {
+		return aout, err
+	}
+	var failures []error
+	for _, err := range errs {
+		if err != nil {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) > 0 {
+		if !params.ContinueOnError {
+			return aout, fmt.Errorf("%d of %d items failed: %w", len(failures), len(items), errors.Join(failures...))
+		}
+		for _, err := range failures {
+			ctx.Logger.LogWarn(err.Error())
+		}
+	}
+	ctx.Logger.LogInfo(fmt.Sprintf("%d items done", len(items)))
+	return aout, nil
+}
@@ -0,0 +1,209 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package actors_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testForeachBP = `
+actions:
+  - id: loop
+    provider: generic
+    action: foreach
+    first: true
+    output: LOOP
+    parameters:
+      items: ["a", "b", "c"]
+      body: body
+      parallel: %d
+      collect: %q
+      continue_on_error: %v
+    next: {ok: [ok], ko: [ko]}
+  - id: body
+    provider: test
+    action: run
+    output: OUT
+    parameters: {value: "{{ item }}-{{ index }}", sleep: 50ms, fail_on: "b-1-fail"}
+  - id: ok
+    provider: test
+    action: run
+    parameters: {value: "{{ LOOP | json }}"}
+  - id: ko
+    provider: test
+    action: run
+    parameters: {value: "{{ LOOP.__error }}"}
+`
+
+func TestForeachSequential(t *testing.T) {
+	r := runTestBlueprint(t, fmt.Sprintf(testForeachBP, 0, "", false))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	runs := getRuns("body")
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 runs of the body, got %d", len(runs))
+	}
+	for i := 1; i < len(runs); i++ {
+		if runs[i].start.Before(runs[i-1].end) {
+			t.Errorf("item %d started before the end of the previous one", i)
+		}
+	}
+	if v := runValue(t, "ok"); v != `[{"OUT":"a-0"},{"OUT":"b-1"},{"OUT":"c-2"}]` {
+		t.Errorf("unexpected outputs %s", v)
+	}
+}
+
+func TestForeachParallel(t *testing.T) {
+	r := runTestBlueprint(t, fmt.Sprintf(testForeachBP, 3, "OUT", false))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	runs := getRuns("body")
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 runs of the body, got %d", len(runs))
+	}
+	var lastStart, firstEnd time.Time
+	for _, run := range runs {
+		if run.start.After(lastStart) {
+			lastStart = run.start
+		}
+		if firstEnd.IsZero() || run.end.Before(firstEnd) {
+			firstEnd = run.end
+		}
+	}
+	if !lastStart.Before(firstEnd) {
+		t.Errorf("the items did not run at the same time")
+	}
+	// collected in the order of the items
+	if v := runValue(t, "ok"); v != `["a-0","b-1","c-2"]` {
+		t.Errorf("unexpected outputs %s", v)
+	}
+}
+
+const testForeachFailBP = `
+actions:
+  - id: loop
+    provider: generic
+    action: foreach
+    first: true
+    output: LOOP
+    parameters:
+      items: ["a", "b", "c"]
+      body: body
+      collect: OUT
+      continue_on_error: %v
+    next: {ok: [ok], ko: [ko]}
+  - id: body
+    provider: test
+    action: run
+    output: OUT
+    parameters: {value: "{{ item }}-{{ index }}", fail_on: "b-1"}
+  - id: ok
+    provider: test
+    action: run
+    parameters: {value: "{{ LOOP | json }}"}
+  - id: ko
+    provider: test
+    action: run
+    parameters: {value: "{{ LOOP.__error }}"}
+`
+
+func TestForeachStopOnError(t *testing.T) {
+	r := runTestBlueprint(t, fmt.Sprintf(testForeachFailBP, false))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	if runs := getRuns("body"); len(runs) != 2 {
+		t.Errorf("expected the items after the failed one to be skipped, got %d runs", len(runs))
+	}
+	if runs := getRuns("ok"); len(runs) != 0 {
+		t.Fatal("the foreach went on through the OK port")
+	}
+	if v := runValue(t, "ko"); !strings.Contains(v, "1 of 3 items failed") || !strings.Contains(v, "b-1 failed") {
+		t.Errorf("unexpected error %s", v)
+	}
+}
+
+func TestForeachContinueOnError(t *testing.T) {
+	r := runTestBlueprint(t, fmt.Sprintf(testForeachFailBP, true))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	if runs := getRuns("body"); len(runs) != 3 {
+		t.Errorf("expected 3 runs of the body, got %d", len(runs))
+	}
+	v := runValue(t, "ok")
+	if !strings.HasPrefix(v, `["a-0",`) || !strings.HasSuffix(v, `,"c-2"]`) {
+		t.Errorf("unexpected outputs %s", v)
+	}
+}
+
+const testForeachRefBP = `
+actions:
+  - id: vars
+    provider: generic
+    action: define_variables
+    first: true
+    parameters:
+      vars:
+        - {key: STACK, value: "x", type: string, stack: true}
+        - {key: STACK, value: "y", type: string, stack: true}
+        - {key: LIST, value: '["p", "q"]', type: string}
+    next: {ok: [loop]}
+  - id: loop
+    provider: generic
+    action: foreach
+    output: LOOP
+    parameters: {items: %q, body: body, collect: OUT}
+    next: {ok: [ok]}
+  - id: body
+    provider: test
+    action: run
+    output: OUT
+    parameters: {value: "{{ item }}-{{ index }}"}
+  - id: ok
+    provider: test
+    action: run
+    parameters: {value: "{{ LOOP | json }}"}
+`
+
+func TestForeachItems(t *testing.T) {
+	for items, want := range map[string]string{
+		// stacked values in push order
+		"{{ STACK }}": `["x-0","y-1"]`,
+		// json array
+		"{{ LIST }}": `["p-0","q-1"]`,
+	} {
+		r := runTestBlueprint(t, fmt.Sprintf(testForeachRefBP, items))
+		if r.ExitCode() != 0 {
+			t.Fatalf("%s: %v", items, r.Error())
+		}
+		if v := runValue(t, "ok"); v != want {
+			t.Errorf("%s: expected %s, got %s", items, want, v)
+		}
+	}
+}
@@ -35,7 +35,6 @@ import (
 	"compress/zlib"
 	"crypto/tls"
 	"encoding"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -140,7 +139,7 @@ func HttpRequest(ctx *ActionContext) (*base.ActionOutput, error) {
 	var req *http.Request
 
 	p := &httpRequestParameters{}
-	if err = json.Unmarshal(ctx.Action.Parameters, p); err != nil {
+	if err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, p); err != nil {
 		return nil, err
 	}
 	if p.Url == nil {
@@ -173,7 +172,7 @@ func HttpRequest(ctx *ActionContext) (*base.ActionOutput, error) {
 		// content_type: "",
 		// }
 		param := &httpRequestParametersMultiPartBody{}
-		if err := util.UnmarshalValidJSON(ctx.Action.Parameters, param); err != nil {
+		if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, param); err != nil {
 			return nil, err
 		}
 
@@ -242,7 +241,7 @@ func HttpRequest(ctx *ActionContext) (*base.ActionOutput, error) {
 		// body part definitions
 		// body is {name: "campo1", value: "valor1"}
 		param := &httpRequestParametersUrlEncodedBody{}
-		if err := util.UnmarshalValidJSON(ctx.Action.Parameters, param); err != nil {
+		if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, param); err != nil {
 			return nil, err
 		}
 		// append key:value
@@ -261,7 +260,7 @@ func HttpRequest(ctx *ActionContext) (*base.ActionOutput, error) {
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	case BodyTypeRaw:
 		param := &httpRequestParametersRawBody{}
-		if err := util.UnmarshalValidJSON(ctx.Action.Parameters, param); err != nil {
+		if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, param); err != nil {
 			return nil, err
 		}
 		body := strings.NewReader(*param.RawBody)
@@ -274,7 +273,7 @@ func HttpRequest(ctx *ActionContext) (*base.ActionOutput, error) {
 	case BodyTypeBinary:
 		param := &httpRequestParametersBinaryBody{}
 		// the body contains the path of a file
-		if err := util.UnmarshalValidJSON(ctx.Action.Parameters, param); err != nil {
+		if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, param); err != nil {
 			return nil, err
 		}
 		file, err := os.Open(*param.BinaryBody)
@@ -357,6 +356,8 @@ func HttpRequest(ctx *ActionContext) (*base.ActionOutput, error) {
 	}
 
 	client := &http.Client{Transport: tr, Jar: jar}
+	// aborted on action timeout
+	req = req.WithContext(ctx.Ctx())
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, errors.Join(fmt.Errorf("http request error"), err)
@@ -24,7 +24,6 @@ package actors
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -35,6 +34,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/develatio/nebulant-cli/base"
 	"github.com/develatio/nebulant-cli/ipc"
@@ -122,7 +122,7 @@ func RunLocalScript(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 
 	p := &runLocalParameters{}
-	if err := json.Unmarshal(ctx.Action.Parameters, p); err != nil {
+	if err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, p); err != nil {
 		return nil, err
 	}
 
@@ -206,7 +206,11 @@ func RunLocalScript(ctx *ActionContext) (*base.ActionOutput, error) {
 	}
 
 	ctx.Logger.LogInfo("Running cmd [" + strings.Join(argv, ", ") + "]")
-	cmd = exec.Command(argv[0], argv[1:]...) // #nosec G204 -- allowed here
+	// killed on action timeout
+	cmd = exec.CommandContext(ctx.Ctx(), argv[0], argv[1:]...) // #nosec G204 -- allowed here
+	killProcessGroup(cmd)
+	// don't wait forever for grandchildren holding the output pipes
+	cmd.WaitDelay = 2 * time.Second
 
 	envVars := os.Environ()
 	for varname := range p.Vars {
@@ -320,7 +324,7 @@ func RunLocalScript(ctx *ActionContext) (*base.ActionOutput, error) {
 
 func DefineEnvs(ctx *ActionContext) (*base.ActionOutput, error) {
 	params := new(defineEnvsParameters)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, params); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
 		return nil, err
 	}
 
@@ -352,7 +356,7 @@ func DefineEnvs(ctx *ActionContext) (*base.ActionOutput, error) {
 func ReadFile(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	params := new(readFileParameters)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, params); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
 		return nil, err
 	}
 
@@ -400,7 +404,7 @@ func ReadFile(ctx *ActionContext) (*base.ActionOutput, error) {
 func WriteFile(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	params := new(writeFileParameters)
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, params); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
 		return nil, err
 	}
 
@@ -30,11 +30,11 @@ package actors
 //
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/util"
 )
 
 type runScriptParameters struct {
@@ -51,7 +51,7 @@ type runScriptParameters struct {
 
 func RunScript(ctx *ActionContext) (*base.ActionOutput, error) {
 	p := &runScriptParameters{}
-	if err := json.Unmarshal(ctx.Action.Parameters, p); err != nil {
+	if err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, p); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,70 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package actors_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// the process of a timed out local script is killed
+func TestRunScriptTimeout(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	start := time.Now()
+	r := runTestBlueprint(t, fmt.Sprintf(`
+actions:
+  - id: a
+    provider: generic
+    action: run_script
+    first: true
+    parameters:
+      target: local
+      entrypoint: /bin/sh -c
+      pass_to_entrypoint_as_single_param: true
+      command: sleep 1 && touch %s
+    timeout: 100ms
+    next: {ko: [b]}
+  - id: b
+    provider: test
+    action: run
+    parameters: {value: ko}
+`, marker))
+	if d := time.Since(start); d >= time.Second {
+		t.Fatalf("the action did not end on timeout (%v)", d)
+	}
+	if v := runValue(t, "b"); v != "ko" {
+		t.Fatalf("expected the ko route, got %q", v)
+	}
+	for _, ar := range r.Report().Actions {
+		if ar.ActionID == "a" && ar.Status != "ko" {
+			t.Errorf("expected a to be ko, got %s", ar.Status)
+		}
+	}
+	// the script would have created the marker by now
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("the script kept running after the timeout (%v)", err)
+	}
+}
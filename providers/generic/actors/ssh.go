@@ -31,7 +31,6 @@ package actors
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -112,7 +111,7 @@ func newSSHDebugShell(ctx *ActionContext, sshClient *nebulantssh.SSHClient) erro
 func RunRemoteScript(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	p := &runRemoteParameters{}
-	if err = json.Unmarshal(ctx.Action.Parameters, p); err != nil {
+	if err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, p); err != nil {
 		return nil, err
 	}
 
@@ -167,10 +166,19 @@ func RunRemoteScript(ctx *ActionContext) (*base.ActionOutput, error) {
 		out <- true
 	}()
 	mainClientEvents := sshClient.Events
+	// on action timeout, close the conn (and his sessions) to
+	// unlock dial and session wait
+	actionDone := ctx.Ctx().Done()
 	go func() {
 	L1:
 		for {
 			select {
+			case <-actionDone:
+				actionDone = nil
+				ctx.Logger.LogDebug("SSH action canceled, disconnecting...")
+				if err := mainclient.Disconnect(); err != nil {
+					ctx.Logger.LogDebug(err.Error())
+				}
 			case evt := <-mainClientEvents:
 				addr := evt.SSHClient.DialAddr
 				if evt.Type == nebulantssh.SSHClientEventMasterClosed {
@@ -427,7 +435,7 @@ func RunRemoteScript(ctx *ActionContext) (*base.ActionOutput, error) {
 // func GenerateKeyPair(ctx *ActionContext) (*base.ActionOutput, error) {
 // 	var err error
 // 	input := &generateKeypairParameters{}
-// 	if err = json.Unmarshal(ctx.Action.Parameters, input); err != nil {
+// 	if err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 // 		return nil, err
 // 	}
 
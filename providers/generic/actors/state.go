@@ -0,0 +1,187 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package actors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/state"
+	"github.com/develatio/nebulant-cli/util"
+)
+
+type stateParameters struct {
+	// namespace of the key, NEBULANT_STATE_NAMESPACE or default if empty
+	Namespace string `json:"namespace"`
+	Key       string `json:"key" validate:"required"`
+}
+
+type getStateParameters struct {
+	stateParameters
+	// value of missing keys. Without default the action fails
+	Default interface{} `json:"default"`
+}
+
+type setStateParameters struct {
+	stateParameters
+	Value interface{} `json:"value"`
+}
+
+// resolve func. Interpolated namespace and key
+func (p *stateParameters) resolve(ctx *ActionContext) (string, string, error) {
+	if p.Namespace == "" {
+		p.Namespace = state.Namespace()
+	}
+	if ctx.Rehearsal {
+		if !strings.Contains(p.Namespace, "{{") {
+			return p.Namespace, p.Key, state.ValidateNamespace(p.Namespace)
+		}
+		return p.Namespace, p.Key, nil
+	}
+	if err := ctx.Store.Interpolate(&p.Namespace); err != nil {
+		return "", "", err
+	}
+	if err := ctx.Store.Interpolate(&p.Key); err != nil {
+		return "", "", err
+	}
+	return p.Namespace, p.Key, state.ValidateNamespace(p.Namespace)
+}
+
+// stateValue func. Interpolate the strings of the value. A string that
+// is exactly one {{ expression }} keeps the native type of the value
+func stateValue(store base.IStore, v interface{}) (interface{}, error) {
+	switch vv := v.(type) {
+	case string:
+		return store.NativeInterpolation(vv)
+	case map[string]interface{}:
+		for k, item := range vv {
+			nv, err := stateValue(store, item)
+			if err != nil {
+				return nil, err
+			}
+			vv[k] = nv
+		}
+	case []interface{}:
+		for i, item := range vv {
+			nv, err := stateValue(store, item)
+			if err != nil {
+				return nil, err
+			}
+			vv[i] = nv
+		}
+	}
+	return v, nil
+}
+
+// GetState func. Read a key of the state that persists across executions
+func GetState(ctx *ActionContext) (*base.ActionOutput, error) {
+	params := new(getStateParameters)
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
+		return nil, err
+	}
+	ns, key, err := params.resolve(ctx)
+	if err != nil || ctx.Rehearsal {
+		return nil, err
+	}
+
+	st, err := state.Default()
+	if err != nil {
+		return nil, err
+	}
+	value, exists, err := st.Get(ctx.Ctx(), ns, key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if params.Default == nil {
+			return nil, fmt.Errorf("state key %s not found in namespace %s", key, ns)
+		}
+		value, err = stateValue(ctx.Store, params.Default)
+		if err != nil {
+			return nil, err
+		}
+		ctx.Logger.LogInfo(fmt.Sprintf("state key %s not found in namespace %s, using the default value", key, ns))
+	}
+
+	aout := base.NewActionOutput(ctx.Action, value, nil)
+	aout.Records[0].Literal = true
+	return aout, nil
+}
+
+// SetState func. Write a key of the state that persists across executions
+func SetState(ctx *ActionContext) (*base.ActionOutput, error) {
+	params := new(setStateParameters)
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
+		return nil, err
+	}
+	ns, key, err := params.resolve(ctx)
+	if err != nil || ctx.Rehearsal {
+		return nil, err
+	}
+
+	value, err := stateValue(ctx.Store, params.Value)
+	if err != nil {
+		return nil, err
+	}
+	st, err := state.Default()
+	if err != nil {
+		return nil, err
+	}
+	if err := st.Set(ctx.Ctx(), ns, key, value); err != nil {
+		return nil, err
+	}
+	ctx.Logger.LogInfo(fmt.Sprintf("state key %s saved in namespace %s", key, ns))
+
+	aout := base.NewActionOutput(ctx.Action, value, nil)
+	aout.Records[0].Literal = true
+	return aout, nil
+}
+
+// DeleteState func. Remove a key of the state that persists across
+// executions. Missing keys are not an error
+func DeleteState(ctx *ActionContext) (*base.ActionOutput, error) {
+	params := new(stateParameters)
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, params); err != nil {
+		return nil, err
+	}
+	ns, key, err := params.resolve(ctx)
+	if err != nil || ctx.Rehearsal {
+		return nil, err
+	}
+
+	st, err := state.Default()
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := st.Delete(ctx.Ctx(), ns, key)
+	if err != nil {
+		return nil, err
+	}
+	if deleted {
+		ctx.Logger.LogInfo(fmt.Sprintf("state key %s deleted from namespace %s", key, ns))
+	} else {
+		ctx.Logger.LogInfo(fmt.Sprintf("state key %s not found in namespace %s, nothing to delete", key, ns))
+	}
+	return nil, nil
+}
@@ -33,6 +33,12 @@ import (
 )
 
 func ActionValidator(action *base.Action) error {
+	return ActionRehearsal(action, nil)
+}
+
+// ActionRehearsal func. Run the actor of the action in rehearsal mode.
+// The typed parameters are resolved against store, if any
+func ActionRehearsal(action *base.Action, store base.IStore) error {
 	if action.Provider != "generic" {
 		return nil
 	}
@@ -40,16 +46,10 @@ func ActionValidator(action *base.Action) error {
 	if !exists {
 		return fmt.Errorf("generic: invalid action name " + action.ActionName)
 	}
-	if al.N == actors.NextOK && len(action.NextAction.NextKo) > 0 {
-		return fmt.Errorf("generic: action " + action.ActionName + " has no KO port")
-	}
-	if al.N == actors.NextKO && len(action.NextAction.NextOk) > 0 {
-		return fmt.Errorf("generic: action " + action.ActionName + " has no OK port")
-	}
-
 	ac := &actors.ActionContext{
 		Rehearsal: true,
 		Action:    action,
+		Store:     store,
 		Logger:    &cast.DummyLogger{},
 	}
 	_, err := al.F(ac)
@@ -41,6 +41,17 @@ type ActionContext struct {
 	Action    *base.Action
 	Store     base.IStore
 	Logger    base.ILogger
+	// canceled on action timeout
+	Context context.Context
+}
+
+// Ctx func. The context of the running action, canceled on
+// timeout. Background if not set
+func (a *ActionContext) Ctx() context.Context {
+	if a.Context == nil {
+		return context.Background()
+	}
+	return a.Context
 }
 
 // func handleActionWaitingUpdate(update *hcloud.Action) error {
@@ -78,7 +89,7 @@ func (a *ActionContext) WaitForManyAndLog(actions []schema.Action, msg string) e
 		return nil
 	}
 
-	return a.HClient.Action.WaitForFunc(context.Background(), upd)
+	return a.HClient.Action.WaitForFunc(a.Ctx(), upd)
 }
 
 func UnmarshallHCloudToSchema(response *hcloud.Response, v interface{}) error {
@@ -123,86 +134,121 @@ const (
 type ActionLayout struct {
 	F ActionFunc
 	N NextType
+	// API calls made by the actor, shown by run --plan
+	C string
+}
+
+// Ports func. The ports (OK/KO) available for the action
+func (a *ActionLayout) Ports() (ok bool, ko bool) {
+	return a.N != NextKO, a.N != NextOK
+}
+
+// Calls func. The external calls made by the action
+func (a *ActionLayout) Calls() string {
+	return a.C
 }
 
 // ActionFuncMap map
 var ActionFuncMap map[string]*ActionLayout = map[string]*ActionLayout{
-	"create_floating_ip":   {F: CreateFloatingIP, N: NextOKKO},
-	"delete_floating_ip":   {F: DeleteFloatingIP, N: NextOKKO},
-	"find_floating_ips":    {F: FindFloatingIPs, N: NextOKKO},
-	"findone_floating_ip":  {F: FindOneFloatingIP, N: NextOKKO},
-	"assign_floating_ip":   {F: AssignFloatingIP, N: NextOKKO},
-	"unassign_floating_ip": {F: UnassignFloatingIP, N: NextOKKO},
-
-	"find_images":   {F: FindImages, N: NextOKKO},
-	"findone_image": {F: FindOneImage, N: NextOKKO},
-	"delete_image":  {F: DeleteImage, N: NextOKKO},
-
-	"create_server":              {F: CreateServer, N: NextOKKO},
-	"delete_server":              {F: DeleteServer, N: NextOKKO},
-	"find_servers":               {F: FindServers, N: NextOKKO},
-	"findone_server":             {F: FindOneServer, N: NextOKKO},
-	"start_server":               {F: PowerOnServer, N: NextOKKO},  // poweron server
-	"stop_server":                {F: PowerOffServer, N: NextOKKO}, // poweroff server
-	"attach_server_to_network":   {F: AttachServerToNetwork, N: NextOKKO},
-	"detach_server_from_network": {F: DetachServerFromNetwork, N: NextOKKO},
-	"create_image_from_server":   {F: CreateImageFromServer, N: NextOKKO},
-
-	"create_network":             {F: CreateNetwork, N: NextOKKO},
-	"delete_network":             {F: DeleteNetwork, N: NextOKKO},
-	"find_networks":              {F: FindNetworks, N: NextOKKO},
-	"findone_network":            {F: FindOneNetwork, N: NextOKKO},
-	"add_subnet_to_network":      {F: AddSubnetToNetwork, N: NextOKKO},
-	"delete_subnet_from_network": {F: DeleteSubnetFromNetwork, N: NextOKKO},
-	"add_route_to_network":       {F: AddRouteToNetwork, N: NextOKKO},
-	"delete_route_from_network":  {F: DeleteRouteFromNetwork, N: NextOKKO},
-
-	"create_volume":  {F: CreateVolume, N: NextOKKO},
-	"delete_volume":  {F: DeleteVolume, N: NextOKKO},
-	"find_volumes":   {F: FindVolumes, N: NextOKKO},
-	"findone_volume": {F: FindOneVolume, N: NextOKKO},
-	"attach_volume":  {F: AttachVolume, N: NextOKKO},
-	"detach_volume":  {F: DetachVolume, N: NextOKKO},
-
-	"find_datacenters":   {F: FindDatacenters, N: NextOKKO},
-	"findone_datacenter": {F: FindOneDatacenter, N: NextOKKO},
-
-	"create_firewall":                {F: CreateFirewall, N: NextOKKO},
-	"delete_firewall":                {F: DeleteFirewall, N: NextOKKO},
-	"find_firewalls":                 {F: FindFirewalls, N: NextOKKO},
-	"findone_firewall":               {F: FindOneFirewall, N: NextOKKO},
-	"apply_firewall_to_resources":    {F: ApplyFirewallToResources, N: NextOKKO},
-	"remove_firewall_from_resources": {F: RemoveFirewallFromResources, N: NextOKKO},
-	"set_rules_firewall":             {F: SetRulesFirewall, N: NextOKKO},
-
-	"find_isos":   {F: FindISOs, N: NextOKKO},
-	"findone_iso": {F: FindOneISO, N: NextOKKO},
-
-	"create_load_balancer":               {F: CreateLoadBalancer, N: NextOKKO},
-	"delete_load_balancer":               {F: DeleteLoadBalancer, N: NextOKKO},
-	"find_load_balancers":                {F: FindLoadBalancers, N: NextOKKO},
-	"findone_load_balancer":              {F: FindOneLoadBalancer, N: NextOKKO},
-	"attach_load_balancer_to_network":    {F: AttachLoadBalancerToNetwork, N: NextOKKO},
-	"dettach_load_balancer_from_network": {F: DetachLoadBalancerFromNetwork, N: NextOKKO},
-	"add_target_to_load_balancer":        {F: AddTargetToLoadBalancer, N: NextOKKO},
-	"remove_target_from_load_balancer":   {F: RemoveTargetFromLoadBalancer, N: NextOKKO},
-	"add_service_to_load_balancer":       {F: AddServiceToLoadBalancer, N: NextOKKO},
-	"delete_service_from_load_balancer":  {F: DeleteServiceFromLoadBalancer, N: NextOKKO},
-
-	"find_locations":   {F: FindLocations, N: NextOKKO},
-	"findone_location": {F: FindOneLocation, N: NextOKKO},
-
-	"create_primary_ip":   {F: CreatePrimaryIP, N: NextOKKO},
-	"delete_primary_ip":   {F: DeletePrimaryIP, N: NextOKKO},
-	"find_primary_ips":    {F: FindPrimaryIPs, N: NextOKKO},
-	"findone_primary_ip":  {F: FindOnePrimaryIP, N: NextOKKO},
-	"assign_primary_ip":   {F: AssignPrimaryIP, N: NextOKKO},
-	"unassign_primary_ip": {F: UnassignPrimaryIP, N: NextOKKO},
-
-	"create_ssh_key":  {F: CreateSSHKey, N: NextOKKO},
-	"delete_ssh_key":  {F: DeleteSSHKey, N: NextOKKO},
-	"find_ssh_keys":   {F: FindSSHKeys, N: NextOKKO},
-	"findone_ssh_key": {F: FindOneSSHKey, N: NextOKKO},
+	"create_floating_ip":   {F: CreateFloatingIP, N: NextOKKO, C: "hcloud:FloatingIP.Create"},
+	"delete_floating_ip":   {F: DeleteFloatingIP, N: NextOKKO, C: "hcloud:FloatingIP.Delete"},
+	"find_floating_ips":    {F: FindFloatingIPs, N: NextOKKO, C: "hcloud:FloatingIP.List"},
+	"findone_floating_ip":  {F: FindOneFloatingIP, N: NextOKKO, C: "hcloud:FloatingIP.GetByID, hcloud:FloatingIP.List"},
+	"assign_floating_ip":   {F: AssignFloatingIP, N: NextOKKO, C: "hcloud:FloatingIP.Assign"},
+	"unassign_floating_ip": {F: UnassignFloatingIP, N: NextOKKO, C: "hcloud:FloatingIP.Unassign"},
+
+	"find_images":   {F: FindImages, N: NextOKKO, C: "hcloud:Image.List, hcloud:Image.GetByID"},
+	"findone_image": {F: FindOneImage, N: NextOKKO, C: "hcloud:Image.List, hcloud:Image.GetByID"},
+	"delete_image":  {F: DeleteImage, N: NextOKKO, C: "hcloud:Image.Delete"},
+
+	"create_server":              {F: CreateServer, N: NextOKKO, C: "hcloud:Server.Create"},
+	"delete_server":              {F: DeleteServer, N: NextOKKO, C: "hcloud:Server.DeleteWithResult"},
+	"find_servers":               {F: FindServers, N: NextOKKO, C: "hcloud:Server.List"},
+	"findone_server":             {F: FindOneServer, N: NextOKKO, C: "hcloud:Server.GetByID, hcloud:Server.List"},
+	"start_server":               {F: PowerOnServer, N: NextOKKO, C: "hcloud:Server.Poweron"},   // poweron server
+	"stop_server":                {F: PowerOffServer, N: NextOKKO, C: "hcloud:Server.Poweroff"}, // poweroff server
+	"attach_server_to_network":   {F: AttachServerToNetwork, N: NextOKKO, C: "hcloud:Server.AttachToNetwork"},
+	"detach_server_from_network": {F: DetachServerFromNetwork, N: NextOKKO, C: "hcloud:Server.DetachFromNetwork"},
+	"create_image_from_server":   {F: CreateImageFromServer, N: NextOKKO, C: "hcloud:Server.CreateImage"},
+
+	"create_network":             {F: CreateNetwork, N: NextOKKO, C: "hcloud:Network.Create"},
+	"delete_network":             {F: DeleteNetwork, N: NextOKKO, C: "hcloud:Network.Delete"},
+	"find_networks":              {F: FindNetworks, N: NextOKKO, C: "hcloud:Network.List"},
+	"findone_network":            {F: FindOneNetwork, N: NextOKKO, C: "hcloud:Network.List"},
+	"add_subnet_to_network":      {F: AddSubnetToNetwork, N: NextOKKO, C: "hcloud:Network.AddSubnet"},
+	"delete_subnet_from_network": {F: DeleteSubnetFromNetwork, N: NextOKKO, C: "hcloud:Network.DeleteSubnet"},
+	"add_route_to_network":       {F: AddRouteToNetwork, N: NextOKKO, C: "hcloud:Network.AddRoute"},
+	"delete_route_from_network":  {F: DeleteRouteFromNetwork, N: NextOKKO, C: "hcloud:Network.DeleteRoute"},
+
+	"create_volume":  {F: CreateVolume, N: NextOKKO, C: "hcloud:Volume.Create"},
+	"delete_volume":  {F: DeleteVolume, N: NextOKKO, C: "hcloud:Volume.Delete"},
+	"find_volumes":   {F: FindVolumes, N: NextOKKO, C: "hcloud:Volume.List"},
+	"findone_volume": {F: FindOneVolume, N: NextOKKO, C: "hcloud:Volume.List"},
+	"attach_volume":  {F: AttachVolume, N: NextOKKO, C: "hcloud:Volume.AttachWithOpts"},
+	"detach_volume":  {F: DetachVolume, N: NextOKKO, C: "hcloud:Volume.Detach"},
+
+	"find_datacenters":   {F: FindDatacenters, N: NextOKKO, C: "hcloud:Datacenter.List"},
+	"findone_datacenter": {F: FindOneDatacenter, N: NextOKKO, C: "hcloud:Datacenter.List"},
+
+	"create_firewall":                {F: CreateFirewall, N: NextOKKO, C: "hcloud:Firewall.Create"},
+	"delete_firewall":                {F: DeleteFirewall, N: NextOKKO, C: "hcloud:Firewall.Delete"},
+	"find_firewalls":                 {F: FindFirewalls, N: NextOKKO, C: "hcloud:Firewall.List"},
+	"findone_firewall":               {F: FindOneFirewall, N: NextOKKO, C: "hcloud:Firewall.GetByID, hcloud:Firewall.List"},
+	"apply_firewall_to_resources":    {F: ApplyFirewallToResources, N: NextOKKO, C: "hcloud:Firewall.ApplyResources"},
+	"remove_firewall_from_resources": {F: RemoveFirewallFromResources, N: NextOKKO, C: "hcloud:Firewall.RemoveResources"},
+	"set_rules_firewall":             {F: SetRulesFirewall, N: NextOKKO, C: "hcloud:Firewall.SetRules"},
+
+	"find_isos":   {F: FindISOs, N: NextOKKO, C: "hcloud:ISO.List"},
+	"findone_iso": {F: FindOneISO, N: NextOKKO, C: "hcloud:ISO.List"},
+
+	"create_load_balancer":               {F: CreateLoadBalancer, N: NextOKKO, C: "hcloud:LoadBalancer.Create"},
+	"delete_load_balancer":               {F: DeleteLoadBalancer, N: NextOKKO, C: "hcloud:LoadBalancer.Delete"},
+	"find_load_balancers":                {F: FindLoadBalancers, N: NextOKKO, C: "hcloud:LoadBalancer.List"},
+	"findone_load_balancer":              {F: FindOneLoadBalancer, N: NextOKKO, C: "hcloud:LoadBalancer.List"},
+	"attach_load_balancer_to_network":    {F: AttachLoadBalancerToNetwork, N: NextOKKO, C: "hcloud:Network.GetByID, hcloud:LoadBalancer.AttachToNetwork"},
+	"dettach_load_balancer_from_network": {F: DetachLoadBalancerFromNetwork, N: NextOKKO, C: "hcloud:LoadBalancer.DetachFromNetwork"},
+	"add_target_to_load_balancer":        {F: AddTargetToLoadBalancer, N: NextOKKO, C: "hcloud:LoadBalancer.AddServerTarget, hcloud:LoadBalancer.AddIPTarget, hcloud:LoadBalancer.AddLabelSelectorTarget"},
+	"remove_target_from_load_balancer":   {F: RemoveTargetFromLoadBalancer, N: NextOKKO, C: "hcloud:LoadBalancer.RemoveServerTarget, hcloud:LoadBalancer.RemoveIPTarget, hcloud:LoadBalancer.RemoveLabelSelectorTarget"},
+	"add_service_to_load_balancer":       {F: AddServiceToLoadBalancer, N: NextOKKO, C: "hcloud:LoadBalancer.AddService"},
+	"delete_service_from_load_balancer":  {F: DeleteServiceFromLoadBalancer, N: NextOKKO, C: "hcloud:LoadBalancer.DeleteService"},
+
+	"find_locations":   {F: FindLocations, N: NextOKKO, C: "hcloud:Location.List"},
+	"findone_location": {F: FindOneLocation, N: NextOKKO, C: "hcloud:Location.List"},
+
+	"create_primary_ip":   {F: CreatePrimaryIP, N: NextOKKO, C: "hcloud:PrimaryIP.Create"},
+	"delete_primary_ip":   {F: DeletePrimaryIP, N: NextOKKO, C: "hcloud:PrimaryIP.Delete"},
+	"find_primary_ips":    {F: FindPrimaryIPs, N: NextOKKO, C: "hcloud:PrimaryIP.List"},
+	"findone_primary_ip":  {F: FindOnePrimaryIP, N: NextOKKO, C: "hcloud:PrimaryIP.List"},
+	"assign_primary_ip":   {F: AssignPrimaryIP, N: NextOKKO, C: "hcloud:PrimaryIP.Assign"},
+	"unassign_primary_ip": {F: UnassignPrimaryIP, N: NextOKKO, C: "hcloud:PrimaryIP.Unassign"},
+
+	"create_ssh_key":  {F: CreateSSHKey, N: NextOKKO, C: "hcloud:SSHKey.Create"},
+	"delete_ssh_key":  {F: DeleteSSHKey, N: NextOKKO, C: "hcloud:SSHKey.Delete"},
+	"find_ssh_keys":   {F: FindSSHKeys, N: NextOKKO, C: "hcloud:SSHKey.List"},
+	"findone_ssh_key": {F: FindOneSSHKey, N: NextOKKO, C: "hcloud:SSHKey.List"},
+}
+
+// ResourceLayout struct. The resource created by an action
+// and the action that deletes it
+type ResourceLayout struct {
+	Type string
+	// delete action
+	D string
+	// other parameters of the delete action
+	X map[string]interface{}
+}
+
+// ResourceFuncMap map. Resources created by action name. The id of the
+// resource is the ID parameter of the delete action
+var ResourceFuncMap map[string]*ResourceLayout = map[string]*ResourceLayout{
+	"create_server":        {Type: "server", D: "delete_server", X: map[string]interface{}{"_waiters": []string{"success"}}},
+	"create_volume":        {Type: "volume", D: "delete_volume"},
+	"create_floating_ip":   {Type: "floating_ip", D: "delete_floating_ip"},
+	"create_primary_ip":    {Type: "primary_ip", D: "delete_primary_ip"},
+	"create_network":       {Type: "network", D: "delete_network"},
+	"create_firewall":      {Type: "firewall", D: "delete_firewall"},
+	"create_load_balancer": {Type: "load_balancer", D: "delete_load_balancer"},
+	"create_ssh_key":       {Type: "ssh_key", D: "delete_ssh_key"},
 }
 
 // GenericHCloudOutput unmarshall response into v and return ActionContext with
@@ -23,7 +23,6 @@
 package actors
 
 import (
-	"context"
 	"fmt"
 
 	"github.com/develatio/nebulant-cli/base"
@@ -40,7 +39,7 @@ type DatacenterListResponseWithMeta struct {
 func FindDatacenters(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.DatacenterListOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -52,7 +51,7 @@ func FindDatacenters(ctx *ActionContext) (*base.ActionOutput, error) {
 	if err != nil {
 		return nil, err
 	}
-	_, response, err := ctx.HClient.Datacenter.List(context.Background(), *input)
+	_, response, err := ctx.HClient.Datacenter.List(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -23,8 +23,6 @@
 package actors
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
@@ -167,12 +165,12 @@ func CreateFirewall(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcFirewallCreateOptsWrap{}
 	output := &schema.FirewallCreateResponse{}
 
-	if err := json.Unmarshal(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -191,7 +189,7 @@ func CreateFirewall(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Firewall.Create(context.Background(), *opts)
+	_, response, err := ctx.HClient.Firewall.Create(ctx.Ctx(), *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -219,7 +217,7 @@ func DeleteFirewall(ctx *ActionContext) (*base.ActionOutput, error) {
 	// only Firewall.ID attr are really used
 	input := &hcFirewallWrap{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -237,7 +235,7 @@ func DeleteFirewall(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	response, err := ctx.HClient.Firewall.Delete(context.Background(), hfwall)
+	response, err := ctx.HClient.Firewall.Delete(ctx.Ctx(), hfwall)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -249,7 +247,7 @@ func DeleteFirewall(ctx *ActionContext) (*base.ActionOutput, error) {
 func FindFirewalls(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.FirewallListOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -262,7 +260,7 @@ func FindFirewalls(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Firewall.List(context.Background(), *input)
+	_, response, err := ctx.HClient.Firewall.List(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -275,7 +273,7 @@ func FindOneFirewall(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	input := &findOneFirewallParameters{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -294,7 +292,7 @@ func FindOneFirewall(ctx *ActionContext) (*base.ActionOutput, error) {
 		if err != nil {
 			return nil, errors.Join(fmt.Errorf("cannot use '%v' as int64 ID", *input.ID), err)
 		}
-		_, response, err := ctx.HClient.Firewall.GetByID(context.Background(), int64id)
+		_, response, err := ctx.HClient.Firewall.GetByID(ctx.Ctx(), int64id)
 		if err != nil {
 			return nil, HCloudErrResponse(err, response)
 		}
@@ -332,7 +330,7 @@ func ApplyFirewallToResources(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &applyResourcesParameters{}
 	output := &schema.FirewallActionApplyToResourcesResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -340,7 +338,7 @@ func ApplyFirewallToResources(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, nil
 	}
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -364,7 +362,7 @@ func ApplyFirewallToResources(ctx *ActionContext) (*base.ActionOutput, error) {
 		resources = append(resources, *hres)
 	}
 
-	_, response, err := ctx.HClient.Firewall.ApplyResources(context.Background(), hfwall, resources)
+	_, response, err := ctx.HClient.Firewall.ApplyResources(ctx.Ctx(), hfwall, resources)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -390,12 +388,12 @@ func RemoveFirewallFromResources(ctx *ActionContext) (*base.ActionOutput, error)
 	input := &removeResourcesParameters{}
 	output := &schema.FirewallActionRemoveFromResourcesResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -423,7 +421,7 @@ func RemoveFirewallFromResources(ctx *ActionContext) (*base.ActionOutput, error)
 		resources = append(resources, *hres)
 	}
 
-	_, response, err := ctx.HClient.Firewall.RemoveResources(context.Background(), hfwall, resources)
+	_, response, err := ctx.HClient.Firewall.RemoveResources(ctx.Ctx(), hfwall, resources)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -449,12 +447,12 @@ func SetRulesFirewall(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &setRulesParameters{}
 	output := &schema.FirewallActionSetRulesResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -473,7 +471,7 @@ func SetRulesFirewall(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Firewall.SetRules(context.Background(), hfwall, input.Opts)
+	_, response, err := ctx.HClient.Firewall.SetRules(ctx.Ctx(), hfwall, input.Opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -23,8 +23,6 @@
 package actors
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -77,12 +75,12 @@ func CreateFloatingIP(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.FloatingIPCreateOpts{}
 	output := &schema.FloatingIPCreateResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +94,7 @@ func CreateFloatingIP(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.FloatingIP.Create(context.Background(), *input)
+	_, response, err := ctx.HClient.FloatingIP.Create(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, err
 	}
@@ -125,7 +123,7 @@ func DeleteFloatingIP(ctx *ActionContext) (*base.ActionOutput, error) {
 	// https://github.com/hetznercloud/hcloud-go/blob/v2.3.0/hcloud/floating_ip.go#L279
 	input := &hcFloatingIPWrap{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -143,7 +141,7 @@ func DeleteFloatingIP(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, err = ctx.HClient.FloatingIP.Delete(context.Background(), hfip)
+	_, err = ctx.HClient.FloatingIP.Delete(ctx.Ctx(), hfip)
 	if err != nil {
 		return nil, err
 	}
@@ -155,7 +153,7 @@ func DeleteFloatingIP(ctx *ActionContext) (*base.ActionOutput, error) {
 func FindFloatingIPs(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.FloatingIPListOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -163,7 +161,7 @@ func FindFloatingIPs(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, nil
 	}
 
-	_, response, err := ctx.HClient.FloatingIP.List(context.Background(), *input)
+	_, response, err := ctx.HClient.FloatingIP.List(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -176,7 +174,7 @@ func FindOneFloatingIP(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	input := &findOneFloatingIPParameters{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -195,7 +193,7 @@ func FindOneFloatingIP(ctx *ActionContext) (*base.ActionOutput, error) {
 		if err != nil {
 			return nil, errors.Join(fmt.Errorf("cannot use '%v' as int64 ID", *input.ID), err)
 		}
-		_, response, err := ctx.HClient.FloatingIP.GetByID(context.Background(), int64id)
+		_, response, err := ctx.HClient.FloatingIP.GetByID(ctx.Ctx(), int64id)
 		if err != nil {
 			return nil, HCloudErrResponse(err, response)
 		}
@@ -231,12 +229,12 @@ func AssignFloatingIP(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &assignFloatingIPParameters{}
 	output := &schema.FloatingIPActionAssignResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -260,7 +258,7 @@ func AssignFloatingIP(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.FloatingIP.Assign(context.Background(), hfip, hsrv)
+	_, response, err := ctx.HClient.FloatingIP.Assign(ctx.Ctx(), hfip, hsrv)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -287,12 +285,12 @@ func UnassignFloatingIP(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &unassignFloatingIPParameters{}
 	output := &schema.FloatingIPActionUnassignResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -311,7 +309,7 @@ func UnassignFloatingIP(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.FloatingIP.Unassign(context.Background(), hfip)
+	_, response, err := ctx.HClient.FloatingIP.Unassign(ctx.Ctx(), hfip)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
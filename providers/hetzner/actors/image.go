@@ -23,8 +23,6 @@
 package actors
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
@@ -78,7 +76,7 @@ func DeleteImage(ctx *ActionContext) (*base.ActionOutput, error) {
 	// only Image.ID attr are really used
 	input := &hcImageWrap{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -96,7 +94,7 @@ func DeleteImage(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	response, err := ctx.HClient.Image.Delete(context.Background(), himg)
+	response, err := ctx.HClient.Image.Delete(ctx.Ctx(), himg)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -111,7 +109,7 @@ func FindImages(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	input := &hcImageListOptsWrap{}
 
-	if err := json.Unmarshal(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -136,7 +134,7 @@ func FindImages(ctx *ActionContext) (*base.ActionOutput, error) {
 		opts.PerPage = 50 // max allowed
 		r := regexp.MustCompile(`(?i)` + *input.Description + ``)
 		for {
-			_, _rsp, err := ctx.HClient.Image.List(context.Background(), *opts)
+			_, _rsp, err := ctx.HClient.Image.List(ctx.Ctx(), *opts)
 			if err != nil {
 				return nil, HCloudErrResponse(err, _rsp)
 			}
@@ -177,7 +175,7 @@ func FindImages(ctx *ActionContext) (*base.ActionOutput, error) {
 		if err != nil {
 			return nil, errors.Join(fmt.Errorf("cannot use '%v' as int64 ID", *input.ID), err)
 		}
-		_, response, err = ctx.HClient.Image.GetByID(context.Background(), int64id)
+		_, response, err = ctx.HClient.Image.GetByID(ctx.Ctx(), int64id)
 		if err != nil {
 			return nil, HCloudErrResponse(err, response)
 		}
@@ -201,7 +199,7 @@ func FindImages(ctx *ActionContext) (*base.ActionOutput, error) {
 	}
 
 	// normal list
-	_, response, err = ctx.HClient.Image.List(context.Background(), *opts)
+	_, response, err = ctx.HClient.Image.List(ctx.Ctx(), *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -23,7 +23,6 @@
 package actors
 
 import (
-	"context"
 	"fmt"
 
 	"github.com/develatio/nebulant-cli/base"
@@ -40,7 +39,7 @@ type ISOListResponseWithMeta struct {
 func FindISOs(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.ISOListOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -53,7 +52,7 @@ func FindISOs(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.ISO.List(context.Background(), *input)
+	_, response, err := ctx.HClient.ISO.List(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -23,8 +23,6 @@
 package actors
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
@@ -496,12 +494,12 @@ func CreateLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcLoadBalancerCreateOptsWrap{}
 	output := &schema.LoadBalancerCreateResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -520,7 +518,7 @@ func CreateLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.LoadBalancer.Create(context.Background(), *opts)
+	_, response, err := ctx.HClient.LoadBalancer.Create(ctx.Ctx(), *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -548,7 +546,7 @@ func DeleteLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 	// only LoadBalancer.ID attr is really used
 	input := &hcLoadBalancerWrap{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -566,7 +564,7 @@ func DeleteLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, err = ctx.HClient.LoadBalancer.Delete(context.Background(), hlb)
+	_, err = ctx.HClient.LoadBalancer.Delete(ctx.Ctx(), hlb)
 	if err != nil {
 		return nil, err
 	}
@@ -578,7 +576,7 @@ func DeleteLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 func FindLoadBalancers(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.LoadBalancerListOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -591,7 +589,7 @@ func FindLoadBalancers(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.LoadBalancer.List(context.Background(), *input)
+	_, response, err := ctx.HClient.LoadBalancer.List(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -629,12 +627,12 @@ func FindOneLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 func AttachLoadBalancerToNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &loadbalancerAttachToNetworkParameters{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -663,7 +661,7 @@ func AttachLoadBalancerToNetwork(ctx *ActionContext) (*base.ActionOutput, error)
 	if input.AttachOpts.lookupAvailableIP != nil {
 		ipnet := input.AttachOpts.lookupAvailableIP
 		hnetID := opts.Network.ID
-		hnet, response, err := ctx.HClient.Network.GetByID(context.Background(), hnetID)
+		hnet, response, err := ctx.HClient.Network.GetByID(ctx.Ctx(), hnetID)
 		if err != nil {
 			return nil, HCloudErrResponse(err, response)
 		}
@@ -701,7 +699,7 @@ func AttachLoadBalancerToNetwork(ctx *ActionContext) (*base.ActionOutput, error)
 				return nil, fmt.Errorf("cannot determine a valid ip for subnet %s", ipnet.String())
 			}
 			opts.IP = net.ParseIP(addr.String())
-			_, response, err := ctx.HClient.LoadBalancer.AttachToNetwork(context.Background(), hlb, *opts)
+			_, response, err := ctx.HClient.LoadBalancer.AttachToNetwork(ctx.Ctx(), hlb, *opts)
 			if herr, ok := err.(hcloud.Error); ok {
 				if herr.Code == hcloud.ErrorCodeIPNotAvailable {
 					// ok, already used ip, keep trying
@@ -731,7 +729,7 @@ func AttachLoadBalancerToNetwork(ctx *ActionContext) (*base.ActionOutput, error)
 			return aout, err
 		}
 	}
-	_, response, err := ctx.HClient.LoadBalancer.AttachToNetwork(context.Background(), hlb, *opts)
+	_, response, err := ctx.HClient.LoadBalancer.AttachToNetwork(ctx.Ctx(), hlb, *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -758,12 +756,12 @@ func DetachLoadBalancerFromNetwork(ctx *ActionContext) (*base.ActionOutput, erro
 	input := &loadbalancerDetachFromNetworkParameters{}
 	output := &schema.LoadBalancerActionDetachFromNetworkResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -786,7 +784,7 @@ func DetachLoadBalancerFromNetwork(ctx *ActionContext) (*base.ActionOutput, erro
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.LoadBalancer.DetachFromNetwork(context.Background(), hlb, *opts)
+	_, response, err := ctx.HClient.LoadBalancer.DetachFromNetwork(ctx.Ctx(), hlb, *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -812,12 +810,12 @@ func AddTargetToLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &loadbalancerAddTargetParameters{}
 	output := &schema.LoadBalancerActionAddTargetResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -852,7 +850,7 @@ func AddTargetToLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 		if input.ServerOpts != nil && input.ServerOpts.UsePrivateIP != nil {
 			opts.UsePrivateIP = input.ServerOpts.UsePrivateIP
 		}
-		_, response, err = ctx.HClient.LoadBalancer.AddServerTarget(context.Background(), hlb, *opts)
+		_, response, err = ctx.HClient.LoadBalancer.AddServerTarget(ctx.Ctx(), hlb, *opts)
 		if err != nil {
 			return nil, err
 		}
@@ -864,7 +862,7 @@ func AddTargetToLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 		if err != nil {
 			return nil, err
 		}
-		_, response, err = ctx.HClient.LoadBalancer.AddIPTarget(context.Background(), hlb, *opts)
+		_, response, err = ctx.HClient.LoadBalancer.AddIPTarget(ctx.Ctx(), hlb, *opts)
 		if err != nil {
 			return nil, err
 		}
@@ -872,7 +870,7 @@ func AddTargetToLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 		if input.LabelSelectorOpts == nil {
 			return nil, fmt.Errorf("please, set label selector opts (label_selector_opts)")
 		}
-		_, response, err = ctx.HClient.LoadBalancer.AddLabelSelectorTarget(context.Background(), hlb, *input.LabelSelectorOpts)
+		_, response, err = ctx.HClient.LoadBalancer.AddLabelSelectorTarget(ctx.Ctx(), hlb, *input.LabelSelectorOpts)
 		if err != nil {
 			return nil, err
 		}
@@ -902,12 +900,12 @@ func RemoveTargetFromLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error
 	input := &loadbalancerRemoveTargetParameters{}
 	output := &schema.LoadBalancerActionRemoveTargetResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -936,7 +934,7 @@ func RemoveTargetFromLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error
 		if err != nil {
 			return nil, err
 		}
-		_, response, err = ctx.HClient.LoadBalancer.RemoveServerTarget(context.Background(), hlb, hsrv)
+		_, response, err = ctx.HClient.LoadBalancer.RemoveServerTarget(ctx.Ctx(), hlb, hsrv)
 		if err != nil {
 			return nil, err
 		}
@@ -948,12 +946,12 @@ func RemoveTargetFromLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error
 		if ip == nil {
 			return nil, fmt.Errorf("invalid ip addr")
 		}
-		_, response, err = ctx.HClient.LoadBalancer.RemoveIPTarget(context.Background(), hlb, ip)
+		_, response, err = ctx.HClient.LoadBalancer.RemoveIPTarget(ctx.Ctx(), hlb, ip)
 		if err != nil {
 			return nil, err
 		}
 	case "label_selector":
-		_, response, err = ctx.HClient.LoadBalancer.RemoveLabelSelectorTarget(context.Background(), hlb, *input.LabelSelector)
+		_, response, err = ctx.HClient.LoadBalancer.RemoveLabelSelectorTarget(ctx.Ctx(), hlb, *input.LabelSelector)
 		if err != nil {
 			return nil, err
 		}
@@ -983,12 +981,12 @@ func AddServiceToLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &loadbalancerAddServiceParameters{}
 	output := &schema.LoadBalancerActionAddServiceResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -1011,7 +1009,7 @@ func AddServiceToLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.LoadBalancer.AddService(context.Background(), hlb, *opts)
+	_, response, err := ctx.HClient.LoadBalancer.AddService(ctx.Ctx(), hlb, *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -1036,7 +1034,7 @@ func AddServiceToLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 func DeleteServiceFromLoadBalancer(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &loadbalancerDeleteServiceParameters{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -1059,7 +1057,7 @@ func DeleteServiceFromLoadBalancer(ctx *ActionContext) (*base.ActionOutput, erro
 		return nil, errors.Join(fmt.Errorf("cannot use '%v' as listen port", input.ListenPort), err)
 	}
 
-	_, response, err := ctx.HClient.LoadBalancer.DeleteService(context.Background(), hlb, int(intPort))
+	_, response, err := ctx.HClient.LoadBalancer.DeleteService(ctx.Ctx(), hlb, int(intPort))
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -23,7 +23,6 @@
 package actors
 
 import (
-	"context"
 	"fmt"
 
 	"github.com/develatio/nebulant-cli/base"
@@ -40,7 +39,7 @@ type LocationListResponseWithMeta struct {
 func FindLocations(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.LocationListOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -48,7 +47,7 @@ func FindLocations(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, nil
 	}
 
-	_, response, err := ctx.HClient.Location.List(context.Background(), *input)
+	_, response, err := ctx.HClient.Location.List(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -23,8 +23,6 @@
 package actors
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
@@ -242,7 +240,7 @@ func CreateNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	input := &hcNetworkCreateOptsWrap{}
 
-	if err := json.Unmarshal(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -260,7 +258,7 @@ func CreateNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Network.Create(context.Background(), *opts)
+	_, response, err := ctx.HClient.Network.Create(ctx.Ctx(), *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -279,7 +277,7 @@ func DeleteNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 	// only Network.ID attr are really used
 	input := &hcNetworkWrap{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -297,7 +295,7 @@ func DeleteNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, err = ctx.HClient.Network.Delete(context.Background(), hnet)
+	_, err = ctx.HClient.Network.Delete(ctx.Ctx(), hnet)
 	if err != nil {
 		return nil, err
 	}
@@ -309,7 +307,7 @@ func DeleteNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 func FindNetworks(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.NetworkListOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -322,7 +320,7 @@ func FindNetworks(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Network.List(context.Background(), *input)
+	_, response, err := ctx.HClient.Network.List(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -362,12 +360,12 @@ func AddSubnetToNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcNetworkAddSubnetOptsWrap{}
 	output := &schema.NetworkActionAddSubnetResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -390,7 +388,7 @@ func AddSubnetToNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Network.AddSubnet(context.Background(), hnet, *opts)
+	_, response, err := ctx.HClient.Network.AddSubnet(ctx.Ctx(), hnet, *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -417,12 +415,12 @@ func DeleteSubnetFromNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcNetworkDeleteSubnetOptsWrap{}
 	output := &schema.NetworkActionDeleteSubnetResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -445,7 +443,7 @@ func DeleteSubnetFromNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Network.DeleteSubnet(context.Background(), hnet, *opts)
+	_, response, err := ctx.HClient.Network.DeleteSubnet(ctx.Ctx(), hnet, *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -472,12 +470,12 @@ func AddRouteToNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcNetworkAddRouteOptsWrap{}
 	output := &schema.NetworkActionAddRouteResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -500,7 +498,7 @@ func AddRouteToNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Network.AddRoute(context.Background(), hnet, *opts)
+	_, response, err := ctx.HClient.Network.AddRoute(ctx.Ctx(), hnet, *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -527,12 +525,12 @@ func DeleteRouteFromNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcNetworkDeleteRouteOptsWrap{}
 	output := &schema.NetworkActionDeleteRouteResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -555,7 +553,7 @@ func DeleteRouteFromNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Network.DeleteRoute(context.Background(), hnet, *opts)
+	_, response, err := ctx.HClient.Network.DeleteRoute(ctx.Ctx(), hnet, *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -23,8 +23,6 @@
 package actors
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -105,12 +103,12 @@ func CreatePrimaryIP(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcPrimaryIPCreateOptsWrap{}
 	output := &schema.PrimaryIPCreateResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +127,7 @@ func CreatePrimaryIP(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.PrimaryIP.Create(context.Background(), *hipcreateopts)
+	_, response, err := ctx.HClient.PrimaryIP.Create(ctx.Ctx(), *hipcreateopts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -157,7 +155,7 @@ func DeletePrimaryIP(ctx *ActionContext) (*base.ActionOutput, error) {
 	// only PrimaryIP.ID attr are really used
 	input := &hcPrimaryIPWrap{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -175,7 +173,7 @@ func DeletePrimaryIP(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, err = ctx.HClient.PrimaryIP.Delete(context.Background(), hip)
+	_, err = ctx.HClient.PrimaryIP.Delete(ctx.Ctx(), hip)
 	if err != nil {
 		return nil, err
 	}
@@ -187,7 +185,7 @@ func DeletePrimaryIP(ctx *ActionContext) (*base.ActionOutput, error) {
 func FindPrimaryIPs(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.PrimaryIPListOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -200,7 +198,7 @@ func FindPrimaryIPs(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.PrimaryIP.List(context.Background(), *input)
+	_, response, err := ctx.HClient.PrimaryIP.List(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -240,12 +238,12 @@ func AssignPrimaryIP(ctx *ActionContext) (*base.ActionOutput, error) {
 	// ok to use hcloud instead scheme here
 	output := &hcloud.PrimaryIPAssignResult{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -264,7 +262,7 @@ func AssignPrimaryIP(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.PrimaryIP.Assign(context.Background(), *hipassignopts)
+	_, response, err := ctx.HClient.PrimaryIP.Assign(ctx.Ctx(), *hipassignopts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -290,12 +288,12 @@ func UnassignPrimaryIP(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &unassignPrimaryIPParameters{}
 	output := &hcloud.PrimaryIPAssignResult{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -314,7 +312,7 @@ func UnassignPrimaryIP(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, errors.Join(fmt.Errorf("cannot use '%v' as int64 ID", input.ID), err)
 	}
 
-	_, response, err := ctx.HClient.PrimaryIP.Unassign(context.Background(), int64id)
+	_, response, err := ctx.HClient.PrimaryIP.Unassign(ctx.Ctx(), int64id)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -23,14 +23,11 @@
 package actors
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"net"
 	"strconv"
 
-	"encoding/json"
-
 	"github.com/develatio/nebulant-cli/base"
 	"github.com/develatio/nebulant-cli/blueprint"
 	"github.com/develatio/nebulant-cli/util"
@@ -212,12 +209,12 @@ func CreateServer(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcServerCreateOptsWrap{}
 	output := &schema.ServerCreateResponse{}
 
-	if err := json.Unmarshal(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -236,7 +233,7 @@ func CreateServer(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Server.Create(context.Background(), *hopts)
+	_, response, err := ctx.HClient.Server.Create(ctx.Ctx(), *hopts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -258,7 +255,7 @@ func CreateServer(ctx *ActionContext) (*base.ActionOutput, error) {
 				}
 				if output.Server.ID == 0 {
 					out := &schema.ActionGetResponse{}
-					_, rsp, err := ctx.HClient.Action.GetByID(context.Background(), output.Action.ID)
+					_, rsp, err := ctx.HClient.Action.GetByID(ctx.Ctx(), output.Action.ID)
 					if err != nil {
 						return nil, err
 					}
@@ -289,12 +286,12 @@ func DeleteServer(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcServerWrap{}
 	output := &schema.ServerDeleteResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -313,7 +310,7 @@ func DeleteServer(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Server.DeleteWithResult(context.Background(), hsrv)
+	_, response, err := ctx.HClient.Server.DeleteWithResult(ctx.Ctx(), hsrv)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -338,7 +335,7 @@ func DeleteServer(ctx *ActionContext) (*base.ActionOutput, error) {
 func FindServers(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.ServerListOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -351,7 +348,7 @@ func FindServers(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Server.List(context.Background(), *input)
+	_, response, err := ctx.HClient.Server.List(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -363,7 +360,7 @@ func FindServers(ctx *ActionContext) (*base.ActionOutput, error) {
 func FindOneServer(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &findOneServerParameters{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -379,7 +376,7 @@ func FindOneServer(ctx *ActionContext) (*base.ActionOutput, error) {
 		if err != nil {
 			return nil, errors.Join(fmt.Errorf("cannot use '%v' as int64 ID", *input.ID), err)
 		}
-		_, response, err := ctx.HClient.Server.GetByID(context.Background(), int64id)
+		_, response, err := ctx.HClient.Server.GetByID(ctx.Ctx(), int64id)
 		if err != nil {
 			return nil, HCloudErrResponse(err, response)
 		}
@@ -428,12 +425,12 @@ func PowerOnServer(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcServerWrap{}
 	output := &schema.ServerActionPoweronResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -452,7 +449,7 @@ func PowerOnServer(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Server.Poweron(context.Background(), hsrv)
+	_, response, err := ctx.HClient.Server.Poweron(ctx.Ctx(), hsrv)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -480,12 +477,12 @@ func PowerOffServer(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcServerWrap{}
 	output := &schema.ServerActionPoweroffResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -504,7 +501,7 @@ func PowerOffServer(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Server.Poweroff(context.Background(), hsrv)
+	_, response, err := ctx.HClient.Server.Poweroff(ctx.Ctx(), hsrv)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -531,12 +528,12 @@ func AttachServerToNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcServerAttachToNetworkOptsWrap{}
 	output := &schema.ServerActionAttachToNetworkResponse{}
 
-	if err := json.Unmarshal(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -560,7 +557,7 @@ func AttachServerToNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Server.AttachToNetwork(context.Background(), hsrv, *opts)
+	_, response, err := ctx.HClient.Server.AttachToNetwork(ctx.Ctx(), hsrv, *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -587,12 +584,12 @@ func DetachServerFromNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcServerDetachFromNetworkOptsWrap{}
 	output := &schema.ServerActionDetachFromNetworkResponse{}
 
-	if err := json.Unmarshal(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -616,7 +613,7 @@ func DetachServerFromNetwork(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Server.DetachFromNetwork(context.Background(), hsrv, *opts)
+	_, response, err := ctx.HClient.Server.DetachFromNetwork(ctx.Ctx(), hsrv, *opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -643,12 +640,12 @@ func CreateImageFromServer(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcServerCreateImageOptsWrap{}
 	output := &schema.ServerActionCreateImageResponse{}
 
-	if err := json.Unmarshal(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -672,7 +669,7 @@ func CreateImageFromServer(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Server.CreateImage(context.Background(), hsrv, opts)
+	_, response, err := ctx.HClient.Server.CreateImage(ctx.Ctx(), hsrv, opts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -23,7 +23,6 @@
 package actors
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -56,7 +55,7 @@ func CreateSSHKey(ctx *ActionContext) (*base.ActionOutput, error) {
 	var err error
 	input := &hcloud.SSHKeyCreateOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -69,7 +68,7 @@ func CreateSSHKey(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.SSHKey.Create(context.Background(), *input)
+	_, response, err := ctx.HClient.SSHKey.Create(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -88,7 +87,7 @@ func DeleteSSHKey(ctx *ActionContext) (*base.ActionOutput, error) {
 	// only SSHKey.ID attr is really used
 	input := &hcSSHKeyWrap{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -106,7 +105,7 @@ func DeleteSSHKey(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	response, err := ctx.HClient.SSHKey.Delete(context.Background(), hsshkey)
+	response, err := ctx.HClient.SSHKey.Delete(ctx.Ctx(), hsshkey)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -118,7 +117,7 @@ func DeleteSSHKey(ctx *ActionContext) (*base.ActionOutput, error) {
 func FindSSHKeys(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.SSHKeyListOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -131,7 +130,7 @@ func FindSSHKeys(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.SSHKey.List(context.Background(), *input)
+	_, response, err := ctx.HClient.SSHKey.List(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
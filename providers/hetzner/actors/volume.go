@@ -23,8 +23,6 @@
 package actors
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -65,12 +63,12 @@ func CreateVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.VolumeCreateOpts{}
 	output := &schema.VolumeCreateResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err = json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err = util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +82,7 @@ func CreateVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Volume.Create(context.Background(), *input)
+	_, response, err := ctx.HClient.Volume.Create(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -112,7 +110,7 @@ func DeleteVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 	// only Volume.ID attr are really used
 	input := &hcVolumeWrap{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -130,7 +128,7 @@ func DeleteVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, err = ctx.HClient.Volume.Delete(context.Background(), hvol)
+	_, err = ctx.HClient.Volume.Delete(ctx.Ctx(), hvol)
 	if err != nil {
 		return nil, err
 	}
@@ -142,7 +140,7 @@ func DeleteVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 func FindVolumes(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcloud.VolumeListOpts{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
@@ -155,7 +153,7 @@ func FindVolumes(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Volume.List(context.Background(), *input)
+	_, response, err := ctx.HClient.Volume.List(ctx.Ctx(), *input)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -194,12 +192,12 @@ func AttachVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &volumeAttachParameters{}
 	output := &schema.VolumeActionAttachVolumeResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -224,7 +222,7 @@ func AttachVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 	}
 	input.AttachOpts.Server = hsrv
 
-	_, response, err := ctx.HClient.Volume.AttachWithOpts(context.Background(), hvol, input.AttachOpts)
+	_, response, err := ctx.HClient.Volume.AttachWithOpts(ctx.Ctx(), hvol, input.AttachOpts)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -251,12 +249,12 @@ func DetachVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 	input := &hcVolumeWrap{}
 	output := &schema.VolumeActionDetachVolumeResponse{}
 
-	if err := util.UnmarshalValidJSON(ctx.Action.Parameters, input); err != nil {
+	if err := util.UnmarshalValidParameters(ctx.Store, ctx.Action.Parameters, input); err != nil {
 		return nil, err
 	}
 
 	internalparams := &blueprint.InternalParameters{}
-	err := json.Unmarshal(ctx.Action.Parameters, internalparams)
+	err := util.UnmarshalParameters(ctx.Store, ctx.Action.Parameters, internalparams)
 	if err != nil {
 		return nil, err
 	}
@@ -275,7 +273,7 @@ func DetachVolume(ctx *ActionContext) (*base.ActionOutput, error) {
 		return nil, err
 	}
 
-	_, response, err := ctx.HClient.Volume.Detach(context.Background(), hvol)
+	_, response, err := ctx.HClient.Volume.Detach(ctx.Ctx(), hvol)
 	if err != nil {
 		return nil, HCloudErrResponse(err, response)
 	}
@@ -35,6 +35,12 @@ import (
 )
 
 func ActionValidator(action *base.Action) error {
+	return ActionRehearsal(action, nil)
+}
+
+// ActionRehearsal func. Run the actor of the action in rehearsal mode.
+// The typed parameters are resolved against store, if any
+func ActionRehearsal(action *base.Action, store base.IStore) error {
 	if action.Provider != "hetznerCloud" {
 		return nil
 	}
@@ -42,17 +48,10 @@ func ActionValidator(action *base.Action) error {
 	if !exists {
 		return fmt.Errorf("hetzner: invalid action name " + action.ActionName)
 	}
-	if al.N == actors.NextOK && len(action.NextAction.NextKo) > 0 {
-		return fmt.Errorf("hetzner: action " + action.ActionName + " has no KO port")
-	}
-
-	if al.N == actors.NextKO && len(action.NextAction.NextOk) > 0 {
-		return fmt.Errorf("hetzner: action " + action.ActionName + " has no OK port")
-	}
-
 	ac := &actors.ActionContext{
 		Rehearsal: true,
 		Action:    action,
+		Store:     store,
 	}
 	_, err := al.F(ac)
 	if err != nil {
@@ -98,11 +97,63 @@ func (p *Provider) HandleAction(actx base.IActionContext) (*base.ActionOutput, e
 
 	if al, exists := actors.ActionFuncMap[action.ActionName]; exists {
 		client := p.store.GetPrivateVar("hetznerClient").(*hcloud.Client)
-		return al.F(actors.NewActionContext(client, action, p.store, p.Logger))
+		actrctx := actors.NewActionContext(client, action, p.store, p.Logger)
+		actrctx.Context = actx.Context()
+		return al.F(actrctx)
 	}
 	return nil, fmt.Errorf("HETZNER: Unknown action: " + action.ActionName)
 }
 
+// CreatedResource func. Report the resource created by the action
+func (p *Provider) CreatedResource(action *base.Action, aout *base.ActionOutput) *base.Resource {
+	rl, exists := actors.ResourceFuncMap[action.ActionName]
+	if !exists || aout == nil || len(aout.Records) <= 0 || aout.Records[0].ValueID == "" {
+		return nil
+	}
+	id := aout.Records[0].ValueID
+	if id == "0" {
+		// the id of the resource could not be determined
+		return nil
+	}
+	params := map[string]interface{}{"ID": id}
+	for k, v := range rl.X {
+		params[k] = v
+	}
+	ra, err := base.NewResourceAction(rl.D, params)
+	if err != nil {
+		return nil
+	}
+	return &base.Resource{
+		Provider:  action.Provider,
+		Type:      rl.Type,
+		ID:        id,
+		CreatedBy: action.ActionID,
+		Teardown:  []*base.ResourceAction{ra},
+	}
+}
+
+// CreatesResource func
+func (p *Provider) CreatesResource(action *base.Action) bool {
+	_, exists := actors.ResourceFuncMap[action.ActionName]
+	return exists
+}
+
+// DeletedResources func. Report the ids of the resources deleted by
+// the action
+func (p *Provider) DeletedResources(action *base.Action) []string {
+	for _, rl := range actors.ResourceFuncMap {
+		if rl.D != action.ActionName {
+			continue
+		}
+		params := string(action.Parameters)
+		if err := p.store.Interpolate(&params); err != nil {
+			return nil
+		}
+		return base.ParameterIDs([]byte(params), "ID")
+	}
+	return nil
+}
+
 // OnActionErrorHook func
 func (p *Provider) OnActionErrorHook(aout *base.ActionOutput) ([]*base.Action, error) {
 
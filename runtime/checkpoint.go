@@ -0,0 +1,356 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/cast"
+	"github.com/develatio/nebulant-cli/config"
+)
+
+// CheckpointVersion const. Bump on incompatible checkpoint changes
+const CheckpointVersion = 1
+
+// ThreadCheckpoint struct. The state of a thread after his last action
+type ThreadCheckpoint struct {
+	// completed action ids, in run order
+	Done []string `json:"done"`
+	// action ids to run next. More than one means a fork
+	Pending []string `json:"pending"`
+	// the action that ended the thread with an unhandled KO.
+	// Also the first pending action
+	Failed  string                        `json:"failed,omitempty"`
+	Records []*base.StorageRecordSnapshot `json:"records"`
+}
+
+// Checkpoint struct. Saved after each action into CheckpointPath(uuid)
+// to allow the resume of failed or stopped executions
+type Checkpoint struct {
+	Version       int       `json:"version"`
+	ExecutionUUID string    `json:"execution_uuid"`
+	Updated       time.Time `json:"updated"`
+	// the values of the secret args are not saved
+	Args []*blueprint.IRBArg `json:"args"`
+	// canonical form of the running blueprint
+	Blueprint json.RawMessage     `json:"blueprint,omitempty"`
+	Threads   []*ThreadCheckpoint `json:"threads"`
+}
+
+// CheckpointsPath func. Dir of the execution checkpoints
+func CheckpointsPath() string {
+	return filepath.Join(config.AppHomePath(), "checkpoints")
+}
+
+// CheckpointPath func
+func CheckpointPath(executionUUID string) string {
+	return filepath.Join(CheckpointsPath(), executionUUID+".json")
+}
+
+// LoadCheckpoint func
+func LoadCheckpoint(executionUUID string) (*Checkpoint, error) {
+	if executionUUID == "" || filepath.Base(executionUUID) != executionUUID {
+		return nil, fmt.Errorf("invalid execution uuid %q", executionUUID)
+	}
+	data, err := os.ReadFile(CheckpointPath(executionUUID)) // #nosec G304 -- own checkpoint dir
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no checkpoint found for execution %s", executionUUID)
+		}
+		return nil, err
+	}
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %v", CheckpointPath(executionUUID), err)
+	}
+	if cp.Version != CheckpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %v", cp.Version)
+	}
+	if len(cp.Threads) <= 0 {
+		return nil, fmt.Errorf("nothing to resume in execution %s", executionUUID)
+	}
+	return cp, nil
+}
+
+// isSecretArg func. True if the value of the arg is not saved
+func isSecretArg(arg *blueprint.IRBArg) bool {
+	return arg.Secret || base.IsSecretName(arg.Name)
+}
+
+// checkpointArgs func. Copy of args without the secret values
+func checkpointArgs(args []*blueprint.IRBArg) []*blueprint.IRBArg {
+	cargs := make([]*blueprint.IRBArg, len(args))
+	for i, arg := range args {
+		carg := *arg
+		if isSecretArg(arg) {
+			carg.Value = nil
+		}
+		cargs[i] = &carg
+	}
+	return cargs
+}
+
+// ResumeArgs func. The saved args and the names of the secret
+// ones, that must be given again to resume the execution
+func (cp *Checkpoint) ResumeArgs() ([]*blueprint.IRBArg, []string) {
+	var args []*blueprint.IRBArg
+	var secrets []string
+	seen := make(map[string]bool)
+	for _, arg := range cp.Args {
+		if !isSecretArg(arg) {
+			args = append(args, arg)
+			continue
+		}
+		if !seen[arg.Name] {
+			seen[arg.Name] = true
+			secrets = append(secrets, arg.Name)
+		}
+	}
+	return args, secrets
+}
+
+// checkpointer struct. Keeps the last state of every
+// thread and writes the checkpoint file on changes
+type checkpointer struct {
+	mu      sync.Mutex
+	path    string
+	cp      *Checkpoint
+	threads map[*Thread]*ThreadCheckpoint
+	// threads in start order, to keep the file stable
+	order []*Thread
+	err   error
+}
+
+func newCheckpointer(irb *blueprint.IRBlueprint) *checkpointer {
+	if irb.ExecutionUUID == nil || *irb.ExecutionUUID == "" || irb.Teardown {
+		return nil
+	}
+	cp := &Checkpoint{
+		Version:       CheckpointVersion,
+		ExecutionUUID: *irb.ExecutionUUID,
+		Args:          checkpointArgs(irb.Args),
+	}
+	if irb.BP != nil {
+		if raw, err := irb.BP.Canonical(); err == nil {
+			cp.Blueprint = raw
+		}
+	}
+	return &checkpointer{
+		path:    CheckpointPath(*irb.ExecutionUUID),
+		cp:      cp,
+		threads: make(map[*Thread]*ThreadCheckpoint),
+	}
+}
+
+// update func. Store the state of th. If keep is false, the
+// thread is removed from the checkpoint (finished ok)
+func (c *checkpointer) update(th *Thread, tcp *ThreadCheckpoint, keep bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.threads[th]; !exists && keep {
+		c.order = append(c.order, th)
+	}
+	if keep {
+		c.threads[th] = tcp
+	} else {
+		delete(c.threads, th)
+	}
+	c.write()
+}
+
+// write func. Called with the lock held
+func (c *checkpointer) write() {
+	c.cp.Threads = c.cp.Threads[:0]
+	var order []*Thread
+	for _, th := range c.order {
+		if tcp, exists := c.threads[th]; exists {
+			c.cp.Threads = append(c.cp.Threads, tcp)
+			order = append(order, th)
+		}
+	}
+	c.order = order
+	c.cp.Updated = time.Now().UTC()
+
+	err := func() error {
+		if len(c.cp.Threads) <= 0 {
+			// nothing to resume
+			if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}
+		return writeJSONFile(c.path, c.cp)
+	}()
+	if err != nil && c.err == nil {
+		// warn once
+		c.err = err
+		cast.LogWarn(fmt.Sprintf("cannot save execution checkpoint: %v", err), &c.cp.ExecutionUUID)
+	}
+}
+
+// writeJSONFile func. Write v to path through a temp file, so
+// readers never see a partial write
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// resumable func. True if the checkpoint has threads to resume
+func (c *checkpointer) resumable() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.threads) > 0 && c.err == nil
+}
+
+// threadCheckpoint func. Build the checkpoint of the thread. Should be
+// called from the thread goroutine, between actions
+func (t *Thread) threadCheckpoint() (*ThreadCheckpoint, bool, error) {
+	tcp := &ThreadCheckpoint{}
+	for _, actx := range t.done {
+		if actx.Type() != base.ContextTypeRegular && actx.Type() != base.ContextTypeJoin {
+			continue
+		}
+		tcp.Done = append(tcp.Done, actx.GetAction().ActionID)
+	}
+
+	var st base.IStore
+	if t.ExitErr != nil {
+		// uncaught KO, the last run action is the failed one
+		if last := t.GetLastRun(); last != nil {
+			st = last.GetStore()
+			tcp.Failed = last.GetAction().ActionID
+			tcp.Pending = append(tcp.Pending, tcp.Failed)
+			if n := len(tcp.Done); n > 0 && tcp.Done[n-1] == tcp.Failed {
+				tcp.Done = tcp.Done[:n-1]
+			}
+		}
+	} else if t.current != nil {
+		st = t.current.GetStore()
+		if t.current.GetRunStatus() != base.RunStatusDone {
+			// loaded, but not run yet
+			tcp.Pending = append(tcp.Pending, t.current.GetAction().ActionID)
+		}
+	}
+	for _, actx := range t.queue {
+		if st == nil {
+			st = actx.GetStore()
+		}
+		if actx.IsThreadPoint() {
+			for _, child := range actx.Children() {
+				tcp.Pending = append(tcp.Pending, child.GetAction().ActionID)
+			}
+			continue
+		}
+		tcp.Pending = append(tcp.Pending, actx.GetAction().ActionID)
+	}
+
+	if len(tcp.Pending) <= 0 {
+		return nil, false, nil
+	}
+	if st != nil {
+		records, err := st.Snapshot()
+		if err != nil {
+			return nil, false, err
+		}
+		tcp.Records = records
+	}
+	return tcp, true, nil
+}
+
+// saveCheckpoint func. Update the checkpoint with the current
+// state of th. Threads of called blueprints are not saved, the
+// caller action will be re-run on resume
+func (r *Runtime) saveCheckpoint(th *Thread) {
+	if r.checkpoint == nil || th.sub != nil {
+		return
+	}
+	if th.cancelled.Load() {
+		// nothing to resume in a canceled branch
+		r.checkpoint.update(th, nil, false)
+		return
+	}
+	tcp, keep, err := th.threadCheckpoint()
+	if err != nil {
+		cast.LogWarn(fmt.Sprintf("cannot save execution checkpoint: %v", err), r.irb.ExecutionUUID)
+		return
+	}
+	r.checkpoint.update(th, tcp, keep)
+}
+
+// ResumeThreads func. Start a thread for every pending action of the
+// checkpoint. newStore should return a new initialized root store
+func (r *Runtime) ResumeThreads(cp *Checkpoint, newStore func() (base.IStore, error)) error {
+	type resumeThread struct {
+		action *base.Action
+		store  base.IStore
+	}
+	var starts []*resumeThread
+	for _, tcp := range cp.Threads {
+		for _, actionID := range tcp.Pending {
+			action, exists := r.irb.Actions[actionID]
+			if !exists {
+				return fmt.Errorf("cannot resume: action %s not found in the blueprint", actionID)
+			}
+			st, err := newStore()
+			if err != nil {
+				return err
+			}
+			if err := st.Restore(tcp.Records, r.irb.Actions); err != nil {
+				return err
+			}
+			starts = append(starts, &resumeThread{action: action, store: st})
+		}
+		if tcp.Failed != "" {
+			cast.LogInfo(fmt.Sprintf("Resuming from failed action %s (%v actions already done)", tcp.Failed, len(tcp.Done)), r.irb.ExecutionUUID)
+		}
+	}
+	if len(starts) <= 0 {
+		return fmt.Errorf("nothing to resume in execution %s", cp.ExecutionUUID)
+	}
+	actxs := make([]base.IActionContext, len(starts))
+	for i, rt := range starts {
+		actxs[i] = r.NewAContext(nil, rt.action)
+		actxs[i].SetStore(rt.store)
+	}
+	if !r.newThreads(actxs) {
+		return fmt.Errorf("cannot resume, the runtime is stopping")
+	}
+	return nil
+}
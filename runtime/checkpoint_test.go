@@ -0,0 +1,141 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+const testCheckpointBP = `
+actions:
+  - id: a
+    provider: test
+    action: run
+    first: true
+    parameters: {value: "hello {{ NAME }}"}
+    output: out_a
+    next: {ok: [b]}
+  - id: b
+    provider: test
+    action: run
+    parameters: {fail: %v, value: "{{ out_a }} {{ password }}"}
+    next: {ok: [c]}
+  - id: c
+    provider: test
+    action: run
+`
+
+func newCheckpointIRB(t *testing.T, fail bool, args []*blueprint.IRBArg) *blueprint.IRBlueprint {
+	t.Helper()
+	irb := newTestIRB(t, fmt.Sprintf(testCheckpointBP, fail))
+	uuid := "test-checkpoint"
+	irb.ExecutionUUID = &uuid
+	irb.Args = args
+	return irb
+}
+
+// a failed execution is resumed from his failed action,
+// with the stores of the checkpoint
+func TestCheckpointResume(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	args := []*blueprint.IRBArg{
+		{Name: "NAME", Value: "world"},
+		{Name: "password", Value: "s3cret"},
+	}
+	irb := newCheckpointIRB(t, true, args)
+	startTestIRB(t, irb, func(r *Runtime) error {
+		st := newTestStore(r)
+		if err := LoadArgs(st, irb.Args); err != nil {
+			return err
+		}
+		actx := r.NewAContext(nil, irb.StartAction)
+		actx.SetStore(st)
+		if !r.NewThread(actx) {
+			return fmt.Errorf("cannot start the runtime")
+		}
+		return nil
+	})
+	if countRuns("a") != 1 || countRuns("b") != 1 || countRuns("c") != 0 {
+		t.Fatalf("unexpected runs a=%d b=%d c=%d", countRuns("a"), countRuns("b"), countRuns("c"))
+	}
+
+	data, err := os.ReadFile(CheckpointPath("test-checkpoint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Error("the secret arg is saved into the checkpoint")
+	}
+	cp, err := LoadCheckpoint("test-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Threads) != 1 || cp.Threads[0].Failed != "b" || strings.Join(cp.Threads[0].Done, ",") != "a" {
+		t.Fatalf("unexpected checkpoint threads %+v", cp.Threads)
+	}
+	cpArgs, secrets := cp.ResumeArgs()
+	if len(cpArgs) != 1 || cpArgs[0].Name != "NAME" || cpArgs[0].Value != "world" {
+		t.Errorf("unexpected resume args %+v", cpArgs)
+	}
+	if len(secrets) != 1 || secrets[0] != "password" {
+		t.Fatalf("expected password to be given again, got %v", secrets)
+	}
+
+	// the secret arg is not given again
+	irb = newCheckpointIRB(t, false, cpArgs)
+	r := NewRuntime(irb, false)
+	err = r.ResumeThreads(cp, func() (base.IStore, error) {
+		st := newTestStore(r)
+		return st, LoadArgs(st, irb.Args)
+	})
+	if err == nil || !strings.Contains(err.Error(), "password") {
+		t.Fatalf("expected missing secret error, got %v", err)
+	}
+
+	irb = newCheckpointIRB(t, false, append(cpArgs, &blueprint.IRBArg{Name: "password", Value: "s3cret"}))
+	startTestIRB(t, irb, func(r *Runtime) error {
+		return r.ResumeThreads(cp, func() (base.IStore, error) {
+			st := newTestStore(r)
+			return st, LoadArgs(st, irb.Args)
+		})
+	})
+	if countRuns("a") != 0 {
+		t.Error("the done action a has been run again")
+	}
+	b := getRuns("b")
+	if len(b) != 1 || b[0].value != "hello world s3cret" {
+		t.Errorf("unexpected runs of b %+v", b)
+	}
+	if countRuns("c") != 1 {
+		t.Errorf("expected c to run once, got %d", countRuns("c"))
+	}
+	if _, err := os.Stat(CheckpointPath("test-checkpoint")); !os.IsNotExist(err) {
+		t.Errorf("the checkpoint was not removed after the resumed run: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/cast"
+)
+
+const (
+	ExitStatusSuccess = "success"
+	ExitStatusFailure = "failure"
+	ExitStatusTimeout = "timeout"
+	ExitStatusStopped = "stopped"
+)
+
+// cleanup struct. The state of the cleanup chains: the finally edges
+// of the groups that ran and the on_exit action of the blueprint. They
+// run once the main graph ends (ok, ko, timeout or stop)
+type cleanup struct {
+	// store of the first thread, base of the cleanup store
+	root base.IStore
+	// last store of every finished thread
+	stores []base.IStore
+	// groups with finally edges that ran, in run order
+	groups []*base.Action
+	// set once the cleanup starts
+	running atomic.Bool
+	done    bool
+}
+
+// pending func. The cleanup is running
+func (c *cleanup) pending() bool {
+	return c.running.Load() && !c.done
+}
+
+// hasCleanup func. Called with the runtime lock held
+func (r *Runtime) hasCleanup() bool {
+	if r.irb.OnExit != nil || len(r.cleanup.groups) > 0 {
+		return true
+	}
+	return r.irb.RollbackOnFailure && r.ledger != nil && len(r.ledger.live()) > 0
+}
+
+// enterGroup func. Register the finally edges of the group into
+// the cleanup of the runtime or of the called blueprint of sub
+func (r *Runtime) enterGroup(sub *subRun, action *base.Action) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	groups := &r.cleanup.groups
+	if sub != nil && sub.bp != nil {
+		groups = &sub.bp.groups
+	}
+	for _, g := range *groups {
+		if g == action {
+			return
+		}
+	}
+	*groups = append(*groups, action)
+}
+
+// exitStatus func. Called with the runtime lock held
+func (r *Runtime) exitStatus() string {
+	var terr *base.TimeoutError
+	switch {
+	case errors.As(context.Cause(r.ctx), &terr) && terr.Global:
+		return ExitStatusTimeout
+	case r.stopRequested:
+		return ExitStatusStopped
+	case r.exitCode > 0:
+		return ExitStatusFailure
+	}
+	return ExitStatusSuccess
+}
+
+// startCleanup func. Start the cleanup chains if there are any and
+// they have not run yet. With rollback on failure, the resources of
+// a failed execution are deleted at the end. Called with the runtime
+// lock held
+func (r *Runtime) startCleanup() bool {
+	if r.cleanup.running.Load() || !r.hasCleanup() || r.cleanup.root == nil {
+		return false
+	}
+	status := r.exitStatus()
+
+	// finally edges of the last entered group first
+	var chains []*base.Action
+	for i := len(r.cleanup.groups) - 1; i >= 0; i-- {
+		chains = append(chains, r.cleanup.groups[i].NextAction.NextFinally...)
+	}
+	if r.irb.OnExit != nil {
+		chains = append(chains, r.irb.OnExit)
+	}
+	// the rollback goes last, cleanup chains could
+	// still need the resources
+	if r.irb.RollbackOnFailure && status != ExitStatusSuccess {
+		if start := r.rollbackChain(); start != nil {
+			chains = append(chains, start)
+		}
+	}
+	if len(chains) <= 0 {
+		return false
+	}
+	r.cleanup.running.Store(true)
+
+	st := r.cleanup.root.Duplicate()
+	for _, s := range r.cleanup.stores {
+		st.Merge(s)
+	}
+	st.SetPrivateVar("EXIT_STATUS", status)
+
+	go r.runCleanup(chains, st, status)
+	return true
+}
+
+// runCleanup func. Run the chains one after another, even if
+// some of them fail. The runtime ends after the last one
+func (r *Runtime) runCleanup(chains []*base.Action, st base.IStore, status string) {
+	cast.LogInfo(fmt.Sprintf("Running cleanup actions (exit status: %s)...", status), r.irb.ExecutionUUID)
+	for _, start := range chains {
+		sub := &subRun{threads: make(map[*Thread]bool), done: make(chan struct{})}
+		if _, err := r.runSub(sub, start, st); err != nil {
+			r.mu.Lock()
+			r.exitCode = r.exitCode + 1
+			r.exitErrs = append(r.exitErrs, fmt.Errorf("cleanup %s failed: %w", start.ActionID, err))
+			r.mu.Unlock()
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cleanup.done = true
+	if len(r.activeThreads) <= 0 {
+		r.end()
+	}
+}
+
+// parentCtx func. The parent of the action contexts of the threads
+// of sub. Cleanup actions run even if the global deadline has been
+// reached
+func (r *Runtime) parentCtx(sub *subRun) context.Context {
+	if sub != nil && sub.ctx != nil {
+		return sub.ctx
+	}
+	if r.cleanup.running.Load() {
+		return context.WithoutCancel(r.ctx)
+	}
+	return r.ctx
+}
+
+// lastStore func. The store of the last action run by the thread
+func (t *Thread) lastStore() base.IStore {
+	if last := t.GetLastRun(); last != nil {
+		return last.GetStore()
+	}
+	if t.current != nil {
+		return t.current.GetStore()
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// a: the first action, inside the groups g1 and g2
+const testCleanupBP = `
+on_exit: x
+actions:
+  - id: g1
+    provider: generic
+    action: group
+    first: true
+    next: {ok: [g2], finally: [f1]}
+  - id: g2
+    provider: generic
+    action: group
+    next: {ok: [a], finally: [f2]}
+  - id: a
+    provider: test
+    action: run
+    parameters: %s
+  - id: f1
+    provider: test
+    action: run
+    parameters: {value: "{{ runtime.exit_status }}"}
+  - id: f2
+    provider: test
+    action: run
+    parameters: {value: "{{ runtime.exit_status }}"}
+  - id: x
+    provider: test
+    action: run
+    parameters: {value: "{{ runtime.exit_status }}"}
+`
+
+// checkCleanup func. The finally chains ran from the last entered
+// group to the first one, then the on_exit chain, all of them
+// seeing status as the exit status
+func checkCleanup(t *testing.T, status string) {
+	t.Helper()
+	var prev *testRun
+	for _, id := range []string{"f2", "f1", "x"} {
+		runs := getRuns(id)
+		if len(runs) != 1 {
+			t.Errorf("expected %s to run once, got %d", id, len(runs))
+			return
+		}
+		if runs[0].value != status {
+			t.Errorf("%s: expected exit status %q, got %q", id, status, runs[0].value)
+		}
+		if prev != nil && runs[0].start.Before(prev.end) {
+			t.Errorf("%s started before the previous cleanup chain ended", id)
+		}
+		prev = runs[0]
+	}
+}
+
+func TestCleanupSuccess(t *testing.T) {
+	r := runTestBlueprint(t, fmt.Sprintf(testCleanupBP, "{}"))
+	checkCleanup(t, ExitStatusSuccess)
+	if r.ExitCode() != 0 {
+		t.Errorf("expected exit code 0, got %d", r.ExitCode())
+	}
+}
+
+func TestCleanupKO(t *testing.T) {
+	r := runTestBlueprint(t, fmt.Sprintf(testCleanupBP, "{fail: true}"))
+	checkCleanup(t, ExitStatusFailure)
+	if r.ExitCode() == 0 {
+		t.Error("the cleanup reset the exit code of the failed execution")
+	}
+}
+
+func TestCleanupTimeout(t *testing.T) {
+	irb := newTestIRB(t, fmt.Sprintf(testCleanupBP, "{sleep: 5s}"))
+	irb.Timeout = 300 * time.Millisecond
+	r := runTestIRB(t, irb)
+	checkCleanup(t, ExitStatusTimeout)
+	if r.ExitCode() == 0 {
+		t.Error("expected a failed exit code after the global timeout")
+	}
+}
+
+func TestCleanupStop(t *testing.T) {
+	irb := newTestIRB(t, fmt.Sprintf(testCleanupBP, "{sleep: 1s}"))
+	startTestIRB(t, irb, func(r *Runtime) error {
+		actx := r.NewAContext(nil, irb.StartAction)
+		actx.SetStore(newTestStore(r))
+		if !r.NewThread(actx) {
+			return fmt.Errorf("cannot start the runtime")
+		}
+		time.AfterFunc(300*time.Millisecond, r.Stop)
+		return nil
+	})
+	checkCleanup(t, ExitStatusStopped)
+}
@@ -25,13 +25,18 @@ package runtime
 import (
 	"context"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/develatio/nebulant-cli/base"
 	"github.com/develatio/nsterm"
 )
 
 type actionContext struct {
-	_dbgname  string
+	_dbgname string
+	// guards ctx and cancel, the action can be
+	// canceled from other goroutines
+	mu        sync.Mutex
 	ctx       context.Context
 	runStatus base.ActionContextRunStatus
 	cancel    func(error)
@@ -48,6 +53,8 @@ type actionContext struct {
 }
 
 func (a *actionContext) Done() <-chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	if a.ctx == nil {
 		return nil
 	}
@@ -62,15 +69,42 @@ func (a *actionContext) EventListener() *base.EventListener {
 	return a.elistener
 }
 
-func (a *actionContext) WithCancelCause() {
-	ctx, cancel := context.WithCancelCause(context.Background())
-	a.ctx = ctx
+func (a *actionContext) Context() context.Context {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.ctx == nil {
+		return context.Background()
+	}
+	return a.ctx
+}
+
+// WithCancelCause func. Init the action context from parent. If
+// timeout > 0, the context is canceled with cause after timeout
+func (a *actionContext) WithCancelCause(parent context.Context, timeout time.Duration, cause error) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ctx, cancel := context.WithCancelCause(parent)
 	a.cancel = cancel
+	if timeout > 0 {
+		var tcancel context.CancelFunc
+		ctx, tcancel = context.WithTimeoutCause(ctx, timeout, cause)
+		a.cancel = func(e error) {
+			cancel(e)
+			tcancel()
+		}
+	}
+	a.ctx = ctx
 }
 
 func (a *actionContext) Cancel(e error) {
-	if a.cancel != nil {
-		a.cancel(e)
+	a.mu.Lock()
+	cancel := a.cancel
+	a.mu.Unlock()
+	if cancel != nil {
+		cancel(e)
 	}
 }
 
@@ -23,9 +23,13 @@
 package runtime
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/cast"
 )
 
 // actioncontext type
@@ -104,11 +108,135 @@ func (j *joinPointContext) EventListener() *base.EventListener {
 	return j.elistener
 }
 
-func (j *joinPointContext) WithCancelCause() {}
-func (j *joinPointContext) Cancel(e error)   {}
+func (j *joinPointContext) Context() context.Context                              { return context.Background() }
+func (j *joinPointContext) WithCancelCause(context.Context, time.Duration, error) {}
+func (j *joinPointContext) Cancel(e error)                                        {}
 
 func (j *joinPointContext) WithDebugInitFunc(f func()) {}
 func (j *joinPointContext) DebugInit()                 {}
 
 func (j *joinPointContext) GetSluvaFD() io.ReadWriteCloser  { return nil }
 func (j *joinPointContext) GetMustarFD() io.ReadWriteCloser { return nil }
+
+// waitJoin func. Block the thread of the join point until the branches
+// needed by the join policy arrive, without polling: the join point is
+// signaled on every arrival and on the end of every action. The branches
+// still running are canceled once the join goes on. Returns a
+// *base.TimeoutError on timeout
+func (r *Runtime) waitJoin(t *Thread, actx base.IActionContext, wake <-chan struct{}) error {
+	action := actx.GetAction()
+	needed := action.JoinPolicy.Needed()
+
+	var timeout <-chan time.Time
+	if d := action.Timeout.Duration(); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	// joins of the cleanup chains run after stop
+	stopped := r.stopped
+	if r.cleanup.running.Load() {
+		stopped = nil
+	}
+
+	var err error
+	cancel := true
+wait:
+	for {
+		if err = r.cjoiner.Err(actx); err != nil {
+			break
+		}
+		arrived := r.cjoiner.Arrived(actx)
+		if needed > 0 && arrived >= needed {
+			break
+		}
+		if !r.hasRunningParents(actx) {
+			if needed > 0 {
+				err = fmt.Errorf("only %d of the %d branches needed by join %s arrived", arrived, needed, action.ActionID)
+			}
+			break
+		}
+		select {
+		case <-wake:
+		case <-timeout:
+			err = &base.TimeoutError{Timeout: action.Timeout.Duration()}
+			break wait
+		case <-stopped:
+			// the runtime stops every thread
+			cancel = false
+			break wait
+		}
+	}
+
+	// discard the branches arriving from now on
+	r.cjoiner.Done(actx)
+	if cancel {
+		r.cancelBranches(t, actx)
+	}
+	// the join point is kept as a tombstone until every branch
+	// ends, a late branch would start the join again otherwise
+	go r.releaseJoin(t, actx, wake, stopped)
+	return err
+}
+
+// releaseJoin func. Free the join point once no branch can arrive
+// to it, to allow the join to happen again (loops, called
+// blueprints...)
+func (r *Runtime) releaseJoin(t *Thread, actx base.IActionContext, wake <-chan struct{}, stopped <-chan struct{}) {
+	defer r.cjoiner.Destroy(actx)
+	for r.hasRunningParents(actx) || r.hasPendingJoin(t, actx) {
+		select {
+		case <-wake:
+		case <-stopped:
+			return
+		}
+	}
+}
+
+// hasPendingJoin func. Tells if a thread other than t is
+// about to arrive to the join point of actx
+func (r *Runtime) hasPendingJoin(t *Thread, actx base.IActionContext) bool {
+	ids := map[string]bool{actx.GetAction().ActionID: true}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for th := range r.activeThreads {
+		if th != t && th.pendingAny(ids) {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelBranches func. Cancel the threads still running
+// the branches of the join point
+func (r *Runtime) cancelBranches(t *Thread, actx base.IActionContext) {
+	action := actx.GetAction()
+	cause := fmt.Errorf("branch canceled by join %s", action.ActionID)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for th := range r.activeThreads {
+		if th == t || th.sub != t.sub || th.cancelled.Load() {
+			continue
+		}
+		// the first queued action if not started yet
+		cur := th.head()
+		if cur == nil {
+			continue
+		}
+		id := cur.GetAction().ActionID
+		if id == action.ActionID {
+			// branch heading to the join point
+			th.cancel(cause)
+			continue
+		}
+		if !action.KnowParentIDs[id] {
+			continue
+		}
+		th.cancel(cause)
+		count++
+	}
+	if count > 0 {
+		cast.LogInfo(fmt.Sprintf("Join %s: %d running branches canceled", action.ActionID, count), r.irb.ExecutionUUID)
+	}
+}
@@ -0,0 +1,215 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/cast"
+	"github.com/develatio/nebulant-cli/storage"
+)
+
+const testJoinBP = `
+actions:
+  - id: s
+    provider: test
+    action: run
+    first: true
+    next: {ok: [a, b, c]}
+  - id: a
+    provider: test
+    action: run
+    output: A
+    parameters: {sleep: %s, value: a}
+    next: {ok: [j]}
+  - id: b
+    provider: test
+    action: run
+    output: B
+    parameters: {sleep: %s, value: b}
+    next: {ok: [j]}
+  - id: c
+    provider: test
+    action: run
+    output: C
+    parameters: {sleep: %s, value: c}
+    next: {ok: [j]}
+  - id: j
+    provider: generic
+    action: join_threads
+    parameters: %s
+    timeout: %s
+    next: {ok: [d], ko: [e]}
+  - id: d
+    provider: test
+    action: run
+    parameters: {value: "{{ A ?? \"-\" }}{{ B ?? \"-\" }}{{ C ?? \"-\" }}"}
+  - id: e
+    provider: test
+    action: run
+`
+
+func joinBP(a, b, c, params, timeout string) string {
+	return fmt.Sprintf(testJoinBP, a, b, c, params, timeout)
+}
+
+func TestJoinAll(t *testing.T) {
+	r := runTestBlueprint(t, joinBP("0s", "50ms", "100ms", "{mode: all}", "0s"))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	runs := getRuns("d")
+	if len(runs) != 1 {
+		t.Fatalf("expected one run after the join, got %d", len(runs))
+	}
+	if runs[0].value != "abc" {
+		t.Errorf("expected the outputs of every branch after the join, got %s", runs[0].value)
+	}
+}
+
+func TestJoinAny(t *testing.T) {
+	r := runTestBlueprint(t, joinBP("0s", "5s", "5s", "{mode: any}", "0s"))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	runs := getRuns("d")
+	if len(runs) != 1 {
+		t.Fatalf("expected one run after the join, got %d", len(runs))
+	}
+	if runs[0].value != "a--" {
+		t.Errorf("expected only the output of the first branch, got %s", runs[0].value)
+	}
+	for _, id := range []string{"b", "c"} {
+		assertCanceled(t, r, id)
+	}
+}
+
+// assertCanceled func. The branch of actionID has been canceled
+// while running or before starting
+func assertCanceled(t *testing.T, r *Runtime, actionID string) {
+	t.Helper()
+	for _, st := range reportStatus(r, actionID) {
+		if st != ActionStatusCanceled {
+			t.Errorf("expected branch %s canceled, got %s", actionID, st)
+		}
+	}
+}
+
+func TestJoinAnyLateBranches(t *testing.T) {
+	// every branch arrives almost at once, the late
+	// ones must not run the join again
+	for i := 0; i < 20; i++ {
+		r := runTestBlueprint(t, joinBP("0s", "0s", "0s", "{mode: any}", "0s"))
+		if r.ExitCode() != 0 {
+			t.Fatal(r.Error())
+		}
+		if n := countRuns("d"); n != 1 {
+			t.Fatalf("expected one run after the join, got %d", n)
+		}
+	}
+}
+
+func TestJoinCount(t *testing.T) {
+	r := runTestBlueprint(t, joinBP("0s", "50ms", "5s", "{count: 2}", "0s"))
+	if r.ExitCode() != 0 {
+		t.Fatal(r.Error())
+	}
+	runs := getRuns("d")
+	if len(runs) != 1 {
+		t.Fatalf("expected one run after the join, got %d", len(runs))
+	}
+	if runs[0].value != "ab-" {
+		t.Errorf("expected the outputs of the two first branches, got %s", runs[0].value)
+	}
+	assertCanceled(t, r, "c")
+}
+
+func TestJoinTimeout(t *testing.T) {
+	start := time.Now()
+	r := runTestBlueprint(t, joinBP("0s", "0s", "5s", "{mode: all}", "100ms"))
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("the join did not time out, the run took %v", elapsed)
+	}
+	if n := countRuns("d"); n != 0 {
+		t.Errorf("expected no run through the OK port, got %d", n)
+	}
+	if n := countRuns("e"); n != 1 {
+		t.Errorf("expected one run through the KO port, got %d", n)
+	}
+	if st := reportStatus(r, "j"); len(st) != 1 || st[0] != ActionStatusKO {
+		t.Errorf("expected the join KO, got %v", st)
+	}
+	assertCanceled(t, r, "c")
+}
+
+func TestJoinTombstone(t *testing.T) {
+	irb := newTestIRB(t, joinBP("0s", "0s", "0s", "{mode: any}", "0s"))
+	r := NewRuntime(irb, false)
+	st := storage.NewStore()
+	st.SetLogger(&cast.Logger{})
+	actxA := r.NewAContext(nil, irb.Actions["a"])
+	actxA.SetStore(st)
+	actxB := r.NewAContext(nil, irb.Actions["b"])
+	actxB.SetStore(st.Duplicate())
+
+	// the first branch arrives
+	jactxA := r.NewAContext(actxA, irb.Actions["j"])
+	wake := r.cjoiner.Join(jactxA)
+	if wake == nil {
+		t.Fatal("the first branch should handle the join point")
+	}
+	th := &Thread{runtime: r}
+	r.activeThreads[th] = true
+	// the second branch is heading to the join point
+	jactxB := r.NewAContext(actxB, irb.Actions["j"])
+	jactxB.SetRunStatus(base.RunStatusArranging)
+	late := &Thread{runtime: r, current: jactxB}
+	r.activeThreads[late] = true
+
+	if err := r.waitJoin(th, jactxA, wake); err != nil {
+		t.Fatal(err)
+	}
+	if !late.cancelled.Load() {
+		t.Error("expected the branch heading to the join point canceled")
+	}
+	if r.cjoiner.Join(jactxB) != nil {
+		t.Fatal("a late branch started the join again")
+	}
+
+	// the late branch ends, the join point is freed
+	jactxB.SetRunStatus(base.RunStatusDone)
+	r.finishThread(late)
+	deadline := time.Now().Add(time.Second)
+	for r.cjoiner.Arrived(jactxA) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the join point was not freed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if r.cjoiner.Join(jactxA) == nil {
+		t.Error("the join should happen again once every branch ends")
+	}
+}
@@ -23,14 +23,22 @@
 package runtime
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/develatio/nebulant-cli/base"
 )
 
 type contextJoinerPoint struct {
+	// signaled on arrivals and on the end of parent actions
 	t chan struct{}
 	p base.IActionContext
+	// branches arrived, including the first one
+	arrived int
+	// the join is going on, late branches are discarded
+	done bool
+	// merge of the store of a branch failed
+	err error
 }
 
 type contextJoiner struct {
@@ -46,11 +54,17 @@ func (j *contextJoiner) Unlock() {
 	j.mu.Unlock()
 }
 
+// Join func. Register the arrival of a branch. The first one gets the
+// channel to wait for the others, the store of the others is merged
+// into the join point and nil is returned
 func (j *contextJoiner) Join(actx base.IActionContext) chan struct{} {
 	j.mu.Lock()
 	defer j.mu.Unlock()
 	action := actx.GetAction()
 	if jpoint, exists := j.pt[action.ActionID]; exists {
+		if jpoint.done {
+			return nil
+		}
 		// jpctx := jpoint.p
 		// If all goes ok, actx should has one or zero parents
 		// because the merge should be done here
@@ -59,17 +73,74 @@ func (j *contextJoiner) Join(actx base.IActionContext) chan struct{} {
 		if len(prs) > 1 {
 			panic("hey dev, this is your fault :*")
 		}
-		jpoint.p.GetStore().Merge(actx.GetStore())
+		store := jpoint.p.GetStore()
+		conflicts, err := store.MergeBranch(actx.GetStore(), action.JoinPolicy.MergeStrategy())
+		for _, c := range conflicts {
+			store.GetLogger().LogWarn(fmt.Sprintf("Join %s: output %s written by %s and %s in different branches (merge: %s)", action.ActionID, c.RefName, c.ActionID, c.SourceActionID, action.JoinPolicy.MergeStrategy()))
+		}
+		if err != nil && jpoint.err == nil {
+			jpoint.err = err
+		}
+		jpoint.arrived++
+		j.signal(jpoint)
 		return nil
 	}
-	ticker := make(chan struct{})
+	ticker := make(chan struct{}, 1)
 	j.pt[action.ActionID] = &contextJoinerPoint{
-		t: ticker,
-		p: actx,
+		t:       ticker,
+		p:       actx,
+		arrived: 1,
 	}
 	return ticker
 }
 
+// Arrived func. The number of branches arrived to the join point
+func (j *contextJoiner) Arrived(actx base.IActionContext) int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if jpoint, exists := j.pt[actx.GetAction().ActionID]; exists {
+		return jpoint.arrived
+	}
+	return 0
+}
+
+// Err func. The error merging the store of the branches, if any
+func (j *contextJoiner) Err(actx base.IActionContext) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if jpoint, exists := j.pt[actx.GetAction().ActionID]; exists {
+		return jpoint.err
+	}
+	return nil
+}
+
+// Done func. Discard the branches arriving from now on
+func (j *contextJoiner) Done(actx base.IActionContext) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if jpoint, exists := j.pt[actx.GetAction().ActionID]; exists {
+		jpoint.done = true
+	}
+}
+
+// Wake func. Make the join points check their branches again
+func (j *contextJoiner) Wake() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, jpoint := range j.pt {
+		j.signal(jpoint)
+	}
+}
+
+// signal func. Called with the lock held
+func (j *contextJoiner) signal(jpoint *contextJoinerPoint) {
+	select {
+	case jpoint.t <- struct{}{}:
+	default:
+		// already signaled
+	}
+}
+
 func (j *contextJoiner) Destroy(actx base.IActionContext) {
 	j.mu.Lock()
 	defer j.mu.Unlock()
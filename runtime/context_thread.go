@@ -25,9 +25,11 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"math/rand"
+	"time"
 
 	"github.com/develatio/nebulant-cli/base"
 )
@@ -109,8 +111,9 @@ func (t *threadPointContext) EventListener() *base.EventListener {
 	return t.elistener
 }
 
-func (t *threadPointContext) WithCancelCause() {}
-func (t *threadPointContext) Cancel(e error)   {}
+func (t *threadPointContext) Context() context.Context                              { return context.Background() }
+func (t *threadPointContext) WithCancelCause(context.Context, time.Duration, error) {}
+func (t *threadPointContext) Cancel(e error)                                        {}
 
 func (t *threadPointContext) WithDebugInitFunc(f func())      {}
 func (t *threadPointContext) DebugInit()                      {}
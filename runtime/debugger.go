@@ -853,7 +853,7 @@ func (d *debugger) ExecCmd(cc *client, cmd string) {
 
 		pv := cc.space.store.GetPrivateVar(path)
 		if pv != nil {
-			fmt.Fprintf(clientFD, "%s", pv)
+			fmt.Fprint(clientFD, base.Redact(fmt.Sprintf("%s", pv)))
 			fmt.Fprintf(clientFD, "\n")
 			return
 		}
@@ -877,11 +877,11 @@ func (d *debugger) ExecCmd(cc *client, cmd string) {
 				ppp := fmt.Sprintf("{{%s}}", strings.Join(aa, "."))
 				err := actxstore.Interpolate(&ppp)
 				if err != nil {
-					fmt.Fprint(clientFD, err.Error())
+					fmt.Fprint(clientFD, base.Redact(err.Error()))
 					fmt.Fprintf(clientFD, "\n")
 					return
 				}
-				fmt.Fprint(clientFD, ppp)
+				fmt.Fprint(clientFD, base.Redact(ppp))
 				fmt.Fprintf(clientFD, "\n")
 				return
 			}
@@ -889,18 +889,18 @@ func (d *debugger) ExecCmd(cc *client, cmd string) {
 			if aa[0] == fmt.Sprintf("%p", store) {
 				v, err := store.GetRawJSONValues()
 				if err != nil {
-					fmt.Fprint(clientFD, err.Error())
+					fmt.Fprint(clientFD, base.Redact(err.Error()))
 					fmt.Fprintf(clientFD, "\n")
 					return
 				}
 				enc, err := json.MarshalIndent(v, "", "    ")
 				if err != nil {
-					fmt.Fprint(clientFD, err.Error())
+					fmt.Fprint(clientFD, base.Redact(err.Error()))
 					fmt.Fprintf(clientFD, "\n")
 					return
 				}
 				fmt.Fprint(clientFD, "storage dump:")
-				fmt.Fprintf(clientFD, "%s\n", enc)
+				fmt.Fprintf(clientFD, "%s\n", base.Redact(string(enc)))
 				return
 			}
 		}
@@ -908,7 +908,7 @@ func (d *debugger) ExecCmd(cc *client, cmd string) {
 		actxstore := d.cursor.GetStore()
 		ppp := fmt.Sprintf("{{%s}}", strings.Join(aa, "."))
 		actxstore.Interpolate(&ppp)
-		fmt.Fprint(clientFD, ppp)
+		fmt.Fprint(clientFD, base.Redact(ppp))
 		fmt.Fprintf(clientFD, "\n")
 	case "u":
 		parents := d.cursor.Parents()
@@ -959,6 +959,9 @@ func (d *debugger) ExecCmd(cc *client, cmd string) {
 					case base.RuntimeStateEnd:
 						statetxt = "done"
 					}
+					if w := th.Waiting(); w != "" {
+						statetxt = "waiting (" + w + ")"
+					}
 					fmt.Fprintf(clientFD, "\tState: %s\n", statetxt)
 					fmt.Fprintf(clientFD, "\tCurrent: %p\n", th.GetCurrent())
 					fmt.Fprintf(clientFD, "\tQueue len: %v\n", len(th.GetQueue()))
@@ -993,7 +996,7 @@ func (d *debugger) ExecCmd(cc *client, cmd string) {
 							prettyJSON.Write([]byte(err.Error()))
 
 						}
-						fmt.Fprintf(clientFD, "\tParameters: %s\n", prettyJSON.String())
+						fmt.Fprintf(clientFD, "\tParameters: %s\n", base.Redact(prettyJSON.String()))
 					}
 				}
 			}
@@ -1014,6 +1017,9 @@ func (d *debugger) ExecCmd(cc *client, cmd string) {
 				case base.RuntimeStateEnd:
 					statetxt = "done"
 				}
+				if w := th.Waiting(); w != "" {
+					statetxt = "waiting (" + w + ")"
+				}
 
 				fmt.Fprint(clientFD, "thread found\n")
 				fmt.Fprintf(clientFD, "State: %s\n", statetxt)
@@ -1028,7 +1034,11 @@ func (d *debugger) ExecCmd(cc *client, cmd string) {
 	case "th":
 		threads := d.runtime.GetThreads()
 		for th := range threads {
-			fmt.Fprintf(clientFD, " thread %p\n", th)
+			if w := th.Waiting(); w != "" {
+				fmt.Fprintf(clientFD, " thread %p (waiting for %s)\n", th, w)
+			} else {
+				fmt.Fprintf(clientFD, " thread %p\n", th)
+			}
 			curr := th.GetCurrent()
 			if curr == nil {
 				continue
@@ -0,0 +1,246 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/cast"
+	"github.com/develatio/nebulant-cli/config"
+)
+
+// LedgerVersion const. Bump on incompatible ledger changes
+const LedgerVersion = 1
+
+// Ledger struct. The resources created by an execution, saved into
+// LedgerPath(uuid) to allow their teardown with nebulant destroy
+type Ledger struct {
+	Version       int              `json:"version"`
+	ExecutionUUID string           `json:"execution_uuid"`
+	Updated       time.Time        `json:"updated"`
+	Resources     []*base.Resource `json:"resources"`
+}
+
+// Live func. The resources not deleted yet, in creation order
+func (l *Ledger) Live() []*base.Resource {
+	var live []*base.Resource
+	for _, res := range l.Resources {
+		if !res.Deleted {
+			live = append(live, res)
+		}
+	}
+	return live
+}
+
+// LedgersPath func. Dir of the resource ledgers
+func LedgersPath() string {
+	return filepath.Join(config.AppHomePath(), "ledgers")
+}
+
+// LedgerPath func
+func LedgerPath(executionUUID string) string {
+	return filepath.Join(LedgersPath(), executionUUID+".json")
+}
+
+// LoadLedger func
+func LoadLedger(executionUUID string) (*Ledger, error) {
+	if executionUUID == "" || filepath.Base(executionUUID) != executionUUID {
+		return nil, fmt.Errorf("invalid execution uuid %q", executionUUID)
+	}
+	data, err := os.ReadFile(LedgerPath(executionUUID)) // #nosec G304 -- own ledger dir
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no resources found for execution %s", executionUUID)
+		}
+		return nil, err
+	}
+	l := &Ledger{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("invalid ledger %s: %v", LedgerPath(executionUUID), err)
+	}
+	if l.Version != LedgerVersion {
+		return nil, fmt.Errorf("unsupported ledger version %v", l.Version)
+	}
+	return l, nil
+}
+
+// resourceLedger struct. Keeps the ledger of the execution and
+// writes it on changes
+type resourceLedger struct {
+	mu   sync.Mutex
+	path string
+	l    *Ledger
+	err  error
+}
+
+func newResourceLedger(irb *blueprint.IRBlueprint) *resourceLedger {
+	if irb.ExecutionUUID == nil || *irb.ExecutionUUID == "" {
+		return nil
+	}
+	l, err := LoadLedger(*irb.ExecutionUUID)
+	if err != nil {
+		// new execution, or resumed one without resources
+		l = &Ledger{
+			Version:       LedgerVersion,
+			ExecutionUUID: *irb.ExecutionUUID,
+		}
+	}
+	return &resourceLedger{
+		path: LedgerPath(*irb.ExecutionUUID),
+		l:    l,
+	}
+}
+
+// add func
+func (rl *resourceLedger) add(res *base.Resource) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	for _, r := range rl.l.Resources {
+		if r.Provider == res.Provider && r.ID == res.ID {
+			// re-run of the same action (eg. resume)
+			r.Deleted = false
+			rl.write()
+			return
+		}
+	}
+	rl.l.Resources = append(rl.l.Resources, res)
+	rl.write()
+}
+
+// remove func. Mark the resources as deleted
+func (rl *resourceLedger) remove(provider string, ids []string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	changed := false
+	for _, id := range ids {
+		for _, r := range rl.l.Resources {
+			if r.Provider == provider && r.ID == id && !r.Deleted {
+				r.Deleted = true
+				changed = true
+			}
+		}
+	}
+	if changed {
+		rl.write()
+	}
+}
+
+// live func. The resources not deleted yet, in creation order
+func (rl *resourceLedger) live() []*base.Resource {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.l.Live()
+}
+
+// write func. Called with the lock held
+func (rl *resourceLedger) write() {
+	rl.l.Updated = time.Now().UTC()
+	err := func() error {
+		if len(rl.l.Live()) <= 0 {
+			// nothing to destroy
+			if err := os.Remove(rl.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}
+		return writeJSONFile(rl.path, rl.l)
+	}()
+	if err != nil && rl.err == nil {
+		// warn once
+		rl.err = err
+		cast.LogWarn(fmt.Sprintf("cannot save the resource ledger: %v", err), &rl.l.ExecutionUUID)
+	}
+}
+
+// recordResources func. Update the ledger with the resources
+// created or deleted by the action
+func (r *Runtime) recordResources(provider base.IProvider, action *base.Action, aout *base.ActionOutput) {
+	if r.ledger == nil {
+		return
+	}
+	rp, ok := provider.(base.IResourceProvider)
+	if !ok {
+		return
+	}
+	if res := rp.CreatedResource(action, aout); res != nil {
+		r.ledger.add(res)
+		return
+	}
+	if ids := rp.DeletedResources(action); len(ids) > 0 {
+		r.ledger.remove(action.Provider, ids)
+	}
+}
+
+// recordCreated func. Register the resource reported in the output of
+// a failed run of the action, if any. True if a resource was found
+func (r *Runtime) recordCreated(provider base.IProvider, action *base.Action, aout *base.ActionOutput) bool {
+	rp, ok := provider.(base.IResourceProvider)
+	if !ok || aout == nil {
+		return false
+	}
+	res := rp.CreatedResource(action, aout)
+	if res == nil {
+		return false
+	}
+	if r.ledger != nil {
+		r.ledger.add(res)
+	}
+	return true
+}
+
+// createsResource func
+func createsResource(provider base.IProvider, action *base.Action) bool {
+	rp, ok := provider.(base.IResourceProvider)
+	return ok && rp.CreatesResource(action)
+}
+
+// rollbackChain func. The first action of the teardown of the live
+// resources, nil if there is nothing to delete
+func (r *Runtime) rollbackChain() *base.Action {
+	if r.ledger == nil {
+		return nil
+	}
+	live := r.ledger.live()
+	if len(live) <= 0 {
+		return nil
+	}
+	cast.LogInfo(fmt.Sprintf("Rolling back %d resources...", len(live)), r.irb.ExecutionUUID)
+	bp, err := blueprint.NewTeardown(live)
+	if err != nil {
+		cast.LogErr(fmt.Sprintf("cannot roll back the execution: %v", err), r.irb.ExecutionUUID)
+		return nil
+	}
+	irb, err := blueprint.GenerateIRB(bp, &blueprint.IRBGenConfig{})
+	if err != nil {
+		cast.LogErr(fmt.Sprintf("cannot roll back the execution: %v", err), r.irb.ExecutionUUID)
+		return nil
+	}
+	return irb.StartAction
+}
@@ -0,0 +1,120 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+const testLedgerBP = `
+actions:
+  - id: c1
+    provider: test
+    action: create
+    first: true
+    parameters: {value: r1}
+    output: R1
+    next: {ok: [c2]}
+  - id: c2
+    provider: test
+    action: create
+    parameters: {value: r2}
+    next: {ok: [d]}
+  - id: d
+    provider: test
+    action: delete
+    parameters: {value: "{{ R1 }}", fail: %v}
+`
+
+func runLedgerBP(t *testing.T, fail, rollback bool) *Runtime {
+	t.Helper()
+	irb := newTestIRB(t, fmt.Sprintf(testLedgerBP, fail))
+	uuid := "test-ledger"
+	irb.ExecutionUUID = &uuid
+	irb.RollbackOnFailure = rollback
+	return runTestIRB(t, irb)
+}
+
+// the created resources are recorded and the deleted ones,
+// found in the interpolated parameters, removed
+func TestLedgerRecord(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	runLedgerBP(t, false, true)
+	l, err := LoadLedger("test-ledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Resources) != 2 {
+		t.Fatalf("expected 2 resources, got %+v", l.Resources)
+	}
+	if res := l.Resources[0]; res.ID != "r1" || res.CreatedBy != "c1" || !res.Deleted {
+		t.Errorf("expected r1 to be deleted, got %+v", res)
+	}
+	live := l.Live()
+	if len(live) != 1 || live[0].ID != "r2" || live[0].CreatedBy != "c2" {
+		t.Errorf("expected r2 to be live, got %+v", live)
+	}
+	// no rollback on success
+	if n := countRuns("destroy-0-delete"); n != 0 {
+		t.Errorf("rollback run on success")
+	}
+}
+
+// the resources of a failed execution are deleted in
+// reverse creation order
+func TestLedgerRollback(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	r := runLedgerBP(t, true, true)
+	first, second := getRuns("destroy-0-delete"), getRuns("destroy-1-delete")
+	if len(first) != 1 || first[0].value != "r2" || len(second) != 1 || second[0].value != "r1" {
+		t.Fatalf("unexpected rollback runs %+v %+v", first, second)
+	}
+	if second[0].start.Before(first[0].end) {
+		t.Error("the rollback actions overlap")
+	}
+	if r.ExitCode() == 0 {
+		t.Error("the rollback reset the exit code of the failed execution")
+	}
+	if _, err := os.Stat(LedgerPath("test-ledger")); !os.IsNotExist(err) {
+		t.Errorf("the ledger was not removed after the rollback: %v", err)
+	}
+}
+
+// without rollback, the resources of a failed
+// execution are kept for nebulant destroy
+func TestLedgerNoRollback(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	runLedgerBP(t, true, false)
+	if n := countRuns("destroy-0-delete"); n != 0 {
+		t.Errorf("rollback run without --rollback-on-failure")
+	}
+	l, err := LoadLedger("test-ledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if live := l.Live(); len(live) != 2 {
+		t.Errorf("expected 2 live resources, got %+v", live)
+	}
+}
@@ -0,0 +1,204 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+// slotQueue struct. Counting semaphore that grants the
+// slots in request order
+type slotQueue struct {
+	mu      sync.Mutex
+	max     int
+	used    int
+	waiters []chan struct{}
+}
+
+func newSlotQueue(max int) *slotQueue {
+	return &slotQueue{max: max}
+}
+
+// tryAcquire func. Take a slot if there is one free
+// and nobody is waiting
+func (q *slotQueue) tryAcquire() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.used < q.max && len(q.waiters) == 0 {
+		q.used++
+		return true
+	}
+	return false
+}
+
+// acquire func. Wait for a slot. Returns false if done
+// is closed before the slot is granted
+func (q *slotQueue) acquire(done <-chan struct{}) bool {
+	if q.tryAcquire() {
+		return true
+	}
+	q.mu.Lock()
+	granted := make(chan struct{})
+	q.waiters = append(q.waiters, granted)
+	q.mu.Unlock()
+
+	select {
+	case <-granted:
+		return true
+	case <-done:
+		q.mu.Lock()
+		for i, w := range q.waiters {
+			if w == granted {
+				q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+				q.mu.Unlock()
+				return false
+			}
+		}
+		q.mu.Unlock()
+		// granted meanwhile, give it to the next one
+		q.release()
+		return false
+	}
+}
+
+// release func. Free the slot, or hand it over to the first waiter
+func (q *slotQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiters) > 0 {
+		close(q.waiters[0])
+		q.waiters = q.waiters[1:]
+		return
+	}
+	q.used--
+}
+
+// rateLimiter struct. Spaces the starts to honor the
+// starts per second budget, in request order
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// reserve func. Returns how long to wait before start
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	return wait
+}
+
+type providerLimiter struct {
+	slots *slotQueue
+	rate  *rateLimiter
+}
+
+// actionLimiter struct. Applies the run limits to the actions
+type actionLimiter struct {
+	global    *slotQueue
+	providers map[string]*providerLimiter
+}
+
+func newActionLimiter(limits *blueprint.RunLimits) *actionLimiter {
+	if limits.IsZero() {
+		return nil
+	}
+	l := &actionLimiter{
+		providers: make(map[string]*providerLimiter),
+	}
+	if limits.MaxParallel > 0 {
+		l.global = newSlotQueue(limits.MaxParallel)
+	}
+	for name, pl := range limits.Providers {
+		plim := &providerLimiter{}
+		if pl.Concurrency > 0 {
+			plim.slots = newSlotQueue(pl.Concurrency)
+		}
+		if pl.RPS > 0 {
+			plim.rate = &rateLimiter{interval: time.Duration(float64(time.Second) / pl.RPS)}
+		}
+		l.providers[name] = plim
+	}
+	return l
+}
+
+// acquire func. Wait until the action of provider can run. The
+// provider slot is taken first, then the global slot (skipped if
+// global is false) and then the rate budget. onWait is called
+// with the reason before blocking. On success, release should be
+// called once the action ends. ok is false if done is closed
+func (l *actionLimiter) acquire(provider string, global bool, done <-chan struct{}, onWait func(reason string)) (release func(), ok bool) {
+	var taken []*slotQueue
+	release = func() {
+		for i := len(taken) - 1; i >= 0; i-- {
+			taken[i].release()
+		}
+	}
+
+	plim := l.providers[provider]
+	if plim != nil && plim.slots != nil {
+		if !plim.slots.tryAcquire() {
+			onWait(fmt.Sprintf("%s concurrency limit", provider))
+			if !plim.slots.acquire(done) {
+				return nil, false
+			}
+		}
+		taken = append(taken, plim.slots)
+	}
+
+	if global && l.global != nil {
+		if !l.global.tryAcquire() {
+			onWait("max parallel actions")
+			if !l.global.acquire(done) {
+				release()
+				return nil, false
+			}
+		}
+		taken = append(taken, l.global)
+	}
+
+	if plim != nil && plim.rate != nil {
+		if wait := plim.rate.reserve(); wait > 0 {
+			onWait(fmt.Sprintf("%s rate limit", provider))
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-done:
+				release()
+				return nil, false
+			}
+		}
+	}
+	return release, true
+}
@@ -0,0 +1,180 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+// waiters func. Number of goroutines waiting for a slot of q
+func waiters(q *slotQueue) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiters)
+}
+
+// waitWaiters func. Wait until n goroutines wait for a slot of q
+func waitWaiters(t *testing.T, q *slotQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for waiters(q) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiters, got %d", n, waiters(q))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSlotQueueFIFO(t *testing.T) {
+	q := newSlotQueue(1)
+	if !q.tryAcquire() {
+		t.Fatal("cannot take the free slot")
+	}
+	if q.tryAcquire() {
+		t.Fatal("slot taken twice")
+	}
+
+	granted := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func(i int) {
+			if q.acquire(nil) {
+				granted <- i
+			}
+		}(i)
+		// queue them in order
+		waitWaiters(t, q, i+1)
+	}
+	// nobody can skip the queue
+	if q.tryAcquire() {
+		t.Fatal("slot taken while others wait")
+	}
+	for want := 0; want < 3; want++ {
+		q.release()
+		select {
+		case got := <-granted:
+			if got != want {
+				t.Fatalf("expected slot granted to %d, got %d", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("slot not granted")
+		}
+	}
+	q.release()
+	if !q.tryAcquire() {
+		t.Fatal("slot not freed")
+	}
+}
+
+func TestSlotQueueCancel(t *testing.T) {
+	q := newSlotQueue(1)
+	q.tryAcquire()
+	done := make(chan struct{})
+	result := make(chan bool)
+	go func() { result <- q.acquire(done) }()
+	waitWaiters(t, q, 1)
+	close(done)
+	if <-result {
+		t.Fatal("slot granted after done")
+	}
+	if n := waiters(q); n != 0 {
+		t.Fatalf("canceled waiter still queued: %d", n)
+	}
+	q.release()
+	if !q.tryAcquire() {
+		t.Fatal("slot leaked by the canceled waiter")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := &rateLimiter{interval: 100 * time.Millisecond}
+	for i := 0; i < 3; i++ {
+		want := time.Duration(i) * l.interval
+		// some slack for the time between calls
+		if wait := l.reserve(); wait > want || wait < want-20*time.Millisecond {
+			t.Errorf("reserve %d: expected wait of %v, got %v", i, want, wait)
+		}
+	}
+}
+
+func TestActionLimiter(t *testing.T) {
+	l := newActionLimiter(&blueprint.RunLimits{
+		MaxParallel: 1,
+		Providers: map[string]*blueprint.ProviderLimit{
+			"aws": {Concurrency: 1, RPS: 10},
+		},
+	})
+	var reasons []string
+	onWait := func(reason string) { reasons = append(reasons, reason) }
+
+	release, ok := l.acquire("aws", true, nil, onWait)
+	if !ok || len(reasons) != 0 {
+		t.Fatalf("first action should not wait: %v", reasons)
+	}
+
+	// provider concurrency
+	done := make(chan struct{})
+	close(done)
+	if _, ok := l.acquire("aws", true, done, onWait); ok {
+		t.Fatal("provider slot taken twice")
+	}
+	if len(reasons) != 1 || reasons[0] != "aws concurrency limit" {
+		t.Fatalf("unexpected wait reasons %v", reasons)
+	}
+
+	// max parallel
+	reasons = nil
+	if _, ok := l.acquire("hetznerCloud", true, done, onWait); ok {
+		t.Fatal("global slot taken twice")
+	}
+	if len(reasons) != 1 || reasons[0] != "max parallel actions" {
+		t.Fatalf("unexpected wait reasons %v", reasons)
+	}
+
+	// sub-routines (callers of blueprints) don't take the provider
+	// slot, and the threads of called blueprints skip the global one
+	reasons = nil
+	subRelease, ok := l.acquire("", false, nil, onWait)
+	if !ok || len(reasons) != 0 {
+		t.Fatalf("sub-routine should not wait: %v", reasons)
+	}
+	subRelease()
+	release()
+
+	// provider rate budget: the next start is spaced
+	reasons = nil
+	start := time.Now()
+	release, ok = l.acquire("aws", true, nil, onWait)
+	if !ok {
+		t.Fatal("cannot acquire")
+	}
+	release()
+	if len(reasons) != 1 || reasons[0] != "aws rate limit" {
+		t.Fatalf("unexpected wait reasons %v", reasons)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("rate limit not honored, waited %v", elapsed)
+	}
+}
@@ -0,0 +1,305 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/develatio/nebulant-cli/base"
+)
+
+const (
+	ActionStatusOK       = "ok"
+	ActionStatusKO       = "ko"
+	ActionStatusCanceled = "canceled"
+)
+
+// ActionReport struct. A run of an action
+type ActionReport struct {
+	ActionID   string    `json:"action_id"`
+	ActionName string    `json:"action"`
+	Provider   string    `json:"provider"`
+	ThreadID   string    `json:"thread_id"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	// seconds
+	Duration float64 `json:"duration"`
+	Status   string  `json:"status"`
+	// the KO has been handled by the KO port
+	Handled bool   `json:"handled,omitempty"`
+	Retries int    `json:"retries,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ReportTotals struct
+type ReportTotals struct {
+	Actions  int `json:"actions"`
+	OK       int `json:"ok"`
+	KO       int `json:"ko"`
+	Canceled int `json:"canceled"`
+	Retries  int `json:"retries"`
+	Threads  int `json:"threads"`
+}
+
+// Report struct. Summary of an execution
+type Report struct {
+	ExecutionUUID string    `json:"execution_uuid"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	// seconds
+	Duration   float64         `json:"duration"`
+	ExitCode   int             `json:"exit_code"`
+	ExitStatus string          `json:"exit_status"`
+	Actions    []*ActionReport `json:"actions"`
+	// unhandled errors
+	Errors []string     `json:"errors"`
+	Totals ReportTotals `json:"totals"`
+}
+
+// reporter struct. Collects the runs of the
+// actions while the runtime is running
+type reporter struct {
+	mu      sync.Mutex
+	start   time.Time
+	actions []*ActionReport
+	threads int
+}
+
+func (rp *reporter) addThread() {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.threads == 0 {
+		rp.start = time.Now()
+	}
+	rp.threads++
+}
+
+func (rp *reporter) addAction(ar *ActionReport) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.actions = append(rp.actions, ar)
+}
+
+// reportAction func. Record the run of the action of actx
+func (r *Runtime) reportAction(t *Thread, actx base.IActionContext, start time.Time, aout *base.ActionOutput, status string, handled bool, aerr error) {
+	action := actx.GetAction()
+	end := time.Now()
+	ar := &ActionReport{
+		ActionID:   action.ActionID,
+		ActionName: action.ActionName,
+		Provider:   action.Provider,
+		ThreadID:   fmt.Sprintf("%p", t),
+		Start:      start.UTC(),
+		End:        end.UTC(),
+		Duration:   end.Sub(start).Seconds(),
+		Status:     status,
+		Handled:    handled,
+	}
+	if aout != nil && len(aout.Records) > 0 {
+		ar.Retries = aout.Records[0].Retries
+	}
+	if aerr != nil {
+		ar.Error = base.Redact(aerr.Error())
+	}
+	r.reporter.addAction(ar)
+}
+
+// Report func. The report of the execution. Should be
+// called once the runtime has ended
+func (r *Runtime) Report() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reporter.mu.Lock()
+	defer r.reporter.mu.Unlock()
+
+	end := time.Now()
+	start := r.reporter.start
+	if start.IsZero() {
+		start = end
+	}
+	rep := &Report{
+		Start:      start.UTC(),
+		End:        end.UTC(),
+		Duration:   end.Sub(start).Seconds(),
+		ExitCode:   r.exitCode,
+		ExitStatus: r.exitStatus(),
+		Actions:    append([]*ActionReport{}, r.reporter.actions...),
+		Errors:     []string{},
+	}
+	if r.irb.ExecutionUUID != nil {
+		rep.ExecutionUUID = *r.irb.ExecutionUUID
+	}
+	for _, err := range r.exitErrs {
+		rep.Errors = append(rep.Errors, base.Redact(err.Error()))
+	}
+	rep.Totals.Threads = r.reporter.threads
+	for _, ar := range rep.Actions {
+		rep.Totals.Actions++
+		rep.Totals.Retries += ar.Retries
+		switch ar.Status {
+		case ActionStatusOK:
+			rep.Totals.OK++
+		case ActionStatusKO:
+			rep.Totals.KO++
+		case ActionStatusCanceled:
+			rep.Totals.Canceled++
+		}
+	}
+	return rep
+}
+
+// WriteJSON func
+func (rep *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+	SystemErr string          `xml:"system-err,omitempty"`
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// WriteJUnit func. Write the report as JUnit XML, every
+// action run is a test case and every KO a failure
+func (rep *Report) WriteJUnit(w io.Writer) error {
+	seconds := func(s float64) string {
+		return fmt.Sprintf("%.3f", s)
+	}
+	suite := junitTestSuite{
+		Name:      "nebulant " + rep.ExecutionUUID,
+		Tests:     rep.Totals.Actions,
+		Failures:  rep.Totals.KO,
+		Skipped:   rep.Totals.Canceled,
+		Time:      seconds(rep.Duration),
+		Timestamp: rep.Start.Format(time.RFC3339),
+		SystemErr: strings.Join(rep.Errors, "\n"),
+	}
+	for _, ar := range rep.Actions {
+		tc := junitTestCase{
+			Name:      ar.ActionID,
+			ClassName: ar.Provider + "." + ar.ActionName,
+			Time:      seconds(ar.Duration),
+		}
+		switch ar.Status {
+		case ActionStatusKO:
+			ftype := "unhandled"
+			if ar.Handled {
+				ftype = "handled"
+			}
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%s KO", ar.ActionName),
+				Type:    ftype,
+				Text:    ar.Error,
+			}
+		case ActionStatusCanceled:
+			tc.Skipped = &junitSkipped{Message: "canceled by join"}
+		}
+		if ar.Retries > 0 {
+			tc.SystemOut = fmt.Sprintf("retries: %d", ar.Retries)
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suites := junitTestSuites{
+		Name:     "nebulant",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteReportFile func. Write the report into path, as JUnit
+// XML if the path ends with .xml or as JSON otherwise
+func WriteReportFile(path string, rep *Report) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	write := rep.WriteJSON
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		write = rep.WriteJUnit
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,167 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testReportBP = `
+actions:
+  - id: a
+    provider: test
+    action: run
+    first: true
+    next: {ok: [b]}
+  - id: b
+    provider: test
+    action: run
+    parameters: {fail: true}
+    retry: {max_attempts: 2, backoff: fixed, delay: 10ms}
+    next: {ko: [c]}
+  - id: c
+    provider: test
+    action: run
+    next: {ok: [d]}
+  - id: d
+    provider: test
+    action: run
+    parameters: {fail: %v}
+`
+
+func testReport(t *testing.T, failLast bool) *Report {
+	t.Helper()
+	return runTestBlueprint(t, fmt.Sprintf(testReportBP, failLast)).Report()
+}
+
+func TestReportTotals(t *testing.T) {
+	rep := testReport(t, false)
+	if rep.ExitCode != 0 || rep.ExitStatus != ExitStatusSuccess || len(rep.Errors) != 0 {
+		t.Fatalf("unexpected exit %d %s %v", rep.ExitCode, rep.ExitStatus, rep.Errors)
+	}
+	want := ReportTotals{Actions: 4, OK: 3, KO: 1, Retries: 1, Threads: 1}
+	if rep.Totals != want {
+		t.Errorf("expected totals %+v, got %+v", want, rep.Totals)
+	}
+	for _, ar := range rep.Actions {
+		if ar.ActionID != "b" {
+			continue
+		}
+		if ar.Status != ActionStatusKO || !ar.Handled || ar.Retries != 1 || ar.Error == "" {
+			t.Errorf("unexpected report of the handled KO %+v", ar)
+		}
+	}
+
+	rep = testReport(t, true)
+	if rep.ExitCode == 0 || rep.ExitStatus != ExitStatusFailure || len(rep.Errors) != 1 {
+		t.Fatalf("unexpected exit %d %s %v", rep.ExitCode, rep.ExitStatus, rep.Errors)
+	}
+	if rep.Totals.KO != 2 {
+		t.Errorf("expected 2 KO, got %d", rep.Totals.KO)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	rep := testReport(t, false)
+	buf := &bytes.Buffer{}
+	if err := rep.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		ExitStatus string `json:"exit_status"`
+		Actions    []struct {
+			ActionID string `json:"action_id"`
+			Status   string `json:"status"`
+			Handled  bool   `json:"handled"`
+			Retries  int    `json:"retries"`
+		} `json:"actions"`
+		Totals map[string]int `json:"totals"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.ExitStatus != ExitStatusSuccess || len(doc.Actions) != 4 {
+		t.Fatalf("unexpected report %s", buf.String())
+	}
+	if b := doc.Actions[1]; b.ActionID != "b" || b.Status != ActionStatusKO || !b.Handled || b.Retries != 1 {
+		t.Errorf("unexpected action report %+v", b)
+	}
+	if doc.Totals["retries"] != 1 || doc.Totals["actions"] != 4 || doc.Totals["ko"] != 1 {
+		t.Errorf("unexpected totals %v", doc.Totals)
+	}
+}
+
+func TestReportJUnit(t *testing.T) {
+	rep := testReport(t, true)
+	path := filepath.Join(t.TempDir(), "report.xml")
+	if err := WriteReportFile(path, rep); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc junitTestSuites
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid JUnit XML: %v\n%s", err, data)
+	}
+	if doc.Tests != 4 || doc.Failures != 2 || len(doc.Suites) != 1 {
+		t.Fatalf("unexpected suites %s", data)
+	}
+	suite := doc.Suites[0]
+	if len(suite.TestCases) != 4 || suite.SystemErr == "" {
+		t.Fatalf("unexpected suite %s", data)
+	}
+	cases := make(map[string]junitTestCase)
+	for _, tc := range suite.TestCases {
+		cases[tc.Name] = tc
+	}
+	if tc := cases["b"]; tc.Failure == nil || tc.Failure.Type != "handled" || tc.SystemOut != "retries: 1" {
+		t.Errorf("unexpected handled KO test case %+v", tc)
+	}
+	if tc := cases["d"]; tc.Failure == nil || tc.Failure.Type != "unhandled" {
+		t.Errorf("unexpected unhandled KO test case %+v", tc)
+	}
+	if tc := cases["a"]; tc.Failure != nil || tc.ClassName != "test.run" {
+		t.Errorf("unexpected OK test case %+v", tc)
+	}
+
+	// json for any other extension
+	path = filepath.Join(t.TempDir(), "report.json")
+	if err := WriteReportFile(path, rep); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(data) {
+		t.Errorf("invalid JSON report %s", data)
+	}
+}
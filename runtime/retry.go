@@ -0,0 +1,117 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/develatio/nebulant-cli/base"
+)
+
+// runAttempts func. Run the action, retrying it as set in his retry
+// policy. The timeout of the action applies to every attempt. Returns
+// the output of the last attempt and the number of failed attempts
+// before it
+func (r *Runtime) runAttempts(provider base.IProvider, actx base.IActionContext, sub *subRun) (*base.ActionOutput, int, error) {
+	action := actx.GetAction()
+	timeout := action.Timeout.Duration()
+	policy := action.Retry
+
+	var aout *base.ActionOutput
+	var aerr error
+	var delay time.Duration
+	attempt := 1
+retry:
+	for ; ; attempt++ {
+		if attempt > 1 {
+			// free the context of the failed attempt
+			actx.Cancel(nil)
+		}
+		actx.WithCancelCause(r.parentCtx(sub), timeout, &base.TimeoutError{Timeout: timeout})
+		sctx, scancel := r.storeContext(actx)
+		actx.GetStore().SetPrivateVar("CONTEXT", sctx)
+		var done <-chan struct{}
+		aout, aerr, done = r.handleAction(provider, actx)
+		scancel()
+		if aerr == nil || policy == nil || attempt >= policy.Attempts() || !policy.Match(aerr) {
+			break
+		}
+		if r.parentCtx(sub).Err() != nil {
+			// stopped or out of time
+			break
+		}
+		// the resource of the failed attempt, if any, is kept
+		// into the ledger to be deleted on teardown
+		created := r.recordCreated(provider, action, aout)
+		select {
+		case <-done:
+		default:
+			// never run the same actor twice at once
+			// on the same store
+			actx.GetStore().GetLogger().LogWarn(fmt.Sprintf("Attempt %d/%d failed: %v. The attempt is still running, not retrying", attempt, policy.Attempts(), aerr))
+			break retry
+		}
+		var terr *base.TimeoutError
+		if !created && errors.As(aerr, &terr) && createsResource(provider, action) {
+			// the request could have reached the provider, a new
+			// attempt could create a second resource
+			actx.GetStore().GetLogger().LogWarn(fmt.Sprintf("Attempt %d/%d timed out. The resource could have been created, not retrying", attempt, policy.Attempts()))
+			break
+		}
+		delay = policy.NextDelay(attempt, delay)
+		actx.GetStore().GetLogger().LogWarn(fmt.Sprintf("Attempt %d/%d failed: %v. Retrying after %v...", attempt, policy.Attempts(), aerr, delay))
+		if !r.sleepRetry(delay, sub) {
+			break
+		}
+	}
+
+	actx.GetStore().SetPrivateVar("CONTEXT", nil)
+
+	if aerr != nil && attempt > 1 {
+		aerr = fmt.Errorf("%w (after %d attempts)", aerr, attempt)
+	}
+	return aout, attempt - 1, aerr
+}
+
+// noRetryOutput func. Copy of aout with a copy of his action that
+// the error hook of the provider does not retry, see Action.MaxRetries
+func noRetryOutput(aout *base.ActionOutput) *base.ActionOutput {
+	action := *aout.Action
+	action.MaxRetries = new(int)
+	return &base.ActionOutput{Action: &action, Records: aout.Records}
+}
+
+// sleepRetry func. Wait before the next attempt. False if
+// the runtime stops while waiting
+func (r *Runtime) sleepRetry(delay time.Duration, sub *subRun) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.parentCtx(sub).Done():
+		return false
+	}
+}
@@ -0,0 +1,76 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package runtime
+
+import (
+	"fmt"
+	"testing"
+)
+
+const testRetryBP = `
+actions:
+  - id: a
+    provider: test
+    action: run
+    first: true
+    parameters: %s
+    timeout: %s
+    retry: {max_attempts: 3, backoff: fixed, delay: 10ms}
+`
+
+func TestRetryAttempts(t *testing.T) {
+	r := runTestBlueprint(t, fmt.Sprintf(testRetryBP, "{fail: true}", "0s"))
+	if n := countRuns("a"); n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+	rep := r.Report()
+	if len(rep.Actions) != 1 || rep.Actions[0].Retries != 2 || rep.Actions[0].Status != ActionStatusKO {
+		t.Fatalf("unexpected report %+v", rep.Actions)
+	}
+}
+
+// the next attempt starts once the actor of the
+// previous one, timed out, has returned
+func TestRetryNoOverlap(t *testing.T) {
+	runTestBlueprint(t, fmt.Sprintf(testRetryBP, "{block: 200ms}", "50ms"))
+	runs := getRuns("a")
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(runs))
+	}
+	for i := 1; i < len(runs); i++ {
+		if runs[i].start.Before(runs[i-1].end) {
+			t.Errorf("attempt %d started before attempt %d ended", i+1, i)
+		}
+	}
+}
+
+// the error hook of the provider runs once the attempts
+// are exhausted, without retrying the action again
+func TestRetryErrorHook(t *testing.T) {
+	runTestBlueprint(t, fmt.Sprintf(testRetryBP, "{fail: true, recover: r}", "0s"))
+	if n := countRuns("a"); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+	if n := countRuns("r"); n != 1 {
+		t.Errorf("expected the recover action to run once, got %d", n)
+	}
+}
@@ -23,6 +23,7 @@
 package runtime
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -35,7 +36,10 @@ import (
 )
 
 func NewRuntime(irb *blueprint.IRBlueprint, serverMode bool) *Runtime {
-	return &Runtime{
+	ctx, cancel := context.WithCancelCause(context.Background())
+	r := &Runtime{
+		ctx:                ctx,
+		cancel:             cancel,
 		irb:                irb,
 		serverMode:         serverMode,
 		actionContextStack: make([]base.IActionContext, 0, 1),
@@ -48,11 +52,37 @@ func NewRuntime(irb *blueprint.IRBlueprint, serverMode bool) *Runtime {
 		activeThreads: make(map[*Thread]bool),
 		evDispatcher:  base.NewEventDispatcher(),
 		exitCode:      0,
+		checkpoint:    newCheckpointer(irb),
+		ledger:        newResourceLedger(irb),
+		limiter:       newActionLimiter(irb.Limits),
+		stopped:       make(chan struct{}),
+		cleanup:       &cleanup{},
+		reporter:      &reporter{},
 	}
+	if r.limiter != nil {
+		cast.LogDebug("Run limits: "+irb.Limits.String(), irb.ExecutionUUID)
+	}
+	if irb.Timeout > 0 {
+		var tcancel context.CancelFunc
+		r.ctx, tcancel = context.WithTimeoutCause(ctx, irb.Timeout, &base.TimeoutError{
+			Timeout: irb.Timeout,
+			Global:  true,
+		})
+		r.cancel = func(e error) {
+			cancel(e)
+			tcancel()
+		}
+		go r.watchDeadline()
+	}
+	return r
 }
 
 type Runtime struct {
 	mu sync.Mutex
+	// parent of all action contexts, carries the
+	// global execution deadline
+	ctx    context.Context
+	cancel context.CancelCauseFunc
 	// ru            sync.Mutex
 	serverMode    bool
 	state         base.RuntimeState
@@ -68,12 +98,63 @@ type Runtime struct {
 	exitErrs []error // uncaught err
 	//
 	savedActionOutputs []*base.ActionOutput
+	// nil if the checkpoints are disabled
+	checkpoint *checkpointer
+	// resources created by the execution. nil if there
+	// is no execution uuid
+	ledger *resourceLedger
+	// nil if there are no run limits
+	limiter *actionLimiter
+	// closed on Stop
+	stopped  chan struct{}
+	stopOnce sync.Once
+	// Stop has been called
+	stopRequested bool
+	// cleanup chains (on_exit and finally of groups)
+	cleanup *cleanup
+	// runs of the actions, for the execution report
+	reporter *reporter
+}
+
+// watchDeadline func. Stop the runtime if the global
+// execution deadline is reached
+func (r *Runtime) watchDeadline() {
+	<-r.ctx.Done()
+	var terr *base.TimeoutError
+	if !errors.As(context.Cause(r.ctx), &terr) {
+		// canceled on runtime end
+		return
+	}
+	r.mu.Lock()
+	r.exitCode = r.exitCode + 1
+	r.exitErrs = append(r.exitErrs, terr)
+	r.mu.Unlock()
+	cast.LogErr(terr.Error()+". Stopping...", r.irb.ExecutionUUID)
+	r.Stop()
 }
 
 func (r *Runtime) hasRunningParents(actx base.IActionContext) bool {
+	parents := actx.GetAction().KnowParentIDs
 	r.cjoiner.Lock()
-	defer r.cjoiner.Unlock()
-	return r.activeActionsID.ExistsAny(actx.GetAction().KnowParentIDs)
+	running := r.activeActionsID.ExistsAny(parents)
+	r.cjoiner.Unlock()
+	if running {
+		return true
+	}
+	// threads of the branches not started yet
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for th := range r.activeThreads {
+		if th.pendingAny(parents) {
+			return true
+		}
+	}
+	return false
+}
+
+// IRB func. The blueprint run by the runtime
+func (r *Runtime) IRB() *blueprint.IRBlueprint {
+	return r.irb
 }
 
 func (r *Runtime) GetStack() []base.IActionContext {
@@ -111,9 +192,35 @@ func (r *Runtime) NewEventListener() *base.EventListener {
 }
 
 func (r *Runtime) NewThread(actx base.IActionContext) bool {
+	return r.newThread(actx, nil)
+}
+
+// newThread func. Start a new thread running actx. If sub is not nil,
+// the thread belongs to a called blueprint (sub-routine)
+func (r *Runtime) newThread(actx base.IActionContext, sub *subRun) bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if r.state == base.RuntimeStateEnding || r.state == base.RuntimeStateEnd {
+	return r._newThread(actx, sub)
+}
+
+// newThreads func. Start a thread for every actx. All the threads are
+// registered before any of them can end, so the runtime can't end
+// between starts
+func (r *Runtime) newThreads(actxs []base.IActionContext) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, actx := range actxs {
+		if !r._newThread(actx, nil) {
+			return false
+		}
+	}
+	return true
+}
+
+// _newThread func. Called with the runtime lock held
+func (r *Runtime) _newThread(actx base.IActionContext, sub *subRun) bool {
+	cleanup := r.cleanup.running.Load() || sub != nil && sub.cleanup
+	if (r.state == base.RuntimeStateEnding || r.state == base.RuntimeStateEnd) && !cleanup {
 		cast.LogDebug(fmt.Sprintf("state ending, prevent start for action %s", actx.GetAction().ActionName), nil)
 		return false
 	}
@@ -125,6 +232,12 @@ func (r *Runtime) NewThread(actx base.IActionContext) bool {
 		runtime:   r,
 		elistener: el,
 		step:      make(chan *threadStackCtrl),
+		sub:       sub,
+	}
+	if sub != nil {
+		sub.threads[th] = true
+	} else if r.cleanup.root == nil {
+		r.cleanup.root = actx.GetStore()
 	}
 	thid := fmt.Sprintf("%p", th)
 	cast.PushBusData(&cast.BusData{
@@ -137,6 +250,7 @@ func (r *Runtime) NewThread(actx base.IActionContext) bool {
 	actx.GetStore().GetLogger().SetThreadID(thid)
 	th.queue = append(th.queue, actx)
 	r.activeThreads[th] = true
+	r.reporter.addThread()
 
 	// start thread in play or pause mode
 	if r.state == base.RuntimeStatePlay {
@@ -161,18 +275,48 @@ func (r *Runtime) finishThread(th *Thread) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	delete(r.activeThreads, th)
-	r.exitCode = r.exitCode + th.ExitCode
-	if th.ExitErr != nil {
-		r.exitErrs = append(r.exitErrs, th.ExitErr)
+	// the join points could be waiting for this thread
+	r.cjoiner.Wake()
+	if th.sub != nil {
+		// errors of called blueprints are handled by
+		// the caller action, not by the runtime
+		th.sub.finishThread(th)
+	} else {
+		r.exitCode = r.exitCode + th.ExitCode
+		if th.ExitErr != nil {
+			r.exitErrs = append(r.exitErrs, th.ExitErr)
+		}
+		if st := th.lastStore(); st != nil {
+			r.cleanup.stores = append(r.cleanup.stores, st)
+		}
 	}
 
+	el := th.EventListener()
+	r.evDispatcher.DestroyEventListener(el)
+
 	// no threads, no activity
 	if len(r.activeThreads) <= 0 {
-		go r.evDispatcher.Dispatch(&runtimeEvent{ecode: base.RuntimeEndEvent})
+		if r.startCleanup() || r.cleanup.pending() {
+			// the runtime ends after the cleanup
+			return
+		}
+		r.end()
 	}
+}
 
-	el := th.EventListener()
-	r.evDispatcher.DestroyEventListener(el)
+// end func. Called with the runtime lock held once there
+// are no more threads nor cleanup chains to run
+func (r *Runtime) end() {
+	r.cancel(nil)
+	if r.checkpoint != nil && r.checkpoint.resumable() {
+		cast.LogInfo(fmt.Sprintf("Execution checkpoint saved. Resume with: nebulant run --resume %s", *r.irb.ExecutionUUID), r.irb.ExecutionUUID)
+	}
+	if r.ledger != nil && !r.irb.Teardown {
+		if live := r.ledger.live(); len(live) > 0 {
+			cast.LogInfo(fmt.Sprintf("%d resources created. Delete them with: nebulant destroy %s", len(live), *r.irb.ExecutionUUID), r.irb.ExecutionUUID)
+		}
+	}
+	go r.evDispatcher.Dispatch(&runtimeEvent{ecode: base.RuntimeEndEvent})
 }
 
 func (r *Runtime) Play() {
@@ -184,7 +328,7 @@ func (r *Runtime) Play() {
 	for th := range threads {
 		th.Play()
 	}
-	r.state = base.RuntimeStatePlay
+	r.setState(base.RuntimeStatePlay)
 	r.DispatchCurrentActiveIdsEvent()
 	cast.PushEvent(cast.EventRuntimeStarted, r.irb.ExecutionUUID)
 }
@@ -196,26 +340,51 @@ func (r *Runtime) Pause() {
 	for th := range threads {
 		th.Pause()
 	}
-	r.state = base.RuntimeStateStill
+	r.setState(base.RuntimeStateStill)
 	r.DispatchCurrentActiveIdsEvent()
 	cast.PushEvent(cast.EventRuntimePaused, r.irb.ExecutionUUID)
 }
 
 func (r *Runtime) Stop() {
 	cast.PushEvent(cast.EventRuntimeStopping, r.irb.ExecutionUUID)
+	r.mu.Lock()
 	r.state = base.RuntimeStateEnding
-	go r.evDispatcher.Dispatch(&runtimeEvent{ecode: base.RuntimeEndEvent})
+	// unlock the actions waiting for run slots
+	r.stopOnce.Do(func() { close(r.stopped) })
+	r.stopRequested = true
+	// with cleanup chains to run, the end event
+	// is dispatched once the cleanup ends
+	deferEnd := r.cleanup.pending() || (len(r.activeThreads) > 0 && r.hasCleanup())
+	r.mu.Unlock()
+	if !deferEnd {
+		go r.evDispatcher.Dispatch(&runtimeEvent{ecode: base.RuntimeEndEvent})
+	}
 	threads := r.GetThreads()
 	for th := range threads {
 		th.Stop()
 	}
-	r.state = base.RuntimeStateEnd
+	r.setState(base.RuntimeStateEnd)
 	r.DispatchCurrentActiveIdsEvent()
 	cast.PushEvent(cast.EventRuntimeOut, r.irb.ExecutionUUID)
 }
 
+// setState func. The state is read by _newThread
+// with the runtime lock held
+func (r *Runtime) setState(state base.RuntimeState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state = state
+}
+
+// GetThreads func. Copy of the running threads
 func (r *Runtime) GetThreads() map[*Thread]bool {
-	return r.activeThreads
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	threads := make(map[*Thread]bool, len(r.activeThreads))
+	for th := range r.activeThreads {
+		threads[th] = true
+	}
+	return threads
 }
 
 func (r *Runtime) _setRunDebugFunc(actx base.IActionContext) {
@@ -241,7 +410,7 @@ func (r *Runtime) _setRunDebugFunc(actx base.IActionContext) {
 	})
 }
 
-func (r *Runtime) setRunFunc(actx base.IActionContext) {
+func (r *Runtime) setRunFunc(actx base.IActionContext, sub *subRun) {
 	action := actx.GetAction()
 	if action.DebugPoint {
 		r._setRunDebugFunc(actx)
@@ -250,6 +419,10 @@ func (r *Runtime) setRunFunc(actx base.IActionContext) {
 	actx.WithRunFunc(func() (*base.ActionOutput, error) {
 		action := actx.GetAction()
 		store := actx.GetStore()
+		// literal secrets of the parameters (passwords,
+		// private keys...), the interpolated ones are
+		// collected by the store
+		base.CollectSecrets(action.Parameters, false)
 		var provider base.IProvider
 		var err error
 		if !store.ExistsProvider(action.Provider) {
@@ -269,11 +442,15 @@ func (r *Runtime) setRunFunc(actx base.IActionContext) {
 			}
 		}
 
-		actx.WithCancelCause()
-		defer actx.Cancel(nil)
 		// l := store.GetLogger()
 		// l.LogInfo(fmt.Sprintf("Running %s", action.ActionName))
-		aout, aerr := provider.HandleAction(actx)
+		aout, retries, aerr := r.runAttempts(provider, actx, sub)
+		defer actx.Cancel(nil)
+		if aerr == nil {
+			r.recordResources(provider, action, aout)
+		} else {
+			r.recordCreated(provider, action, aout)
+		}
 
 		if aerr != nil {
 			// ssh run could return non nil aout with
@@ -285,10 +462,10 @@ func (r *Runtime) setRunFunc(actx base.IActionContext) {
 			aout.Records[0].Fail = true
 			aout.Records[0].Error = aerr
 		}
-
 		// aout is nil on action return nil, nil
 		if aout != nil {
 			for idx := 0; idx < len(aout.Records); idx++ {
+				aout.Records[idx].Retries = retries
 				err := store.Insert(aout.Records[idx], action.Provider)
 				if err != nil {
 					log.Panic(err.Error())
@@ -304,6 +481,151 @@ func (r *Runtime) setRunFunc(actx base.IActionContext) {
 	})
 }
 
+// closedChan is returned by handleAction when the actor has returned
+var closedChan = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// handleAction func. Run the action through the provider. If the
+// action has a deadline, return a *base.TimeoutError once it's
+// reached. An actor that doesn't honor the context is waited for
+// until the runtime stops. done is closed once the actor returns
+func (r *Runtime) handleAction(provider base.IProvider, actx base.IActionContext) (*base.ActionOutput, error, <-chan struct{}) {
+	ctx := actx.Context()
+	if _, ok := ctx.Deadline(); !ok {
+		aout, aerr := provider.HandleAction(actx)
+		return aout, aerr, closedChan
+	}
+
+	type result struct {
+		aout *base.ActionOutput
+		aerr error
+	}
+	rr := make(chan *result, 1)
+	done := make(chan struct{})
+	go func() {
+		aout, aerr := provider.HandleAction(actx)
+		// closed before sending the result, done is always
+		// closed once the result is received
+		close(done)
+		rr <- &result{aout: aout, aerr: aerr}
+	}()
+
+	// actions of the cleanup chains run after stop
+	stopped := r.stopped
+	if r.cleanup.running.Load() {
+		stopped = nil
+	}
+	var res *result
+	select {
+	case res = <-rr:
+	case <-ctx.Done():
+		// give the actor a moment to clean up
+		// (kill process, close conn...) and return
+		select {
+		case res = <-rr:
+		case <-time.After(2 * time.Second):
+			// the actor could keep writing into the store, wait
+			// for it before routing the action
+			actx.GetStore().GetLogger().LogWarn(fmt.Sprintf("%s: the action ignored the timeout and is still running, waiting for it to end...", actx.GetAction().ActionID))
+			select {
+			case res = <-rr:
+			case <-stopped:
+				res = &result{}
+			}
+		}
+	}
+
+	var terr *base.TimeoutError
+	if errors.As(context.Cause(ctx), &terr) {
+		return res.aout, &base.TimeoutError{Timeout: terr.Timeout, Global: terr.Global, Err: res.aerr}, done
+	}
+	return res.aout, res.aerr, done
+}
+
+// storeContext func. Context of the reads of the store made while the
+// action runs ({{ state.key }}). Canceled on timeout and on stop
+func (r *Runtime) storeContext(actx base.IActionContext) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(actx.Context())
+	if r.cleanup.running.Load() {
+		// actions of the cleanup chains run after stop
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-r.stopped:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// waitActionSlot func. Block th until the action can run honoring the
+// run limits of the runtime and of the called blueprints of th. Threads
+// of called blueprints are not counted in the max parallel limit of the
+// caller, the caller action already has a slot. The callers don't take
+// provider slots, their actions do. ok is false if the runtime stops
+// meanwhile
+func (r *Runtime) waitActionSlot(th *Thread, action *base.Action) (release func(), ok bool) {
+	var limiters []*actionLimiter
+	if r.limiter != nil {
+		limiters = append(limiters, r.limiter)
+	}
+	if th.sub != nil {
+		limiters = append(limiters, th.sub.limiters...)
+	}
+	if len(limiters) <= 0 || action.DebugPoint || r.cleanup.running.Load() || th.sub != nil && th.sub.cleanup {
+		return func() {}, true
+	}
+	provider := action.Provider
+	if action.SubRoutine {
+		provider = ""
+	}
+	defer th.waiting.Store("")
+	var releases []func()
+	release = func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+	for _, limiter := range limiters {
+		// the max parallel limit of the runtime counts the
+		// actions of the main graph, the limit of a called
+		// blueprint counts his own actions
+		global := th.sub == nil
+		if limiter != r.limiter {
+			global = !action.SubRoutine
+		}
+		lrelease, ok := limiter.acquire(provider, global, r.stopped, r.onActionWait(th, action))
+		if !ok {
+			release()
+			return nil, false
+		}
+		releases = append(releases, lrelease)
+	}
+	return release, true
+}
+
+// onActionWait func. Notify that the action of th waits for a slot
+func (r *Runtime) onActionWait(th *Thread, action *base.Action) func(reason string) {
+	return func(reason string) {
+		th.waiting.Store(reason)
+		cast.PushMixedLogEventBusData(&cast.BusData{
+			EventID:       cast.EP(cast.EventActionWaiting),
+			ActionID:      &action.ActionID,
+			ActionName:    &action.ActionName,
+			LogLevel:      cast.EP(base.InfoLevel),
+			M:             cast.SEP("waiting (" + reason + ")"),
+			ThreadID:      cast.SEP(fmt.Sprintf("%p", th)),
+			ExecutionUUID: r.irb.ExecutionUUID,
+			Timestamp:     time.Now().UTC().UnixMicro(),
+		})
+	}
+}
+
 func (r *Runtime) setDebugInitFunc(actx base.IActionContext) {
 	actx.WithDebugInitFunc(func() {
 		// Pause exec
@@ -350,12 +672,13 @@ func (r *Runtime) DispatchCurrentActiveIdsEvent() {
 	cast.PushState(r.activeActionsID.Slice(), cast.EventRuntimeStarted, r.irb.ExecutionUUID)
 }
 
-// switchContext activates actx and deactivates his parents
-func (r *Runtime) switchContext(actx base.IActionContext) {
+// switchContext activates actx of a thread of sub and
+// deactivates his parents
+func (r *Runtime) switchContext(actx base.IActionContext, sub *subRun) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	todeactivate := actx.Parents()
-	r._activateContext(actx)
+	r._activateContext(actx, sub)
 	for _, dactx := range todeactivate {
 		r._deactivateContext(dactx)
 	}
@@ -366,12 +689,12 @@ func (r *Runtime) switchContext(actx base.IActionContext) {
 //   - initialize actx event listener
 //   - if this action is join point, add
 //     to active join points
-func (r *Runtime) _activateContext(actx base.IActionContext) {
+func (r *Runtime) _activateContext(actx base.IActionContext, sub *subRun) {
 	defer r.DispatchCurrentActiveIdsEvent()
 	ev := r.evDispatcher.NewEventListener()
 	actx.WithEventListener(ev)
 	if actx.Type() == base.ContextTypeRegular {
-		r.setRunFunc(actx)
+		r.setRunFunc(actx, sub)
 		r.setDebugInitFunc(actx)
 	}
 
@@ -390,6 +713,8 @@ func (r *Runtime) _deactivateContext(actx base.IActionContext) {
 	r.evDispatcher.DestroyEventListener(el)
 
 	r.activeActionsID.Less(actx)
+	// the join points could be waiting for this action
+	r.cjoiner.Wake()
 }
 
 // NewActionContext func creates a new base.IActionContext
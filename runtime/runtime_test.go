@@ -0,0 +1,289 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/cast"
+	"github.com/develatio/nebulant-cli/storage"
+)
+
+// testParams struct. Parameters of the actions of the test provider
+type testParams struct {
+	// time the action takes, honoring the context
+	Sleep string `json:"sleep"`
+	// time the action takes, ignoring the context
+	Block string `json:"block"`
+	Fail  bool   `json:"fail"`
+	// interpolated and saved as the action output
+	Value string `json:"value"`
+	// id of the action run by the error hook once
+	// the failed action cannot be retried
+	Recover string `json:"recover"`
+}
+
+// testRun struct. A run of an action of the test provider
+type testRun struct {
+	value string
+	start time.Time
+	end   time.Time
+}
+
+// testRuns registry, reset by runTestBlueprint
+var testRuns = &struct {
+	mu   sync.Mutex
+	runs map[string][]*testRun
+}{runs: make(map[string][]*testRun)}
+
+func countRuns(actionID string) int {
+	testRuns.mu.Lock()
+	defer testRuns.mu.Unlock()
+	return len(testRuns.runs[actionID])
+}
+
+func getRuns(actionID string) []*testRun {
+	testRuns.mu.Lock()
+	defer testRuns.mu.Unlock()
+	return append([]*testRun{}, testRuns.runs[actionID]...)
+}
+
+type testProvider struct {
+	store base.IStore
+}
+
+func (p *testProvider) DumpPrivateVars(freshStore base.IStore) {}
+
+func (p *testProvider) OnActionErrorHook(aout *base.ActionOutput) ([]*base.Action, error) {
+	params := &testParams{}
+	if err := json.Unmarshal(aout.Action.Parameters, params); err != nil || params.Recover == "" {
+		return nil, nil
+	}
+	if aout.Action.MaxRetries == nil || *aout.Action.MaxRetries > 0 {
+		// retry, as the default hook does
+		return []*base.Action{aout.Action}, nil
+	}
+	return []*base.Action{{
+		ActionID:   params.Recover,
+		Provider:   "test",
+		ActionName: "run",
+		Parameters: json.RawMessage(`{}`),
+	}}, nil
+}
+
+// CreatedResource func. The create actions create a
+// resource with the id of their value
+func (p *testProvider) CreatedResource(action *base.Action, aout *base.ActionOutput) *base.Resource {
+	if action.ActionName != "create" || aout == nil || len(aout.Records) <= 0 || aout.Records[0].ValueID == "" {
+		return nil
+	}
+	id := aout.Records[0].ValueID
+	ra, err := base.NewResourceAction("delete", map[string]string{"value": id})
+	if err != nil {
+		return nil
+	}
+	return &base.Resource{
+		Provider:  action.Provider,
+		Type:      "test",
+		ID:        id,
+		CreatedBy: action.ActionID,
+		Teardown:  []*base.ResourceAction{ra},
+	}
+}
+
+// DeletedResources func. The delete actions delete the
+// resources with the ids of their value
+func (p *testProvider) DeletedResources(action *base.Action) []string {
+	if action.ActionName != "delete" {
+		return nil
+	}
+	params := string(action.Parameters)
+	if err := p.store.Interpolate(&params); err != nil {
+		return nil
+	}
+	return base.ParameterIDs([]byte(params), "value")
+}
+
+func (p *testProvider) CreatesResource(action *base.Action) bool {
+	return action.ActionName == "create"
+}
+
+func (p *testProvider) HandleAction(actx base.IActionContext) (*base.ActionOutput, error) {
+	action := actx.GetAction()
+	params := &testParams{}
+	if err := json.Unmarshal(action.Parameters, params); err != nil {
+		return nil, err
+	}
+	run := &testRun{start: time.Now()}
+	defer func() {
+		run.end = time.Now()
+		testRuns.mu.Lock()
+		defer testRuns.mu.Unlock()
+		testRuns.runs[action.ActionID] = append(testRuns.runs[action.ActionID], run)
+	}()
+	if params.Sleep != "" {
+		d, err := time.ParseDuration(params.Sleep)
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(d):
+		case <-actx.Context().Done():
+			return nil, context.Cause(actx.Context())
+		}
+	}
+	if params.Block != "" {
+		d, err := time.ParseDuration(params.Block)
+		if err != nil {
+			return nil, err
+		}
+		time.Sleep(d)
+	}
+	if params.Fail {
+		return nil, fmt.Errorf("%s failed", action.ActionID)
+	}
+	run.value = params.Value
+	if err := actx.GetStore().Interpolate(&run.value); err != nil {
+		return nil, err
+	}
+	return base.NewActionOutput(action, run.value, &run.value), nil
+}
+
+func TestMain(m *testing.M) {
+	cast.InitSystemBus()
+	newTestProvider := func(store base.IStore) (base.IProvider, error) {
+		return &testProvider{store: store}, nil
+	}
+	cast.SBus.RegisterProviderInitFunc("test", newTestProvider)
+	// group actions, the generic provider
+	// cannot be imported from the runtime
+	cast.SBus.RegisterProviderInitFunc("generic", newTestProvider)
+	os.Exit(m.Run())
+}
+
+func newTestIRB(t *testing.T, data string) *blueprint.IRBlueprint {
+	t.Helper()
+	bp, err := blueprint.NewFromYAML([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	irb, err := blueprint.GenerateIRB(bp, &blueprint.IRBGenConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return irb
+}
+
+// newTestStore func. A root store for the runtime r
+func newTestStore(r *Runtime) base.IStore {
+	st := storage.NewStore()
+	st.SetLogger(&cast.Logger{})
+	st.SetPrivateVar("RUNTIME", r)
+	return st
+}
+
+// runTestIRB func. Run irb from his start action and wait
+// until the runtime ends
+func runTestIRB(t *testing.T, irb *blueprint.IRBlueprint) *Runtime {
+	t.Helper()
+	return startTestIRB(t, irb, func(r *Runtime) error {
+		actx := r.NewAContext(nil, irb.StartAction)
+		actx.SetStore(newTestStore(r))
+		if !r.NewThread(actx) {
+			return fmt.Errorf("cannot start the runtime")
+		}
+		return nil
+	})
+}
+
+// startTestIRB func. Run irb starting his threads with start
+// and wait until the runtime ends
+func startTestIRB(t *testing.T, irb *blueprint.IRBlueprint, start func(r *Runtime) error) *Runtime {
+	t.Helper()
+	testRuns.mu.Lock()
+	testRuns.runs = make(map[string][]*testRun)
+	testRuns.mu.Unlock()
+
+	r := NewRuntime(irb, false)
+	end := r.NewEventListener().WaitUntilChan([]base.EventCode{base.RuntimeEndEvent})
+	if err := start(r); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-end:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the runtime did not end")
+	}
+	return r
+}
+
+func runTestBlueprint(t *testing.T, data string) *Runtime {
+	t.Helper()
+	return runTestIRB(t, newTestIRB(t, data))
+}
+
+func reportStatus(r *Runtime, actionID string) []string {
+	var status []string
+	for _, ar := range r.Report().Actions {
+		if ar.ActionID == actionID {
+			status = append(status, ar.Status)
+		}
+	}
+	return status
+}
+
+// an action ignoring his timeout is routed
+// once the actor returns
+func TestTimeoutWaitsActor(t *testing.T) {
+	r := runTestBlueprint(t, `
+actions:
+  - id: a
+    provider: test
+    action: run
+    first: true
+    parameters: {block: 2500ms}
+    timeout: 50ms
+    next: {ko: [b]}
+  - id: b
+    provider: test
+    action: run
+`)
+	a, b := getRuns("a"), getRuns("b")
+	if len(a) != 1 || len(b) != 1 {
+		t.Fatalf("expected a and b to run once, got %d and %d", len(a), len(b))
+	}
+	if b[0].start.Before(a[0].end) {
+		t.Error("the ko route started before the actor returned")
+	}
+	if status := reportStatus(r, "a"); len(status) != 1 || status[0] != ActionStatusKO {
+		t.Errorf("expected a to be ko, got %v", status)
+	}
+}
@@ -0,0 +1,224 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/blueprint"
+)
+
+// subRun struct. The group of threads running a called blueprint
+type subRun struct {
+	threads map[*Thread]bool
+	// the store of the last action of every finished thread
+	stores []base.IStore
+	errs   []error
+	done   chan struct{}
+	// parent of the action contexts, carries the timeout of the
+	// called blueprint and the deadline of the caller action. nil
+	// for the cleanup chains of the runtime
+	ctx context.Context
+	// run limits of the called blueprints, outermost first
+	limiters []*actionLimiter
+	// sub-routine of the called blueprint whose cleanup chains
+	// run the finally edges of the groups entered by the threads
+	// (itself, or the caller of a foreach body). nil if the
+	// groups belong to the runtime
+	bp *subRun
+	// groups with finally edges that ran, in run order
+	groups []*base.Action
+	// runs the cleanup chains of a called blueprint, the
+	// threads can start after stop
+	cleanup bool
+}
+
+type subRunKey struct{}
+
+// newSubRun func. The sub-routine run by the caller action of ctx.
+// The limits and the blueprint of the caller sub-routine, if any,
+// are inherited
+func newSubRun(ctx context.Context) *subRun {
+	sub := &subRun{
+		threads: make(map[*Thread]bool),
+		done:    make(chan struct{}),
+	}
+	if parent, ok := ctx.Value(subRunKey{}).(*subRun); ok {
+		sub.limiters = parent.limiters
+		sub.bp = parent.bp
+	}
+	sub.ctx = context.WithValue(ctx, subRunKey{}, sub)
+	return sub
+}
+
+// err func. The cause of the end of the sub-routine context, if
+// the called blueprint or the caller action are out of time
+func (s *subRun) err() error {
+	if s == nil || s.ctx == nil || s.ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(s.ctx)
+}
+
+// finishThread func. Called by runtime with the runtime lock held
+func (s *subRun) finishThread(th *Thread) {
+	delete(s.threads, th)
+	if last := th.GetLastRun(); last != nil && last.GetStore() != nil {
+		s.stores = append(s.stores, last.GetStore())
+	}
+	if th.ExitErr != nil {
+		s.errs = append(s.errs, th.ExitErr)
+	}
+	if len(s.threads) <= 0 {
+		close(s.done)
+	}
+}
+
+// LoadArgs func. Insert the parsed blueprint args into the store. Args
+// repeated in the cli are stacked.
+func LoadArgs(st base.IStore, args []*blueprint.IRBArg) error {
+	for _, irbarg := range args {
+		if st.ExistsRefName(irbarg.Name) {
+			// this is an stack var wich can acumulate values
+			err := st.Push(&base.StorageRecord{
+				RefName: irbarg.Name,
+				Aout:    nil,
+				Value:   irbarg.Value,
+				Action:  nil,
+				Secret:  irbarg.Secret,
+			}, "")
+			if err != nil {
+				return err
+			}
+		} else {
+			err := st.Insert(&base.StorageRecord{
+				RefName: irbarg.Name,
+				Aout:    nil,
+				Value:   irbarg.Value,
+				Literal: true,
+				Action:  nil,
+				Secret:  irbarg.Secret,
+			}, "")
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RunBlueprint func. Run the irb as a sub-routine of the current execution
+// using st as root store. The threads of the irb run into this runtime (they
+// can be paused, stopped and debugged as any other thread) under the timeout
+// and the limits of the irb. ctx is the context of the caller action. Blocks
+// until all the threads of the irb and his cleanup chains end and returns the
+// stores of the last actions and the uncaught errors of the irb.
+func (r *Runtime) RunBlueprint(ctx context.Context, irb *blueprint.IRBlueprint, st base.IStore) ([]base.IStore, error) {
+	if irb.StartAction == nil {
+		return nil, fmt.Errorf("first action id not found")
+	}
+	if irb.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, irb.Timeout, &base.TimeoutError{Timeout: irb.Timeout})
+		defer cancel()
+	}
+	sub := newSubRun(ctx)
+	sub.bp = sub
+	if limiter := newActionLimiter(irb.Limits); limiter != nil {
+		sub.limiters = append(append([]*actionLimiter{}, sub.limiters...), limiter)
+	}
+	stores, err := r.runSub(sub, irb.StartAction, st)
+	if cerr := r.runSubCleanup(sub, irb, st, stores, err); cerr != nil {
+		err = errors.Join(err, cerr)
+	}
+	return stores, err
+}
+
+// runSubCleanup func. Run the finally edges of the groups entered by
+// the called blueprint (last group first) and then his on_exit chain,
+// even if some of them fail. The chains run after the timeout of the
+// called blueprint and after stop
+func (r *Runtime) runSubCleanup(sub *subRun, irb *blueprint.IRBlueprint, root base.IStore, stores []base.IStore, err error) error {
+	r.mu.Lock()
+	var chains []*base.Action
+	for i := len(sub.groups) - 1; i >= 0; i-- {
+		chains = append(chains, sub.groups[i].NextAction.NextFinally...)
+	}
+	stopped := r.stopRequested
+	r.mu.Unlock()
+	if irb.OnExit != nil {
+		chains = append(chains, irb.OnExit)
+	}
+	if len(chains) <= 0 {
+		return nil
+	}
+
+	var terr *base.TimeoutError
+	status := ExitStatusSuccess
+	switch {
+	case errors.As(sub.err(), &terr):
+		status = ExitStatusTimeout
+	case stopped:
+		status = ExitStatusStopped
+	case err != nil:
+		status = ExitStatusFailure
+	}
+	st := root.Duplicate()
+	for _, s := range stores {
+		st.Merge(s)
+	}
+	st.SetPrivateVar("EXIT_STATUS", status)
+
+	ctx := context.WithoutCancel(sub.ctx)
+	var errs []error
+	for _, start := range chains {
+		csub := newSubRun(ctx)
+		csub.cleanup = true
+		if _, err := r.runSub(csub, start, st); err != nil {
+			errs = append(errs, fmt.Errorf("cleanup %s failed: %w", start.ActionID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunBody func. Run the body of a foreach action as a sub-routine
+// using st as root store. Blocks like RunBlueprint.
+func (r *Runtime) RunBody(ctx context.Context, action *base.Action, st base.IStore) ([]base.IStore, error) {
+	if action.BodyAction == nil {
+		return nil, fmt.Errorf("action %s has no body", action.ActionID)
+	}
+	return r.runSub(newSubRun(ctx), action.BodyAction, st)
+}
+
+func (r *Runtime) runSub(sub *subRun, start *base.Action, st base.IStore) ([]base.IStore, error) {
+	actx := r.NewAContext(nil, start)
+	actx.SetStore(st)
+	if !r.newThread(actx, sub) {
+		return nil, fmt.Errorf("cannot start %s, the runtime is stopping", start.ActionName)
+	}
+	<-sub.done
+	return sub.stores, errors.Join(sub.errs...)
+}
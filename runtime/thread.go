@@ -26,6 +26,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/develatio/nebulant-cli/base"
@@ -52,41 +54,145 @@ type Thread struct {
 	ThreadStep ThreadStep
 	state      base.RuntimeState
 	step       chan *threadStackCtrl
-	queue      []base.IActionContext
-	done       []base.IActionContext
-	current    base.IActionContext
-	ExitCode   int
-	ExitErr    error // uncaught err
-	runtime    *Runtime
-	elistener  *base.EventListener
+	// guards state, step, queue, done, current and the run
+	// status of current. Only the thread goroutine writes the
+	// queue, the other goroutines read it with the lock held
+	mu        sync.Mutex
+	queue     []base.IActionContext
+	done      []base.IActionContext
+	current   base.IActionContext
+	ExitCode  int
+	ExitErr   error // uncaught err
+	runtime   *Runtime
+	elistener *base.EventListener
+	// not nil if the thread runs a called blueprint
+	sub *subRun
+	// not empty while the current action waits
+	// for a free slot of the run limits. Read by
+	// the debugger from other goroutines
+	waiting atomic.Value
+	// set when a join cancels the branch of the thread
+	cancelled atomic.Bool
 }
 
 func (t *Thread) GetQueue() []base.IActionContext {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.queue
 }
 
 func (t *Thread) GetDone() []base.IActionContext {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.done
 }
 
 func (t *Thread) GetState() base.RuntimeState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.state
 }
 
+// pausedStep func. The step chan if the thread is paused
+func (t *Thread) pausedStep() chan *threadStackCtrl {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state == base.RuntimeStateStill {
+		return t.step
+	}
+	return nil
+}
+
+// ending func. The thread should end before his next action
+func (t *Thread) ending() bool {
+	return t.GetState() == base.RuntimeStateEnding || t.cancelled.Load()
+}
+
+func (t *Thread) setState(state base.RuntimeState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state = state
+}
+
 func (t *Thread) GetCurrent() base.IActionContext {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.current
 }
 
+// head func. The current action or, if there is no
+// current action yet, the first queued one
+func (t *Thread) head() base.IActionContext {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == nil && len(t.queue) > 0 {
+		return t.queue[0]
+	}
+	return t.current
+}
+
+// pushDone func. Mark actx as run by the thread
+func (t *Thread) pushDone(actx base.IActionContext) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = append(t.done, actx)
+}
+
+// pushQueue func. Put actx at the start of the queue
+func (t *Thread) pushQueue(actx base.IActionContext) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.queue = append([]base.IActionContext{actx}, t.queue...)
+}
+
+// setRunStatus func. Set the run status of the current action
+func (t *Thread) setRunStatus(s base.ActionContextRunStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current != nil {
+		t.current.SetRunStatus(s)
+	}
+}
+
+// pendingAny func. Tells if the current action, not run yet,
+// or any of the queued actions is one of ids
+func (t *Thread) pendingAny(ids map[string]bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current != nil && t.current.GetRunStatus() != base.RunStatusDone {
+		if ids[t.current.GetAction().ActionID] {
+			return true
+		}
+	}
+	for _, actx := range t.queue {
+		if ids[actx.GetAction().ActionID] {
+			return true
+		}
+	}
+	return false
+}
+
+// Waiting func. The reason why the current action is
+// waiting to run, empty if not waiting
+func (t *Thread) Waiting() string {
+	w, _ := t.waiting.Load().(string)
+	return w
+}
+
 func (t *Thread) EventListener() *base.EventListener {
 	return t.elistener
 }
 
 func (t *Thread) Pause() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.step = make(chan *threadStackCtrl)
 	t.state = base.RuntimeStateStill
 }
 
 func (t *Thread) Play() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.state == base.RuntimeStatePlay {
 		return
 	}
@@ -95,17 +201,24 @@ func (t *Thread) Play() {
 }
 
 func (t *Thread) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.state == base.RuntimeStateStill {
 		close(t.step)
 	}
 	t.state = base.RuntimeStateEnding
 }
 
+// running func. Tells if the current action is running
+func (t *Thread) running() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current != nil && t.current.GetRunStatus() == base.RunStatusRunning
+}
+
 func (t *Thread) StackUp() (<-chan struct{}, bool) {
-	if t.current != nil {
-		if t.current.GetRunStatus() == base.RunStatusRunning {
-			return nil, false
-		}
+	if t.running() {
+		return nil, false
 	}
 
 	stepctrl := &threadStackCtrl{
@@ -115,8 +228,11 @@ func (t *Thread) StackUp() (<-chan struct{}, bool) {
 
 	// fill chan and return true on ok
 	// this will not wait for chan pop
+	t.mu.Lock()
+	step := t.step
+	t.mu.Unlock()
 	select {
-	case t.step <- stepctrl:
+	case step <- stepctrl:
 		return stepctrl.confirm, true
 	default:
 		// Hey developer!,  what a wonderful day!
@@ -125,10 +241,8 @@ func (t *Thread) StackUp() (<-chan struct{}, bool) {
 }
 
 func (t *Thread) Step() (<-chan struct{}, bool) {
-	if t.current != nil {
-		if t.current.GetRunStatus() == base.RunStatusRunning {
-			return nil, false
-		}
+	if t.running() {
+		return nil, false
 	}
 
 	stepctrl := &threadStackCtrl{
@@ -138,8 +252,11 @@ func (t *Thread) Step() (<-chan struct{}, bool) {
 
 	// fill chan and return true on ok
 	// this will not wait for chan pop
+	t.mu.Lock()
+	step := t.step
+	t.mu.Unlock()
 	select {
-	case t.step <- stepctrl:
+	case step <- stepctrl:
 		return stepctrl.confirm, true
 	default:
 		// Hey developer!,  what a wonderful day!
@@ -147,8 +264,20 @@ func (t *Thread) Step() (<-chan struct{}, bool) {
 	}
 }
 
+// cancel func. Stop the thread and cancel his running action. The
+// thread ends without errors. Safe to call from other goroutines,
+// the thread goroutine sees the cancelled flag between actions
+func (t *Thread) cancel(cause error) {
+	t.cancelled.Store(true)
+	if cur := t.GetCurrent(); cur != nil {
+		cur.Cancel(cause)
+	}
+}
+
 // return true if this thread is handling or has handled actx
 func (t *Thread) hasActionContext(actx base.IActionContext) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.current != nil && t.current == actx {
 		return true
 	}
@@ -167,6 +296,8 @@ func (t *Thread) hasActionContext(actx base.IActionContext) bool {
 
 func (t *Thread) _loadPrev() bool {
 	t.ThreadStep = ThreadBeforeAction
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.current == nil {
 		// cannot prev in unkown action
 		return false
@@ -195,6 +326,8 @@ func (t *Thread) _loadPrev() bool {
 
 func (t *Thread) _loadNext() bool {
 	t.ThreadStep = ThreadBeforeAction
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if len(t.queue) <= 0 {
 		t.current = nil
 		return false
@@ -235,49 +368,68 @@ func (t *Thread) _runCurrent() {
 	var nexts []*base.Action
 
 	if actx.IsThreadPoint() {
-		t.runtime.switchContext(actx)
+		t.runtime.switchContext(actx, t.sub)
 		for _, fkactx := range actx.Children() {
-			if !t.runtime.NewThread(fkactx) {
+			if !t.runtime.newThread(fkactx, t.sub) {
 				// cannot star new thread, probably a stop
 				// event has been received, so stop exec
 				return
 			}
 		}
 		// t.runtime.deactivateContext(actx)
-		t.done = append(t.done, actx)
+		t.pushDone(actx)
 		// stop exec, no more actx stored in queue
 		// so this should be the last actx
 		return
 	}
 
-	t.runtime.switchContext(actx) // deactivate parent, activate self (actx)
+	if t.cancelled.Load() {
+		return
+	}
+
+	// the branch arrives to the join point before leaving his
+	// last action, so the join never goes on without his store
+	var wake chan struct{}
+	if action.JoinThreadsPoint {
+		wake = t.runtime.cjoiner.Join(actx)
+	}
+
+	t.runtime.switchContext(actx, t.sub) // deactivate parent, activate self (actx)
 
 	t.ThreadStep = ThreadIntoAction
 	defer func() {
 		t.ThreadStep = ThreadAfterAction
 	}()
 
+	start := time.Now()
+	var jerr error
 	if action.JoinThreadsPoint {
-		tt := t.runtime.cjoiner.Join(actx)
-		if tt == nil {
+		if wake == nil {
 			// destroy this thread, there is already
 			// a thread handling the join point
 			t.runtime._deactivateContext(actx)
 			return
 		}
+		jerr = t.runtime.waitJoin(t, actx, wake)
+	}
 
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-		for {
-			<-ticker.C
-			if t.state == base.RuntimeStateEnding || t.state == base.RuntimeStateEnd {
-				break
-			}
-			if t.runtime.hasRunningParents(actx) {
-				continue
-			}
-			break
+	if !action.JoinThreadsPoint {
+		release, ok := t.runtime.waitActionSlot(t, action)
+		if !ok {
+			// stopped while waiting, keep actx as pending
+			t.pushQueue(actx)
+			return
 		}
+		defer release()
+		if t.cancelled.Load() {
+			t.pushDone(actx)
+			return
+		}
+		start = time.Now()
+	}
+
+	if len(action.NextAction.NextFinally) > 0 {
+		t.runtime.enterGroup(t.sub, action)
 	}
 
 	cast.PushMixedLogEventBusData(&cast.BusData{
@@ -289,18 +441,42 @@ func (t *Thread) _runCurrent() {
 		ExecutionUUID: t.runtime.irb.ExecutionUUID,
 		Timestamp:     time.Now().UTC().UnixMicro(),
 	})
-	aout, aerr := actx.RunAction()
+	var aout *base.ActionOutput
+	var aerr error
+	if jerr != nil {
+		aerr = jerr
+	} else {
+		aout, aerr = actx.RunAction()
+	}
+
+	if t.cancelled.Load() {
+		// the branch was canceled by a join, the
+		// result of the action is discarded
+		t.runtime.reportAction(t, actx, start, aout, ActionStatusCanceled, false, nil)
+		t.pushDone(actx)
+		return
+	}
 
 	// recopilate nexts and ExitCode
 	if aerr != nil {
-		provider, err := actx.GetStore().GetProvider(action.Provider)
-		if err != nil {
-			// hey dev, this is your fault. Only an internal action has no
-			// provider and you should handle these actions before this
-			log.Panic(errors.Join(err, fmt.Errorf("cannot obtain provider %s", action.Provider)))
+		var err error
+		if !action.JoinThreadsPoint {
+			provider, perr := actx.GetStore().GetProvider(action.Provider)
+			if perr != nil {
+				// hey dev, this is your fault. Only an internal action has no
+				// provider and you should handle these actions before this
+				log.Panic(errors.Join(perr, fmt.Errorf("cannot obtain provider %s", action.Provider)))
+			}
+			hookout := aout
+			if action.Retry != nil {
+				// the attempts of the retry policy are exhausted,
+				// the hook can still recover the error but
+				// should not retry the action again
+				hookout = noRetryOutput(aout)
+			}
+			nexts, err = provider.OnActionErrorHook(hookout)
+			// update action err on provider err hook err (nil will be ignored)
 		}
-		nexts, err = provider.OnActionErrorHook(aout)
-		// update action err on provider err hook err (nil will be ignored)
 
 		aerr = errors.Join(fmt.Errorf("%s %s KO", action.ActionID, action.ActionName), aerr, err)
 		if nexts == nil {
@@ -319,6 +495,12 @@ func (t *Thread) _runCurrent() {
 		nexts = action.NextAction.NextOk
 	}
 
+	if aerr != nil {
+		t.runtime.reportAction(t, actx, start, aout, ActionStatusKO, len(nexts) > 0, aerr)
+	} else {
+		t.runtime.reportAction(t, actx, start, aout, ActionStatusOK, false, nil)
+	}
+
 	// determine KO/OK action event
 	if t.ExitCode > 0 {
 		lvl := base.ErrorLevel
@@ -358,7 +540,7 @@ func (t *Thread) _runCurrent() {
 	case 0:
 		// no more actions, errs and exit code
 		// keep as setted before
-		t.done = append(t.done, actx)
+		t.pushDone(actx)
 		return
 	case 1:
 		// reset exit code and err if exists
@@ -367,24 +549,24 @@ func (t *Thread) _runCurrent() {
 		// NewAContext will create the JoinThreadContext,
 		// right before the run of that context
 		nactx := t.runtime.NewAContext(actx, nexts[0])
-		t.done = append(t.done, actx)
+		t.pushDone(actx)
 		// put new actx at first to prevent fails on t.Back()
-		t.queue = append([]base.IActionContext{nactx}, t.queue...)
+		t.pushQueue(nactx)
 	default:
 		// reset exit code and err if exists
 		t.ExitCode = 0
 		t.ExitErr = nil
 		// more than one, new threads needed
-		t.done = append(t.done, actx)
+		t.pushDone(actx)
 		threadactx := t.runtime.NewAContextThread(actx, nexts)
-		t.queue = append([]base.IActionContext{threadactx}, t.queue...)
+		t.pushQueue(threadactx)
 	}
 }
 
 // closes the thread, his llops and his event listeners
 func (t *Thread) close() {
 	t.ThreadStep = ThreadClose
-	t.state = base.RuntimeStateEnding
+	t.setState(base.RuntimeStateEnding)
 
 	if t.current != nil {
 		// deactivate current action id
@@ -395,10 +577,13 @@ func (t *Thread) close() {
 		t.runtime._deactivateContext(t.done[len(t.done)-1])
 	}
 
+	// keep or discard the thread in the checkpoint
+	t.runtime.saveCheckpoint(t)
+
 	// remove thread t
 	t.runtime.finishThread(t)
 	cast.LogDebug("Thread finished", t.runtime.irb.ExecutionUUID)
-	t.state = base.RuntimeStateEnd
+	t.setState(base.RuntimeStateEnd)
 	// TODO: close t.elistener?
 }
 
@@ -422,6 +607,9 @@ func (t *Thread) Init() {
 	var stpctrl *threadStackCtrl = &threadStackCtrl{back: false}
 	var more bool
 
+	// register the pending actions of the new thread
+	t.runtime.saveCheckpoint(t)
+
 	for {
 		// este control del load me gusta, lo recupero y lo
 		// dejo por aquí
@@ -466,7 +654,16 @@ func (t *Thread) Init() {
 			return
 		}
 
-		if t.state == base.RuntimeStateEnding {
+		if t.ending() {
+			return
+		}
+		if err := t.sub.err(); err != nil {
+			// the called blueprint or his caller are out of
+			// time, the pending actions are not run
+			t.ExitCode = 1
+			if t.ExitErr == nil {
+				t.ExitErr = err
+			}
 			return
 		}
 		// uninitialized step is nil
@@ -474,24 +671,20 @@ func (t *Thread) Init() {
 		// this is the step and confirm before-run
 		// allow step-ing only if the thread state
 		// is in pause
-		if t.state == base.RuntimeStateStill && t.step != nil {
+		if step := t.pausedStep(); step != nil {
 			// getting from closed step, returns nil
-			stpctrl = <-t.step
+			stpctrl = <-step
 			if stpctrl != nil && stpctrl.back {
 				// backing from here means that we should
 				// leave all ready to re-run the same actx
 				// it is possible that current is nil
-				if t.current != nil {
-					t.current.SetRunStatus(base.RunStatusReady)
-				}
+				t.setRunStatus(base.RunStatusReady)
 				goto preload
 			}
 			// set the run status before step confirm
 			// to protect thread from t.Step() calls
 			// in this state
-			if t.current != nil {
-				t.current.SetRunStatus(base.RunStatusRunning)
-			}
+			t.setRunStatus(base.RunStatusRunning)
 			// on close step, confirm is nil
 			if stpctrl != nil && stpctrl.confirm != nil {
 				stpctrl.confirm <- struct{}{}
@@ -499,25 +692,26 @@ func (t *Thread) Init() {
 		}
 
 		t._runCurrent()
-		t.current.SetRunStatus(base.RunStatusDone)
+		t.setRunStatus(base.RunStatusDone)
+		t.runtime.saveCheckpoint(t)
 
-		if t.state == base.RuntimeStateEnding {
+		if t.ending() {
 			return
 		}
 		// closed step is not nil
 		// allow step-ing only if the thread state
 		// is in pause
-		if t.state == base.RuntimeStateStill && t.step != nil {
+		if step := t.pausedStep(); step != nil {
 			// closed step returns nil on get
 			// awaiting chan also returns nil on close
-			stpctrl = <-t.step
+			stpctrl = <-step
 			if stpctrl != nil && stpctrl.back {
 				// backing from here means we should goto
 				// to afterload actx and waiting step to
 				// re-run same actx
 				// set the status ready to leave all ready
 				// on goto prerun to re-run actx
-				t.current.SetRunStatus(base.RunStatusArranging)
+				t.setRunStatus(base.RunStatusArranging)
 				goto prerun
 			}
 		}
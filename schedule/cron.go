@@ -0,0 +1,234 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronDayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// how far Next looks for a matching time
+const cronMaxYears = 5
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: cronMonthNames}
+	// 7 is also sunday
+	cronDow = cronField{name: "day of week", min: 0, max: 7, names: cronDayNames}
+)
+
+// Cron struct. A standard five fields cron expression:
+// minute hour day-of-month month day-of-week
+type Cron struct {
+	expr   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// day of month or day of week are *, if both are
+	// restricted a day matches if any of them matches
+	domStar bool
+	dowStar bool
+}
+
+// ParseCron func. Parse a five fields cron expression. Lists (1,2),
+// ranges (1-5), steps (*/15, 0-30/5), month and day names (jan, mon)
+// and the @yearly, @monthly, @weekly, @daily and @hourly macros are
+// supported
+func ParseCron(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "@") {
+		m, ok := cronMacros[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cron macro %s", spec)
+		}
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("bad cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+	c := &Cron{
+		expr:    expr,
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	for i, dst := range []struct {
+		f    cronField
+		bits *uint64
+	}{
+		{cronMinute, &c.minute},
+		{cronHour, &c.hour},
+		{cronDom, &c.dom},
+		{cronMonth, &c.month},
+		{cronDow, &c.dow},
+	} {
+		*dst.bits, err = dst.f.parse(fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("bad cron expression %q: %w", expr, err)
+		}
+	}
+	// 7 is sunday
+	if c.dow&(1<<7) > 0 {
+		c.dow = c.dow | 1
+	}
+	if c.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("bad cron expression %q: never matches", expr)
+	}
+	return c, nil
+}
+
+// parse func. Parse a field into a bitset of the allowed values
+func (f cronField) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		rng, step, hasStep := strings.Cut(item, "/")
+		start, end := f.min, f.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err error
+			if start, err = f.value(a); err != nil {
+				return 0, err
+			}
+			if end, err = f.value(b); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("bad %s range %s", f.name, rng)
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			start = v
+			if !hasStep {
+				// a/step means from a to max
+				end = v
+			}
+		}
+		inc := 1
+		if hasStep {
+			var err error
+			inc, err = strconv.Atoi(step)
+			if err != nil || inc <= 0 {
+				return 0, fmt.Errorf("bad %s step %s", f.name, step)
+			}
+		}
+		for v := start; v <= end; v += inc {
+			bits = bits | 1<<uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value func. A number or a name of the field
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad %s %q", f.name, s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s %d out of range [%d-%d]", f.name, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// String func
+func (c *Cron) String() string {
+	return c.expr
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) > 0
+	dow := c.dow&(1<<uint(t.Weekday())) > 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next func. The first time after t matching the expression, in the
+// location of t. Zero time if there is no match in the next years
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + cronMaxYears
+	for t.Year() <= limit {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
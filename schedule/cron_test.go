@@ -0,0 +1,115 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/develatio/nebulant-cli/schedule"
+)
+
+func TestCronNext(t *testing.T) {
+	// wednesday
+	from := time.Date(2024, time.May, 15, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.May, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.May, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, time.May, 16, 3, 0, 0, 0, time.UTC)},
+		{"30 9-17/4 * * *", time.Date(2024, time.May, 15, 13, 30, 0, 0, time.UTC)},
+		{"0 0 * * mon", time.Date(2024, time.May, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.May, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,20 jun *", time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// day of month or day of week
+		{"0 12 1 * fri", time.Date(2024, time.May, 17, 12, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.May, 15, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		c, err := schedule.ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if next := c.Next(from); !next.Equal(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.expr, tt.expected, next)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@sometimes",
+		"0 0 30 2 *",
+	} {
+		if _, err := schedule.ParseCron(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
+
+func TestFileValidate(t *testing.T) {
+	f := &schedule.File{Jobs: []*schedule.Job{
+		{Name: "a", Cron: "@daily", Blueprint: "develatio/utils/debug"},
+	}}
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if f.History != schedule.DefaultHistory || f.Jobs[0].History != schedule.DefaultHistory {
+		t.Errorf("expected default history %d", schedule.DefaultHistory)
+	}
+	if f.Jobs[0].Overlap != schedule.OverlapSkip {
+		t.Errorf("expected default overlap %s, got %s", schedule.OverlapSkip, f.Jobs[0].Overlap)
+	}
+
+	for name, job := range map[string]*schedule.Job{
+		"bad overlap":  {Name: "a", Cron: "@daily", Blueprint: "x/y/z", Overlap: "never"},
+		"no blueprint": {Name: "a", Cron: "@daily"},
+		"bad timeout":  {Name: "a", Cron: "@daily", Blueprint: "x/y/z", Timeout: "soon"},
+		"bad name":     {Name: "a/b", Cron: "@daily", Blueprint: "x/y/z"},
+	} {
+		f := &schedule.File{Jobs: []*schedule.Job{job}}
+		if err := f.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	dup := &schedule.File{Jobs: []*schedule.Job{
+		{Name: "a", Cron: "@daily", Blueprint: "x/y/z"},
+		{Name: "a", Cron: "@daily", Blueprint: "x/y/z"},
+	}}
+	if err := dup.Validate(); err == nil {
+		t.Error("expected duplicated job error")
+	}
+}
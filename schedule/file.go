@@ -0,0 +1,215 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package schedule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// don't start a run while the previous one is running
+	OverlapSkip = "skip"
+	// start the run when the previous one ends. Only
+	// one run is queued
+	OverlapQueue = "queue"
+	// stop the running run and start the new one
+	OverlapReplace = "replace"
+	// run concurrently
+	OverlapAllow = "allow"
+)
+
+// DefaultHistory is the number of results kept per job
+const DefaultHistory = 10
+
+// File struct. A schedule file (YAML or JSON)
+//
+//	history: 10
+//	jobs:
+//	  - name: nightly-backup
+//	    cron: "0 3 * * *"
+//	    blueprint: develatio/ops/backup
+//	    args: ["--region=eu-west-1"]
+//	    overlap: skip
+type File struct {
+	// results kept per job, DefaultHistory if zero
+	History int    `yaml:"history" json:"history"`
+	Jobs    []*Job `yaml:"jobs" json:"jobs"`
+	// dir of the file, relative blueprint and
+	// vars file paths are resolved from here
+	dir string
+}
+
+// Job struct
+type Job struct {
+	Name string `yaml:"name" json:"name"`
+	Cron string `yaml:"cron" json:"cron"`
+	// url of the blueprint (org/coll/bp, https://...,
+	// file://...) or a path starting with ./, ../ or /
+	Blueprint string   `yaml:"blueprint" json:"blueprint"`
+	Args      []string `yaml:"args" json:"args"`
+	VarsFile  string   `yaml:"vars_file" json:"vars_file"`
+	// skip (default), queue, replace or allow
+	Overlap string `yaml:"overlap" json:"overlap"`
+	// stop the run after this time (e.g. 30m)
+	Timeout string `yaml:"timeout" json:"timeout"`
+	// results kept for this job, File.History if zero
+	History int `yaml:"history" json:"history"`
+	// refuse to run the blueprint (and the blueprints
+	// called by it) without a valid signature
+	RequireSignature bool `yaml:"require_signature" json:"require_signature"`
+	// dir with the trusted public keys, blueprint.TrustedKeysPath() if empty
+	TrustedKeys string `yaml:"trusted_keys" json:"trusted_keys"`
+
+	cron    *Cron
+	timeout time.Duration
+}
+
+// LoadFile func
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- Not a file inclusion, just a schedule file
+	if err != nil {
+		return nil, err
+	}
+	f := &File{}
+	// JSON is also YAML
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("cannot parse schedule file %s: %w", path, err)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	f.dir = filepath.Dir(abs)
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Validate func. Check the jobs and set the defaults
+func (f *File) Validate() error {
+	if f.History < 0 {
+		return fmt.Errorf("history should be greater than zero")
+	}
+	if f.History == 0 {
+		f.History = DefaultHistory
+	}
+	if len(f.Jobs) <= 0 {
+		return fmt.Errorf("the schedule has no jobs")
+	}
+	names := make(map[string]bool)
+	for i, job := range f.Jobs {
+		if job.Name == "" {
+			return fmt.Errorf("job %d has no name", i)
+		}
+		if strings.ContainsAny(job.Name, "/\\ ") {
+			return fmt.Errorf("bad job name %q: spaces and slashes are not allowed", job.Name)
+		}
+		if names[job.Name] {
+			return fmt.Errorf("duplicated job %s", job.Name)
+		}
+		names[job.Name] = true
+		if err := job.validate(f); err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+	}
+	return nil
+}
+
+func (j *Job) validate(f *File) error {
+	var err error
+	if j.cron, err = ParseCron(j.Cron); err != nil {
+		return err
+	}
+	if j.Blueprint == "" {
+		return fmt.Errorf("no blueprint")
+	}
+	switch j.Overlap {
+	case "":
+		j.Overlap = OverlapSkip
+	case OverlapSkip, OverlapQueue, OverlapReplace, OverlapAllow:
+	default:
+		return fmt.Errorf("unknown overlap policy %s, use one of %s, %s, %s or %s", j.Overlap, OverlapSkip, OverlapQueue, OverlapReplace, OverlapAllow)
+	}
+	if j.Timeout != "" {
+		if j.timeout, err = time.ParseDuration(j.Timeout); err != nil {
+			return fmt.Errorf("bad timeout: %w", err)
+		}
+	}
+	if j.History < 0 {
+		return fmt.Errorf("history should be greater than zero")
+	}
+	if j.History == 0 {
+		j.History = f.History
+	}
+	if j.VarsFile != "" && !filepath.IsAbs(j.VarsFile) {
+		j.VarsFile = filepath.Join(f.dir, j.VarsFile)
+	}
+	if isPath(j.Blueprint) && !filepath.IsAbs(j.Blueprint) {
+		j.Blueprint = filepath.Join(f.dir, j.Blueprint)
+	}
+	if j.TrustedKeys != "" && !filepath.IsAbs(j.TrustedKeys) {
+		j.TrustedKeys = filepath.Join(f.dir, j.TrustedKeys)
+	}
+	return nil
+}
+
+func isPath(s string) bool {
+	return strings.HasPrefix(s, "./") || strings.HasPrefix(s, "../") || filepath.IsAbs(s)
+}
+
+// Next func. The next run of the job after t
+func (j *Job) Next(t time.Time) time.Time {
+	return j.cron.Next(t)
+}
+
+// blueprintURL func
+func (j *Job) blueprintURL() (*blueprint.BlueprintURL, error) {
+	if isPath(j.Blueprint) {
+		return blueprint.ParsePath(j.Blueprint)
+	}
+	return blueprint.ParseURL(j.Blueprint)
+}
+
+// irb func. A new IRB of the job blueprint, the blueprint is
+// read again on every run
+func (j *Job) irb() (*blueprint.IRBlueprint, error) {
+	bpUrl, err := j.blueprintURL()
+	if err != nil {
+		return nil, err
+	}
+	return blueprint.NewIRBFromAny(bpUrl, &blueprint.IRBGenConfig{
+		Args:             j.Args,
+		VarsFile:         j.VarsFile,
+		Timeout:          j.timeout,
+		RequireSignature: j.RequireSignature,
+		TrustedKeys:      j.TrustedKeys,
+	})
+}
@@ -0,0 +1,64 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package schedule_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/schedule"
+)
+
+func TestLoadFileSignature(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schedule.yaml")
+	data := `
+jobs:
+  - name: signed
+    cron: "0 3 * * *"
+    blueprint: ./bp.yaml
+    require_signature: true
+    trusted_keys: ./keys
+  - name: unsigned
+    cron: "0 4 * * *"
+    blueprint: develatio/ops/backup
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sf, err := schedule.LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := sf.Jobs[0]
+	if !signed.RequireSignature {
+		t.Error("expected require_signature in the signed job")
+	}
+	if signed.TrustedKeys != filepath.Join(dir, "keys") {
+		t.Errorf("expected trusted keys relative to the schedule file, got %s", signed.TrustedKeys)
+	}
+	if sf.Jobs[1].RequireSignature || sf.Jobs[1].TrustedKeys != "" {
+		t.Error("unexpected signature settings in the unsigned job")
+	}
+}
@@ -0,0 +1,358 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/cast"
+	"github.com/develatio/nebulant-cli/config"
+	"github.com/develatio/nebulant-cli/executive"
+	"github.com/develatio/nebulant-cli/runtime"
+)
+
+const (
+	// the run is still running
+	ResultRunning = "running"
+	// the blueprint cannot be loaded or the run cannot start
+	ResultError = "error"
+)
+
+// Result struct. A run of a job
+type Result struct {
+	ExecutionUUID string    `json:"execution_uuid,omitempty"`
+	Scheduled     time.Time `json:"scheduled"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	// seconds
+	Duration float64 `json:"duration"`
+	// running, error or the exit status of the
+	// runtime (success, failure, stopped, timeout)
+	Status   string          `json:"status"`
+	ExitCode int             `json:"exit_code"`
+	Error    string          `json:"error,omitempty"`
+	Report   *runtime.Report `json:"report,omitempty"`
+}
+
+// HistoryPath func. Dir of the results of the scheduled jobs
+func HistoryPath() string {
+	return filepath.Join(config.AppHomePath(), "schedule")
+}
+
+type jobState struct {
+	mu  sync.Mutex
+	job *Job
+	// running results by execution uuid
+	running map[string]*Result
+	queued  bool
+	skipped int
+	next    time.Time
+	// oldest first
+	results []*Result
+}
+
+// clock interface. The time source of the scheduler
+type clock interface {
+	Now() time.Time
+	// NewTimer func. As time.NewTimer, returns the channel
+	// and the stop func of the timer
+	NewTimer(d time.Duration) (<-chan time.Time, func() bool)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	timer := time.NewTimer(d)
+	return timer.C, timer.Stop
+}
+
+// Scheduler struct. Run the jobs of a schedule file
+// through the Director
+type Scheduler struct {
+	file   *File
+	jobs   []*jobState
+	byName map[string]*jobState
+	stop   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+	clock  clock
+	// hand the runs and the stop instructions to the Director
+	handleIRB   func(hirbcfg *executive.HandleIRBConfig)
+	instruction func(instr *executive.ExecCtrlInstruction)
+}
+
+// NewScheduler func. The history of the jobs is loaded
+// from HistoryPath
+func NewScheduler(f *File) *Scheduler {
+	s := &Scheduler{
+		file:   f,
+		byName: make(map[string]*jobState),
+		stop:   make(chan struct{}),
+		clock:  realClock{},
+		handleIRB: func(hirbcfg *executive.HandleIRBConfig) {
+			executive.MDirector.HandleIRB <- hirbcfg
+		},
+		instruction: func(instr *executive.ExecCtrlInstruction) {
+			executive.MDirector.ExecInstruction <- instr
+		},
+	}
+	for _, job := range f.Jobs {
+		js := &jobState{
+			job:     job,
+			running: make(map[string]*Result),
+		}
+		if err := js.loadHistory(); err != nil {
+			cast.LogWarn(fmt.Sprintf("Cannot load the history of job %s: %v", job.Name, err), nil)
+		}
+		s.jobs = append(s.jobs, js)
+		s.byName[job.Name] = js
+	}
+	return s
+}
+
+// Start func. Start to wait for the jobs. The Director should
+// be running in server mode
+func (s *Scheduler) Start() {
+	for _, js := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(js)
+	}
+}
+
+// Stop func. No more runs are started, the running
+// ones are not stopped
+func (s *Scheduler) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	s.wg.Wait()
+}
+
+func (s *Scheduler) stopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// loop func. Wait for every activation of the job
+func (s *Scheduler) loop(js *jobState) {
+	defer s.wg.Done()
+	for {
+		next := js.job.cron.Next(s.clock.Now())
+		js.mu.Lock()
+		js.next = next
+		js.mu.Unlock()
+		if next.IsZero() {
+			return
+		}
+		cast.LogInfo(fmt.Sprintf("[Schedule] Next run of %s at %s", js.job.Name, next.Format(time.RFC3339)), nil)
+		fire, stop := s.clock.NewTimer(next.Sub(s.clock.Now()))
+		select {
+		case <-s.stop:
+			stop()
+			return
+		case <-fire:
+			s.Trigger(js.job.Name, next)
+		}
+	}
+}
+
+// Trigger func. Run the job now, as its overlap policy allows
+func (s *Scheduler) Trigger(name string, scheduled time.Time) error {
+	js, ok := s.byName[name]
+	if !ok {
+		return fmt.Errorf("unknown job %s", name)
+	}
+	if s.stopped() {
+		return fmt.Errorf("the scheduler is stopped")
+	}
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if len(js.running) > 0 {
+		switch js.job.Overlap {
+		case OverlapSkip:
+			js.skipped++
+			cast.LogWarn(fmt.Sprintf("[Schedule] %s is still running, skipping this run", js.job.Name), nil)
+			return nil
+		case OverlapQueue:
+			js.queued = true
+			cast.LogInfo(fmt.Sprintf("[Schedule] %s is still running, the run is queued", js.job.Name), nil)
+			return nil
+		case OverlapReplace:
+			js.queued = true
+			cast.LogInfo(fmt.Sprintf("[Schedule] %s is still running, stopping it", js.job.Name), nil)
+			for uuid := range js.running {
+				instr := &executive.ExecCtrlInstruction{
+					Instruction:   executive.ExecStop,
+					ExecutionUUID: cast.SEP(uuid),
+				}
+				// don't block the job while the
+				// director ends the runs
+				go s.instruction(instr)
+			}
+			return nil
+		}
+	}
+	s.run(js, scheduled)
+	return nil
+}
+
+// run func. Start a new run of the job. Called with
+// the job lock held
+func (s *Scheduler) run(js *jobState, scheduled time.Time) {
+	res := &Result{
+		Scheduled: scheduled,
+		Start:     s.clock.Now(),
+		Status:    ResultRunning,
+	}
+	js.addResult(res)
+
+	irb, err := js.job.irb()
+	if err != nil {
+		res.End = s.clock.Now()
+		res.Status = ResultError
+		res.ExitCode = 1
+		res.Error = base.Redact(err.Error())
+		cast.LogErr(fmt.Sprintf("[Schedule] Cannot run %s: %v", js.job.Name, err), nil)
+		js.saveHistory()
+		return
+	}
+	res.ExecutionUUID = *irb.ExecutionUUID
+	js.running[res.ExecutionUUID] = res
+	cast.LogInfo(fmt.Sprintf("[Schedule] Running %s (execution %s)", js.job.Name, res.ExecutionUUID), nil)
+
+	manager := executive.NewManager(true)
+	manager.PrepareIRB(irb)
+	hirbcfg := &executive.HandleIRBConfig{
+		Manager: manager,
+		OnEnd: func(m *executive.Manager, err error) {
+			s.finish(js, res, m, err)
+		},
+	}
+	// OnEnd takes the job lock
+	go s.handleIRB(hirbcfg)
+}
+
+// finish func. Save the result of the run and start
+// the queued run if any
+func (s *Scheduler) finish(js *jobState, res *Result, m *executive.Manager, err error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	delete(js.running, res.ExecutionUUID)
+	res.End = s.clock.Now()
+	res.Duration = res.End.Sub(res.Start).Seconds()
+	if m != nil && m.Runtime != nil {
+		res.Report = m.Runtime.Report()
+		res.Status = res.Report.ExitStatus
+		res.ExitCode = res.Report.ExitCode
+	}
+	if err != nil {
+		res.Status = ResultError
+		res.Error = base.Redact(err.Error())
+		if res.ExitCode == 0 {
+			res.ExitCode = 1
+		}
+	}
+	if res.ExitCode > 0 {
+		cast.LogErr(fmt.Sprintf("[Schedule] %s ended with status %s", js.job.Name, res.Status), nil)
+	} else {
+		cast.LogInfo(fmt.Sprintf("[Schedule] %s ended with status %s", js.job.Name, res.Status), nil)
+	}
+	js.saveHistory()
+
+	if js.queued && len(js.running) == 0 && !s.stopped() {
+		js.queued = false
+		go func() {
+			js.mu.Lock()
+			defer js.mu.Unlock()
+			if !s.stopped() {
+				s.run(js, s.clock.Now())
+			}
+		}()
+	}
+}
+
+// addResult func. Called with the job lock held
+func (js *jobState) addResult(res *Result) {
+	js.results = append(js.results, res)
+	if over := len(js.results) - js.job.History; over > 0 {
+		js.results = js.results[over:]
+	}
+}
+
+func (js *jobState) historyPath() string {
+	return filepath.Join(HistoryPath(), js.job.Name+".json")
+}
+
+func (js *jobState) loadHistory() error {
+	data, err := os.ReadFile(js.historyPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var results []*Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		return err
+	}
+	for _, res := range results {
+		if res.Status == ResultRunning {
+			// the scheduler ended while running
+			res.Status = ResultError
+			res.Error = "interrupted"
+		}
+		js.addResult(res)
+	}
+	return nil
+}
+
+// saveHistory func. Called with the job lock held
+func (js *jobState) saveHistory() {
+	data, err := json.Marshal(js.results)
+	if err == nil {
+		err = os.MkdirAll(HistoryPath(), 0700)
+	}
+	if err == nil {
+		tmp := js.historyPath() + ".tmp"
+		if err = os.WriteFile(tmp, data, 0600); err == nil {
+			err = os.Rename(tmp, js.historyPath())
+		}
+	}
+	if err != nil {
+		cast.LogWarn(fmt.Sprintf("Cannot save the history of job %s: %v", js.job.Name, err), nil)
+	}
+}
@@ -0,0 +1,276 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package schedule
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/develatio/nebulant-cli/cast"
+	"github.com/develatio/nebulant-cli/executive"
+)
+
+func TestMain(m *testing.M) {
+	cast.InitSystemBus()
+	os.Exit(m.Run())
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// fakeClock struct. The timers fire when the clock is set past them
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	added  chan struct{}
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, added: make(chan struct{}, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := &fakeTimer{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, timer)
+	c.added <- struct{}{}
+	return timer.c, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, t := range c.timers {
+			if t == timer {
+				c.timers = append(c.timers[:i], c.timers[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// set func. Move the clock to now, firing the due timers
+func (c *fakeClock) set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+	var pending []*fakeTimer
+	for _, t := range c.timers {
+		if t.at.After(now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- now
+	}
+	c.timers = pending
+}
+
+// waitTimer func. Wait until the scheduler waits for the next run
+func (c *fakeClock) waitTimer(t *testing.T) {
+	t.Helper()
+	select {
+	case <-c.added:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the scheduler is not waiting for the next run")
+	}
+}
+
+// fakeDirector struct. Receives the runs and the stop
+// instructions of the scheduler
+type fakeDirector struct {
+	runs  chan *executive.HandleIRBConfig
+	stops chan *executive.ExecCtrlInstruction
+}
+
+func (d *fakeDirector) nextRun(t *testing.T) *executive.HandleIRBConfig {
+	t.Helper()
+	select {
+	case hirbcfg := <-d.runs:
+		return hirbcfg
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job did not run")
+	}
+	return nil
+}
+
+func (d *fakeDirector) noRun(t *testing.T) {
+	t.Helper()
+	select {
+	case <-d.runs:
+		t.Fatal("unexpected run of the job")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+const testScheduleBP = `
+actions:
+  - id: a
+    provider: generic
+    action: log
+    first: true
+`
+
+// newTestScheduler func. A scheduler of a job run every
+// minute with the overlap policy, started at now
+func newTestScheduler(t *testing.T, overlap string, now time.Time) (*Scheduler, *fakeClock, *fakeDirector) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bp.yaml"), []byte(testScheduleBP), 0600); err != nil {
+		t.Fatal(err)
+	}
+	data := "jobs:\n  - name: job\n    cron: \"* * * * *\"\n    blueprint: ./bp.yaml\n    overlap: " + overlap + "\n"
+	path := filepath.Join(dir, "schedule.yaml")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sf, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clk := newFakeClock(now)
+	d := &fakeDirector{
+		runs:  make(chan *executive.HandleIRBConfig, 8),
+		stops: make(chan *executive.ExecCtrlInstruction, 8),
+	}
+	s := NewScheduler(sf)
+	s.clock = clk
+	s.handleIRB = func(hirbcfg *executive.HandleIRBConfig) { d.runs <- hirbcfg }
+	s.instruction = func(instr *executive.ExecCtrlInstruction) { d.stops <- instr }
+	s.Start()
+	t.Cleanup(s.Stop)
+	return s, clk, d
+}
+
+func TestSchedulerFire(t *testing.T) {
+	start := time.Date(2024, time.May, 15, 10, 7, 30, 0, time.UTC)
+	s, clk, d := newTestScheduler(t, OverlapSkip, start)
+	js := s.byName["job"]
+
+	clk.waitTimer(t)
+	// not yet
+	clk.set(start.Add(20 * time.Second))
+	d.noRun(t)
+
+	scheduled := time.Date(2024, time.May, 15, 10, 8, 0, 0, time.UTC)
+	clk.set(scheduled)
+	hirbcfg := d.nextRun(t)
+	clk.waitTimer(t)
+
+	js.mu.Lock()
+	if len(js.results) != 1 || !js.results[0].Scheduled.Equal(scheduled) || js.results[0].Status != ResultRunning {
+		t.Errorf("expected a running result scheduled at %v, got %+v", scheduled, js.results)
+	}
+	if next := time.Date(2024, time.May, 15, 10, 9, 0, 0, time.UTC); !js.next.Equal(next) {
+		t.Errorf("expected the next run at %v, got %v", next, js.next)
+	}
+	js.mu.Unlock()
+
+	clk.set(scheduled.Add(5 * time.Second))
+	hirbcfg.OnEnd(nil, nil)
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if len(js.running) != 0 {
+		t.Error("the run did not end")
+	}
+	if d := js.results[0].Duration; d != 5 {
+		t.Errorf("expected a run of 5s on the scheduler clock, got %vs", d)
+	}
+}
+
+func TestSchedulerOverlap(t *testing.T) {
+	start := time.Date(2024, time.May, 15, 10, 7, 30, 0, time.UTC)
+	first := time.Date(2024, time.May, 15, 10, 8, 0, 0, time.UTC)
+	second := first.Add(time.Minute)
+	tests := []struct {
+		overlap string
+		// the second activation starts a run while the
+		// first one is running
+		concurrent bool
+		// the second activation stops the first run
+		stops bool
+		// the end of the first run starts the second one
+		afterEnd bool
+		skipped  int
+	}{
+		{overlap: OverlapSkip, skipped: 1},
+		{overlap: OverlapQueue, afterEnd: true},
+		{overlap: OverlapReplace, stops: true, afterEnd: true},
+		{overlap: OverlapAllow, concurrent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.overlap, func(t *testing.T) {
+			s, clk, d := newTestScheduler(t, tt.overlap, start)
+			js := s.byName["job"]
+
+			clk.waitTimer(t)
+			clk.set(first)
+			run1 := d.nextRun(t)
+			clk.waitTimer(t)
+
+			clk.set(second)
+			clk.waitTimer(t)
+			if tt.concurrent {
+				d.nextRun(t)
+			} else {
+				d.noRun(t)
+			}
+			select {
+			case instr := <-d.stops:
+				if !tt.stops {
+					t.Error("unexpected stop of the running run")
+				} else if *instr.ExecutionUUID != *run1.Manager.IRB.ExecutionUUID || instr.Instruction != executive.ExecStop {
+					t.Errorf("unexpected instruction %+v", instr)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.stops {
+					t.Error("the running run was not stopped")
+				}
+			}
+
+			run1.OnEnd(nil, nil)
+			if tt.afterEnd {
+				d.nextRun(t)
+			} else {
+				d.noRun(t)
+			}
+			js.mu.Lock()
+			defer js.mu.Unlock()
+			if js.skipped != tt.skipped {
+				t.Errorf("expected %d skipped runs, got %d", tt.skipped, js.skipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package schedule
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/develatio/nebulant-cli/nhttpd"
+)
+
+// JobStatus struct
+type JobStatus struct {
+	Name      string    `json:"name"`
+	Cron      string    `json:"cron"`
+	Blueprint string    `json:"blueprint"`
+	Overlap   string    `json:"overlap"`
+	Next      time.Time `json:"next"`
+	// execution uuids of the running runs
+	Running []string `json:"running"`
+	Queued  bool     `json:"queued"`
+	// runs skipped by the overlap policy
+	Skipped int `json:"skipped"`
+	// the last result, without report
+	Last *Result `json:"last,omitempty"`
+	// newest first, only in the job detail
+	Results []*Result `json:"results,omitempty"`
+}
+
+// Status func. Status of every job
+func (s *Scheduler) Status() []*JobStatus {
+	var sts []*JobStatus
+	for _, js := range s.jobs {
+		sts = append(sts, js.status(false))
+	}
+	return sts
+}
+
+// JobStatus func. Status of the job with his results
+func (s *Scheduler) JobStatus(name string) (*JobStatus, bool) {
+	js, ok := s.byName[name]
+	if !ok {
+		return nil, false
+	}
+	return js.status(true), true
+}
+
+func (js *jobState) status(results bool) *JobStatus {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	st := &JobStatus{
+		Name:      js.job.Name,
+		Cron:      js.job.Cron,
+		Blueprint: js.job.Blueprint,
+		Overlap:   js.job.Overlap,
+		Next:      js.next,
+		Running:   []string{},
+		Queued:    js.queued,
+		Skipped:   js.skipped,
+	}
+	for uuid := range js.running {
+		st.Running = append(st.Running, uuid)
+	}
+	sort.Strings(st.Running)
+	if len(js.results) > 0 {
+		last := *js.results[len(js.results)-1]
+		last.Report = nil
+		st.Last = &last
+	}
+	if results {
+		st.Results = []*Result{}
+		for i := len(js.results) - 1; i >= 0; i-- {
+			res := *js.results[i]
+			st.Results = append(st.Results, &res)
+		}
+	}
+	return st
+}
+
+// AddViews func. Expose the status of the jobs through the
+// local http server:
+//
+//	GET  /schedule/             status of every job
+//	GET  /schedule/<job>/       status and results of a job
+//	POST /schedule/<job>/run/   run the job now
+func (s *Scheduler) AddViews(srv *nhttpd.Httpd) {
+	srv.AddView(`^/schedule/?$`, s.jobsView)
+	srv.AddView(`^/schedule/([^/]+)/?$`, s.jobView)
+	srv.AddView(`^/schedule/([^/]+)/run/?$`, s.runJobView)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Scheduler) jobsView(w http.ResponseWriter, r *http.Request, matches [][]string) {
+	if r.Method != "GET" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": s.Status()})
+}
+
+func (s *Scheduler) jobView(w http.ResponseWriter, r *http.Request, matches [][]string) {
+	if r.Method != "GET" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	st, ok := s.JobStatus(matches[0][1])
+	if !ok {
+		http.Error(w, "404 Not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (s *Scheduler) runJobView(w http.ResponseWriter, r *http.Request, matches [][]string) {
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Origin")
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name := matches[0][1]
+	if _, ok := s.byName[name]; !ok {
+		http.Error(w, "404 Not found", http.StatusNotFound)
+		return
+	}
+	if err := s.Trigger(name, s.clock.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,150 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// a lock older than this is considered abandoned by a dead process
+const staleLockAge = 30 * time.Second
+
+// FileBackend struct. One json file per namespace. The revision of
+// the document is the version checked on save, under a lock file
+type FileBackend struct {
+	Dir string
+	// max time waiting for the lock
+	LockTimeout time.Duration
+}
+
+// NewFileBackend func
+func NewFileBackend(dir string) *FileBackend {
+	return &FileBackend{Dir: dir, LockTimeout: 10 * time.Second}
+}
+
+func (b *FileBackend) path(namespace string) string {
+	return filepath.Join(b.Dir, namespace+".json")
+}
+
+// Load func
+func (b *FileBackend) Load(ctx context.Context, namespace string) (*Document, error) {
+	// the read doesn't block, but a canceled
+	// read fails as in the other backends
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	doc := &Document{Values: make(map[string]json.RawMessage)}
+	data, err := os.ReadFile(b.path(namespace))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, doc); err != nil {
+			return nil, fmt.Errorf("corrupted state file %s: %v", b.path(namespace), err)
+		}
+	}
+	doc.version = strconv.Itoa(doc.Revision)
+	return doc, nil
+}
+
+// Save func
+func (b *FileBackend) Save(ctx context.Context, namespace string, doc *Document) error {
+	if err := os.MkdirAll(b.Dir, 0700); err != nil {
+		return err
+	}
+	unlock, err := b.lock(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := b.Load(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	if current.version != doc.version {
+		return ErrConflict
+	}
+	next := *doc
+	next.Revision = current.Revision + 1
+	next.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(&next, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(b.Dir, namespace+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), b.path(namespace)); err != nil {
+		return err
+	}
+	doc.Revision = next.Revision
+	doc.Updated = next.Updated
+	doc.version = strconv.Itoa(next.Revision)
+	return nil
+}
+
+// lock func. Lock file shared with other processes
+func (b *FileBackend) lock(ctx context.Context, namespace string) (func(), error) {
+	lockpath := b.path(namespace) + ".lock"
+	deadline := time.Now().Add(b.LockTimeout)
+	for {
+		f, err := os.OpenFile(lockpath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			fmt.Fprintf(f, "%d", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lockpath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if finfo, err := os.Stat(lockpath); err == nil && time.Since(finfo.ModTime()) > staleLockAge {
+			os.Remove(lockpath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("state namespace %s is locked by %s", namespace, lockpath)
+		}
+		select {
+		case <-time.After(20 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
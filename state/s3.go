@@ -0,0 +1,142 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// S3Backend struct. One object per namespace. Saves are conditional
+// writes on the ETag of the loaded object (If-Match), or on the object
+// not existing (If-None-Match) for new namespaces. The service must
+// support conditional writes, as AWS S3, Cloudflare R2 and MinIO do
+type S3Backend struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Backend func. endpoint is the url of S3 compatible services,
+// empty for AWS S3
+func NewS3Backend(ctx context.Context, bucket string, prefix string, endpoint string, region string) (*S3Backend, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	} else if endpoint != "" {
+		opts = append(opts, awsconfig.WithRegion("auto"))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &S3Backend{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+func (b *S3Backend) key(namespace string) string {
+	return b.prefix + namespace + ".json"
+}
+
+// Load func
+func (b *S3Backend) Load(ctx context.Context, namespace string) (*Document, error) {
+	doc := &Document{Values: make(map[string]json.RawMessage)}
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.key(namespace)),
+	})
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) || responseStatus(err) == http.StatusNotFound {
+			// new namespace, version "" is saved with If-None-Match
+			return doc, nil
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	doc.version = aws.ToString(out.ETag)
+	return doc, nil
+}
+
+// Save func
+func (b *S3Backend) Save(ctx context.Context, namespace string, doc *Document) error {
+	next := *doc
+	next.Revision++
+	next.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(&next, "", "  ")
+	if err != nil {
+		return err
+	}
+	condition := smithyhttp.SetHeaderValue("If-None-Match", "*")
+	if doc.version != "" {
+		condition = smithyhttp.SetHeaderValue("If-Match", doc.version)
+	}
+	out, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(b.key(namespace)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}, s3.WithAPIOptions(condition))
+	if err != nil {
+		switch responseStatus(err) {
+		case http.StatusPreconditionFailed, http.StatusConflict:
+			return ErrConflict
+		}
+		return err
+	}
+	doc.Revision = next.Revision
+	doc.Updated = next.Updated
+	doc.version = aws.ToString(out.ETag)
+	return nil
+}
+
+func responseStatus(err error) int {
+	var rerr *smithyhttp.ResponseError
+	if errors.As(err, &rerr) {
+		return rerr.HTTPStatusCode()
+	}
+	return 0
+}
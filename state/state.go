@@ -0,0 +1,261 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package state implements the key-value store that persists across
+// executions, used by the get_state, set_state and delete_state actions
+// and by the {{ state.key }} interpolations.
+//
+// Keys are grouped by namespace. Every namespace is one document of the
+// backend, a local file by default or an object of an S3 compatible
+// bucket. Writes use optimistic locking: a document is only saved if it
+// was not modified since it was loaded, otherwise the change is retried
+// over the fresh document.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/develatio/nebulant-cli/config"
+)
+
+// DefaultNamespace const
+const DefaultNamespace = "default"
+
+// updateAttempts is the max number of times a change is retried on
+// conflicts with other executions
+const updateAttempts = 10
+
+// ErrConflict var. The document was modified by another execution
+// after it was loaded
+var ErrConflict = errors.New("state modified by another execution")
+
+// errUnchanged is returned by update funcs that changed nothing, to
+// skip the save
+var errUnchanged = errors.New("unchanged")
+
+var namespaceRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Document struct. The keys of a namespace
+type Document struct {
+	Revision int                        `json:"revision"`
+	Updated  time.Time                  `json:"updated"`
+	Values   map[string]json.RawMessage `json:"values"`
+	// opaque version of the loaded document, checked on save
+	version string
+}
+
+// Backend interface
+type Backend interface {
+	// Load returns the document of the namespace, empty if it does
+	// not exists yet
+	Load(ctx context.Context, namespace string) (*Document, error)
+	// Save writes the document if the namespace was not modified
+	// since the document was loaded, ErrConflict otherwise
+	Save(ctx context.Context, namespace string, doc *Document) error
+}
+
+// Store struct
+type Store struct {
+	backend Backend
+}
+
+// NewStore func
+func NewStore(backend Backend) *Store {
+	return &Store{backend: backend}
+}
+
+// ValidateNamespace func.
+func ValidateNamespace(namespace string) error {
+	if !namespaceRegexp.MatchString(namespace) {
+		return fmt.Errorf("invalid state namespace %q, use letters, numbers, '.', '_' and '-'", namespace)
+	}
+	return nil
+}
+
+func validateKey(key string) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("empty state key")
+	}
+	return nil
+}
+
+// Get func. Value of the key, exists false if the key is not set
+func (s *Store) Get(ctx context.Context, namespace string, key string) (value interface{}, exists bool, err error) {
+	if err := ValidateNamespace(namespace); err != nil {
+		return nil, false, err
+	}
+	if err := validateKey(key); err != nil {
+		return nil, false, err
+	}
+	doc, err := s.backend.Load(ctx, namespace)
+	if err != nil {
+		return nil, false, err
+	}
+	raw, exists := doc.Values[key]
+	if !exists {
+		return nil, false, nil
+	}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set func.
+func (s *Store) Set(ctx context.Context, namespace string, key string, value interface{}) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.Update(ctx, namespace, func(values map[string]json.RawMessage) error {
+		values[key] = raw
+		return nil
+	})
+}
+
+// Delete func. Tells if the key existed
+func (s *Store) Delete(ctx context.Context, namespace string, key string) (bool, error) {
+	if err := validateKey(key); err != nil {
+		return false, err
+	}
+	deleted := false
+	err := s.Update(ctx, namespace, func(values map[string]json.RawMessage) error {
+		if _, deleted = values[key]; !deleted {
+			return errUnchanged
+		}
+		delete(values, key)
+		return nil
+	})
+	return deleted, err
+}
+
+// Update func. Change the values of the namespace with fn. fn is
+// called again over the fresh values if other execution modified the
+// namespace in the meantime
+func (s *Store) Update(ctx context.Context, namespace string, fn func(values map[string]json.RawMessage) error) error {
+	if err := ValidateNamespace(namespace); err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		doc, err := s.backend.Load(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		if doc.Values == nil {
+			doc.Values = make(map[string]json.RawMessage)
+		}
+		if err := fn(doc.Values); err != nil {
+			if errors.Is(err, errUnchanged) {
+				return nil
+			}
+			return err
+		}
+		err = s.backend.Save(ctx, namespace, doc)
+		if !errors.Is(err, ErrConflict) || attempt >= updateAttempts {
+			return err
+		}
+		// wait a bit before trying again over the fresh document
+		delay := time.Duration(attempt*50+rand.Intn(50)) * time.Millisecond
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// DefaultPath func. Dir of the file backend
+func DefaultPath() string {
+	return filepath.Join(config.AppHomePath(), "state")
+}
+
+// Namespace func. Namespace of the {{ state.key }} interpolations and
+// of the actions without namespace: NEBULANT_STATE_NAMESPACE or default
+func Namespace() string {
+	if ns := os.Getenv("NEBULANT_STATE_NAMESPACE"); ns != "" {
+		return ns
+	}
+	return DefaultNamespace
+}
+
+var defaultStore struct {
+	once  sync.Once
+	store *Store
+	err   error
+}
+
+// Default func. The store configured by the environment:
+//
+//	NEBULANT_STATE_BACKEND    file (default) or s3
+//	NEBULANT_STATE_PATH       dir of the file backend
+//	NEBULANT_STATE_BUCKET     bucket of the s3 backend
+//	NEBULANT_STATE_PREFIX     prefix of the objects, nebulant-state/ by default
+//	NEBULANT_STATE_ENDPOINT   url of S3 compatible services (R2, MinIO...)
+//	NEBULANT_STATE_REGION     region of the bucket
+//
+// The s3 backend reads the credentials as the AWS cli does
+func Default() (*Store, error) {
+	defaultStore.once.Do(func() {
+		backend, err := backendFromEnv()
+		if err != nil {
+			defaultStore.err = err
+			return
+		}
+		defaultStore.store = NewStore(backend)
+	})
+	return defaultStore.store, defaultStore.err
+}
+
+func backendFromEnv() (Backend, error) {
+	switch strings.ToLower(os.Getenv("NEBULANT_STATE_BACKEND")) {
+	case "", "file":
+		path := os.Getenv("NEBULANT_STATE_PATH")
+		if path == "" {
+			path = DefaultPath()
+		}
+		return NewFileBackend(path), nil
+	case "s3":
+		bucket := os.Getenv("NEBULANT_STATE_BUCKET")
+		if bucket == "" {
+			return nil, fmt.Errorf("NEBULANT_STATE_BUCKET is required by the s3 state backend")
+		}
+		prefix, exists := os.LookupEnv("NEBULANT_STATE_PREFIX")
+		if !exists {
+			prefix = "nebulant-state/"
+		}
+		return NewS3Backend(context.Background(), bucket, prefix, os.Getenv("NEBULANT_STATE_ENDPOINT"), os.Getenv("NEBULANT_STATE_REGION"))
+	}
+	return nil, fmt.Errorf("unknown state backend %q, use file or s3", os.Getenv("NEBULANT_STATE_BACKEND"))
+}
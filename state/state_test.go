@@ -0,0 +1,150 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package state_test
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/develatio/nebulant-cli/state"
+)
+
+func testStore(t *testing.T, store *state.Store) {
+	ctx := context.Background()
+	if err := store.Set(ctx, "prod", "ami", "ami-123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "prod", "servers", []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	v, exists, err := store.Get(ctx, "prod", "ami")
+	if err != nil || !exists || v != "ami-123" {
+		t.Errorf("unexpected get result %v %v %v", v, exists, err)
+	}
+	if _, exists, _ := store.Get(ctx, "other", "ami"); exists {
+		t.Errorf("namespaces are not isolated")
+	}
+	deleted, err := store.Delete(ctx, "prod", "ami")
+	if err != nil || !deleted {
+		t.Errorf("unexpected delete result %v %v", deleted, err)
+	}
+	if _, exists, _ := store.Get(ctx, "prod", "ami"); exists {
+		t.Errorf("deleted key still exists")
+	}
+
+	// concurrent changes of the same namespace are not lost
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				err := store.Update(ctx, "prod", func(values map[string]json.RawMessage) error {
+					var n int
+					json.Unmarshal(values["counter"], &n)
+					values["counter"] = json.RawMessage(fmt.Sprintf("%d", n+1))
+					return nil
+				})
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	v, _, _ = store.Get(ctx, "prod", "counter")
+	if v != float64(20) {
+		t.Errorf("expected counter 20, got %v", v)
+	}
+	v, _, _ = store.Get(ctx, "prod", "servers")
+	if list, ok := v.([]interface{}); !ok || len(list) != 2 {
+		t.Errorf("unexpected list %v", v)
+	}
+
+	if err := store.Set(ctx, "../etc", "a", 1); err == nil {
+		t.Errorf("expected invalid namespace error")
+	}
+}
+
+func TestFileBackend(t *testing.T) {
+	testStore(t, state.NewStore(state.NewFileBackend(t.TempDir())))
+}
+
+// fakeS3 is a minimal S3 (path style GET/PUT) with conditional writes
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func etag(b []byte) string {
+	sum := md5.Sum(b)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, exists := f.objects[r.URL.Path]
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			return
+		}
+		w.Header().Set("ETag", etag(obj))
+		w.Write(obj)
+	case http.MethodPut:
+		if (r.Header.Get("If-None-Match") == "*" && exists) ||
+			(r.Header.Get("If-Match") != "" && (!exists || r.Header.Get("If-Match") != etag(obj))) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code></Error>`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+		w.Header().Set("ETag", etag(body))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3Backend(t *testing.T) {
+	srv := httptest.NewServer(&fakeS3{objects: make(map[string][]byte)})
+	defer srv.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	backend, err := state.NewS3Backend(context.Background(), "bucket", "state/", srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, state.NewStore(backend))
+}
@@ -0,0 +1,232 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/develatio/nebulant-cli/config"
+	"github.com/develatio/nebulant-cli/expr"
+	"github.com/develatio/nebulant-cli/state"
+)
+
+// envVar func. Value of {{ env.NAME }}, {{ env.random }} gives a
+// random number
+func envVar(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if len(name) <= 0 {
+		return "", fmt.Errorf("environment var access with empty var name env")
+	}
+	if strings.ToLower(name) == "random" {
+		return fmt.Sprintf("%d", rand.Int31n(99999)), nil
+	}
+	varval, exists := os.LookupEnv(name)
+	if !exists {
+		return "", &expr.UndefinedError{Msg: "'" + name + "' environment var not found"}
+	}
+	return varval, nil
+}
+
+// runtimeVar func. Value of {{ runtime.NAME }}
+func (s *Store) runtimeVar(name string) (string, error) {
+	if len(name) <= 0 {
+		return "", fmt.Errorf("runtime var access with empty var name")
+	}
+	switch strings.ToLower(name) {
+	case "os":
+		return runtime.GOOS, nil
+	case "arch":
+		return runtime.GOARCH, nil
+	case "numcpu":
+		return strconv.Itoa(runtime.NumCPU()), nil
+	case "version":
+		return config.Version, nil
+	case "versiondate":
+		return config.VersionDate, nil
+	case "exit_status":
+		status, ok := s.private["EXIT_STATUS"].(string)
+		if !ok {
+			return "", fmt.Errorf("runtime.exit_status is only available in on_exit and finally actions")
+		}
+		return status, nil
+	}
+	return "", fmt.Errorf("Unknown runtime var name " + name)
+}
+
+var stateKeyRegexp = regexp.MustCompile(`^(?:\.([^.\[]+)|\[\s*"([^"]*)"\s*\]|\[\s*'([^']*)'\s*\])`)
+
+// context func. The context of the running action, set by the runtime
+// as private var. Bounds the reads of the state backend
+func (s *Store) context() context.Context {
+	if ctx, ok := s.GetPrivateVar("CONTEXT").(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+// stateVar func. Value of {{ state.key }} (or {{ state["my key"] }})
+// in the state namespace of the execution. Read only
+func (s *Store) stateVar(path string) (interface{}, error) {
+	m := stateKeyRegexp.FindStringSubmatch(path)
+	if m == nil {
+		return nil, fmt.Errorf("state access with empty key")
+	}
+	key := strings.TrimSpace(m[1] + m[2] + m[3])
+	st, err := state.Default()
+	if err != nil {
+		return nil, err
+	}
+	ns := state.Namespace()
+	v, exists, err := st.Get(s.context(), ns, key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &expr.UndefinedError{Msg: fmt.Sprintf("state key %s not found in namespace %s", key, ns)}
+	}
+	if rest := path[len(m[0]):]; rest != "" {
+		return expr.JSONPath(v, "$"+rest)
+	}
+	return v, nil
+}
+
+// interpolateExpression func. Replaces an expression as
+// {{ a.b ?? "x" | upper }} by his rendered value
+func (s *Store) interpolateExpression(sourcetext *string, match string, ex *expr.Expr) error {
+	v, err := s.Evaluate(ex)
+	if err != nil {
+		return err
+	}
+	text := expr.String(v)
+	if text == "" {
+		s.logger.LogWarn("Interpolation results in an empty string replacement for " + match)
+	}
+	*sourcetext = strings.Replace(*sourcetext, match, text, 1)
+	return nil
+}
+
+// Evaluate func. Evaluates an expression against the store, returning
+// his native value
+func (s *Store) Evaluate(ex *expr.Expr) (interface{}, error) {
+	return ex.Eval(expr.ResolverFunc(s.resolveValue))
+}
+
+// resolveValue func. Native value of a reference path as a.b[0].c.
+// Missing references and paths return an expr.UndefinedError so that
+// defaults ({{ a.b ?? "x" }}) can be applied
+func (s *Store) resolveValue(refpath string) (interface{}, error) {
+	refname := expr.RefName(refpath)
+	if refname == "" {
+		return nil, fmt.Errorf("cannot determine reference")
+	}
+	path := strings.TrimPrefix(refpath, refname)
+	switch strings.ToLower(refname) {
+	case "env":
+		return envVar(strings.TrimPrefix(path, "."))
+	case "runtime":
+		return s.runtimeVar(strings.TrimPrefix(path, "."))
+	case "state":
+		// state was added later, outputs named state
+		// keep working
+		if !s.ExistsRefName(refname) {
+			return s.stateVar(path)
+		}
+	}
+
+	record, exists := s.recordsByRefName[refname]
+	if !exists {
+		return nil, &expr.UndefinedError{Msg: fmt.Sprintf("var reference %s does not exists", refname)}
+	}
+
+	if len(path) <= 0 {
+		if len(record.ValueID) > 0 {
+			return record.ValueID, nil
+		}
+		if !record.Literal {
+			return nil, fmt.Errorf("%s is not a primitive value. Specify one of it's attributes, eg. %s.attribute", refname, refname)
+		}
+		if reflect.ValueOf(record.Value).Kind() == reflect.String {
+			return record.Value.(string), nil
+		}
+		return decodeRecordJSON(record.JSONValue)
+	}
+
+	switch lpath := strings.ToLower(path); {
+	case lpath == ".__haserror":
+		return record.Fail, nil
+	case lpath == ".__error":
+		return record.ErrorStr, nil
+	case lpath == ".__retries":
+		return record.Retries, nil
+	case lpath == ".__internal":
+		return fmt.Sprintf("%v", record.Value), nil
+	case lpath == ".__plain":
+		return fmt.Sprintf("%v", record.PlainValue), nil
+	case lpath == ".__json":
+		return decodeRecordJSON(record.JSONValue)
+	case lpath == ".__id":
+		if len(record.ValueID) <= 0 {
+			return nil, &expr.UndefinedError{Msg: "var reference " + refname + " has no ID"}
+		}
+		return record.ValueID, nil
+	case strings.HasPrefix(path, ".__plain."):
+		attr, exists := record.PlainValue[path[1:]]
+		if !exists {
+			return nil, &expr.UndefinedError{Msg: "path " + path[1:] + " does not exists"}
+		}
+		return attr.Value, nil
+	}
+	if len(record.JSONValue) <= 0 {
+		return nil, &expr.UndefinedError{Msg: fmt.Sprintf("path %s of %s not found", path, refname)}
+	}
+	v, err := expr.JSONPath(json.RawMessage(record.JSONValue), "$"+path)
+	if err != nil {
+		if expr.IsUndefined(err) {
+			return nil, &expr.UndefinedError{Msg: fmt.Sprintf("path %s of %s not found", path, refname)}
+		}
+		return nil, fmt.Errorf("Invalid path %s %s", path, err.Error())
+	}
+	return v, nil
+}
+
+// decodeRecordJSON func. String records keep the raw string as
+// JSONValue, so non JSON values are returned as they are
+func decodeRecordJSON(b []byte) (interface{}, error) {
+	if len(b) <= 0 {
+		return nil, nil
+	}
+	v, err := expr.Decode(b)
+	if err != nil {
+		return string(b), nil
+	}
+	return v, nil
+}
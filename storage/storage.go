@@ -27,18 +27,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
 	"os"
 	"reflect"
 	"regexp"
-	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
 
 	"github.com/bhmj/jsonslice"
 	"github.com/develatio/nebulant-cli/base"
-	"github.com/develatio/nebulant-cli/config"
+	"github.com/develatio/nebulant-cli/expr"
 )
 
 // Store struct
@@ -106,6 +105,128 @@ func (s *Store) Merge(source base.IStore) {
 	}
 }
 
+// MergeBranch func. Merge the store of a branch arriving to a join point.
+// Outputs written by actions of different branches are merged following
+// the strategy (see base.JoinMerge consts) and returned as conflicts.
+func (s *Store) MergeBranch(source base.IStore, strategy string) ([]*base.MergeConflict, error) {
+	ss := source.(*Store)
+	var conflicts []*base.MergeConflict
+
+	refnames := make([]string, 0, len(ss.recordsByRefName))
+	for k := range ss.recordsByRefName {
+		refnames = append(refnames, k)
+	}
+	sort.Strings(refnames)
+
+	for _, k := range refnames {
+		v := ss.recordsByRefName[k]
+		current, exists := s.recordsByRefName[k]
+		if exists && writtenBefore(v, current) && !writtenBefore(current, v) {
+			// the branch has an older value
+			continue
+		}
+		if !exists || !branchConflict(current, v) {
+			s.recordsByRefName[k] = v
+			continue
+		}
+		conflicts = append(conflicts, &base.MergeConflict{
+			RefName:        k,
+			ActionID:       current.Action.ActionID,
+			SourceActionID: v.Action.ActionID,
+		})
+		switch strategy {
+		case base.JoinMergeStack:
+			var items []interface{}
+			if stack, ok := current.Value.(*base.StorageRecordStack); ok {
+				items = append([]interface{}{v.Value}, stack.Items...)
+			} else {
+				items = []interface{}{v.Value, current.Value}
+			}
+			if err := s.insertMerged(v, &base.StorageRecordStack{Items: items}, false, current.Secret); err != nil {
+				return conflicts, err
+			}
+		case base.JoinMergeNamespace:
+			branches := make(base.StorageRecordBranches)
+			if prev, ok := current.Value.(base.StorageRecordBranches); ok {
+				for id, val := range prev {
+					branches[id] = val
+				}
+			} else {
+				branches[current.Action.ActionID] = current.Value
+			}
+			branches[v.Action.ActionID] = v.Value
+			if err := s.insertMerged(v, branches, true, current.Secret); err != nil {
+				return conflicts, err
+			}
+		case base.JoinMergeFail:
+			// keep the current value, the join will fail
+		default:
+			s.recordsByRefName[k] = v
+		}
+	}
+	for k, v := range ss.recordsByActionID {
+		s.recordsByActionID[k] = v
+	}
+	for k, v := range ss.aoutByActionID {
+		s.aoutByActionID[k] = v
+	}
+	for k, v := range ss.private {
+		s.private[k] = v
+	}
+
+	if strategy == base.JoinMergeFail && len(conflicts) > 0 {
+		var msgs []string
+		for _, c := range conflicts {
+			msgs = append(msgs, fmt.Sprintf("%s (written by %s and %s)", c.RefName, c.ActionID, c.SourceActionID))
+		}
+		return conflicts, fmt.Errorf("merge conflict on join: %s", strings.Join(msgs, ", "))
+	}
+	return conflicts, nil
+}
+
+// writtenBefore func. Tells if the action of the record a
+// runs before the action of the record b
+func writtenBefore(a *base.StorageRecord, b *base.StorageRecord) bool {
+	if b.Action == nil {
+		return false
+	}
+	if a.Action == nil {
+		return true
+	}
+	if a.Action.ActionID == b.Action.ActionID {
+		return false
+	}
+	return base.IsAncestor(a.Action, b.Action)
+}
+
+// branchConflict func. Tells if the records have been written
+// by actions running in different branches, so none of them
+// is the last value of the output
+func branchConflict(current *base.StorageRecord, source *base.StorageRecord) bool {
+	if current.Action == nil || source.Action == nil {
+		return false
+	}
+	if current.Action.ActionID == source.Action.ActionID {
+		return false
+	}
+	return !writtenBefore(current, source) && !writtenBefore(source, current)
+}
+
+// insertMerged func. Set the merged value of the outputs of a join
+func (s *Store) insertMerged(source *base.StorageRecord, value interface{}, literal bool, secret bool) error {
+	record := &base.StorageRecord{
+		RefName: source.RefName,
+		Aout:    source.Aout,
+		Value:   value,
+		Action:  source.Action,
+		Literal: literal,
+		Secret:  secret || source.Secret,
+	}
+	s.recordsByRefName[record.RefName] = record
+	collectRecordSecrets(record)
+	return record.BuildInternals()
+}
+
 // Duplicate func.
 // Make a copy of current store to be used in newly created children threads.
 func (s *Store) Duplicate() base.IStore {
@@ -261,6 +382,10 @@ func (s *Store) Push(sr *base.StorageRecord, providerPrefix string) error {
 	recordstack := &base.StorageRecordStack{
 		Items: items,
 	}
+	secret := sr.Secret
+	if csr, err := s.GetByRefName(sr.RefName); err == nil && csr.Secret {
+		secret = true
+	}
 
 	// insert record (override previous if exists)
 	err := s.Insert(&base.StorageRecord{
@@ -268,6 +393,7 @@ func (s *Store) Push(sr *base.StorageRecord, providerPrefix string) error {
 		Aout:    sr.Aout,
 		Value:   recordstack,
 		Action:  sr.Action,
+		Secret:  secret,
 	}, providerPrefix)
 	if err != nil {
 		return err
@@ -289,9 +415,19 @@ func (s *Store) Insert(record *base.StorageRecord, providerPrefix string) error
 	if len(record.RefName) > 0 {
 		s.recordsByRefName[record.RefName] = record
 	}
+	collectRecordSecrets(record)
 	return record.BuildInternals()
 }
 
+// collectRecordSecrets func. Register the secret values of the
+// record, all of them if the record is secret
+func collectRecordSecrets(record *base.StorageRecord) {
+	if base.IsSecretName(record.RefName) {
+		record.Secret = true
+	}
+	base.CollectSecrets(record.Value, record.Secret)
+}
+
 // can be called ReferenceInterpolation? maybe InterpolateReferences?
 func (s *Store) Interpolate(sourcetext *string) error {
 	if sourcetext == nil {
@@ -307,172 +443,188 @@ func (s *Store) Interpolate(sourcetext *string) error {
 	}
 
 	for _, match := range matches {
-		// match[0] == "{{ a.b.c }}""
-		// match[1] == " a.b.c "
-		var refpath string = strings.TrimSpace(match[1])
-		var refname = ""
-
-		// Catch AWS_EC2 from AWS_EC2.foo.bar or AWS_EC2[0]
-		_m := regexp.MustCompile(`(?:\\.|[^.[|\\]+)+`).FindAllStringSubmatch(refpath, -1)
-		if len(_m) <= 0 {
-			return fmt.Errorf("cannot determine reference")
-		}
-
-		// obtain record in db referenced by refname
-		// _m ->[[AWS_EC2] [networkInterfaceSet] [0]] ...]
-		refname = _m[0][0]
-		if strings.ToLower(refname) == "env" {
-			refpath = strings.TrimPrefix(refpath, refname)
-			refpath = strings.TrimPrefix(refpath, ".")
-			if len(refpath) <= 0 {
-				return fmt.Errorf("environment var access with empty var name " + refname)
-			}
-			if strings.ToLower(strings.TrimSpace(refpath)) == "random" {
-				*sourcetext = strings.Replace(*sourcetext, match[0], fmt.Sprintf("%d", rand.Int31n(99999)), 1)
-				continue
-			}
-			varval, exists := os.LookupEnv(strings.TrimSpace(refpath))
-			if !exists {
-				return fmt.Errorf("'" + refpath + "' environment var not found")
-			}
-			if varval == "" {
-				s.logger.LogWarn("Interpolation results in an empty string replacement for " + match[0])
+		ex, perr := expr.Parse(strings.TrimSpace(match[1]))
+		// state was added along with the expressions, it has no legacy syntax
+		if perr == nil && (!ex.IsReference() || s.isStateRef(ex)) {
+			if err := s.interpolateExpression(sourcetext, match[0], ex); err != nil {
+				return err
 			}
-			*sourcetext = strings.Replace(*sourcetext, match[0], varval, 1)
 			continue
 		}
-
-		if strings.ToLower(refname) == "runtime" {
-			refpath = strings.TrimPrefix(refpath, refname)
-			refpath = strings.TrimPrefix(refpath, ".")
-			if len(refpath) <= 0 {
-				return fmt.Errorf("runtime var access with empty var name")
-			}
-			switch strings.ToLower(refpath) {
-			case "os":
-				*sourcetext = strings.Replace(*sourcetext, match[0], runtime.GOOS, 1)
-			case "arch":
-				*sourcetext = strings.Replace(*sourcetext, match[0], runtime.GOARCH, 1)
-			case "numcpu":
-				*sourcetext = strings.Replace(*sourcetext, match[0], strconv.Itoa(runtime.NumCPU()), 1)
-			case "version":
-				*sourcetext = strings.Replace(*sourcetext, match[0], config.Version, 1)
-			case "versiondate":
-				*sourcetext = strings.Replace(*sourcetext, match[0], config.VersionDate, 1)
-			default:
-				return fmt.Errorf("Unknown runtime var name " + refpath)
+		if err := s.interpolateReference(sourcetext, match); err != nil {
+			if perr != nil {
+				// not a plain reference nor a valid expression
+				return perr
 			}
-			continue
+			return err
 		}
+	}
+	return nil
+}
+
+// isStateRef func. True if ex is a reference to the state
+// namespace and not to a record named state
+func (s *Store) isStateRef(ex *expr.Expr) bool {
+	refname := expr.RefName(ex.String())
+	return strings.ToLower(refname) == "state" && !s.ExistsRefName(refname)
+}
 
-		record, exists := s.recordsByRefName[refname]
-		if !exists {
-			return fmt.Errorf("var reference %s does not exists (ES1) (store:%p)", refname, s)
+// interpolateReference func. Replaces a plain reference, optionally
+// followed by json paths ({{ a.b | $[0].c }})
+func (s *Store) interpolateReference(sourcetext *string, match []string) error {
+	// match[0] == "{{ a.b.c }}""
+	// match[1] == " a.b.c "
+	var refpath string = strings.TrimSpace(match[1])
+	var refname = ""
+
+	// Catch AWS_EC2 from AWS_EC2.foo.bar or AWS_EC2[0]
+	_m := regexp.MustCompile(`(?:\\.|[^.[|\\]+)+`).FindAllStringSubmatch(refpath, -1)
+	if len(_m) <= 0 {
+		return fmt.Errorf("cannot determine reference")
+	}
+
+	// obtain record in db referenced by refname
+	// _m ->[[AWS_EC2] [networkInterfaceSet] [0]] ...]
+	refname = _m[0][0]
+	if strings.ToLower(refname) == "env" {
+		varval, err := envVar(strings.TrimPrefix(strings.TrimPrefix(refpath, refname), "."))
+		if err != nil {
+			return err
 		}
+		if varval == "" {
+			s.logger.LogWarn("Interpolation results in an empty string replacement for " + match[0])
+		}
+		*sourcetext = strings.Replace(*sourcetext, match[0], varval, 1)
+		return nil
+	}
 
-		// refpath -> .foo.bar or [foo.bar] or empty if no path provided
-		refpath = strings.TrimPrefix(refpath, refname)
-		if len(refpath) <= 0 {
-			if len(record.ValueID) > 0 {
-				*sourcetext = strings.Replace(*sourcetext, match[0], record.ValueID, 1)
-			} else if !record.Literal {
-				return fmt.Errorf("{{ %s }} is not a primitive value. Specify one of it's attributes, eg. {{ %s.attribute }}", match[0], match[0])
+	if strings.ToLower(refname) == "runtime" {
+		varval, err := s.runtimeVar(strings.TrimPrefix(strings.TrimPrefix(refpath, refname), "."))
+		if err != nil {
+			return err
+		}
+		*sourcetext = strings.Replace(*sourcetext, match[0], varval, 1)
+		return nil
+	}
 
-			} else if reflect.ValueOf(record.Value).Kind() == reflect.String {
-				if record.Value.(string) == "" {
-					s.logger.LogWarn("Interpolation results in an empty string replacement for " + match[0])
-				}
-				*sourcetext = strings.Replace(*sourcetext, match[0], record.Value.(string), 1)
-			} else {
-				// return json by default
-				if string(record.JSONValue) == "" {
-					s.logger.LogWarn("Interpolation results in an empty string replacement for " + match[0])
-				}
-				*sourcetext = strings.Replace(*sourcetext, match[0], string(record.JSONValue), 1)
+	record, exists := s.recordsByRefName[refname]
+	if !exists {
+		return fmt.Errorf("var reference %s does not exists (ES1) (store:%p)", refname, s)
+	}
+
+	// refpath -> .foo.bar or [foo.bar] or empty if no path provided
+	refpath = strings.TrimPrefix(refpath, refname)
+	if len(refpath) <= 0 {
+		if len(record.ValueID) > 0 {
+			*sourcetext = strings.Replace(*sourcetext, match[0], record.ValueID, 1)
+		} else if !record.Literal {
+			return fmt.Errorf("{{ %s }} is not a primitive value. Specify one of it's attributes, eg. {{ %s.attribute }}", match[0], match[0])
+
+		} else if reflect.ValueOf(record.Value).Kind() == reflect.String {
+			if record.Value.(string) == "" {
+				s.logger.LogWarn("Interpolation results in an empty string replacement for " + match[0])
 			}
-			continue
+			*sourcetext = strings.Replace(*sourcetext, match[0], record.Value.(string), 1)
+		} else {
+			// return json by default
+			if string(record.JSONValue) == "" {
+				s.logger.LogWarn("Interpolation results in an empty string replacement for " + match[0])
+			}
+			*sourcetext = strings.Replace(*sourcetext, match[0], string(record.JSONValue), 1)
 		}
-		// add root char ($) to initial refpath,
-		// this replaces AWS_EC2.foo.bar by $.foo.bar
-		refpath = "$" + refpath
+		return nil
+	}
+	// add root char ($) to initial refpath,
+	// this replaces AWS_EC2.foo.bar by $.foo.bar
+	refpath = "$" + refpath
 
-		r := regexp.MustCompile(`(?:\\.|"(.*?)"|[^|\\]+)+`)
-		jpaths := r.FindAllStringSubmatch(refpath, -1)
-		if len(jpaths) <= 0 {
-			continue
-		}
+	r := regexp.MustCompile(`(?:\\.|"(.*?)"|[^|\\]+)+`)
+	jpaths := r.FindAllStringSubmatch(refpath, -1)
+	if len(jpaths) <= 0 {
+		return nil
+	}
 
-		var jpathTargetValue []byte
-		if record.Literal {
+	var jpathTargetValue []byte
+	if record.Literal {
+		jpathTargetValue = record.JSONValue
+	}
+	// every json path of the chain reads the
+	// result of the previous one
+	jpathSource := record.JSONValue
+	for _, jpathm := range jpaths {
+		jpath := strings.TrimSpace(jpathm[0])
+		if strings.ToLower(jpath) == "$.__haserror" {
+			if record.Fail {
+				jpathTargetValue = []byte("true")
+			} else {
+				jpathTargetValue = []byte("false")
+			}
+		} else if strings.ToLower(jpath) == "$.__error" {
+			jpathTargetValue = []byte(record.ErrorStr)
+		} else if strings.ToLower(jpath) == "$.__retries" {
+			jpathTargetValue = []byte(strconv.Itoa(record.Retries))
+		} else if strings.ToLower(jpath) == "$.__internal" {
+			jpathTargetValue = []byte(fmt.Sprintf("%v", record.Value))
+		} else if strings.ToLower(jpath) == "$.__plain" {
+			jpathTargetValue = []byte(fmt.Sprintf("%v", record.PlainValue))
+		} else if strings.ToLower(jpath) == "$.__json" {
 			jpathTargetValue = record.JSONValue
-		}
-		for _, jpathm := range jpaths {
-			jpath := jpathm[0]
-			if strings.ToLower(jpath) == "$.__haserror" {
-				if record.Fail {
-					jpathTargetValue = []byte("true")
-				} else {
-					jpathTargetValue = []byte("false")
-				}
-			} else if strings.ToLower(jpath) == "$.__error" {
-				jpathTargetValue = []byte(record.ErrorStr)
-			} else if strings.ToLower(jpath) == "$.__internal" {
-				jpathTargetValue = []byte(fmt.Sprintf("%v", record.Value))
-			} else if strings.ToLower(jpath) == "$.__plain" {
-				jpathTargetValue = []byte(fmt.Sprintf("%v", record.PlainValue))
-			} else if strings.ToLower(jpath) == "$.__json" {
-				jpathTargetValue = record.JSONValue
-			} else if strings.ToLower(jpath) == "$.__id" {
-				if len(record.ValueID) <= 0 {
-					return fmt.Errorf("var reference " + refname + " has no ID (ES2)")
-				}
-				jpathTargetValue = []byte(record.ValueID)
-			} else if strings.HasPrefix(jpath, "$.__plain.") {
-				attr, exists := record.PlainValue[jpath[1:]]
-				if !exists {
-					availPaths := fmt.Sprintf("%v", record.PlainValue)
-					return fmt.Errorf("path " + jpath[1:] + " does not exists (ES3). Available paths: " + availPaths)
-				}
-				if attr.IsString {
-					jpathTargetValue = []byte(attr.Value.(string))
-				} else {
-					jpathTargetValue = []byte(fmt.Sprintf("%v", attr.Value))
-				}
+		} else if strings.ToLower(jpath) == "$.__id" {
+			if len(record.ValueID) <= 0 {
+				return fmt.Errorf("var reference " + refname + " has no ID (ES2)")
+			}
+			jpathTargetValue = []byte(record.ValueID)
+		} else if strings.HasPrefix(jpath, "$.__plain.") {
+			attr, exists := record.PlainValue[jpath[1:]]
+			if !exists {
+				availPaths := fmt.Sprintf("%v", record.PlainValue)
+				return fmt.Errorf("path " + jpath[1:] + " does not exists (ES3). Available paths: " + availPaths)
+			}
+			if attr.IsString {
+				jpathTargetValue = []byte(attr.Value.(string))
 			} else {
-				enc, err := jsonslice.Get(record.JSONValue, strings.TrimSpace(jpath))
+				jpathTargetValue = []byte(fmt.Sprintf("%v", attr.Value))
+			}
+		} else {
+			enc, err := jsonslice.Get(jpathSource, jpath)
+			if err != nil {
+				return fmt.Errorf("Invalid path " + jpath + " " + err.Error())
+			}
+			jpathSource = enc
+			val := string(enc)
+			if strings.HasPrefix(val, "\"") && strings.HasSuffix(val, "\"") {
+				var str string
+				err = json.Unmarshal(enc, &str)
 				if err != nil {
-					return fmt.Errorf("Invalid path " + jpath + " " + err.Error())
+					return fmt.Errorf(err.Error() + ": `" + string(enc) + "`")
 				}
-				val := string(enc)
-				if strings.HasPrefix(val, "\"") && strings.HasSuffix(val, "\"") {
-					var str string
-					err = json.Unmarshal(enc, &str)
-					if err != nil {
-						return fmt.Errorf(err.Error() + ": `" + string(enc) + "`")
-					}
-					jpathTargetValue = []byte(str)
-				} else if len(enc) <= 0 {
-					s.logger.LogWarn(fmt.Sprintf("JSON Path result in empty value. Maybe you want to fix it, here is the raw json value: %s", record.JSONValue))
-				} else {
-					var prettyJSON bytes.Buffer
-					err = json.Indent(&prettyJSON, enc, "", "    ")
-					if err != nil {
-						return fmt.Errorf(err.Error() + ": `" + string(enc) + "`")
-					}
-					jpathTargetValue = prettyJSON.Bytes()
+				jpathTargetValue = []byte(str)
+			} else if len(enc) <= 0 {
+				s.logger.LogWarn(fmt.Sprintf("JSON Path result in empty value. Maybe you want to fix it, here is the raw json value: %s", record.JSONValue))
+			} else {
+				var prettyJSON bytes.Buffer
+				err = json.Indent(&prettyJSON, enc, "", "    ")
+				if err != nil {
+					return fmt.Errorf(err.Error() + ": `" + string(enc) + "`")
 				}
+				jpathTargetValue = prettyJSON.Bytes()
 			}
 		}
-		if string(jpathTargetValue) == "" {
-			s.logger.LogWarn("Interpolation results in an empty string replacement for " + match[0])
-		}
-		*sourcetext = strings.Replace(*sourcetext, match[0], string(jpathTargetValue), 1)
 	}
+	if string(jpathTargetValue) == "" {
+		s.logger.LogWarn("Interpolation results in an empty string replacement for " + match[0])
+	}
+	*sourcetext = strings.Replace(*sourcetext, match[0], string(jpathTargetValue), 1)
 	return nil
 }
 
 func (s *Store) DeepInterpolation(v interface{}) error {
-	return s.recursiveInterpolation(reflect.ValueOf(v), make(map[interface{}]bool))
+	if err := s.recursiveInterpolation(reflect.ValueOf(v), make(map[interface{}]bool)); err != nil {
+		return err
+	}
+	// resolved values of secret fields (password, privkey...)
+	base.CollectSecrets(v, false)
+	return nil
 }
 
 func (s *Store) recursiveInterpolation(v reflect.Value, il map[interface{}]bool) error {
@@ -616,6 +768,7 @@ func (s *Store) GetPlain() (map[string]string, error) {
 		} else {
 			result[refname+".__haserror"] = "false"
 		}
+		result[refname+".__retries"] = strconv.Itoa(sr.Retries)
 		for path, attr := range sr.PlainValue {
 			spath := strings.TrimPrefix(path, ".__plain")
 			if spath == refname || len(spath) <= 0 {
@@ -712,3 +865,144 @@ func (s *Store) DumpValuesToJSONFile() (*os.File, error) {
 	}
 	return f, nil
 }
+
+// Snapshot func. Returns the serializable part of the records. Values
+// are stored as json, the action outputs and errors are discarded
+// (the error string is kept). The values of secret records are left
+// out, Restore takes them from the store (eg. the args given again)
+func (s *Store) Snapshot() ([]*base.StorageRecordSnapshot, error) {
+	snaps := make(map[*base.StorageRecord]*base.StorageRecordSnapshot)
+	var order []*base.StorageRecordSnapshot
+	get := func(record *base.StorageRecord) (*base.StorageRecordSnapshot, error) {
+		if snap, exists := snaps[record]; exists {
+			return snap, nil
+		}
+		snap := &base.StorageRecordSnapshot{
+			ValueID:  record.ValueID,
+			Literal:  record.Literal,
+			Fail:     record.Fail,
+			Error:    record.ErrorStr,
+			IsString: record.IsString,
+			Retries:  record.Retries,
+			Secret:   record.Secret,
+		}
+		if record.Error != nil {
+			snap.Error = record.Error.Error()
+		}
+		if record.Secret && record.Value != nil {
+			_, snap.IsString = record.Value.(string)
+			_, snap.Stack = record.Value.(*base.StorageRecordStack)
+			snap.Redacted = true
+			snaps[record] = snap
+			order = append(order, snap)
+			return snap, nil
+		}
+		var err error
+		switch v := record.Value.(type) {
+		case nil:
+		case string:
+			snap.IsString = true
+			snap.Value, err = json.Marshal(v)
+		case *base.StorageRecordStack:
+			snap.Stack = true
+			snap.Value, err = json.Marshal(v.Items)
+		default:
+			if len(record.JSONValue) > 0 {
+				// keep the json representation used on interpolation
+				snap.Value = append(json.RawMessage(nil), record.JSONValue...)
+			} else {
+				snap.Value, err = json.Marshal(v)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot snapshot %s: %v", record.RefName, err)
+		}
+		if snap.Value != nil && !json.Valid(snap.Value) {
+			return nil, fmt.Errorf("cannot snapshot %s: invalid json value", record.RefName)
+		}
+		snaps[record] = snap
+		order = append(order, snap)
+		return snap, nil
+	}
+	for refname, record := range s.recordsByRefName {
+		snap, err := get(record)
+		if err != nil {
+			return nil, err
+		}
+		snap.RefName = refname
+	}
+	for actionID, record := range s.recordsByActionID {
+		snap, err := get(record)
+		if err != nil {
+			return nil, err
+		}
+		snap.ActionID = actionID
+	}
+	for valueKey, record := range s.recordsByValueID {
+		snap, err := get(record)
+		if err != nil {
+			return nil, err
+		}
+		snap.ValueKey = valueKey
+	}
+	return order, nil
+}
+
+// Restore func. Load the records of a snapshot (see Snapshot). The
+// value of the redacted secret records is taken from the records with
+// the same ref name already in the store
+func (s *Store) Restore(snaps []*base.StorageRecordSnapshot, actions map[string]*base.Action) error {
+	for _, snap := range snaps {
+		record := &base.StorageRecord{
+			RefName:  snap.RefName,
+			ValueID:  snap.ValueID,
+			Literal:  snap.Literal,
+			Fail:     snap.Fail,
+			ErrorStr: snap.Error,
+			Retries:  snap.Retries,
+			Secret:   snap.Secret,
+		}
+		if snap.ActionID != "" {
+			record.Action = actions[snap.ActionID]
+		}
+		if len(snap.Value) > 0 {
+			var value interface{}
+			if err := json.Unmarshal(snap.Value, &value); err != nil {
+				return fmt.Errorf("cannot restore %s: %v", snap.RefName, err)
+			}
+			if snap.Stack {
+				items, _ := value.([]interface{})
+				value = &base.StorageRecordStack{Items: items}
+			}
+			record.Value = value
+		}
+		if snap.Redacted {
+			given, exists := s.recordsByRefName[snap.RefName]
+			if snap.RefName == "" || !exists {
+				name := snap.RefName
+				if name == "" {
+					name = "output of " + snap.ActionID
+				}
+				return fmt.Errorf("the secret %s is not saved in checkpoints, please provide it again", name)
+			}
+			record.Value = given.Value
+		}
+		if err := record.BuildInternals(); err != nil {
+			return fmt.Errorf("cannot restore %s: %v", snap.RefName, err)
+		}
+		collectRecordSecrets(record)
+		if !snap.IsString && !snap.Stack && len(snap.Value) > 0 {
+			record.JSONValue = append([]byte(nil), snap.Value...)
+		}
+		if snap.RefName != "" {
+			s.recordsByRefName[snap.RefName] = record
+		}
+		if snap.ActionID != "" {
+			s.recordsByActionID[snap.ActionID] = record
+		}
+		if snap.ValueKey != "" {
+			s.recordsByValueID[snap.ValueKey] = record
+		}
+	}
+	return nil
+}
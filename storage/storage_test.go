@@ -23,14 +23,20 @@
 package storage_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/develatio/nebulant-cli/base"
+	"github.com/develatio/nebulant-cli/state"
 	"github.com/develatio/nebulant-cli/storage"
+	"github.com/develatio/nebulant-cli/util"
 )
 
 // Provider struct
@@ -192,15 +198,18 @@ func TestInterpolate(t *testing.T) {
 func TestSingleReferences(t *testing.T) {
 	var err error
 	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
 	store.Insert(&base.StorageRecord{
 		RefName: "SINGLE_VAR_NAME",
 		Aout:    nil,
 		Value:   "varvalue",
+		Literal: true,
 	}, "generic")
 	store.Insert(&base.StorageRecord{
 		RefName: "SINGLE_VAR_NAME2",
 		Aout:    nil,
 		Value:   "varvalue2",
+		Literal: true,
 	}, "generic")
 
 	tests := []tsie{
@@ -227,15 +236,18 @@ func TestSingleReferences(t *testing.T) {
 
 func TestDeepInterpolation(t *testing.T) {
 	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
 	store.Insert(&base.StorageRecord{
 		RefName: "SINGLE_VAR_NAME",
 		Aout:    nil,
 		Value:   "varvalue",
+		Literal: true,
 	}, "generic")
 	store.Insert(&base.StorageRecord{
 		RefName: "SINGLE_VAR_NAME2",
 		Aout:    nil,
 		Value:   "varvalue2",
+		Literal: true,
 	}, "generic")
 
 	text1 := "holi"
@@ -294,6 +306,7 @@ func TestDeepInterpolation(t *testing.T) {
 func TestMixedReferences(t *testing.T) {
 	var err error
 	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
 	ref := "OUTPUT_VAR_NAME"
 	action := &base.Action{
 		Provider: "generic",
@@ -320,28 +333,16 @@ func TestMixedReferences(t *testing.T) {
 		RefName: "SINGLE_VAR_NAME",
 		Aout:    nil,
 		Value:   "varvalue",
+		Literal: true,
 	}, "generic")
 
+	// {{ ref }} of a record with id is the id
 	text := "a test {{ OUTPUT_VAR_NAME }} {{ SINGLE_VAR_NAME }}"
 	err = store.Interpolate(&text)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
-	expected :=
-		`a test {
-    "imageId": "test",
-    "name": "test1234",
-    "tagSet": [
-        {
-            "key": "tagkey0",
-            "value": "tagvalue0"
-        },
-        {
-            "key": "tagkey1",
-            "value": "tagvalue1"
-        }
-    ]
-} varvalue`
+	expected := "a test test varvalue"
 	if text != expected {
 		t.Errorf("text interpolation failed in mixed reference")
 	}
@@ -359,6 +360,7 @@ func TestMixedReferences(t *testing.T) {
 func TestMagicReferences(t *testing.T) {
 	var err error
 	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
 	store.Insert(&base.StorageRecord{
 		RefName: "SINGLE_VAR_NAME",
 		Aout:    nil,
@@ -466,6 +468,7 @@ func TestEnv(t *testing.T) {
 	os.Setenv("VARNAME", "VARVALUE")
 	text := "{{ ENV.VARNAME }}"
 	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
 	store.Interpolate(&text)
 	if text != "VARVALUE" {
 		t.Errorf("text interpolation failed for ENV.")
@@ -474,6 +477,7 @@ func TestEnv(t *testing.T) {
 
 func TestDuplicate(t *testing.T) {
 	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
 	store.Insert(&base.StorageRecord{
 		RefName: "SINGLE_VAR_NAME",
 		Aout:    nil,
@@ -564,6 +568,7 @@ func TestDuplicate(t *testing.T) {
 
 func TestMerge(t *testing.T) {
 	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
 	store.Insert(&base.StorageRecord{
 		RefName: "SINGLE_VAR_NAME",
 		Aout:    nil,
@@ -593,17 +598,64 @@ func TestMerge(t *testing.T) {
 	if err == nil {
 		t.Errorf("Undefined var should be nil")
 	}
-	tp3, err := store.GetProvider("testprovider")
-	if err != nil {
-		t.Errorf(err.Error())
+	// the providers keep his own store, they are not merged
+	if store.ExistsProvider("testprovider") {
+		t.Errorf("providers should not be merged")
+	}
+}
+
+func TestMergeBranch(t *testing.T) {
+	// s -> a -> c, s -> b
+	s := &base.Action{ActionID: "s"}
+	a := &base.Action{ActionID: "a", Parents: []*base.Action{s}}
+	b := &base.Action{ActionID: "b", Parents: []*base.Action{s}}
+	c := &base.Action{ActionID: "c", Parents: []*base.Action{a}}
+
+	branches := func() (base.IStore, base.IStore) {
+		fork := storage.NewStore()
+		fork.SetLogger(&fakeLogger{})
+		fork.Insert(&base.StorageRecord{RefName: "PRE", Value: "s", Action: s, Literal: true}, "generic")
+		left := fork.Duplicate()
+		left.Insert(&base.StorageRecord{RefName: "PRE", Value: "a", Action: a, Literal: true}, "generic")
+		left.Insert(&base.StorageRecord{RefName: "OUT", Value: "a", Action: a, Literal: true}, "generic")
+		left.Insert(&base.StorageRecord{RefName: "OUT", Value: "c", Action: c, Literal: true}, "generic")
+		right := fork.Duplicate()
+		right.Insert(&base.StorageRecord{RefName: "OUT", Value: "b", Action: b, Literal: true}, "generic")
+		return left, right
 	}
-	if tp != tp3 {
-		t.Errorf("provider should be the same after merge, got %v, expected %v", tp3, tp)
+
+	for strategy, tc := range map[string][2]string{
+		base.JoinMergeLast:      {`{{ PRE }} {{ OUT }}`, `a b`},
+		base.JoinMergeStack:     {`{{ PRE }} {{ OUT.Items | json }}`, `a ["b","c"]`},
+		base.JoinMergeNamespace: {`{{ PRE }} {{ OUT | json }} {{ OUT.c }}`, `a {"b":"b","c":"c"} c`},
+	} {
+		left, right := branches()
+		conflicts, err := left.MergeBranch(right, strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// PRE written by s is overwritten by a, no conflict
+		if len(conflicts) != 1 || conflicts[0].RefName != "OUT" || conflicts[0].ActionID != "c" || conflicts[0].SourceActionID != "b" {
+			t.Errorf("%s: unexpected conflicts %v", strategy, conflicts)
+		}
+		text := tc[0]
+		if err := left.Interpolate(&text); err != nil {
+			t.Fatalf("%s: %v", strategy, err)
+		}
+		if text != tc[1] {
+			t.Errorf("%s: expected %q, got %q", strategy, tc[1], text)
+		}
+	}
+
+	left, right := branches()
+	if _, err := left.MergeBranch(right, base.JoinMergeFail); err == nil || !strings.Contains(err.Error(), "c and b") {
+		t.Errorf("expected merge conflict error, got %v", err)
 	}
 }
 
 func TestPrivatevar(t *testing.T) {
 	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
 	store.SetPrivateVar("VARNAME", "varvalue")
 	pr := store.GetPrivateVar("VARNAME")
 	if pr.(string) != "varvalue" {
@@ -618,6 +670,7 @@ func TestPrivatevar(t *testing.T) {
 func TestGetByActionID(t *testing.T) {
 	var err error
 	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
 	ref := "OUTPUT_VAR_NAME"
 	action := &base.Action{
 		ActionID: "actionTestID",
@@ -653,6 +706,7 @@ func TestGetByActionID(t *testing.T) {
 func TestProviders(t *testing.T) {
 	tp := &testProvider{}
 	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
 	store.StoreProvider("testprovider", tp)
 	tp2, err := store.GetProvider("testprovider")
 	if err != nil {
@@ -693,6 +747,7 @@ func TestLogger(t *testing.T) {
 func TestJSONPath(t *testing.T) {
 	var err error
 	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
 	ref := "OUTPUT_VAR_NAME"
 	action := &base.Action{
 		Provider: "generic",
@@ -738,3 +793,392 @@ func TestJSONPath(t *testing.T) {
 	}
 
 }
+
+func TestSnapshotRestore(t *testing.T) {
+	var err error
+	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
+	ref := "OUTPUT_VAR_NAME"
+	action := &base.Action{
+		ActionID: "a1",
+		Provider: "aws",
+		Output:   &ref,
+	}
+	demoresult := &ec2.Image{
+		ImageId: aws.String("ami-test"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("tagkey0"),
+				Value: aws.String("tagvalue0"),
+			},
+		},
+	}
+	aout := base.NewActionOutput(action, demoresult, demoresult.ImageId)
+	err = store.Insert(aout.Records[0], action.Provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Insert(&base.StorageRecord{RefName: "LITERAL", Value: "literal value", Literal: true}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"one", "two"} {
+		err = store.Push(&base.StorageRecord{RefName: "STACK", Value: v}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snaps, err := store.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := storage.NewStore()
+	restored.SetLogger(&fakeLogger{})
+	err = restored.Restore(snaps, map[string]*base.Action{"a1": action})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := "{{ OUTPUT_VAR_NAME }} {{ OUTPUT_VAR_NAME.tagSet[0].value }} {{ LITERAL }}"
+	err = restored.Interpolate(&text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "ami-test tagvalue0 literal value" {
+		t.Errorf("restored interpolation failed: %s", text)
+	}
+
+	record, err := restored.GetByRefName("STACK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stack, ok := record.Value.(*base.StorageRecordStack)
+	if !ok || len(stack.Items) != 2 {
+		t.Errorf("stack var not restored: %v", record.Value)
+	}
+	if record, err := restored.GetByRefName("OUTPUT_VAR_NAME"); err != nil || record.Action != action {
+		t.Errorf("record not linked to his action")
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
+	records := []*base.StorageRecord{
+		{RefName: "TOKEN", Value: "explicit-secret-value", Literal: true, Secret: true},
+		{RefName: "db_password", Value: "by-name-secret-value", Literal: true},
+		{RefName: "RESULT", Value: `{"user": "root", "passphrase": "nested-secret-value"}`, Literal: true},
+		{RefName: "PLAIN", Value: "plain-value", Literal: true},
+	}
+	for _, record := range records {
+		if err := store.Insert(record, "generic"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// interpolation resolves the real values
+	text := "{{ TOKEN }} {{ db_password }} {{ PLAIN }}"
+	if err := store.Interpolate(&text); err != nil {
+		t.Fatal(err)
+	}
+	if text != "explicit-secret-value by-name-secret-value plain-value" {
+		t.Errorf("secret interpolation failed: %s", text)
+	}
+
+	redacted := base.Redact(text + " nested-secret-value root")
+	expected := fmt.Sprintf("%s %s plain-value %s root", base.SecretMask, base.SecretMask, base.SecretMask)
+	if redacted != expected {
+		t.Errorf("expected %q, got %q", expected, redacted)
+	}
+
+	// interpolated params with secret names
+	params := &struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{Username: "{{ PLAIN }}", Password: "{{ PLAIN }}-suffix"}
+	if err := store.DeepInterpolation(params); err != nil {
+		t.Fatal(err)
+	}
+	if params.Password != "plain-value-suffix" {
+		t.Errorf("deep interpolation failed: %s", params.Password)
+	}
+	if base.Redact("pass plain-value-suffix") != "pass "+base.SecretMask {
+		t.Errorf("interpolated secret param not redacted")
+	}
+}
+
+func TestSnapshotSecrets(t *testing.T) {
+	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
+	records := []*base.StorageRecord{
+		{RefName: "TOKEN", Value: "snapshot-secret-value", Literal: true, Secret: true},
+		{RefName: "db_password", Value: "snapshot-by-name-value", Literal: true},
+		{RefName: "PLAIN", Value: "plain-value", Literal: true},
+	}
+	for _, record := range records {
+		if err := store.Insert(record, "generic"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snaps, err := store.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(snaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "snapshot-secret-value") || strings.Contains(string(data), "snapshot-by-name-value") {
+		t.Errorf("secret values saved in the snapshot: %s", data)
+	}
+	if !strings.Contains(string(data), "plain-value") {
+		t.Errorf("plain value not saved in the snapshot: %s", data)
+	}
+
+	// the secrets must be given again
+	restored := storage.NewStore()
+	restored.SetLogger(&fakeLogger{})
+	if err := restored.Restore(snaps, nil); err == nil {
+		t.Errorf("expected error restoring without the secrets")
+	}
+
+	restored = storage.NewStore()
+	restored.SetLogger(&fakeLogger{})
+	for _, record := range []*base.StorageRecord{
+		{RefName: "TOKEN", Value: "given-secret-value", Literal: true},
+		{RefName: "db_password", Value: "given-by-name-value", Literal: true},
+	} {
+		if err := restored.Insert(record, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := restored.Restore(snaps, nil); err != nil {
+		t.Fatal(err)
+	}
+	text := "{{ TOKEN }} {{ db_password }} {{ PLAIN }}"
+	if err := restored.Interpolate(&text); err != nil {
+		t.Fatal(err)
+	}
+	if text != "given-secret-value given-by-name-value plain-value" {
+		t.Errorf("restored interpolation failed: %s", text)
+	}
+	if record, err := restored.GetByRefName("TOKEN"); err != nil || !record.Secret {
+		t.Errorf("restored record is not secret")
+	}
+}
+
+func TestExpressions(t *testing.T) {
+	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
+	records := []*base.StorageRecord{
+		{RefName: "NAME", Value: "  Nebulant  ", Literal: true},
+		{RefName: "COUNT", Value: 3, Literal: true},
+		{RefName: "SERVER", Value: `{"name": "web", "tags": ["a", "b", "c"], "size": 2, "null": null}`, Literal: true},
+	}
+	for _, record := range records {
+		if err := store.Insert(record, "generic"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Setenv("NEBULANT_EXPR_TEST", "env-value")
+	defer os.Unsetenv("NEBULANT_EXPR_TEST")
+
+	cases := map[string]string{
+		// classic syntax
+		"{{ SERVER.name }}":            "web",
+		"{{ SERVER.tags[1] }}":         "b",
+		"{{ env.NEBULANT_EXPR_TEST }}": "env-value",
+		"{{NAME}}-{{ COUNT }}":         "  Nebulant  -3",
+		// defaults
+		`{{ SERVER.missing ?? "x" }}`:    "x",
+		`{{ MISSING.a.b ?? 'y' }}`:       "y",
+		`{{ SERVER.null ?? "z" }}`:       "z",
+		`{{ env.NEBULANT_NOPE ?? "e" }}`: "e",
+		`{{ SERVER.name ?? "x" }}`:       "web",
+		// filters
+		"{{ NAME | trim | upper }}":        "NEBULANT",
+		"{{ NAME | trim | lower }}":        "nebulant",
+		"{{ SERVER.name | b64enc }}":       "d2Vi",
+		`{{ "d2Vi" | b64dec }}`:            "web",
+		`{{ SERVER.tags | join("-") }}`:    "a-b-c",
+		"{{ SERVER.tags | join }}":         "a,b,c",
+		"{{ SERVER.tags | length }}":       "3",
+		"{{ SERVER.tags | json }}":         `["a","b","c"]`,
+		"{{ SERVER.name | sha256 }}":       "4b5e57f6eb2f42b9039b3d1e13929295f231749c510cbe341cd68036d9af97e2",
+		"{{ SERVER.tags | $[0] | upper }}": "A",
+		// arithmetic, comparison and ternaries
+		"{{ COUNT * 2 + SERVER.size }}":                   "8",
+		"{{ COUNT / 2 }}":                                 "1.5",
+		"{{ -COUNT % 2 }}":                                "-1",
+		`{{ "id-" + COUNT }}`:                             "id-3",
+		`{{ COUNT > 2 ? "many" : "few" }}`:                "many",
+		`{{ COUNT == "3" && !false }}`:                    "true",
+		`{{ SERVER.name != "web" || COUNT <= 1 }}`:        "false",
+		`{{ (COUNT - 1) * 2 >= 4 ? SERVER.name : "no" }}`: "web",
+	}
+	for source, expected := range cases {
+		text := source
+		if err := store.Interpolate(&text); err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+		if text != expected {
+			t.Errorf("%s: expected %q, got %q", source, expected, text)
+		}
+	}
+
+	errs := map[string]string{
+		"{{ COUNT + }}":                "column 8",
+		`{{ COUNT | nope }}`:           "unknown filter",
+		"{{ COUNT / 0 }}":              "division by zero",
+		`{{ "abc" | length(1) }}`:      "takes no arguments",
+		"{{ SERVER.missing | upper }}": "not found",
+	}
+	for source, expected := range errs {
+		text := source
+		err := store.Interpolate(&text)
+		if err == nil {
+			t.Errorf("%s: expected error, got %q", source, text)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error containing %q, got %q", source, expected, err.Error())
+		}
+	}
+}
+
+func TestTypedInterpolation(t *testing.T) {
+	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
+	records := []*base.StorageRecord{
+		{RefName: "SG", Value: `{"ids": ["sg-1", "sg-2"], "count": 2, "server": {"id": 42, "name": "web"}}`, Literal: true},
+		{RefName: "SIZE", Value: "8", Literal: true},
+	}
+	for _, record := range records {
+		if err := store.Insert(record, "generic"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type server struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	type embedded struct {
+		Enabled *bool
+	}
+	params := &struct {
+		embedded
+		SecurityGroupIds []*string
+		Count            *int64          `json:"count"`
+		Size             int             `json:"size"`
+		ServerID         *string         `json:"server_id"`
+		Name             string          `json:"name"`
+		Server           *server         `json:"server"`
+		Double           float64         `json:"double"`
+		Raw              json.RawMessage `json:"raw"`
+	}{}
+	data := []byte(`{
+		"securitygroupids": "{{ SG.ids }}",
+		"count": "{{ SG.count }}",
+		"size": "{{ SIZE }}",
+		"server_id": "{{ SG.server.id }}",
+		"name": "{{ SG.server.name }}-{{ SIZE }}",
+		"server": "{{ SG.server }}",
+		"double": "{{ SIZE * 1.5 }}",
+		"raw": "{{ SG.ids }}",
+		"Enabled": "{{ SG.count > 1 }}"
+	}`)
+	if err := util.UnmarshalParameters(store, data, params); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeepInterpolation(params); err != nil {
+		t.Fatal(err)
+	}
+	if len(params.SecurityGroupIds) != 2 || *params.SecurityGroupIds[1] != "sg-2" {
+		t.Errorf("list not injected: %v", params.SecurityGroupIds)
+	}
+	if params.Count == nil || *params.Count != 2 || params.Size != 8 || params.Double != 12 {
+		t.Errorf("numbers not injected: %v %v %v", params.Count, params.Size, params.Double)
+	}
+	if params.ServerID == nil || *params.ServerID != "42" || params.Name != "web-8" {
+		t.Errorf("string fields changed: %v %v", params.ServerID, params.Name)
+	}
+	if params.Server == nil || params.Server.ID != 42 || params.Server.Name != "web" {
+		t.Errorf("object not injected: %v", params.Server)
+	}
+	if params.Enabled == nil || !*params.Enabled {
+		t.Errorf("bool not injected in embedded struct: %v", params.Enabled)
+	}
+	if string(params.Raw) != `"{{ SG.ids }}"` {
+		t.Errorf("raw value changed: %s", params.Raw)
+	}
+
+	// without store (validation) the expressions are placeholders
+	sleep := &struct {
+		Seconds int64 `json:"seconds" validate:"required"`
+	}{}
+	if err := util.UnmarshalValidParameters(nil, []byte(`{"seconds": "{{ SIZE }}"}`), sleep); err != nil {
+		t.Errorf("validation without store failed: %v", err)
+	}
+
+	// undefined references fail before unmarshalling
+	if err := util.UnmarshalParameters(store, []byte(`{"count": "{{ NOPE }}"}`), params); err == nil {
+		t.Errorf("expected error of undefined reference")
+	}
+}
+
+func TestStateInterpolation(t *testing.T) {
+	t.Setenv("NEBULANT_STATE_PATH", t.TempDir())
+	t.Setenv("NEBULANT_STATE_NAMESPACE", "test")
+	st, err := state.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := st.Set(ctx, "test", "ami", "ami-123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Set(ctx, "test", "cfg", map[string]interface{}{"sizes": []int{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := storage.NewStore()
+	store.SetLogger(&fakeLogger{})
+	text := `{{ state.ami }} {{ state["cfg"].sizes[1] }} {{ state.cfg.sizes | length }} {{ state.nope ?? "x" }}`
+	if err := store.Interpolate(&text); err != nil {
+		t.Fatal(err)
+	}
+	if text != "ami-123 2 2 x" {
+		t.Errorf("unexpected state interpolation %q", text)
+	}
+	text = "{{ state.nope }}"
+	if err := store.Interpolate(&text); err == nil {
+		t.Errorf("expected error of missing state key")
+	}
+
+	// the reads are bound to the context of the running action
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	store.SetPrivateVar("CONTEXT", cctx)
+	text = "{{ state.ami }}"
+	if err := store.Interpolate(&text); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled read, got %v", err)
+	}
+	store.SetPrivateVar("CONTEXT", nil)
+
+	// outputs named state are not hidden by the state namespace
+	for _, name := range []string{"state", "State"} {
+		if err := store.Insert(&base.StorageRecord{RefName: name, Value: map[string]interface{}{"ami": "ami-456"}, Literal: true}, ""); err != nil {
+			t.Fatal(err)
+		}
+		text = fmt.Sprintf("{{ %s.ami }} {{ %s.ami | upper }}", name, name)
+		if err := store.Interpolate(&text); err != nil {
+			t.Fatal(err)
+		}
+		if text != "ami-456 AMI-456" {
+			t.Errorf("%s: unexpected record interpolation %q", name, text)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package storage
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/develatio/nebulant-cli/expr"
+	"github.com/develatio/nebulant-cli/util"
+)
+
+// a text with one single {{ }} expression
+var singleExpressionRegexp = regexp.MustCompile(`^\s*{{([^{}]*)}}\s*$`)
+
+// TypedInterpolation func. Returns data, a json document that is going
+// to be unmarshalled into v, with the values that are exactly one
+// {{ expression }} replaced by the native json value of the expression,
+// so lists, numbers and objects of previous outputs can fill the list,
+// numeric or struct fields of v. Values of string fields are left as
+// they are, for the usual DeepInterpolation
+func (s *Store) TypedInterpolation(data []byte, v interface{}) ([]byte, error) {
+	return util.TypedJSON(data, v, func(expression string, t reflect.Type) (interface{}, bool, error) {
+		ex, err := expr.Parse(expression)
+		if err != nil {
+			// not an expression, leave it to the string interpolation
+			return nil, false, nil
+		}
+		nv, err := s.Evaluate(ex)
+		if err != nil {
+			return nil, false, err
+		}
+		return nv, true, nil
+	})
+}
+
+// NativeInterpolation func. Native value of a text that is exactly one
+// {{ expression }}. Any other text is interpolated as usual
+func (s *Store) NativeInterpolation(text string) (interface{}, error) {
+	if m := singleExpressionRegexp.FindStringSubmatch(text); m != nil {
+		if ex, err := expr.Parse(strings.TrimSpace(m[1])); err == nil {
+			return s.Evaluate(ex)
+		}
+	}
+	if err := s.Interpolate(&text); err != nil {
+		return nil, err
+	}
+	return text, nil
+}
@@ -0,0 +1,98 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package subcom
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/subsystem"
+)
+
+var convertOutput *string
+var convertStripLayout *bool
+
+func parseConvertFs(cmdline *flag.FlagSet) (*flag.FlagSet, error) {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(cmdline.Output())
+	convertOutput = fs.String("o", "", "Write the converted blueprint to file instead of stdout")
+	convertStripLayout = fs.Bool("strip-layout", false, "Drop builder canvas layout (undo history, positions) on json to yaml conversion")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "\nUsage: nebulant convert [options] filepath\n")
+		fmt.Fprintf(fs.Output(), "\nConvert builder json blueprints into yaml and yaml blueprints into builder json.\n")
+		fmt.Fprintf(fs.Output(), "\nOptions:\n")
+		subsystem.PrintDefaults(fs)
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant convert ./project.nbp > project.yaml\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant convert -o project.nbp ./project.yaml\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant convert -strip-layout ./project.nbp > project.yaml\n")
+		fmt.Fprintf(fs.Output(), "\n\n")
+	}
+	err := fs.Parse(cmdline.Args()[1:])
+	if err != nil {
+		return fs, err
+	}
+	return fs, nil
+}
+
+func ConvertCmd(nblc *subsystem.NBLcommand) (int, error) {
+	fs, err := parseConvertFs(nblc.CommandLine())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, nil
+		}
+		return 1, err
+	}
+	src := fs.Arg(0)
+	if src == "" {
+		fs.Usage()
+		return 1, fmt.Errorf("please provide the path of the blueprint to convert")
+	}
+
+	data, err := os.ReadFile(src) // #nosec G304 -- Not a file inclusion, just a blueprint read
+	if err != nil {
+		return 1, err
+	}
+
+	var out []byte
+	if blueprint.IsYAML(src, data) {
+		out, err = blueprint.YAMLToBuilder(data)
+	} else {
+		out, err = blueprint.BuilderToYAML(data, !*convertStripLayout)
+	}
+	if err != nil {
+		return 1, err
+	}
+
+	if *convertOutput == "" {
+		fmt.Fprint(nblc.Stdout, string(out))
+		return 0, nil
+	}
+	if err := os.WriteFile(*convertOutput, out, 0600); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,121 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package subcom
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/executive"
+	"github.com/develatio/nebulant-cli/runtime"
+	"github.com/develatio/nebulant-cli/subsystem"
+)
+
+var destroyList *bool
+
+func parseDestroyFs(cmdline *flag.FlagSet) (*flag.FlagSet, error) {
+	fs := flag.NewFlagSet("destroy", flag.ContinueOnError)
+	fs.SetOutput(cmdline.Output())
+	destroyList = fs.Bool("list", false, "List the resources without deleting them")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "\nUsage: nebulant destroy [options] <execution-uuid>\n")
+		fmt.Fprintf(fs.Output(), "\nDelete the resources created by an execution, in reverse creation order.\n")
+		fmt.Fprintf(fs.Output(), "\nOptions:\n")
+		subsystem.PrintDefaults(fs)
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant destroy <execution-uuid>\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant destroy --list <execution-uuid>\n")
+		fmt.Fprintf(fs.Output(), "\n\n")
+	}
+	err := fs.Parse(cmdline.Args()[1:])
+	if err != nil {
+		return fs, err
+	}
+	return fs, nil
+}
+
+func DestroyCmd(nblc *subsystem.NBLcommand) (int, error) {
+	fs, err := parseDestroyFs(nblc.CommandLine())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, nil
+		}
+		return 1, err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1, fmt.Errorf("please provide the execution uuid")
+	}
+	executionUUID := fs.Arg(0)
+	ledger, err := runtime.LoadLedger(executionUUID)
+	if err != nil {
+		return 1, err
+	}
+	live := ledger.Live()
+	if *destroyList {
+		for _, res := range live {
+			fmt.Fprintf(nblc.Stdout, "%s\t%s\t%s\t(created by %s)\n", res.Provider, res.Type, res.ID, res.CreatedBy)
+		}
+		return 0, nil
+	}
+
+	bp, err := blueprint.NewTeardown(live)
+	if err != nil {
+		return 1, err
+	}
+	irb, err := blueprint.GenerateIRB(bp, &blueprint.IRBGenConfig{})
+	if err != nil {
+		return 1, err
+	}
+	// same execution uuid, so the ledger of the
+	// execution is updated on every delete
+	irb.ExecutionUUID = &executionUUID
+	irb.BP.ExecutionUUID = &executionUUID
+	irb.Teardown = true
+
+	manager := executive.NewManager(false)
+	manager.PrepareIRB(irb)
+
+	// Director in one run mode
+	err = executive.InitDirector(false, false)
+	if err != nil {
+		return 1, err
+	}
+	executive.MDirector.HandleIRB <- &executive.HandleIRBConfig{Manager: manager}
+	executive.MDirector.Wait()
+
+	ledger, err = runtime.LoadLedger(executionUUID)
+	if err != nil {
+		// no ledger, all the resources were deleted
+		return 0, nil
+	}
+	if live := ledger.Live(); len(live) > 0 {
+		for _, res := range live {
+			fmt.Fprintf(nblc.Stdout, "%s\t%s\t%s\t(not deleted)\n", res.Provider, res.Type, res.ID)
+		}
+		return 1, fmt.Errorf("%d resources could not be deleted", len(live))
+	}
+	return 0, nil
+}
@@ -0,0 +1,121 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package subcom
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/subsystem"
+)
+
+var diffFormat *string
+var diffExitCode *bool
+
+func parseDiffFs(cmdline *flag.FlagSet) (*flag.FlagSet, error) {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(cmdline.Output())
+	diffFormat = fs.String("format", "text", "Output format: text or json")
+	diffExitCode = fs.Bool("exit-code", false, "Exit with status 1 if there are differences")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "\nUsage: nebulant diff [options] <a> <b>\n")
+		fmt.Fprintf(fs.Output(), "\nShow semantic differences between two blueprints. Builder canvas and undo history are ignored.\n")
+		fmt.Fprintf(fs.Output(), "Local files are used when they exist, remote blueprints otherwise.\n")
+		fmt.Fprintf(fs.Output(), "\nOptions:\n")
+		subsystem.PrintDefaults(fs)
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant diff ./old.nbp ./new.nbp\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant diff develatio/utils/debug:1.0.0 develatio/utils/debug:1.0.1\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant diff develatio/utils/debug ./debug.yaml\n")
+		fmt.Fprintf(fs.Output(), "\n\n")
+	}
+	err := fs.Parse(cmdline.Args()[1:])
+	if err != nil {
+		return fs, err
+	}
+	return fs, nil
+}
+
+func DiffCmd(nblc *subsystem.NBLcommand) (int, error) {
+	fs, err := parseDiffFs(nblc.CommandLine())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, nil
+		}
+		return 1, err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 1, fmt.Errorf("please provide the two blueprints to compare")
+	}
+
+	var bps [2]*blueprint.Blueprint
+	for i := 0; i < 2; i++ {
+		bps[i], err = loadDiffBlueprint(fs.Arg(i))
+		if err != nil {
+			return 1, err
+		}
+	}
+	diff, err := blueprint.DiffBlueprints(bps[0], bps[1])
+	if err != nil {
+		return 1, err
+	}
+
+	switch *diffFormat {
+	case "text":
+		err = diff.WriteText(nblc.Stdout)
+	case "json":
+		enc := json.NewEncoder(nblc.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(diff)
+	default:
+		return 1, fmt.Errorf("unknown output format %s", *diffFormat)
+	}
+	if err != nil {
+		return 1, err
+	}
+	if *diffExitCode && !diff.Empty() {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// loadDiffBlueprint func. Prefer an existing local file over a
+// remote blueprint with the same path.
+func loadDiffBlueprint(path string) (*blueprint.Blueprint, error) {
+	var bpUrl *blueprint.BlueprintURL
+	var err error
+	if fi, serr := os.Stat(path); serr == nil && !fi.IsDir() {
+		bpUrl, err = blueprint.ParsePath(path)
+	} else {
+		bpUrl, err = blueprint.ParseURL(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return blueprint.NewFromAny(bpUrl)
+}
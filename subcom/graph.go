@@ -0,0 +1,113 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package subcom
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/subsystem"
+)
+
+var graphFileFlag *bool
+var graphFormat *string
+var graphOutput *string
+
+func parseGraphFs(cmdline *flag.FlagSet) (*flag.FlagSet, error) {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	fs.SetOutput(cmdline.Output())
+	graphFileFlag = fs.Bool("f", false, "Read local file")
+	graphFormat = fs.String("format", "dot", "Output format: dot or mermaid")
+	graphOutput = fs.String("o", "", "Write the graph to file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "\nUsage: nebulant graph [options] [org/coll/bp] [-f filepath]\n")
+		fmt.Fprintf(fs.Output(), "\nExport the compiled graph of the blueprint as Graphviz DOT or Mermaid.\n")
+		fmt.Fprintf(fs.Output(), "\nOptions:\n")
+		subsystem.PrintDefaults(fs)
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant graph -f ./local/file/project.nbp | dot -Tsvg > project.svg\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant graph -format mermaid develatio/utils/debug\n")
+		fmt.Fprintf(fs.Output(), "\n\n")
+	}
+	err := fs.Parse(cmdline.Args()[1:])
+	if err != nil {
+		return fs, err
+	}
+	return fs, nil
+}
+
+func GraphCmd(nblc *subsystem.NBLcommand) (int, error) {
+	fs, err := parseGraphFs(nblc.CommandLine())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, nil
+		}
+		return 1, err
+	}
+	bluePrintFilePath := fs.Arg(0)
+	if bluePrintFilePath == "" {
+		fs.Usage()
+		return 1, fmt.Errorf("please provide addr to the blueprint you want to export")
+	}
+	if *graphFormat != "dot" && *graphFormat != "mermaid" {
+		return 1, fmt.Errorf("unknown output format %s", *graphFormat)
+	}
+
+	var bpUrl *blueprint.BlueprintURL
+	if *graphFileFlag {
+		bpUrl, err = blueprint.ParsePath(bluePrintFilePath)
+	} else {
+		bpUrl, err = blueprint.ParseURL(bluePrintFilePath)
+	}
+	if err != nil {
+		return 1, err
+	}
+	irb, err := blueprint.NewIRBFromAny(bpUrl, &blueprint.IRBGenConfig{})
+	if err != nil {
+		return 1, err
+	}
+
+	out := nblc.Stdout
+	if *graphOutput != "" {
+		f, err := os.Create(*graphOutput)
+		if err != nil {
+			return 1, err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	g := blueprint.NewGraph(irb)
+	if *graphFormat == "mermaid" {
+		err = g.WriteMermaid(out)
+	} else {
+		err = g.WriteDOT(out)
+	}
+	if err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
@@ -26,24 +26,79 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/develatio/nebulant-cli/base"
 	"github.com/develatio/nebulant-cli/blueprint"
 	"github.com/develatio/nebulant-cli/cast"
 	"github.com/develatio/nebulant-cli/config"
 	"github.com/develatio/nebulant-cli/executive"
+	"github.com/develatio/nebulant-cli/runtime"
 	"github.com/develatio/nebulant-cli/subsystem"
 )
 
+var runVarsFile *string
+var runRequireSignature *bool
+var runTrustedKeys *string
+var runSignature *string
+var runPlan *bool
+var runTimeout *time.Duration
+var runResume *string
+var runMaxParallel *int
+var runRollbackOnFailure *bool
+var runReport *string
+var runProviderLimits map[string]*blueprint.ProviderLimit
+
 func parseRunFs(cmdline *flag.FlagSet) (*flag.FlagSet, error) {
 	fs := flag.NewFlagSet("run", flag.ContinueOnError)
 	fs.SetOutput(cmdline.Output())
 	config.ForceFileFlag = fs.Bool("f", false, "Run local file")
+	runVarsFile = fs.String("vars-file", "", "Read blueprint args from a .json or .env file")
+	runPlan = fs.Bool("plan", false, "Show what the blueprint would do without running it")
+	runResume = fs.String("resume", "", "Resume a failed or stopped execution from his checkpoint")
+	runMaxParallel = fs.Int("max-parallel", 0, "Max actions running at once. Zero for no limit")
+	runProviderLimits = make(map[string]*blueprint.ProviderLimit)
+	fs.Func("provider-limit", "Limit the actions of a provider as provider=concurrency[:rps] (e.g. hetznerCloud=4:2). Repeatable", func(s string) error {
+		name, limit, err := blueprint.ParseProviderLimit(s)
+		if err != nil {
+			return err
+		}
+		runProviderLimits[name] = limit
+		return nil
+	})
+	runTimeout = fs.Duration("timeout", 0, "Stop the execution after this time (e.g. 30s, 10m, 1h)")
+	runRollbackOnFailure = fs.Bool("rollback-on-failure", false, "Delete the resources created by the execution if it fails")
+	runReport = fs.String("report", "", "Write a report of the execution into this file, as JUnit XML if it ends with .xml or as JSON otherwise")
+	runRequireSignature = fs.Bool("require-signature", false, "Refuse to run blueprints without a valid signature")
+	runTrustedKeys = fs.String("trusted-keys", blueprint.TrustedKeysPath(), "Dir with the trusted public keys (*.pub)")
+	runSignature = fs.String("signature", "", "Detached signature file. Defaults to <filepath>.sig")
+	config.OfflineFlag = fs.Bool("offline", false, "Resolve remote blueprints only from the local cache")
+	config.LockFileFlag = fs.String("lockfile", blueprint.DefaultLockFile, "Lockfile pinning remote blueprints to version and hash")
+	config.UpdateLockFlag = fs.Bool("update-lock", false, "Pin the fetched remote blueprint into the lockfile")
 	fs.Usage = func() {
-		fmt.Fprintf(fs.Output(), "\nUsage: nebulant run [org/coll/bp] [-f filepath] [--varname=varvalue --varname=varvalue]\n\n")
+		fmt.Fprintf(fs.Output(), "\nUsage: nebulant run [--plan] [--resume execution-uuid] [--timeout duration] [--rollback-on-failure] [--report file.json|file.xml] [--max-parallel n] [--provider-limit provider=concurrency[:rps]] [--vars-file file] [--require-signature] [org/coll/bp] [-f filepath] [--varname=varvalue --varname=varvalue]\n\n")
 		fmt.Fprintf(fs.Output(), "Examples:\n")
 		fmt.Fprintf(fs.Output(), "\tnebulant run develatio/utils/debug\n")
 		fmt.Fprintf(fs.Output(), "\tnebulant run -f ./local/file/project.nbp\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run -f ./local/file/project.yaml\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run https://example.com/project.yaml#sha256=<hex>\n")
+		fmt.Fprintf(fs.Output(), "\tgen-blueprint.sh | nebulant run -\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --vars-file vars.json -f ./local/file/project.nbp\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --plan -f ./local/file/project.nbp\t(dry-run)\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --timeout 30m -f ./local/file/project.nbp\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --max-parallel 8 --provider-limit hetznerCloud=4:2 -f ./local/file/project.nbp\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --rollback-on-failure -f ./local/file/project.nbp\t(delete created resources on failure)\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --report report.xml -f ./local/file/project.nbp\t(JUnit report for CI)\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --resume <execution-uuid>\t(continue from the failed action)\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --resume <execution-uuid> -f ./local/file/fixed.nbp\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --resume <execution-uuid> -- --password=secret\t(secret args are not saved, give them again)\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --update-lock develatio/utils/debug\t(pin into nebulant.lock)\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --offline develatio/utils/debug\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run --require-signature --trusted-keys ./keys -f ./local/file/project.nbp\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant run develatio/utils/debug --help\t(show blueprint parameters)\n")
 		fmt.Fprintf(fs.Output(), "\n\n")
 	}
 	err := fs.Parse(cmdline.Args()[1:])
@@ -62,13 +117,23 @@ func RunCmd(nblc *subsystem.NBLcommand) (int, error) {
 		return 1, err
 	}
 	bluePrintFilePath := fs.Arg(0)
+	if *runResume != "" {
+		return resumeRun(fs)
+	}
 	if bluePrintFilePath == "" {
 		fs.Usage()
 		return 1, fmt.Errorf("please provide addr to the blueprint you want to execute")
 	}
 
-	cast.LogInfo("Processing blueprint...", nil)
-	irbConf := &blueprint.IRBGenConfig{}
+	irbConf := &blueprint.IRBGenConfig{
+		VarsFile:          *runVarsFile,
+		RequireSignature:  *runRequireSignature,
+		TrustedKeys:       *runTrustedKeys,
+		SignaturePath:     *runSignature,
+		Timeout:           *runTimeout,
+		Limits:            runLimits(),
+		RollbackOnFailure: *runRollbackOnFailure,
+	}
 	args := fs.Args()
 	if len(args) > 1 {
 		irbConf.Args = args[1:]
@@ -83,9 +148,19 @@ func RunCmd(nblc *subsystem.NBLcommand) (int, error) {
 		return 1, err
 	}
 
+	if wantsBPHelp(irbConf.Args) {
+		bp, err := blueprint.NewFromAny(bpUrl)
+		if err != nil {
+			return 1, err
+		}
+		printBPParameters(nblc.Stdout, bluePrintFilePath, bp)
+		return 0, nil
+	}
+
+	cast.LogInfo("Processing blueprint...", nil)
 	irb, err := blueprint.NewIRBFromAny(bpUrl, irbConf)
 	if err != nil {
-		if bpUrl.Scheme != "file" && bpUrl.UrlPath != "" {
+		if bpUrl.Scheme == "nebulant" && bpUrl.UrlPath != "" {
 			if fi, err2 := os.Stat(bpUrl.FilePath); err2 == nil && !fi.IsDir() {
 				return 1, errors.Join(err, fmt.Errorf("did you want to run file %s?, try adding the -f attribute: `nebulant -f %s`. You can also use file:// scheme: `nebulant file://%s", bpUrl.FilePath, bpUrl.FilePath, bpUrl.FilePath))
 			}
@@ -93,12 +168,198 @@ func RunCmd(nblc *subsystem.NBLcommand) (int, error) {
 		}
 		return 1, err
 	}
+	if *runPlan {
+		plan := blueprint.NewPlan(irb)
+		if err := plan.WriteText(nblc.Stdout); err != nil {
+			return 1, err
+		}
+		if err := plan.Err(); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+	manager := executive.NewManager(false)
+	manager.PrepareIRB(irb)
+	manager.ReportPath = *runReport
+
 	// Director in one run mode
 	err = executive.InitDirector(false, false)
 	if err != nil {
 		return 1, err
 	}
-	executive.MDirector.HandleIRB <- &executive.HandleIRBConfig{IRB: irb}
+	executive.MDirector.HandleIRB <- &executive.HandleIRBConfig{Manager: manager}
 	executive.MDirector.Wait()
 	return 0, nil
 }
+
+// runLimits func. The run limits from the cli flags, nil if none
+func runLimits() *blueprint.RunLimits {
+	limits := &blueprint.RunLimits{
+		MaxParallel: *runMaxParallel,
+		Providers:   runProviderLimits,
+	}
+	if limits.IsZero() {
+		return nil
+	}
+	return limits
+}
+
+// resumeRun func. Continue the execution saved in the checkpoint. The
+// saved blueprint is used unless a (fixed) blueprint is provided
+func resumeRun(fs *flag.FlagSet) (int, error) {
+	cp, err := runtime.LoadCheckpoint(*runResume)
+	if err != nil {
+		return 1, err
+	}
+	cpArgs, secrets := cp.ResumeArgs()
+	irbConf := &blueprint.IRBGenConfig{
+		VarsFile:          *runVarsFile,
+		RequireSignature:  *runRequireSignature,
+		TrustedKeys:       *runTrustedKeys,
+		SignaturePath:     *runSignature,
+		Timeout:           *runTimeout,
+		Limits:            runLimits(),
+		RollbackOnFailure: *runRollbackOnFailure,
+		ParsedArgs:        cpArgs,
+	}
+	bluePrintFilePath := fs.Arg(0)
+	if strings.HasPrefix(bluePrintFilePath, "-") {
+		// only args, after --
+		irbConf.Args = fs.Args()
+		bluePrintFilePath = ""
+	} else if len(fs.Args()) > 1 {
+		irbConf.Args = fs.Args()[1:]
+	}
+	if missing, err := missingArgs(irbConf, secrets); err != nil {
+		return 1, err
+	} else if len(missing) > 0 {
+		return 1, fmt.Errorf("secret args are not saved in checkpoints, please provide them again: nebulant run --resume %s [-f <filepath>] -- --%s=<value>", cp.ExecutionUUID, strings.Join(missing, "=<value> --"))
+	}
+
+	var irb *blueprint.IRBlueprint
+	if bluePrintFilePath != "" {
+		var bpUrl *blueprint.BlueprintURL
+		if config.ForceFileFlag != nil && *config.ForceFileFlag {
+			bpUrl, err = blueprint.ParsePath(bluePrintFilePath)
+		} else {
+			bpUrl, err = blueprint.ParseURL(bluePrintFilePath)
+		}
+		if err != nil {
+			return 1, err
+		}
+		irb, err = blueprint.NewIRBFromAny(bpUrl, irbConf)
+		if err != nil {
+			return 1, err
+		}
+	} else {
+		if len(cp.Blueprint) <= 0 {
+			return 1, fmt.Errorf("the checkpoint has no blueprint, please provide it: nebulant run --resume %s -f <filepath>", cp.ExecutionUUID)
+		}
+		if irbConf.RequireSignature {
+			return 1, fmt.Errorf("cannot verify the signature of the checkpoint blueprint, please provide the signed blueprint")
+		}
+		bp, err := blueprint.NewFromBytes(cp.Blueprint)
+		if err != nil {
+			return 1, err
+		}
+		irb, err = blueprint.GenerateIRB(bp, irbConf)
+		if err != nil {
+			return 1, err
+		}
+	}
+	// keep the execution uuid, so the checkpoint is
+	// updated instead of creating a new one
+	irb.ExecutionUUID = &cp.ExecutionUUID
+	irb.BP.ExecutionUUID = &cp.ExecutionUUID
+
+	manager := executive.NewManager(false)
+	manager.PrepareIRB(irb)
+	manager.Resume = cp
+	manager.ReportPath = *runReport
+
+	// Director in one run mode
+	err = executive.InitDirector(false, false)
+	if err != nil {
+		return 1, err
+	}
+	executive.MDirector.HandleIRB <- &executive.HandleIRBConfig{Manager: manager}
+	executive.MDirector.Wait()
+	return 0, nil
+}
+
+// missingArgs func. The names not given as cli arg nor into the vars file
+func missingArgs(irbConf *blueprint.IRBGenConfig, names []string) ([]string, error) {
+	if len(names) <= 0 {
+		return nil, nil
+	}
+	given, err := blueprint.ParseBPArgs(irbConf.Args)
+	if err != nil {
+		return nil, err
+	}
+	if irbConf.VarsFile != "" {
+		fargs, err := blueprint.ParseVarsFile(irbConf.VarsFile)
+		if err != nil {
+			return nil, err
+		}
+		given = append(given, fargs...)
+	}
+	exists := make(map[string]bool)
+	for _, arg := range given {
+		exists[arg.Name] = true
+	}
+	var missing []string
+	for _, name := range names {
+		if !exists[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// wantsBPHelp func. True if -h, -help or --help is given as blueprint arg
+func wantsBPHelp(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "-h", "-help", "--help", "--h":
+			return true
+		}
+	}
+	return false
+}
+
+func printBPParameters(w io.Writer, bpPath string, bp *blueprint.Blueprint) {
+	fmt.Fprintf(w, "\nUsage: nebulant run %s [--varname=varvalue --varname=varvalue]\n", bpPath)
+	if len(bp.Parameters) <= 0 {
+		fmt.Fprintf(w, "\nThis blueprint declares no parameters.\n\n")
+		return
+	}
+	fmt.Fprintf(w, "\nParameters:\n")
+	for _, p := range bp.Parameters {
+		line := fmt.Sprintf("  --%s=<%s>", p.Name, p.GetType())
+		if p.Required {
+			line = line + " (required)"
+		}
+		secret := p.Secret || base.IsSecretName(p.Name)
+		if secret {
+			line = line + " (secret)"
+		}
+		if p.Description != "" {
+			line = line + "\t" + p.Description
+		}
+		fmt.Fprintln(w, line)
+		var details []string
+		if p.GetType() == blueprint.ParameterTypeEnum {
+			details = append(details, "one of: "+strings.Join(p.Choices, ", "))
+		}
+		if p.Regex != "" {
+			details = append(details, "format: "+p.Regex)
+		}
+		if p.Default != nil && !secret {
+			details = append(details, fmt.Sprintf("default: %v", p.Default))
+		}
+		if len(details) > 0 {
+			fmt.Fprintf(w, "      %s\n", strings.Join(details, "; "))
+		}
+	}
+	fmt.Fprintln(w)
+}
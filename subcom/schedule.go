@@ -0,0 +1,138 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package subcom
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/develatio/nebulant-cli/cast"
+	"github.com/develatio/nebulant-cli/config"
+	"github.com/develatio/nebulant-cli/executive"
+	"github.com/develatio/nebulant-cli/nhttpd"
+	"github.com/develatio/nebulant-cli/schedule"
+	"github.com/develatio/nebulant-cli/subsystem"
+)
+
+var scheduleCheck *bool
+var scheduleRequireSignature *bool
+var scheduleTrustedKeys *string
+
+func parseScheduleFs(cmdline *flag.FlagSet) (*flag.FlagSet, error) {
+	fs := flag.NewFlagSet("schedule", flag.ContinueOnError)
+	fs.SetOutput(cmdline.Output())
+	config.AddrFlag = fs.String("b", config.SERVER_ADDR+":"+config.SERVER_PORT, "Bind addr:port (ipv4) or [::1]:port (ipv6) of the status server")
+	config.Ipv6Flag = fs.Bool("6", false, "Force ipv6")
+	scheduleCheck = fs.Bool("check", false, "Validate the schedule file and show the next run of every job")
+	scheduleRequireSignature = fs.Bool("require-signature", false, "Refuse to run the blueprints of the jobs without a valid signature")
+	scheduleTrustedKeys = fs.String("trusted-keys", "", "Dir with the trusted public keys (*.pub) of the jobs without trusted_keys")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "\nUsage: nebulant schedule [options] <schedule-file>\n")
+		fmt.Fprintf(fs.Output(), "\nRun the jobs of the schedule file at the times of their cron expressions.\n")
+		fmt.Fprintf(fs.Output(), "The status of the jobs is served at http://<addr>/schedule/\n")
+		fmt.Fprintf(fs.Output(), "\nOptions:\n")
+		subsystem.PrintDefaults(fs)
+		fmt.Fprintf(fs.Output(), "\nSchedule file:\n")
+		fmt.Fprintf(fs.Output(), "\thistory: 10\t\t\t\t(results kept per job)\n")
+		fmt.Fprintf(fs.Output(), "\tjobs:\n")
+		fmt.Fprintf(fs.Output(), "\t  - name: nightly-backup\n")
+		fmt.Fprintf(fs.Output(), "\t    cron: \"0 3 * * *\"\n")
+		fmt.Fprintf(fs.Output(), "\t    blueprint: develatio/ops/backup\t(or ./local/file.yaml)\n")
+		fmt.Fprintf(fs.Output(), "\t    args: [\"--region=eu-west-1\"]\n")
+		fmt.Fprintf(fs.Output(), "\t    overlap: skip\t\t\t(skip, queue, replace or allow)\n")
+		fmt.Fprintf(fs.Output(), "\t    timeout: 30m\n")
+		fmt.Fprintf(fs.Output(), "\t    require_signature: true\t\t(also for the called blueprints)\n")
+		fmt.Fprintf(fs.Output(), "\t    trusted_keys: ./keys\n")
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant schedule ./schedule.yaml\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant schedule --check ./schedule.yaml\n")
+		fmt.Fprintf(fs.Output(), "\tcurl http://localhost:15678/schedule/nightly-backup/\n")
+		fmt.Fprintf(fs.Output(), "\n\n")
+	}
+	err := fs.Parse(cmdline.Args()[1:])
+	if err != nil {
+		return fs, err
+	}
+	if err := resolveBindAddr(); err != nil {
+		return fs, err
+	}
+	return fs, nil
+}
+
+func ScheduleCmd(nblc *subsystem.NBLcommand) (int, error) {
+	fs, err := parseScheduleFs(nblc.CommandLine())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, nil
+		}
+		return 1, err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1, fmt.Errorf("please provide the schedule file")
+	}
+	sf, err := schedule.LoadFile(fs.Arg(0))
+	if err != nil {
+		return 1, err
+	}
+	for _, job := range sf.Jobs {
+		if *scheduleRequireSignature {
+			job.RequireSignature = true
+		}
+		if job.TrustedKeys == "" {
+			job.TrustedKeys = *scheduleTrustedKeys
+		}
+	}
+	if *scheduleCheck {
+		now := time.Now()
+		for _, job := range sf.Jobs {
+			fmt.Fprintf(nblc.Stdout, "%s\t%s\tnext run at %s\n", job.Name, job.Cron, job.Next(now).Format(time.RFC3339))
+		}
+		return 0, nil
+	}
+
+	// Director in server mode, managers are kept
+	// running between the runs of the jobs
+	err = executive.InitDirector(true, false)
+	if err != nil {
+		return 1, err
+	}
+	sch := schedule.NewScheduler(sf)
+	srv := nhttpd.GetServer()
+	sch.AddViews(srv)
+	errc := srv.ServeIfNot()
+	sch.Start()
+	cast.LogInfo(fmt.Sprintf("Scheduler started with %d jobs. Status at http://%s/schedule/", len(sf.Jobs), srv.GetAddr()), nil)
+
+	// until shutdown
+	err = <-errc
+	sch.Stop()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return 2, err
+	}
+	executive.MDirector.Wait()
+	return 0, nil
+}
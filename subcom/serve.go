@@ -50,27 +50,31 @@ func parseServeFs(cmdline *flag.FlagSet) (*flag.FlagSet, error) {
 		return fs, err
 	}
 
-	var tcpaddr *net.TCPAddr
+	if err := resolveBindAddr(); err != nil {
+		return fs, err
+	}
+
+	return fs, nil
+}
+
+// resolveBindAddr func. Set the server addr and port
+// from the -b and -6 flags
+func resolveBindAddr() error {
+	network := "tcp"
 	if *config.Ipv6Flag {
-		tcpaddr, err = net.ResolveTCPAddr("tcp6", *config.AddrFlag)
-		if err != nil {
-			return fs, err
-		}
-	} else {
-		tcpaddr, err = net.ResolveTCPAddr("tcp", *config.AddrFlag)
-		if err != nil {
-			return fs, err
-		}
+		network = "tcp6"
+	}
+	tcpaddr, err := net.ResolveTCPAddr(network, *config.AddrFlag)
+	if err != nil {
+		return err
 	}
 	host, port, err := net.SplitHostPort(tcpaddr.String())
 	if err != nil {
-		return fs, err
+		return err
 	}
-
 	config.SERVER_ADDR = host
 	config.SERVER_PORT = port
-
-	return fs, nil
+	return nil
 }
 
 func ServeCmd(nblc *subsystem.NBLcommand) (int, error) {
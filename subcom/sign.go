@@ -0,0 +1,131 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package subcom
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/subsystem"
+)
+
+var signKeygen *string
+var signKey *string
+var signOutput *string
+var signForceFile *bool
+
+func parseSignFs(cmdline *flag.FlagSet) (*flag.FlagSet, error) {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	fs.SetOutput(cmdline.Output())
+	signKeygen = fs.String("keygen", "", "Generate a new ed25519 key pair with the given name and exit")
+	signKey = fs.String("key", "default", "Name or path of the private key used to sign")
+	signOutput = fs.String("o", "", "Write the signature to file. Defaults to <filepath>.sig")
+	signForceFile = fs.Bool("f", false, "Sign local file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "\nUsage: nebulant sign [options] [org/coll/bp] [-f filepath]\n")
+		fmt.Fprintf(fs.Output(), "\nCreate a detached ed25519 signature of the blueprint. Keys are stored in %s\n", blueprint.KeysPath())
+		fmt.Fprintf(fs.Output(), "\nOptions:\n")
+		subsystem.PrintDefaults(fs)
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant sign -keygen default\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant sign -f ./project.nbp\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant sign -key release -o debug.sig develatio/utils/debug\n")
+		fmt.Fprintf(fs.Output(), "\n\n")
+	}
+	err := fs.Parse(cmdline.Args()[1:])
+	if err != nil {
+		return fs, err
+	}
+	return fs, nil
+}
+
+func SignCmd(nblc *subsystem.NBLcommand) (int, error) {
+	fs, err := parseSignFs(nblc.CommandLine())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, nil
+		}
+		return 1, err
+	}
+
+	if *signKeygen != "" {
+		priv, pub, err := blueprint.GenerateKeyPair(*signKeygen)
+		if err != nil {
+			return 1, err
+		}
+		fmt.Fprintf(nblc.Stdout, "private key: %s\npublic key: %s\n", priv, pub)
+		fmt.Fprintf(nblc.Stdout, "copy the public key into the trusted keys dir (%s) of the hosts that should run your blueprints\n", blueprint.TrustedKeysPath())
+		return 0, nil
+	}
+
+	src := fs.Arg(0)
+	if src == "" {
+		fs.Usage()
+		return 1, fmt.Errorf("please provide addr to the blueprint you want to sign")
+	}
+	var bpUrl *blueprint.BlueprintURL
+	if *signForceFile {
+		bpUrl, err = blueprint.ParsePath(src)
+	} else {
+		bpUrl, err = blueprint.ParseURL(src)
+	}
+	if err != nil {
+		return 1, err
+	}
+	out := *signOutput
+	if out == "" {
+		out = blueprint.SignaturePath(bpUrl)
+	}
+	if out == "" {
+		return 1, fmt.Errorf("please provide the signature output path (-o) for remote blueprints")
+	}
+
+	priv, err := blueprint.LoadPrivateKey(blueprint.ResolveKeyPath(*signKey, ".key"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 1, fmt.Errorf("private key not found, generate one with `nebulant sign -keygen %s`", *signKey)
+		}
+		return 1, err
+	}
+	bp, err := blueprint.NewFromAny(bpUrl)
+	if err != nil {
+		return 1, err
+	}
+	sig, err := blueprint.SignBlueprint(bp, priv)
+	if err != nil {
+		return 1, err
+	}
+	data, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return 1, err
+	}
+	if err := os.WriteFile(out, append(data, '\n'), 0600); err != nil {
+		return 1, err
+	}
+	fmt.Fprintf(nblc.Stdout, "signature written to %s (key %s)\n", out, sig.KeyID)
+	return 0, nil
+}
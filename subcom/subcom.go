@@ -50,6 +50,62 @@ func RegisterSubcommands() {
 			Sec:           subsystem.SecMain,
 			Call:          RunCmd,
 		},
+		"schedule": {
+			UpgradeTerm:   true,
+			WelcomeMsg:    true,
+			InitProviders: true,
+			Help:          "  schedule\t\t" + term.EmojiSet["Rocket"] + " Run blueprints on a schedule (cron)\n",
+			Sec:           subsystem.SecMain,
+			Call:          ScheduleCmd,
+		},
+		"convert": {
+			UpgradeTerm:   false,
+			WelcomeMsg:    false,
+			InitProviders: false,
+			Help:          "  convert\t\t" + term.EmojiSet["Memo"] + " Convert blueprints between builder json and yaml\n",
+			Sec:           subsystem.SecMain,
+			Call:          ConvertCmd,
+		},
+		"destroy": {
+			UpgradeTerm:   true,
+			WelcomeMsg:    false,
+			InitProviders: true,
+			Help:          "  destroy\t\t" + term.EmojiSet["CrossMark"] + " Delete the resources created by an execution\n",
+			Sec:           subsystem.SecMain,
+			Call:          DestroyCmd,
+		},
+		"validate": {
+			UpgradeTerm:   false,
+			WelcomeMsg:    false,
+			InitProviders: true,
+			Help:          "  validate\t\t" + term.EmojiSet["CheckMarkButton"] + " Check blueprint for errors without running it\n",
+			Sec:           subsystem.SecMain,
+			Call:          ValidateCmd,
+		},
+		"graph": {
+			UpgradeTerm:   false,
+			WelcomeMsg:    false,
+			InitProviders: true,
+			Help:          "  graph\t\t\t" + term.EmojiSet["CardIndexDividers"] + " Export blueprint graph as DOT or Mermaid\n",
+			Sec:           subsystem.SecMain,
+			Call:          GraphCmd,
+		},
+		"diff": {
+			UpgradeTerm:   false,
+			WelcomeMsg:    false,
+			InitProviders: false,
+			Help:          "  diff\t\t\t" + term.EmojiSet["FaceWithMonocle"] + " Show semantic differences between two blueprints\n",
+			Sec:           subsystem.SecMain,
+			Call:          DiffCmd,
+		},
+		"sign": {
+			UpgradeTerm:   false,
+			WelcomeMsg:    false,
+			InitProviders: false,
+			Help:          "  sign\t\t\t" + term.EmojiSet["Key"] + " Sign blueprints and manage signing keys\n",
+			Sec:           subsystem.SecMain,
+			Call:          SignCmd,
+		},
 		"assets": {
 			UpgradeTerm:   true,
 			WelcomeMsg:    true,
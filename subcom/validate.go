@@ -0,0 +1,215 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package subcom
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/develatio/nebulant-cli/blueprint"
+	"github.com/develatio/nebulant-cli/config"
+	"github.com/develatio/nebulant-cli/subsystem"
+)
+
+var validateFileFlag *bool
+var validateFormat *string
+var validateStrict *bool
+
+func parseValidateFs(cmdline *flag.FlagSet) (*flag.FlagSet, error) {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(cmdline.Output())
+	validateFileFlag = fs.Bool("f", false, "Validate local file")
+	validateFormat = fs.String("format", "text", "Output format: text, json or sarif")
+	validateStrict = fs.Bool("strict", false, "Exit with error on warnings too")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "\nUsage: nebulant validate [options] [org/coll/bp] [-f filepath]\n")
+		fmt.Fprintf(fs.Output(), "\nCompile the blueprint and run static checks over it without executing anything.\n")
+		fmt.Fprintf(fs.Output(), "\nOptions:\n")
+		subsystem.PrintDefaults(fs)
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant validate develatio/utils/debug\n")
+		fmt.Fprintf(fs.Output(), "\tnebulant validate -format sarif -f ./local/file/project.nbp > lint.sarif\n")
+		fmt.Fprintf(fs.Output(), "\n\n")
+	}
+	err := fs.Parse(cmdline.Args()[1:])
+	if err != nil {
+		return fs, err
+	}
+	return fs, nil
+}
+
+func ValidateCmd(nblc *subsystem.NBLcommand) (int, error) {
+	fs, err := parseValidateFs(nblc.CommandLine())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, nil
+		}
+		return 1, err
+	}
+	bluePrintFilePath := fs.Arg(0)
+	if bluePrintFilePath == "" {
+		fs.Usage()
+		return 1, fmt.Errorf("please provide addr to the blueprint you want to validate")
+	}
+
+	var bpUrl *blueprint.BlueprintURL
+	if *validateFileFlag {
+		bpUrl, err = blueprint.ParsePath(bluePrintFilePath)
+	} else {
+		bpUrl, err = blueprint.ParseURL(bluePrintFilePath)
+	}
+	if err != nil {
+		return 1, err
+	}
+	bp, err := blueprint.NewFromAny(bpUrl)
+	if err != nil {
+		return 1, err
+	}
+
+	issues := blueprint.Validate(bp)
+	switch *validateFormat {
+	case "text":
+		err = writeIssuesText(nblc.Stdout, issues)
+	case "json":
+		err = writeIssuesJSON(nblc.Stdout, issues)
+	case "sarif":
+		err = writeIssuesSARIF(nblc.Stdout, bluePrintFilePath, issues)
+	default:
+		return 1, fmt.Errorf("unknown output format %s", *validateFormat)
+	}
+	if err != nil {
+		return 1, err
+	}
+
+	for _, issue := range issues {
+		if issue.Severity == blueprint.LintError || *validateStrict {
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func writeIssuesText(w io.Writer, issues []*blueprint.LintIssue) error {
+	nerr := 0
+	for _, issue := range issues {
+		if issue.Severity == blueprint.LintError {
+			nerr++
+		}
+		where := ""
+		if issue.ActionID != "" {
+			where = " action " + issue.ActionID + ":"
+		}
+		if _, err := fmt.Fprintf(w, "%s [%s]%s %s\n", issue.Severity, issue.Rule, where, issue.Message); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d error(s), %d warning(s)\n", nerr, len(issues)-nerr)
+	return err
+}
+
+func writeIssuesJSON(w io.Writer, issues []*blueprint.LintIssue) error {
+	if issues == nil {
+		issues = []*blueprint.LintIssue{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{"issues": issues})
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+	LogicalLocations []map[string]string `json:"logicalLocations,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []*sarifLocation  `json:"locations"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+func writeIssuesSARIF(w io.Writer, source string, issues []*blueprint.LintIssue) error {
+	var ruleIDs []string
+	for id := range blueprint.LintRules {
+		ruleIDs = append(ruleIDs, id)
+	}
+	sort.Strings(ruleIDs)
+	rules := []*sarifRule{}
+	for _, id := range ruleIDs {
+		rules = append(rules, &sarifRule{ID: id, ShortDescription: sarifMessage{Text: blueprint.LintRules[id]}})
+	}
+
+	results := []*sarifResult{}
+	for _, issue := range issues {
+		loc := &sarifLocation{}
+		loc.PhysicalLocation.ArtifactLocation.URI = source
+		res := &sarifResult{
+			RuleID:    issue.Rule,
+			Level:     string(issue.Severity),
+			Message:   sarifMessage{Text: issue.Message},
+			Locations: []*sarifLocation{loc},
+		}
+		if issue.ActionID != "" {
+			loc.LogicalLocations = []map[string]string{{"name": issue.ActionID, "kind": "object"}}
+			res.Properties = map[string]string{"action_id": issue.ActionID}
+		}
+		results = append(results, res)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool": map[string]interface{}{
+					"driver": map[string]interface{}{
+						"name":    "nebulant",
+						"version": config.Version,
+						"rules":   rules,
+					},
+				},
+				"results": results,
+			},
+		},
+	})
+}
@@ -36,10 +36,14 @@ import (
 	"github.com/develatio/nebulant-cli/cast"
 	"github.com/develatio/nebulant-cli/config"
 	"github.com/develatio/nebulant-cli/providers/aws"
+	awsactors "github.com/develatio/nebulant-cli/providers/aws/actors"
 	"github.com/develatio/nebulant-cli/providers/azure"
 	"github.com/develatio/nebulant-cli/providers/cloudflare"
+	cloudflareactors "github.com/develatio/nebulant-cli/providers/cloudflare/actors"
 	"github.com/develatio/nebulant-cli/providers/generic"
+	genericactors "github.com/develatio/nebulant-cli/providers/generic/actors"
 	"github.com/develatio/nebulant-cli/providers/hetzner"
+	hetzneractors "github.com/develatio/nebulant-cli/providers/hetzner/actors"
 	"github.com/develatio/nebulant-cli/term"
 )
 
@@ -165,6 +169,15 @@ func PrepareCmd(cmd *NBLcommand) error {
 		blueprint.ActionValidators["genericsValidator"] = generic.ActionValidator
 		blueprint.ActionValidators["hetznerValidator"] = hetzner.ActionValidator
 		blueprint.ActionValidators["cloudflareValidator"] = cloudflare.ActionValidator
+		blueprint.RegisterActions("aws", awsactors.ActionFuncMap)
+		blueprint.RegisterActions("generic", genericactors.ActionFuncMap)
+		blueprint.RegisterActions("hetznerCloud", hetzneractors.ActionFuncMap)
+		blueprint.RegisterActions("cloudflare", cloudflareactors.ActionFuncMap)
+		blueprint.ActionRehearsalFuncs["aws"] = aws.ActionRehearsal
+		blueprint.ActionRehearsalFuncs["azure"] = azure.ActionRehearsal
+		blueprint.ActionRehearsalFuncs["generic"] = generic.ActionRehearsal
+		blueprint.ActionRehearsalFuncs["hetznerCloud"] = hetzner.ActionRehearsal
+		blueprint.ActionRehearsalFuncs["cloudflare"] = cloudflare.ActionRehearsal
 	}
 
 	return nil
@@ -26,6 +26,7 @@ var EmojiSet map[string]string = map[string]string{
 	"CheckMarkButton":              "✅",
 	"CrossMarkButton":              "❎",
 	"CrossMark":                    "❌",
+	"Memo":                         "📝",
 }
 
 var noEmojiSupportSet map[string]string = map[string]string{
@@ -54,4 +55,5 @@ var noEmojiSupportSet map[string]string = map[string]string{
 	"CheckMarkButton":              "VV",
 	"CrossMarkButton":              "--",
 	"CrossMark":                    "XX",
+	"Memo":                         "  ",
 }
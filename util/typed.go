@@ -0,0 +1,275 @@
+// MIT License
+//
+// Copyright (C) 2024  Develatio Technologies S.L.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package util
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/develatio/nebulant-cli/expr"
+)
+
+// a json string value with one single {{ }} expression
+var singleExpressionRegexp = regexp.MustCompile(`^\s*{{([^{}]*)}}\s*$`)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// TypedResolver func. Native value of the expression (the text between
+// {{ and }}) for a field of type t. ok false keeps the original text
+type TypedResolver func(expression string, t reflect.Type) (value interface{}, ok bool, err error)
+
+// TypedJSON func. Returns data, a json document that is going to be
+// unmarshalled into v, with the string values that are exactly one
+// {{ expression }} replaced by the value given by resolve, when the
+// matching field of v expects no string. The value is adapted to the
+// type of the field (numbers into string lists, numeric strings into
+// numbers...)
+func TypedJSON(data []byte, v interface{}, resolve TypedResolver) ([]byte, error) {
+	if !bytes.Contains(data, []byte("{{")) {
+		return data, nil
+	}
+	var tree interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&tree); err != nil {
+		// let the unmarshal report the error
+		return data, nil
+	}
+	changed := false
+	tree, err := typedValue(tree, reflect.TypeOf(v), resolve, &changed)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return data, nil
+	}
+	return json.Marshal(tree)
+}
+
+func typedValue(v interface{}, t reflect.Type, resolve TypedResolver, changed *bool) (interface{}, error) {
+	t = indirectType(t)
+	if t == nil {
+		return v, nil
+	}
+	switch vv := v.(type) {
+	case string:
+		if !acceptsNative(t) {
+			return v, nil
+		}
+		m := singleExpressionRegexp.FindStringSubmatch(vv)
+		if m == nil {
+			return v, nil
+		}
+		nv, ok, err := resolve(strings.TrimSpace(m[1]), t)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return v, nil
+		}
+		*changed = true
+		return coerceNative(nv, t), nil
+	case map[string]interface{}:
+		for key, item := range vv {
+			nv, err := typedValue(item, fieldType(t, key), resolve, changed)
+			if err != nil {
+				return nil, err
+			}
+			vv[key] = nv
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return v, nil
+		}
+		for i, item := range vv {
+			nv, err := typedValue(item, t.Elem(), resolve, changed)
+			if err != nil {
+				return nil, err
+			}
+			vv[i] = nv
+		}
+	}
+	return v, nil
+}
+
+// TypedPlaceholder func. Value that passes the unmarshal and the
+// required validation of a field of type t, used to validate the
+// parameters before the real values are known
+func TypedPlaceholder(t reflect.Type) interface{} {
+	t = indirectType(t)
+	switch t.Kind() {
+	case reflect.Bool:
+		return true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return 1
+	case reflect.Slice, reflect.Array:
+		return []interface{}{}
+	}
+	return map[string]interface{}{}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// acceptsNative func. Strings, bytes, interfaces and types with their
+// own unmarshal keep receiving the interpolated text
+func acceptsNative(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Interface, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return false
+	case reflect.Slice:
+		// []byte is unmarshalled from base64 text
+		if t.Elem().Kind() == reflect.Uint8 {
+			return false
+		}
+	}
+	pt := reflect.PtrTo(t)
+	if pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+		return false
+	}
+	return true
+}
+
+// fieldType func. Type of the value of key inside t, matching struct
+// fields as encoding/json does. nil if unknown
+func fieldType(t reflect.Type, key string) reflect.Type {
+	t = indirectType(t)
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem()
+	case reflect.Struct:
+		if f, ok := findField(t, key, make(map[reflect.Type]bool)); ok {
+			return f.Type
+		}
+	}
+	return nil
+}
+
+func findField(t reflect.Type, key string, visited map[reflect.Type]bool) (reflect.StructField, bool) {
+	if visited[t] {
+		return reflect.StructField{}, false
+	}
+	visited[t] = true
+	var folded *reflect.StructField
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			if et := indirectType(f.Type); et.Kind() == reflect.Struct {
+				embedded = append(embedded, et)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if name == key {
+			return f, true
+		}
+		if folded == nil && strings.EqualFold(name, key) {
+			ff := f
+			folded = &ff
+		}
+	}
+	if folded != nil {
+		return *folded, true
+	}
+	for _, et := range embedded {
+		if f, ok := findField(et, key, visited); ok {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// coerceNative func. Adapt the scalars of the native value to the
+// target type: numbers into string fields, numeric strings into
+// numeric fields...
+func coerceNative(v interface{}, t reflect.Type) interface{} {
+	t = indirectType(t)
+	if t == nil {
+		return v
+	}
+	switch t.Kind() {
+	case reflect.String:
+		switch v.(type) {
+		case float64, bool, json.Number:
+			return expr.String(v)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if s, ok := v.(string); ok {
+			if _, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return json.Number(strings.TrimSpace(s))
+			}
+		}
+		if f, ok := v.(float64); ok && t.Kind() != reflect.Float32 && t.Kind() != reflect.Float64 {
+			// avoid exponents on big integers
+			return json.Number(expr.String(f))
+		}
+	case reflect.Bool:
+		if s, ok := v.(string); ok {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if list, ok := v.([]interface{}); ok {
+			for i, item := range list {
+				list[i] = coerceNative(item, t.Elem())
+			}
+		}
+	case reflect.Map, reflect.Struct:
+		if m, ok := v.(map[string]interface{}); ok {
+			for key, item := range m {
+				m[key] = coerceNative(item, fieldType(t, key))
+			}
+		}
+	}
+	return v
+}
@@ -46,6 +46,44 @@ type UIStore interface {
 	GetValueByReference(reference *string) (interface{}, error)
 }
 
+// ParametersStore interface
+type ParametersStore interface {
+	TypedInterpolation(data []byte, v interface{}) ([]byte, error)
+}
+
+// UnmarshalParameters func. json.Unmarshal of action parameters, the
+// values with one single {{ expression }} are injected with their
+// native type (lists, numbers, objects) into the non string fields.
+// store can be nil
+func UnmarshalParameters(store ParametersStore, data []byte, v interface{}) error {
+	data, err := typedParameters(store, data, v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// UnmarshalValidParameters func. As UnmarshalParameters, with the
+// validations of UnmarshalValidJSON
+func UnmarshalValidParameters(store ParametersStore, data []byte, v interface{}) error {
+	data, err := typedParameters(store, data, v)
+	if err != nil {
+		return err
+	}
+	return UnmarshalValidJSON(data, v)
+}
+
+// typedParameters func. Without store (actions validated before the
+// execution) the expressions are replaced by placeholders
+func typedParameters(store ParametersStore, data []byte, v interface{}) ([]byte, error) {
+	if store == nil {
+		return TypedJSON(data, v, func(expression string, t reflect.Type) (interface{}, bool, error) {
+			return TypedPlaceholder(t), true, nil
+		})
+	}
+	return store.TypedInterpolation(data, v)
+}
+
 // UnmarshalValidJSON func
 func UnmarshalValidJSON(data []byte, v interface{}) error {
 	jsonErr := json.Unmarshal(data, v)